@@ -0,0 +1,146 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// capturePaused is set with the admin API to forward requests without
+	// storing them, until capture is resumed.
+	capturePaused atomic.Bool
+	// proxiedRequests counts the requests forwarded since the proxy started.
+	proxiedRequests atomic.Int64
+	// inFlightRequests counts the requests being forwarded, from when they are
+	// received until their response is written.
+	inFlightRequests atomic.Int64
+)
+
+// adminListenAddr returns the address the admin API listens on, which is on
+// localhost unless another host is given, such as for ":9989".
+func adminListenAddr(addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", errors.New("--admin-addr expects host:port or :port, such as 127.0.0.1:9989")
+	}
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// newAdminHandler serves the admin API of the proxy, every request must carry
+// the token as a bearer token:
+//
+//	GET  /stats                      requests proxied, in flight and stored, and whether capture is on
+//	GET  /capture                    whether requests are stored
+//	POST /capture?enabled=true|false pauses or resumes storing requests, toggles without enabled
+//	POST /cleanup?before=YYYY-mm-dd  deletes the requests made before the time, as the cleanup command
+//	GET  /log-level                  the least severe level of the messages logged
+//	POST /log-level?level=info|warn|error
+//	                                 logs every request, only warnings and requests with warnings or
+//	                                 errors, or only failed requests
+func newAdminHandler(token string, startedAt time.Time, responseCache bool) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /stats", func(w http.ResponseWriter, r *http.Request) {
+		stored, err := persistence.CountRequests()
+		if err != nil {
+			writeAdminError(w, http.StatusInternalServerError, err)
+			return
+		}
+		stats := map[string]any{
+			"started_at":         startedAt.Format(time.RFC3339),
+			"uptime":             time.Since(startedAt).Round(time.Second).String(),
+			"capture":            !capturePaused.Load(),
+			"proxied_requests":   proxiedRequests.Load(),
+			"in_flight_requests": inFlightRequests.Load(),
+			"stored_requests":    stored,
+		}
+		if responseCache {
+			stats["response_cache"] = map[string]int64{
+				"hits":   responseCacheHits.Load(),
+				"misses": responseCacheMisses.Load(),
+			}
+		}
+		writeAdminJSON(w, stats)
+	})
+	mux.HandleFunc("GET /capture", func(w http.ResponseWriter, r *http.Request) {
+		writeAdminJSON(w, map[string]bool{"capture": !capturePaused.Load()})
+	})
+	mux.HandleFunc("POST /capture", func(w http.ResponseWriter, r *http.Request) {
+		enabled := capturePaused.Load()
+		if value := r.URL.Query().Get("enabled"); value != "" {
+			var err error
+			if enabled, err = strconv.ParseBool(value); err != nil {
+				writeAdminError(w, http.StatusBadRequest, errors.New("enabled expects true or false"))
+				return
+			}
+		}
+		if capturePaused.Swap(!enabled) == enabled {
+			logCaptureToggled(enabled)
+		}
+		writeAdminJSON(w, map[string]bool{"capture": enabled})
+	})
+	mux.HandleFunc("POST /cleanup", func(w http.ResponseWriter, r *http.Request) {
+		before := r.URL.Query().Get("before")
+		if err := checkDateTime(before); err != nil {
+			writeAdminError(w, http.StatusBadRequest, err)
+			return
+		}
+		// Requests are stored while holding loggingMutex, cleaning up at the
+		// same time would fail with the database locked.
+		loggingMutex.Lock()
+		result, err := persistence.Cleanup(before)
+		loggingMutex.Unlock()
+		if err != nil {
+			writeAdminError(w, http.StatusInternalServerError, err)
+			return
+		}
+		deleted, err := result.RowsAffected()
+		if err != nil {
+			writeAdminError(w, http.StatusInternalServerError, err)
+			return
+		}
+		logAdminCleanup(before, deleted)
+		writeAdminJSON(w, map[string]int64{"deleted": deleted})
+	})
+	mux.HandleFunc("GET /log-level", func(w http.ResponseWriter, r *http.Request) {
+		writeAdminJSON(w, map[string]string{"level": logLevel(currentLogLevel.Load()).String()})
+	})
+	mux.HandleFunc("POST /log-level", func(w http.ResponseWriter, r *http.Request) {
+		level, err := parseLogLevel(r.URL.Query().Get("level"))
+		if err != nil {
+			writeAdminError(w, http.StatusBadRequest, err)
+			return
+		}
+		if logLevel(currentLogLevel.Swap(int32(level))) != level {
+			logLogLevelChanged(level)
+		}
+		writeAdminJSON(w, map[string]string{"level": level.String()})
+	})
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bearer := []byte("Bearer " + token)
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), bearer) != 1 {
+			writeAdminError(w, http.StatusUnauthorized, errors.New("invalid admin token"))
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func writeAdminJSON(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(value)
+}
+
+func writeAdminError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/mattn/go-sqlite3"
+	"github.com/spf13/cobra"
+	"github.com/tidwall/gjson"
+)
+
+// auditCheck inspects a request and returns a description of the problem found,
+// or an empty string if the request passes the check.
+type auditCheck struct {
+	Name  string
+	Check func(request *Request) string
+}
+
+var auditChecks = []*auditCheck{
+	{
+		Name: "error",
+		Check: func(request *Request) string {
+			if request.HasError() {
+				return request.Status()
+			}
+			return ""
+		},
+	},
+	{
+		Name: "finish_reason",
+		Check: func(request *Request) string {
+			if finishReason, err := request.FinishReason(); err == nil && finishReason == "length" {
+				return "output was cut off by max_tokens"
+			}
+			return ""
+		},
+	},
+	{
+		Name: "tool_calls",
+		Check: func(request *Request) string {
+			if request.HasToolCallResponse() && !request.IsToolCall() {
+				return "tool calls returned but no tools offered"
+			}
+			return ""
+		},
+	},
+}
+
+func auditCommand() *cobra.Command {
+	var (
+		n           int64
+		chatOnly    bool
+		predicates  []string
+		checkPolicy bool
+		policyPath  string
+		toolCalls   bool
+		required    []string
+		since       string
+		until       string
+	)
+	cmd := &cobra.Command{
+		Use:         "audit",
+		Short:       "Find Moonshot AI requests with potential problems",
+		Annotations: map[string]string{databaseAnnotation: databaseRead},
+		Run: func(cmd *cobra.Command, args []string) {
+			var predicate string
+			if parsed, err := Predicates(predicates).Parse(); err != nil {
+				logFatal(fmt.Errorf("predicate: %w", err))
+			} else {
+				predicate = parsed
+			}
+			if toolCalls {
+				predicate = andConditions(predicate, toolCallCondition)
+			}
+			for _, value := range []string{since, until} {
+				if value == "" {
+					continue
+				}
+				if err := checkDateTime(value); err != nil {
+					logFatal(err)
+				}
+			}
+			for i, param := range required {
+				required[i] = strings.TrimSpace(param)
+				if required[i] == "" {
+					logFatal(errors.New("--require expects names of body parameters, such as max_tokens,temperature"))
+				}
+			}
+			checks := auditChecks
+			if checkPolicy {
+				policy, err := loadContentPolicy(policyPath)
+				if err != nil {
+					logFatal(err)
+				}
+				checks = append(slices.Clip(checks), &auditCheck{
+					Name:  "content_policy",
+					Check: policy.Check,
+				})
+			}
+			requests, err := persistence.ListRequests(n, chatOnly, predicate)
+			if err != nil {
+				if sqliteErr := new(sqlite3.Error); errors.As(err, sqliteErr) {
+					logFatal(sqliteErr)
+				}
+				logFatal(err)
+			}
+			if since != "" || until != "" {
+				// created_at is compared as text, in the same way as the queries
+				// of export --since/--until.
+				requests = slices.DeleteFunc(requests, func(request *Request) bool {
+					createdAt := request.CreatedAt.Format(time.DateTime)
+					return since != "" && createdAt < since || until != "" && createdAt >= until
+				})
+			}
+			if len(required) > 0 {
+				renderMissingParams(requests, required)
+				return
+			}
+			t.AppendHeader(table.Row{
+				"id",
+				"check",
+				"problem",
+				"chatcmpl",
+				"requested_at",
+			})
+			var problems int
+			for _, request := range requests {
+				for _, check := range checks {
+					if problem := check.Check(request); problem != "" {
+						t.AppendRow(table.Row{
+							strconv.FormatInt(request.ID, 10),
+							check.Name,
+							problem,
+							request.ChatCmpl(),
+							request.CreatedAt.Format(time.DateTime),
+						})
+						problems++
+					}
+				}
+			}
+			t.AppendFooter(table.Row{"total", strconv.Itoa(problems)})
+			t.Render()
+		},
+	}
+	flags := cmd.PersistentFlags()
+	flags.Int64VarP(&n, "n", "n", 0, "number of recent requests to audit, 0 means all")
+	flags.BoolVar(&chatOnly, "chatonly", false, "audit chat requests only")
+	flags.StringArrayVarP(&predicates, "predicate", "p", nil, "predicate is used to set the conditions for query requests")
+	flags.BoolVar(&toolCalls, "tool-calls", false, "audit requests offering tools or functions only")
+	flags.BoolVar(&checkPolicy, "check-content-policy", false, "report messages matching the phrases or patterns of the content policy")
+	flags.StringVar(&policyPath, "content-policy", defaultContentPolicyPath(), "path of the TOML file of the content policy")
+	flags.StringSliceVar(&required, "require", nil, "report the chat requests whose body omits any of the parameters, such as max_tokens,temperature, grouped by model")
+	flags.StringVar(&since, "since", "", "only audit requests made at or after this time, YYYY-mm-dd or YYYY-mm-dd HH:MM:SS")
+	flags.StringVar(&until, "until", "", "only audit requests made before this time, YYYY-mm-dd or YYYY-mm-dd HH:MM:SS")
+	cmd.MarkFlagsMutuallyExclusive("require", "check-content-policy")
+	return cmd
+}
+
+// missingParams returns the parameters of required that the body of the chat
+// request does not set, in the order they are required, nested parameters are
+// given as paths such as stream_options.include_usage. Requests whose body
+// cannot be inspected, such as truncated ones, are not reported.
+func (r *Request) missingParams(required []string) (missing []string) {
+	if !r.IsChat() || r.IsRequestBodyTruncated() || !gjson.Valid(r.RequestBody.String) {
+		return nil
+	}
+	for _, param := range required {
+		if !gjson.Get(r.RequestBody.String, param).Exists() {
+			missing = append(missing, param)
+		}
+	}
+	return missing
+}
+
+// renderMissingParams prints, for each model, the requests omitting required
+// parameters, how often each parameter is omitted and the row ids.
+func renderMissingParams(requests []*Request, required []string) {
+	type offenders struct {
+		ids     []string
+		missing map[string]int
+	}
+	var (
+		models   []string
+		byModel  = make(map[string]*offenders)
+		offended int
+	)
+	// ListRequests returns the latest request first, ids are listed in the
+	// order in which requests are made.
+	for i := len(requests) - 1; i >= 0; i-- {
+		request := requests[i]
+		missing := request.missingParams(required)
+		if len(missing) == 0 {
+			continue
+		}
+		model := request.ModelName()
+		group, ok := byModel[model]
+		if !ok {
+			group = &offenders{missing: make(map[string]int, len(required))}
+			byModel[model] = group
+			models = append(models, model)
+		}
+		group.ids = append(group.ids, strconv.FormatInt(request.ID, 10))
+		for _, param := range missing {
+			group.missing[param]++
+		}
+		offended++
+	}
+	slices.Sort(models)
+	t.AppendHeader(table.Row{"model", "requests", "missing", "ids"})
+	for _, model := range models {
+		group := byModel[model]
+		var missing []string
+		for _, param := range required {
+			if count := group.missing[param]; count > 0 {
+				missing = append(missing, param+" ("+strconv.Itoa(count)+")")
+			}
+		}
+		t.AppendRow(table.Row{model, len(group.ids), strings.Join(missing, ", "), strings.Join(group.ids, ",")})
+	}
+	t.AppendFooter(table.Row{"total", offended, "", ""})
+	t.Render()
+}
@@ -0,0 +1,548 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/mattn/go-sqlite3"
+	"github.com/spf13/cobra"
+)
+
+func browseCommand() *cobra.Command {
+	var (
+		n          int64
+		chatOnly   bool
+		predicates []string
+		key        = defaultAPIKey()
+	)
+	cmd := &cobra.Command{
+		Use:         "browse",
+		Short:       "Browse Moonshot AI requests interactively",
+		Annotations: map[string]string{databaseAnnotation: databaseRead},
+		Run: func(cmd *cobra.Command, args []string) {
+			var predicate string
+			if parsed, err := Predicates(predicates).Parse(); err != nil {
+				logFatal(fmt.Errorf("predicate: %w", err))
+			} else {
+				predicate = parsed
+			}
+			requests, err := persistence.ListRequests(n, chatOnly, predicate)
+			if err != nil {
+				if sqliteErr := new(sqlite3.Error); errors.As(err, sqliteErr) {
+					logFatal(sqliteErr)
+				}
+				logFatal(err)
+			}
+			if len(requests) == 0 {
+				logFatal(sql.ErrNoRows)
+			}
+			b := &browser{
+				requests: requests,
+				visible:  requests,
+				key:      key,
+			}
+			if err = b.run(os.Stdin, os.Stdout); err != nil {
+				logFatal(err)
+			}
+		},
+	}
+	flags := cmd.PersistentFlags()
+	flags.Int64VarP(&n, "n", "n", 200, "number of requests to load")
+	flags.BoolVar(&chatOnly, "chatonly", false, "chat only output")
+	flags.StringArrayVarP(&predicates, "predicate", "p", nil, "predicate is used to set the conditions for query requests")
+	flags.StringVarP(&key, "key", "k", key, "API key used to replay requests, defaults to $MOONSHOT_API_KEY")
+	return cmd
+}
+
+const (
+	keyUp = iota + 0x100
+	keyDown
+	keyPageUp
+	keyPageDown
+	keyEscape
+)
+
+// browserMode tells what the keys typed are used for.
+type browserMode int
+
+const (
+	modeNormal browserMode = iota
+	modeFilter
+	modeTag
+)
+
+const browserHelp = "↑/↓ select  tab focus  / filter  t tag  c category  e export  r replay  q quit"
+
+// browser shows the list of requests on the left and the details of the selected
+// request on the right.
+type browser struct {
+	requests []*Request
+	visible  []*Request
+	key      string
+
+	selected     int
+	offset       int
+	detailOffset int
+	focusDetail  bool
+	mode         browserMode
+	filter       string
+	input        string
+	message      string
+
+	width, height int
+	detailID      int64
+	detailWidth   int
+	detailLines   []string
+}
+
+func (b *browser) run(in *os.File, out *os.File) error {
+	restore, err := makeRaw(int(in.Fd()))
+	if err != nil {
+		return err
+	}
+	defer restore()
+	w := bufio.NewWriter(out)
+	// Switch to the alternate screen and hide the cursor.
+	w.WriteString("\x1b[?1049h\x1b[?25l")
+	defer func() {
+		w.WriteString("\x1b[?25h\x1b[?1049l")
+		w.Flush()
+	}()
+	var buf [32]byte
+	for {
+		if b.width, b.height, err = terminalSize(int(out.Fd())); err != nil {
+			return err
+		}
+		b.render(w)
+		if err = w.Flush(); err != nil {
+			return err
+		}
+		nRead, err := in.Read(buf[:])
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		for _, key := range parseKeys(buf[:nRead]) {
+			if quit := b.handle(key, w); quit {
+				return nil
+			}
+		}
+	}
+}
+
+// parseKeys converts the bytes read from a raw terminal to keys, escape sequences
+// of arrow and page keys are converted to the key constants.
+func parseKeys(data []byte) []rune {
+	var keys []rune
+	for len(data) > 0 {
+		switch {
+		case strings.HasPrefix(string(data), "\x1b[A"), strings.HasPrefix(string(data), "\x1bOA"):
+			keys, data = append(keys, keyUp), data[3:]
+		case strings.HasPrefix(string(data), "\x1b[B"), strings.HasPrefix(string(data), "\x1bOB"):
+			keys, data = append(keys, keyDown), data[3:]
+		case strings.HasPrefix(string(data), "\x1b[5~"):
+			keys, data = append(keys, keyPageUp), data[4:]
+		case strings.HasPrefix(string(data), "\x1b[6~"):
+			keys, data = append(keys, keyPageDown), data[4:]
+		case strings.HasPrefix(string(data), "\x1b["):
+			// Ignore other escape sequences, such as function keys.
+			end := 2
+			for end < len(data) && !('@' <= data[end] && data[end] <= '~') {
+				end++
+			}
+			data = data[min(end+1, len(data)):]
+		case data[0] == 0x1b:
+			keys, data = append(keys, keyEscape), data[1:]
+		default:
+			r, size := []rune(string(data[:runeLen(data)]))[0], runeLen(data)
+			keys, data = append(keys, r), data[size:]
+		}
+	}
+	return keys
+}
+
+func runeLen(data []byte) int {
+	for size := 1; size <= len(data) && size <= 4; size++ {
+		if r := []rune(string(data[:size])); len(r) == 1 && r[0] != '�' {
+			return size
+		}
+	}
+	return 1
+}
+
+func (b *browser) listHeight() int {
+	return max(b.height-2, 1)
+}
+
+// handle applies the key, quit is set if the browser should exit.
+func (b *browser) handle(key rune, w *bufio.Writer) (quit bool) {
+	if b.mode != modeNormal {
+		switch key {
+		case '\r', '\n':
+			if b.mode == modeTag && b.input != "" && len(b.visible) > 0 {
+				b.tag(b.visible[b.selected], strings.TrimSpace(b.input))
+			}
+			b.mode, b.input = modeNormal, ""
+		case keyEscape, 0x03:
+			if b.mode == modeFilter {
+				b.applyFilter("")
+			}
+			b.mode, b.input = modeNormal, ""
+		case 0x7f, 0x08:
+			if runes := []rune(b.input); len(runes) > 0 {
+				b.input = string(runes[:len(runes)-1])
+			}
+		default:
+			if key >= ' ' && key < keyUp {
+				b.input += string(key)
+			}
+		}
+		if b.mode == modeFilter {
+			b.applyFilter(b.input)
+		}
+		return false
+	}
+	b.message = ""
+	switch key {
+	case 'q', 0x03:
+		return true
+	case 'j', keyDown:
+		b.move(1)
+	case 'k', keyUp:
+		b.move(-1)
+	case keyPageDown, ' ':
+		b.move(b.listHeight())
+	case keyPageUp:
+		b.move(-b.listHeight())
+	case 'g':
+		b.move(-len(b.visible) - len(b.detailLines))
+	case 'G':
+		b.move(len(b.visible) + len(b.detailLines))
+	case '\t':
+		b.focusDetail = !b.focusDetail
+	case '/':
+		b.mode, b.input = modeFilter, b.filter
+	case 't':
+		if len(b.visible) > 0 {
+			b.mode, b.input = modeTag, ""
+		}
+	case 'c':
+		if len(b.visible) > 0 {
+			b.categorize(b.visible[b.selected])
+		}
+	case 'e':
+		b.export()
+	case 'r':
+		b.message = "replaying..."
+		b.render(w)
+		w.Flush()
+		b.replay()
+	}
+	return false
+}
+
+func (b *browser) move(delta int) {
+	if b.focusDetail {
+		b.detailOffset = max(min(b.detailOffset+delta, len(b.detailLines)-b.listHeight()), 0)
+		return
+	}
+	if len(b.visible) == 0 {
+		return
+	}
+	b.selected = max(min(b.selected+delta, len(b.visible)-1), 0)
+	b.detailOffset = 0
+}
+
+// applyFilter keeps requests whose ident, model, status or request body contains
+// the filter, case-insensitively.
+func (b *browser) applyFilter(filter string) {
+	b.filter = filter
+	b.selected, b.offset, b.detailOffset = 0, 0, 0
+	if filter == "" {
+		b.visible = b.requests
+		return
+	}
+	filter = strings.ToLower(filter)
+	b.visible = slices.DeleteFunc(slices.Clone(b.requests), func(request *Request) bool {
+		for _, field := range []string{
+			request.Ident(),
+			request.ModelName(),
+			request.Status(),
+			request.RequestBody.String,
+		} {
+			if strings.Contains(strings.ToLower(field), filter) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func (b *browser) tag(request *Request, tag string) {
+	if err := checkWritable("tag requests"); err != nil {
+		b.message = err.Error()
+		return
+	}
+	if !request.Tags.Add(tag) {
+		return
+	}
+	if err := persistence.SetTags(request.ID, request.Tags); err != nil {
+		b.message = err.Error()
+		return
+	}
+	b.detailLines = nil
+	b.message = fmt.Sprintf("tagged #%d with %q", request.ID, tag)
+}
+
+// browserCategories are cycled through by the c key, the empty category unsets
+// the category.
+var browserCategories = []string{"", "goodcase", "badcase"}
+
+func (b *browser) categorize(request *Request) {
+	if err := checkWritable("categorize requests"); err != nil {
+		b.message = err.Error()
+		return
+	}
+	next := browserCategories[(slices.Index(browserCategories, request.Category.String)+1)%len(browserCategories)]
+	category := sql.NullString{String: next, Valid: next != ""}
+	if err := persistence.SetCategory(request.ID, category); err != nil {
+		b.message = err.Error()
+		return
+	}
+	request.Category = category
+	b.detailLines = nil
+	if next == "" {
+		b.message = fmt.Sprintf("unset the category of #%d", request.ID)
+	} else {
+		b.message = fmt.Sprintf("categorized #%d as %s", request.ID, next)
+	}
+}
+
+func (b *browser) export() {
+	if len(b.visible) == 0 {
+		return
+	}
+	request := b.visible[b.selected]
+	filename := genFilename(request)
+	file, err := os.Create(filename)
+	if err != nil {
+		b.message = err.Error()
+		return
+	}
+	defer file.Close()
+	if err = encodeRequest(file, request, false); err != nil {
+		b.message = err.Error()
+		return
+	}
+	b.message = "exported to " + filename
+}
+
+// replay sends the selected request again and stores the result as a new row
+// whose parent_id is the selected request.
+func (b *browser) replay() {
+	if len(b.visible) == 0 {
+		return
+	}
+	request := b.visible[b.selected]
+	if err := checkWritable("store replayed requests"); err != nil {
+		b.message = err.Error()
+		return
+	}
+	if request.IsRequestBodyTruncated() {
+		b.message = "request body is truncated, unable to replay " + request.Ident()
+		return
+	}
+	body := []byte(request.RequestBody.String)
+	createdAt := time.Now()
+	newRequest, response, err := sendReplay(request, body, b.key, "")
+	if err != nil {
+		b.message = err.Error()
+		return
+	}
+	defer response.Body.Close()
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		b.message = err.Error()
+		return
+	}
+	result := newReplayResult(request, newRequest, body, response, string(responseBody))
+	result.CreatedAt = SqliteTime{createdAt}
+	result.Latency = sql.NullInt64{Int64: int64(time.Since(createdAt)), Valid: true}
+	if result.ID, err = persistence.InsertRequest(result); err != nil {
+		b.message = err.Error()
+		return
+	}
+	if result.ResponseContentType.String == "text/event-stream" {
+		result.ResponseBody.String = mergeCompletion(result.ResponseBody.String)
+	}
+	b.requests = append([]*Request{result}, b.requests...)
+	b.applyFilter(b.filter)
+	b.message = fmt.Sprintf("replayed #%d as #%d, %s", request.ID, result.ID, response.Status)
+}
+
+func (b *browser) render(w *bufio.Writer) {
+	w.WriteString("\x1b[H")
+	if b.width < 40 || b.height < 5 {
+		w.WriteString("\x1b[2Jterminal is too small")
+		return
+	}
+	listWidth := min(48, b.width*2/5)
+	detailWidth := b.width - listWidth - 1
+	height := b.listHeight()
+	if b.selected < b.offset {
+		b.offset = b.selected
+	} else if b.selected >= b.offset+height {
+		b.offset = b.selected - height + 1
+	}
+	title := fmt.Sprintf(" MoonPalace  %d/%d requests", len(b.visible), len(b.requests))
+	if b.filter != "" {
+		title += "  filter: " + b.filter
+	}
+	writeLine(w, "\x1b[7m"+runewidth.FillRight(runewidth.Truncate(title, b.width, ""), b.width)+"\x1b[0m")
+	var detail []string
+	if len(b.visible) > 0 {
+		detail = b.details(b.visible[b.selected], detailWidth)
+	}
+	for i := 0; i < height; i++ {
+		var left string
+		if index := b.offset + i; index < len(b.visible) {
+			left = runewidth.FillRight(runewidth.Truncate(b.summary(b.visible[index]), listWidth, "…"), listWidth)
+			if index == b.selected {
+				if b.focusDetail {
+					left = "\x1b[1m" + left + "\x1b[0m"
+				} else {
+					left = "\x1b[7m" + left + "\x1b[0m"
+				}
+			}
+		} else {
+			left = strings.Repeat(" ", listWidth)
+		}
+		var right string
+		if index := b.detailOffset + i; index < len(detail) {
+			right = detail[index]
+		}
+		writeLine(w, left+"│"+right)
+	}
+	var status string
+	switch b.mode {
+	case modeFilter:
+		status = "/" + b.input
+	case modeTag:
+		status = "tag: " + b.input
+	default:
+		status = b.message
+		if status == "" {
+			status = browserHelp
+		}
+	}
+	w.WriteString("\x1b[7m" + runewidth.FillRight(runewidth.Truncate(status, b.width, ""), b.width) + "\x1b[0m")
+}
+
+func writeLine(w *bufio.Writer, line string) {
+	w.WriteString(line)
+	// Clear the rest of the line and move to the next one.
+	w.WriteString("\x1b[K\r\n")
+}
+
+func (b *browser) summary(request *Request) string {
+	status := "---"
+	if request.ResponseStatusCode.Valid {
+		status = strconv.FormatInt(request.ResponseStatusCode.Int64, 10)
+	}
+	summary := fmt.Sprintf("#%-5d %s %s %s",
+		request.ID,
+		status,
+		request.CreatedAt.Format("01-02 15:04:05"),
+		request.ModelName(),
+	)
+	if len(request.Tags) > 0 {
+		summary += " [" + strings.Join(request.Tags, ",") + "]"
+	}
+	return summary
+}
+
+// details returns the lines of the metadata, request and response of the request
+// wrapped to the width, lines are cached until another request is selected.
+func (b *browser) details(request *Request, width int) []string {
+	if b.detailID == request.ID && b.detailWidth == width && b.detailLines != nil {
+		return b.detailLines
+	}
+	var text strings.Builder
+	metadata := request.Metadata()
+	names := make([]string, 0, len(metadata))
+	for name := range metadata {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	text.WriteString("METADATA\n")
+	for _, name := range names {
+		text.WriteString(name + ": " + metadata[name] + "\n")
+	}
+	if request.Category.Valid {
+		text.WriteString("category: " + request.Category.String + "\n")
+	}
+	if len(request.Tags) > 0 {
+		text.WriteString("tags: " + strings.Join(request.Tags, ", ") + "\n")
+	}
+	text.WriteString("\nREQUEST\n")
+	text.WriteString(request.RequestMethod + " " + request.Url() + "\n")
+	text.WriteString(request.RequestHeader.String + "\n")
+	text.WriteString(formatJSON(request.RequestBody.String) + "\n")
+	text.WriteString("\nRESPONSE\n")
+	text.WriteString(request.Status() + "\n")
+	text.WriteString(request.ResponseHeader.String + "\n")
+	text.WriteString(formatJSON(request.ResponseBody.String) + "\n")
+	if request.Error.Valid && request.Error.String != "" {
+		text.WriteString("\nERROR\n" + request.Error.String + "\n")
+	}
+	var lines []string
+	for _, line := range strings.Split(sanitizeLine(text.String()), "\n") {
+		lines = append(lines, wrapLine(line, width)...)
+	}
+	b.detailID, b.detailWidth, b.detailLines = request.ID, width, lines
+	return lines
+}
+
+// sanitizeLine expands tabs and drops control characters, which would break the
+// layout of the terminal, newlines are kept.
+func sanitizeLine(s string) string {
+	s = strings.ReplaceAll(s, "\t", "    ")
+	return strings.Map(func(r rune) rune {
+		if r != '\n' && (r < ' ' || r == 0x7f) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+func wrapLine(line string, width int) []string {
+	if runewidth.StringWidth(line) <= width {
+		return []string{line}
+	}
+	var (
+		lines   []string
+		current strings.Builder
+		used    int
+	)
+	for _, r := range line {
+		if rw := runewidth.RuneWidth(r); used+rw > width {
+			lines = append(lines, current.String())
+			current.Reset()
+			used = 0
+		}
+		current.WriteRune(r)
+		used += runewidth.RuneWidth(r)
+	}
+	return append(lines, current.String())
+}
@@ -0,0 +1,121 @@
+// Package canonical rewrites JSON documents in a canonical form, so that
+// semantically equal documents are byte-for-byte equal: keys of objects are
+// sorted, numbers are formatted in their shortest form and HTML characters are
+// not escaped.
+package canonical
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidJSON = errors.New("canonical: invalid JSON")
+
+// Compact returns the canonical form of data without insignificant spaces.
+func Compact(data []byte) ([]byte, error) {
+	return Indent(data, "", "")
+}
+
+// Indent returns the canonical form of data, indented as json.MarshalIndent.
+func Indent(data []byte, prefix, indent string) ([]byte, error) {
+	if !json.Valid(data) {
+		return nil, ErrInvalidJSON
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent(prefix, indent)
+	// Maps are encoded with sorted keys by encoding/json.
+	if err := encoder.Encode(normalize(value)); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+func normalize(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			v[key] = normalize(field)
+		}
+	case []any:
+		for i, element := range v {
+			v[i] = normalize(element)
+		}
+	case json.Number:
+		return Number(v)
+	}
+	return value
+}
+
+// maxExactInteger is the maximum integer that float64 represents exactly.
+const maxExactInteger = 1 << 53
+
+// Number formats n in its shortest form, such as 1 for 1.0 or 1.00 and 100 for
+// 1e2, numbers whose shortest form as a float64 is not the same decimal value,
+// such as integers out of the range of int64 or decimals with more digits than
+// a float64 holds, are kept as is to avoid losing precision.
+func Number(n json.Number) json.Number {
+	if i, err := strconv.ParseInt(string(n), 10, 64); err == nil {
+		return json.Number(strconv.FormatInt(i, 10))
+	} else if !strings.ContainsAny(string(n), ".eE") {
+		return n
+	}
+	f, err := strconv.ParseFloat(string(n), 64)
+	if err != nil {
+		return n
+	}
+	var shortest string
+	if f == 0 {
+		// Without the sign of negative zero, as for integers.
+		shortest = "0"
+	} else if f == math.Trunc(f) && math.Abs(f) < maxExactInteger {
+		shortest = strconv.FormatFloat(f, 'f', -1, 64)
+	} else {
+		shortest = strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	if !sameDecimal(string(n), shortest) {
+		return n
+	}
+	return json.Number(shortest)
+}
+
+// sameDecimal reports whether the numbers a and b, in the syntax of JSON, are
+// the same decimal value.
+func sameDecimal(a, b string) bool {
+	aNeg, aDigits, aExp := decimal(a)
+	bNeg, bDigits, bExp := decimal(b)
+	if aDigits == "" || bDigits == "" {
+		// Zero, whatever its sign.
+		return aDigits == bDigits
+	}
+	return aNeg == bNeg && aDigits == bDigits && aExp == bExp
+}
+
+// decimal returns the significant digits of the number n, without leading and
+// trailing zeros, and exp such that n is 0.digits times 10 to the power of exp.
+func decimal(n string) (negative bool, digits string, exp int) {
+	if strings.HasPrefix(n, "-") {
+		negative, n = true, n[1:]
+	}
+	if i := strings.IndexAny(n, "eE"); i >= 0 {
+		exp, _ = strconv.Atoi(strings.TrimPrefix(n[i+1:], "+"))
+		n = n[:i]
+	}
+	integer, fraction, _ := strings.Cut(n, ".")
+	exp += len(integer)
+	digits = integer + fraction
+	trimmed := strings.TrimLeft(digits, "0")
+	exp -= len(digits) - len(trimmed)
+	return negative, strings.TrimRight(trimmed, "0"), exp
+}
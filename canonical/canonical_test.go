@@ -0,0 +1,77 @@
+package canonical
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+)
+
+func TestCompact(t *testing.T) {
+	type testcase struct {
+		a, b string
+		want string
+	}
+	var testcases = []testcase{
+		{
+			a:    `{"model":"moonshot-v1-8k","temperature":0.3,"messages":[{"role":"user","content":"hi"}]}`,
+			b:    `{"messages":[{"content":"hi","role":"user"}],"temperature":0.30,"model":"moonshot-v1-8k"}`,
+			want: `{"messages":[{"content":"hi","role":"user"}],"model":"moonshot-v1-8k","temperature":0.3}`,
+		},
+		{
+			a:    `{"b": {"d": 1, "c": [3, 2, 1]}, "a": null}`,
+			b:    "{\n  \"a\": null,\n  \"b\": {\"c\": [3, 2, 1], \"d\": 1.0}\n}",
+			want: `{"a":null,"b":{"c":[3,2,1],"d":1}}`,
+		},
+		{
+			a:    `{"max_tokens":1e3,"text":"<tag> & &"}`,
+			b:    `{"text":"<tag> & &","max_tokens":1000}`,
+			want: `{"max_tokens":1000,"text":"<tag> & &"}`,
+		},
+	}
+	for i, tc := range testcases {
+		t.Run(strconv.Itoa(i+1), func(t *testing.T) {
+			a, err := Compact([]byte(tc.a))
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := Compact([]byte(tc.b))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(a) != string(b) {
+				t.Errorf("canonical forms differ:\na: %s\nb: %s", a, b)
+			}
+			if string(a) != tc.want {
+				t.Errorf("canonical form of %s: \nwant: %s\ngot:  %s", tc.a, tc.want, a)
+			}
+		})
+	}
+	if _, err := Compact([]byte(`{"a":`)); err != ErrInvalidJSON {
+		t.Errorf("expects ErrInvalidJSON for invalid JSON, got %v", err)
+	}
+}
+
+func TestNumber(t *testing.T) {
+	var testcases = map[string]string{
+		"0":                       "0",
+		"-0":                      "0",
+		"1.0":                     "1",
+		"1.50":                    "1.5",
+		"1e2":                     "100",
+		"-2.5E-3":                 "-0.0025",
+		"1e21":                    "1e+21",
+		"12345678901234567890":    "12345678901234567890",
+		"0.30000000000000004":     "0.30000000000000004",
+		"1.000000000000000000001": "1.000000000000000000001",
+		"12345678901234567.0":     "12345678901234567.0",
+		"1e400":                   "1e400",
+		"-0.0":                    "0",
+		"0.00e5":                  "0",
+		"1234.5e-2":               "12.345",
+	}
+	for n, want := range testcases {
+		if got := Number(json.Number(n)); string(got) != want {
+			t.Errorf("Number(%s): want %s, got %s", n, want, got)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os/exec"
+	"runtime"
+)
+
+// clipboardCommands lists the commands that read stdin into the system clipboard,
+// the first one found in PATH is used.
+var clipboardCommands = map[string][][]string{
+	"darwin":  {{"pbcopy"}},
+	"windows": {{"clip"}},
+	"linux": {
+		{"wl-copy"},
+		{"xclip", "-selection", "clipboard"},
+		{"xsel", "--clipboard", "--input"},
+	},
+}
+
+var errNoClipboard = errors.New("no clipboard available, none of pbcopy/clip/wl-copy/xclip/xsel is found")
+
+func writeClipboard(data []byte) error {
+	for _, command := range clipboardCommands[runtime.GOOS] {
+		path, err := exec.LookPath(command[0])
+		if err != nil {
+			continue
+		}
+		cmd := exec.Command(path, command[1:]...)
+		cmd.Stdin = bytes.NewReader(data)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			if len(output) > 0 {
+				return errors.New(command[0] + ": " + string(bytes.TrimSpace(output)))
+			}
+			return errors.New(command[0] + ": " + err.Error())
+		}
+		return nil
+	}
+	return errNoClipboard
+}
+
+// openClipboard returns a writer whose content is copied to the clipboard when
+// closeFunc is called.
+func openClipboard() (w io.Writer, closeFunc func()) {
+	buf := new(bytes.Buffer)
+	return buf, func() {
+		if err := writeClipboard(buf.Bytes()); err != nil {
+			logFatal(err)
+		}
+		logger.Println("copied to clipboard")
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MoonshotAI/moonpalace/diff"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+)
+
+func compareCommand() *cobra.Command {
+	var (
+		dbA, dbB string
+		chatOnly bool
+		details  bool
+		showDiff bool
+	)
+	cmd := &cobra.Command{
+		Use:   "compare",
+		Short: "Compare the responses to the same requests captured in two databases",
+		Run: func(cmd *cobra.Command, args []string) {
+			requestsA, err := loadComparedRequests(dbA, chatOnly)
+			if err != nil {
+				logFatal(err)
+			}
+			requestsB, err := loadComparedRequests(dbB, chatOnly)
+			if err != nil {
+				logFatal(err)
+			}
+			pairs, onlyA, onlyB := matchRequests(requestsA, requestsB)
+			renderComparison(len(requestsA), len(requestsB), pairs, onlyA, onlyB)
+			if details && len(pairs) > 0 {
+				fmt.Println()
+				renderComparedPairs(pairs)
+			}
+			if showDiff {
+				for _, pair := range pairs {
+					a, b := pair.a.comparedResponse(), pair.b.comparedResponse()
+					if a == b {
+						continue
+					}
+					fmt.Printf("\n%s %s\n", boldWhite("body hash"), pair.hash)
+					writeColoredDiff(os.Stdout, diff.Unified(
+						"a/#"+strconv.FormatInt(pair.a.ID, 10),
+						"b/#"+strconv.FormatInt(pair.b.ID, 10),
+						strings.Split(a, "\n"),
+						strings.Split(b, "\n"),
+						1,
+					))
+				}
+			}
+		},
+	}
+	flags := cmd.PersistentFlags()
+	flags.StringVar(&dbA, "db-a", "", "database captured before, such as before.sqlite")
+	flags.StringVar(&dbB, "db-b", "", "database captured after, such as after.sqlite")
+	flags.BoolVar(&chatOnly, "chatonly", false, "only compare chat completions requests")
+	flags.BoolVar(&details, "details", false, "print a row for each matched pair of requests")
+	flags.BoolVar(&showDiff, "diff", false, "print the difference of the responses of each matched pair whose responses differ")
+	cmd.MarkPersistentFlagRequired("db-a")
+	cmd.MarkPersistentFlagRequired("db-b")
+	cmd.MarkPersistentFlagFilename("db-a")
+	cmd.MarkPersistentFlagFilename("db-b")
+	return cmd
+}
+
+// loadComparedRequests loads all the requests of the database, which is opened
+// read-only so that it is neither created nor migrated.
+func loadComparedRequests(path string, chatOnly bool) ([]*Request, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	db := NewPersistence(sqlDriver, "file:"+path+"?mode=ro")
+	requests, err := db.GetRequestsByRange(0, 0, "", nil, "", "", "", nil, nil, false, false, false, "", "", 0)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if chatOnly {
+		requests = slices.DeleteFunc(requests, func(request *Request) bool {
+			return !request.IsChat()
+		})
+	}
+	for _, request := range requests {
+		if request.ResponseContentType.String == "text/event-stream" {
+			request.ResponseBody.String = mergeCompletion(request.ResponseBody.String)
+		}
+	}
+	return requests, nil
+}
+
+// comparedPair is a request of each database with the same body.
+type comparedPair struct {
+	hash string
+	a, b *Request
+}
+
+// matchRequests pairs the requests with the same body hash, requests sent
+// several times with the same body are paired in the order they are made.
+func matchRequests(requestsA, requestsB []*Request) (pairs []*comparedPair, onlyA, onlyB int) {
+	byHash := make(map[string][]*Request)
+	for _, request := range requestsB {
+		hash := request.BodyHash()
+		if hash == "" {
+			// Truncated bodies, whose hash is unknown, match no request.
+			onlyB++
+			continue
+		}
+		byHash[hash] = append(byHash[hash], request)
+	}
+	for _, request := range requestsA {
+		hash := request.BodyHash()
+		if matched := byHash[hash]; hash != "" && len(matched) > 0 {
+			pairs = append(pairs, &comparedPair{hash: hash, a: request, b: matched[0]})
+			byHash[hash] = matched[1:]
+		} else {
+			onlyA++
+		}
+	}
+	for _, unmatched := range byHash {
+		onlyB += len(unmatched)
+	}
+	return pairs, onlyA, onlyB
+}
+
+// comparedResponse returns the part of the response compared, which is the
+// status with the assistant reply and the finish reason of chat completions, or
+// with the body otherwise, as ids and timestamps of responses always differ.
+func (r *Request) comparedResponse() string {
+	if reply, err := r.AssistantReply(); err == nil {
+		finishReason, _ := r.FinishReason()
+		return "[" + r.Status() + "]\n" + reply + "\n[finish_reason: " + finishReason + "]"
+	}
+	return "[" + r.Status() + "]\n" + r.ResponseBody.String + r.Error.String
+}
+
+// renderComparison prints the number of requests of each database, and the
+// error rates and latency percentiles of the matched requests.
+func renderComparison(nA, nB int, pairs []*comparedPair, onlyA, onlyB int) {
+	var (
+		errorsA, errorsB   int
+		latencyA, latencyB []time.Duration
+		differing          int
+	)
+	for _, pair := range pairs {
+		if pair.a.HasError() {
+			errorsA++
+		}
+		if pair.b.HasError() {
+			errorsB++
+		}
+		if pair.a.Latency.Valid && pair.b.Latency.Valid {
+			latencyA = append(latencyA, time.Duration(pair.a.Latency.Int64))
+			latencyB = append(latencyB, time.Duration(pair.b.Latency.Int64))
+		}
+		if pair.a.comparedResponse() != pair.b.comparedResponse() {
+			differing++
+		}
+	}
+	slices.Sort(latencyA)
+	slices.Sort(latencyB)
+	t.AppendHeader(table.Row{"", "a", "b", "delta"})
+	t.AppendRows([]table.Row{
+		{"requests", nA, nB, formatSigned(strconv.Itoa(nB - nA))},
+		{"matched", len(pairs), len(pairs), ""},
+		{"unmatched", onlyA, onlyB, ""},
+		{
+			"error rate",
+			formatPercent(errorsA, len(pairs)),
+			formatPercent(errorsB, len(pairs)),
+			formatSigned(formatPercent(errorsB-errorsA, len(pairs))),
+		},
+	})
+	if len(latencyA) > 0 {
+		for _, p := range latencyPercentiles {
+			latencyPA, latencyPB := percentileOf(latencyA, p), percentileOf(latencyB, p)
+			t.AppendRow(table.Row{
+				"p" + strconv.Itoa(p) + " latency",
+				formatSeconds(latencyPA),
+				formatSeconds(latencyPB),
+				formatSigned(formatSeconds(latencyPB - latencyPA)),
+			})
+		}
+	}
+	t.AppendFooter(table.Row{"differing responses", "", "", strconv.Itoa(differing) + " (" + formatPercent(differing, len(pairs)) + ")"})
+	t.Render()
+}
+
+func renderComparedPairs(pairs []*comparedPair) {
+	pairsTable := table.NewWriter()
+	pairsTable.SetOutputMirror(os.Stdout)
+	pairsTable.SetStyle(style)
+	pairsTable.AppendHeader(table.Row{"hash", "a", "b", "status", "latency", "response"})
+	for _, pair := range pairs {
+		status := pair.a.Status()
+		if pair.b.Status() != status {
+			status += " -> " + pair.b.Status()
+		}
+		latency := "-"
+		if pair.a.Latency.Valid && pair.b.Latency.Valid {
+			latency = formatSigned(formatSeconds(time.Duration(pair.b.Latency.Int64 - pair.a.Latency.Int64)))
+		}
+		response := green("same")
+		if pair.a.comparedResponse() != pair.b.comparedResponse() {
+			response = red("differs")
+		}
+		pairsTable.AppendRow(table.Row{pair.hash[:12], pair.a.ID, pair.b.ID, status, latency, response})
+	}
+	pairsTable.Render()
+}
+
+func formatPercent(n, total int) string {
+	if total == 0 {
+		return "-"
+	}
+	return strconv.FormatFloat(float64(n)/float64(total)*100, 'f', 1, 64) + "%"
+}
+
+func formatSeconds(latency time.Duration) string {
+	return strconv.FormatFloat(latency.Seconds(), 'f', 3, 64) + "s"
+}
+
+// formatSigned prefixes positive deltas with a plus sign.
+func formatSigned(delta string) string {
+	if delta != "-" && !strings.HasPrefix(delta, "-") && strings.Trim(delta, "0.%s") != "" {
+		return "+" + delta
+	}
+	return delta
+}
@@ -0,0 +1,199 @@
+package diff
+
+import (
+	"strconv"
+	"strings"
+)
+
+type Op int
+
+const (
+	Equal Op = iota
+	Delete
+	Insert
+)
+
+func (op Op) Prefix() string {
+	switch op {
+	case Delete:
+		return "-"
+	case Insert:
+		return "+"
+	default:
+		return " "
+	}
+}
+
+type Line struct {
+	Op   Op
+	Text string
+}
+
+// Lines computes the shortest edit script that turns a into b, using the
+// O(ND) algorithm described by Eugene W. Myers.
+func Lines(a, b []string) []*Line {
+	var (
+		n, m   = len(a), len(b)
+		max    = n + m
+		offset = max + 1
+		v      = make([]int, 2*max+3)
+		trace  [][]int
+	)
+SEARCH:
+	for d := 0; d <= max; d++ {
+		snapshot := make([]int, len(v))
+		copy(snapshot, v)
+		trace = append(trace, snapshot)
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break SEARCH
+			}
+		}
+	}
+	var (
+		lines = make([]*Line, 0, max)
+		x, y  = n, m
+	)
+	for d := len(trace) - 1; d >= 0; d-- {
+		var (
+			v     = trace[d]
+			k     = x - y
+			prevK int
+		)
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			lines = append(lines, &Line{Op: Equal, Text: a[x]})
+		}
+		if d > 0 {
+			if x == prevX {
+				lines = append(lines, &Line{Op: Insert, Text: b[prevY]})
+			} else {
+				lines = append(lines, &Line{Op: Delete, Text: a[prevX]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+	return lines
+}
+
+type Hunk struct {
+	FromLine, FromCount int
+	ToLine, ToCount     int
+	Lines               []*Line
+}
+
+func (h *Hunk) Header() string {
+	return "@@ -" + hunkRange(h.FromLine, h.FromCount) + " +" + hunkRange(h.ToLine, h.ToCount) + " @@"
+}
+
+func hunkRange(line, count int) string {
+	if count == 1 {
+		return strconv.Itoa(line)
+	}
+	if count == 0 {
+		// An empty range starts at the line before, as GNU diff does.
+		line--
+	}
+	return strconv.Itoa(line) + "," + strconv.Itoa(count)
+}
+
+// Hunks groups changed lines into hunks, each change is surrounded by at most
+// context unchanged lines, and no hunk is produced if nothing has changed.
+func Hunks(lines []*Line, context int) []*Hunk {
+	var (
+		hunks      []*Hunk
+		hunk       *Hunk
+		fromLine   = 1
+		toLine     = 1
+		lastChange = -1
+	)
+	nextChanges := make([]int, len(lines))
+	for i, next := len(lines)-1, -1; i >= 0; i-- {
+		if lines[i].Op != Equal {
+			next = i
+		}
+		nextChanges[i] = next
+	}
+	for i, line := range lines {
+		if line.Op != Equal {
+			lastChange = i
+		}
+		inHunk := hunk != nil
+		if line.Op == Equal {
+			next := nextChanges[i]
+			nearPrev := lastChange >= 0 && i-lastChange <= context
+			nearNext := next >= 0 && next-i <= context
+			if !nearPrev && !nearNext {
+				if inHunk {
+					hunks = append(hunks, hunk)
+					hunk = nil
+				}
+				fromLine++
+				toLine++
+				continue
+			}
+		}
+		if !inHunk {
+			hunk = &Hunk{FromLine: fromLine, ToLine: toLine}
+		}
+		hunk.Lines = append(hunk.Lines, line)
+		switch line.Op {
+		case Equal:
+			hunk.FromCount++
+			hunk.ToCount++
+			fromLine++
+			toLine++
+		case Delete:
+			hunk.FromCount++
+			fromLine++
+		case Insert:
+			hunk.ToCount++
+			toLine++
+		}
+	}
+	if hunk != nil {
+		hunks = append(hunks, hunk)
+	}
+	return hunks
+}
+
+// Unified formats the difference between a and b in the unified format.
+func Unified(fromFile, toFile string, a, b []string, context int) string {
+	hunks := Hunks(Lines(a, b), context)
+	if len(hunks) == 0 {
+		return ""
+	}
+	var builder strings.Builder
+	builder.WriteString("--- " + fromFile + "\n")
+	builder.WriteString("+++ " + toFile + "\n")
+	for _, hunk := range hunks {
+		builder.WriteString(hunk.Header() + "\n")
+		for _, line := range hunk.Lines {
+			builder.WriteString(line.Op.Prefix() + line.Text + "\n")
+		}
+	}
+	return builder.String()
+}
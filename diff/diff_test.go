@@ -0,0 +1,68 @@
+package diff
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestLines(t *testing.T) {
+	type testcase struct {
+		a, b string
+		want string
+	}
+	var testcases = []testcase{
+		{a: "", b: "", want: ""},
+		{a: "a b c", b: "a b c", want: "=a =b =c"},
+		{a: "", b: "a b", want: "+a +b"},
+		{a: "a b", b: "", want: "-a -b"},
+		{a: "a b c", b: "a x c", want: "=a -b +x =c"},
+		{a: "a b c a b b a", b: "c b a b a c", want: "-a -b =c +b =a =b -b =a +c"},
+	}
+	for i, tc := range testcases {
+		t.Run(strconv.Itoa(i+1), func(t *testing.T) {
+			lines := Lines(strings.Fields(tc.a), strings.Fields(tc.b))
+			got := make([]string, 0, len(lines))
+			for _, line := range lines {
+				if line.Op == Equal {
+					got = append(got, "="+line.Text)
+				} else {
+					got = append(got, line.Op.Prefix()+line.Text)
+				}
+			}
+			if strings.Join(got, " ") != tc.want {
+				t.Errorf("diff %q and %q: \nwant: %s\ngot:  %s", tc.a, tc.b, tc.want, strings.Join(got, " "))
+			}
+		})
+	}
+}
+
+func TestUnified(t *testing.T) {
+	var (
+		a = strings.Split("1 2 3 4 5 6 7 8 9 10 11 12", " ")
+		b = strings.Split("1 2 x 4 5 6 7 8 9 10 11 y", " ")
+	)
+	want := `--- a
++++ b
+@@ -1,6 +1,6 @@
+ 1
+ 2
+-3
++x
+ 4
+ 5
+ 6
+@@ -9,4 +9,4 @@
+ 9
+ 10
+ 11
+-12
++y
+`
+	if got := Unified("a", "b", a, b, 3); got != want {
+		t.Errorf("unified diff: \nwant:\n%s\ngot:\n%s", want, got)
+	}
+	if got := Unified("a", "b", a, a, 3); got != "" {
+		t.Errorf("unified diff of identical input, expects empty, got:\n%s", got)
+	}
+}
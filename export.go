@@ -2,16 +2,32 @@ package main
 
 import (
 	"bufio"
+	"cmp"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
-	"net/textproto"
+	"math"
+	"math/rand"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/MoonshotAI/moonpalace/jsonpointer"
+	"github.com/jedib0t/go-pretty/v6/table"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
 )
 
 func exportCommand() *cobra.Command {
@@ -19,70 +35,787 @@ func exportCommand() *cobra.Command {
 		id                int64
 		chatcmpl          string
 		requestID         string
+		chatcmplFile      string
+		uidFile           string
+		chatcmplRegex     string
 		output            string
 		directory         string
 		escapeHTML        bool
 		goodCase, badCase bool
 		tags              []string
 		curl              bool
+		contentType       string
+		apiKeyEnv         string
+		baseURLEnv        string
+		diffAgainst       string
+		minTokens         int64
+		maxTokens         int64
+		estimateTokens    bool
+		idRange           string
+		uid               string
+		merge             bool
+		indent            int
+		dryRun            bool
+		format            string
+		splitBy           []string
+		anonymizeUIDs     bool
+		toClipboard       bool
+		modelFamily       string
+		excludedModels    []string
+		excludedHeaders   []string
+		validateSchema    bool
+		strictSchema      bool
+		playgroundURL     string
+		finishReasons     []string
+		atTime            string
+		since             string
+		until             string
+		filterUIDs        []string
+		pathPrefix        string
+		filterTagsAny     []string
+		filterTagsAll     []string
+		categoryUnset     bool
+		hasSystemPrompt   bool
+		noSystemPrompt    bool
+		hashPrefix        string
+		promptContains    []string
+		follow            bool
+		minConversation   int
+		maxConversation   int
+		limit             int64
+		afterID           int64
+		afterChatcmpl     string
+		normalizeJSON     bool
+		prettyHeaders     bool
+		canonicalHeaders  bool
+		assertContent     bool
+		injectTurn        string
+		completionPrefix  string
+		extractPointer    string
+		extractFrom       string
+		binaryMode        string
+		durationMin       time.Duration
+		bodySizeMin       int64
+		bodySizeMax       int64
+		durationMax       time.Duration
+		stripBase64       bool
+		hashBase64        bool
+		stripTools        bool
+		stripParts        bool
+		hideReasoning     bool
+		sample            int
+		trainTestSplit    float64
+		seed              int64
+		tokensPerFile     int64
 	)
 	cmd := &cobra.Command{
-		Use:   "export",
-		Short: "Export a Moonshot AI request",
+		Use:         "export",
+		Short:       "Export a Moonshot AI request",
+		Annotations: map[string]string{databaseAnnotation: databaseRead},
 		Run: func(cmd *cobra.Command, args []string) {
-			request, err := persistence.GetRequest(id, chatcmpl, requestID)
-			if err != nil {
-				if errors.Is(err, sql.ErrNoRows) {
-					logFatal(sql.ErrNoRows)
+			for _, path := range []string{output, directory} {
+				if err := checkObjectURL(path); err != nil {
+					logFatal(err)
 				}
-				logFatal(err)
 			}
-			if curl {
-				if err := writeCurlCommand(os.Stdout, request); err != nil {
+			if follow {
+				if chatcmpl == "" {
+					logFatal(errors.New("--follow requires --chatcmpl"))
+				}
+				if isS3URL(output) {
+					logFatal(errors.New("--follow writes the response as it grows, which is not supported with S3"))
+				}
+				outputStream, closeOutput := openOutput(output)
+				defer closeOutput()
+				if err := followResponse(outputStream, chatcmpl); err != nil {
 					logFatal(err)
 				}
 				return
 			}
-			if request.IsChat() {
+			var (
+				requests []*Request
+				encode   func(io.Writer, *Request, bool) error
+				filename func(*Request) string
+				// bucketed is set if multiple requests are written to the same file
+				// when exporting to a directory.
+				bucketed bool
+				// splitSets maps each request to "train" or "test" with
+				// --train-test-split.
+				splitSets map[*Request]string
+			)
+			if !cmd.Flags().Changed("format") && !merge && !curl && directory == "" && output != "stdout" {
+				if inferred, ok := formatOfOutput(output); ok {
+					format = inferred
+				} else if filepath.Ext(output) != "" {
+					logWarning(fmt.Sprintf("unable to infer the format from the extension of %s, exporting as JSON, use --format to choose another format", output))
+				}
+			}
+			switch format {
+			case "json":
+				encode, filename = encodeRequest, genFilename
+			case "ndjson", "jsonl":
+				encode = encodeNDJSON
+				filename = func(request *Request) string {
+					return genBucketFilename(request, splitBy, "."+format)
+				}
+				bucketed = true
+			case "openai-fine-tune-v2":
+				encode = encodeFineTuneV2
+				filename = func(request *Request) string {
+					return genBucketFilename(request, splitBy, ".jsonl")
+				}
+				bucketed = true
+			case "transcript":
+				encode = func(w io.Writer, request *Request, _ bool) error {
+					return writeTranscript(w, request, hideReasoning)
+				}
+				filename = func(request *Request) string {
+					return strings.TrimSuffix(genFilename(request), ".json") + ".txt"
+				}
+			case "typescript":
+				encode = func(w io.Writer, request *Request, _ bool) error {
+					return writeTypeScript(w, request)
+				}
+				filename = func(request *Request) string {
+					return strings.TrimSuffix(genFilename(request), ".json") + ".ts"
+				}
+			case "langchain-messages":
+				encode, filename = encodeLangChainMessages, genFilename
+			case "playground-link":
+				// Conversations too long for a link are written next to the
+				// exported links, or to the working directory.
+				fallbackDir := "."
+				if directory != "" && !isS3URL(directory) {
+					fallbackDir = directory
+				}
+				encode = func(w io.Writer, request *Request, _ bool) error {
+					return writePlaygroundLink(w, request, playgroundURL, fallbackDir)
+				}
+				filename = func(request *Request) string {
+					return strings.TrimSuffix(genFilename(request), ".json") + ".url"
+				}
+			case "test-script", "markdown-issue", "sdk-go":
+				for _, name := range []string{apiKeyEnv, baseURLEnv} {
+					if name == "" {
+						continue
+					}
+					if err := checkEnvName(name); err != nil {
+						logFatal(err)
+					}
+				}
+				switch format {
+				case "markdown-issue":
+					encode = func(w io.Writer, request *Request, _ bool) error {
+						return writeMarkdownIssue(w, request, apiKeyEnv, baseURLEnv, canonicalHeaders, hideReasoning)
+					}
+					filename = func(request *Request) string {
+						return strings.TrimSuffix(genFilename(request), ".json") + ".md"
+					}
+				case "sdk-go":
+					encode = func(w io.Writer, request *Request, _ bool) error {
+						return writeSDKGo(w, request, apiKeyEnv, baseURLEnv, canonicalHeaders)
+					}
+					filename = func(request *Request) string {
+						return strings.TrimSuffix(genFilename(request), ".json") + ".go"
+					}
+				default:
+					encode = func(w io.Writer, request *Request, _ bool) error {
+						return writeTestScript(w, request, assertContent, apiKeyEnv, baseURLEnv, canonicalHeaders)
+					}
+					filename = func(request *Request) string {
+						return strings.TrimSuffix(genFilename(request), ".json") + ".sh"
+					}
+				}
+			case "parquet":
+				// All requests are written to a single file, whose footer is
+				// written after the last request.
+				if directory != "" {
+					logFatal(errors.New("--format parquet writes all requests to a single file, use --output instead of --directory"))
+				}
+				filename, bucketed = genFilename, true
+			default:
+				logFatal(fmt.Errorf("unsupported format %q, available formats are \"json\"/\"ndjson\"/\"openai-fine-tune-v2\"/\"transcript\"/\"typescript\"/\"langchain-messages\"/\"playground-link\"/\"test-script\"/\"markdown-issue\"/\"sdk-go\"/\"parquet\"", format))
+			}
+			if assertContent && format != "test-script" {
+				logFatal(errors.New("--assert-content is only supported with --format test-script"))
+			}
+			// An S3 URL ending with a slash is a prefix, under which each request
+			// is uploaded as if exported to a directory.
+			if isS3URL(output) && strings.HasSuffix(output, "/") {
+				if merge || format == "parquet" {
+					logFatal(errors.New("--merge and --format parquet require an object key rather than a prefix as the output"))
+				}
+				directory, output = output, "stdout"
+			}
+			if trainTestSplit != 0 {
 				switch {
-				case goodCase:
-					request.Category = "goodcase"
-				case badCase:
-					request.Category = "badcase"
+				case trainTestSplit <= 0 || trainTestSplit >= 1:
+					logFatal(errors.New("--train-test-split expects the ratio of the training set between 0 and 1, such as 0.8"))
+				case directory == "":
+					logFatal(errors.New("--train-test-split writes train.jsonl and test.jsonl, use --directory instead of --output"))
+				case format != "json" && format != "ndjson" && format != "jsonl" && format != "openai-fine-tune-v2":
+					logFatal(errors.New("--train-test-split writes JSON lines, --format " + format + " is not supported"))
+				}
+				// Training examples are split as they are, other formats are
+				// written as NDJSON.
+				if format != "openai-fine-tune-v2" {
+					encode = encodeNDJSON
 				}
-				if len(tags) > 0 {
-					request.Tags = tags
+				bucketed = true
+				filename = func(request *Request) string {
+					return splitSets[request] + ".jsonl"
 				}
 			}
-			var outputStream io.Writer
-			if directory != "" {
-				var file *os.File
-				file, err = os.Create(filepath.Join(directory, genFilename(request)))
+			for i, key := range splitBy {
+				switch {
+				case key != "model" && key != "date" && key != "uid":
+					logFatal(fmt.Errorf("unsupported split key %q, available keys are \"model\"/\"date\"/\"uid\"", key))
+				case slices.Contains(splitBy[:i], key):
+					logFatal(fmt.Errorf("split key %q is given more than once", key))
+				case !bucketed:
+					logFatal(errors.New("--split-by is only supported with --format ndjson"))
+				}
+			}
+			if tokensPerFile < 0 {
+				logFatal(errors.New("--max-tokens-per-file expects a positive number of tokens"))
+			}
+			if tokensPerFile > 0 {
+				switch {
+				case !bucketed || format == "parquet":
+					logFatal(errors.New("--max-tokens-per-file is only supported with JSON lines, such as --format ndjson"))
+				case directory == "" && (output == "stdout" || output == "stderr"):
+					logFatal(errors.New("--max-tokens-per-file writes numbered files, use --output with a file path or --directory"))
+				}
+			}
+			if !slices.Contains(binaryModes, binaryMode) {
+				logFatal(fmt.Errorf("unsupported binary mode %q, available modes are %s", binaryMode, strings.Join(binaryModes, "/")))
+			}
+			if atTime != "" {
+				if uid == "" {
+					logFatal(errors.New("--at-time must be used together with --uid"))
+				}
+				request, err := selectRequest(0, "", "", uid, atTime)
+				if err != nil {
+					if errors.Is(err, sql.ErrNoRows) {
+						logFatal(sql.ErrNoRows)
+					}
+					logFatal(err)
+				}
+				requests = []*Request{request}
+			} else if idRange != "" || uid != "" || since != "" || until != "" || pathPrefix != "" ||
+				len(filterTagsAny) > 0 || len(filterTagsAll) > 0 || chatcmplRegex != "" || categoryUnset ||
+				hasSystemPrompt || noSystemPrompt || hashPrefix != "" || len(promptContains) > 0 || afterID > 0 || afterChatcmpl != "" ||
+				minConversation > 0 || maxConversation > 0 {
+				for _, value := range []string{since, until} {
+					if value == "" {
+						continue
+					}
+					if err := checkDateTime(value); err != nil {
+						logFatal(err)
+					}
+				}
+				var pattern *regexp.Regexp
+				if chatcmplRegex != "" {
+					var err error
+					if pattern, err = regexp.Compile(chatcmplRegex); err != nil {
+						logFatal(fmt.Errorf("--chatcmpl-regex: %w", err))
+					}
+				}
+				if minConversation > 0 && maxConversation > 0 && minConversation > maxConversation {
+					logFatal(errors.New("--filter-conversation-length-min must not be greater than --filter-conversation-length-max"))
+				}
+				// The chatcmpl pattern and the conversation length are matched
+				// after the query, so the limit is applied to the matched requests
+				// instead.
+				matchedAfterQuery := pattern != nil || minConversation > 0 || maxConversation > 0
+				if matchedAfterQuery && idRange == "" && uid == "" && since == "" && until == "" && pathPrefix == "" &&
+					len(filterTagsAny) == 0 && len(filterTagsAll) == 0 && !categoryUnset &&
+					!hasSystemPrompt && !noSystemPrompt && hashPrefix == "" && len(promptContains) == 0 && afterID == 0 && afterChatcmpl == "" {
+					logWarning("--chatcmpl-regex and --filter-conversation-length-min/max are matched against every stored request, " +
+						"which may be slow on large databases, use --id-range/--since/--until to narrow down the scan")
+				}
+				if hashPrefix != "" {
+					var err error
+					if hashPrefix, err = checkHashPrefix(hashPrefix); err != nil {
+						logFatal(err)
+					}
+				}
+				var idFrom, idTo int64
+				if idRange != "" {
+					var err error
+					if idFrom, idTo, err = parseIDRange(idRange); err != nil {
+						logFatal(err)
+					}
+				}
+				if afterID > 0 || afterChatcmpl != "" {
+					after, err := resolveAfterID(afterID, afterChatcmpl)
+					if err != nil {
+						logFatal(err)
+					}
+					idFrom = max(idFrom, after+1)
+				}
+				// The other filters drop fetched requests too, so the limit is
+				// applied once every filter has run instead.
+				filteredAfterQuery := minTokens > 0 || maxTokens > 0 ||
+					durationMin > 0 || durationMax > 0 || bodySizeMin > 0 || bodySizeMax > 0 ||
+					modelFamily != "" || len(excludedModels) > 0 || len(finishReasons) > 0
+				rangeLimit := limit
+				if matchedAfterQuery || filteredAfterQuery {
+					rangeLimit = 0
+				}
+				// The users of --filter-uid are matched by the query, in chunks
+				// which leave room for the other parameters.
+				uidChunks := [][]string{nil}
+				if len(filterUIDs) > 0 {
+					uids := slices.Clone(filterUIDs)
+					slices.Sort(uids)
+					uids = slices.Compact(uids)
+					uidChunks = uidChunks[:0]
+					for start := 0; start < len(uids); start += sqliteMaxVariables / 2 {
+						uidChunks = append(uidChunks, uids[start:min(start+sqliteMaxVariables/2, len(uids))])
+					}
+				}
+				for _, uids := range uidChunks {
+					matched, err := persistence.GetRequestsByRange(
+						idFrom,
+						idTo,
+						uid,
+						uids,
+						since,
+						until,
+						escapeLike(pathPrefix),
+						compactTags(filterTagsAny),
+						compactTags(filterTagsAll),
+						categoryUnset,
+						hasSystemPrompt,
+						noSystemPrompt,
+						hashPrefix,
+						likePatterns(promptContains),
+						rangeLimit,
+					)
+					if err != nil {
+						logFatal(err)
+					}
+					requests = append(requests, matched...)
+				}
+				if len(uidChunks) > 1 {
+					slices.SortFunc(requests, func(a, b *Request) int { return cmp.Compare(a.ID, b.ID) })
+				}
+				if pattern != nil {
+					requests = slices.DeleteFunc(requests, func(request *Request) bool {
+						return !pattern.MatchString(request.ChatCmpl())
+					})
+				}
+				if minConversation > 0 || maxConversation > 0 {
+					requests = slices.DeleteFunc(requests, func(request *Request) bool {
+						length := request.ConversationLength()
+						return !request.IsChat() || length < minConversation || maxConversation > 0 && length > maxConversation
+					})
+				}
+				if len(requests) == 0 {
+					logFatal(sql.ErrNoRows)
+				}
+			} else if chatcmplFile != "" {
+				chatcmpls, err := readIdentFile(chatcmplFile)
+				if err != nil {
+					logFatal(err)
+				}
+				if len(chatcmpls) == 0 {
+					logFatal(errors.New("no chatcmpl found in " + chatcmplFile))
+				}
+				// A chatcmpl listed twice would otherwise export its request
+				// twice when the copies fall in different chunks.
+				slices.Sort(chatcmpls)
+				chatcmpls = slices.Compact(chatcmpls)
+				for start := 0; start < len(chatcmpls); start += sqliteMaxVariables {
+					matched, err := persistence.GetRequestsByChatcmpls(chatcmpls[start:min(start+sqliteMaxVariables, len(chatcmpls))])
+					if err != nil {
+						logFatal(err)
+					}
+					requests = append(requests, matched...)
+				}
+				if len(requests) == 0 {
+					logFatal(sql.ErrNoRows)
+				}
+				slices.SortFunc(requests, func(a, b *Request) int { return cmp.Compare(a.ID, b.ID) })
+			} else if uidFile != "" {
+				uids, err := readIdentFile(uidFile)
 				if err != nil {
 					logFatal(err)
 				}
-				defer file.Close()
-				outputStream = file
+				if len(uids) == 0 {
+					logFatal(errors.New("no uid found in " + uidFile))
+				}
+				// A uid listed twice would otherwise export its requests twice
+				// when the copies fall in different chunks.
+				slices.Sort(uids)
+				uids = slices.Compact(uids)
+				for start := 0; start < len(uids); start += sqliteMaxVariables {
+					matched, err := persistence.GetRequestsByUIDs(uids[start:min(start+sqliteMaxVariables, len(uids))])
+					if err != nil {
+						logFatal(err)
+					}
+					requests = append(requests, matched...)
+				}
+				if len(requests) == 0 {
+					logFatal(sql.ErrNoRows)
+				}
+				slices.SortFunc(requests, func(a, b *Request) int { return cmp.Compare(a.ID, b.ID) })
 			} else {
-				switch output {
-				case "stdout":
-					outputStream = os.Stdout
-				case "stderr":
-					outputStream = os.Stderr
-				default:
-					file, err := os.Create(output)
+				request, err := persistence.GetRequest(id, chatcmpl, requestID, "", "")
+				if err != nil {
+					if errors.Is(err, sql.ErrNoRows) {
+						logFatal(sql.ErrNoRows)
+					}
+					logFatal(err)
+				}
+				requests = []*Request{request}
+			}
+			if minTokens > 0 || maxTokens > 0 {
+				requests = filterTokens(requests, minTokens, maxTokens, estimateTokens)
+				if len(requests) == 0 {
+					logFatal(errors.New("no request has a token count within the range"))
+				}
+			}
+			openStream := func(defaultOutput string) (io.Writer, func()) {
+				if toClipboard {
+					return openClipboard()
+				}
+				return openOutput(defaultOutput)
+			}
+			// The range query matches the users itself, the requests selected
+			// otherwise, such as with --id or --chatcmpl-file, are filtered here.
+			if len(filterUIDs) > 0 {
+				requests = slices.DeleteFunc(requests, func(request *Request) bool {
+					return !slices.Contains(filterUIDs, request.MoonshotUID.String)
+				})
+				if len(requests) == 0 {
+					logFatal(errors.New("no request made by user " + strings.Join(filterUIDs, "/")))
+				}
+			}
+			if durationMin > 0 || durationMax > 0 {
+				if durationMax > 0 && durationMin > durationMax {
+					logFatal(errors.New("--filter-duration-min must not be greater than --filter-duration-max"))
+				}
+				fetched := len(requests)
+				requests = slices.DeleteFunc(requests, func(request *Request) bool {
+					latency := time.Duration(request.Latency.Int64)
+					return !request.Latency.Valid ||
+						durationMin > 0 && latency < durationMin ||
+						durationMax > 0 && latency > durationMax
+				})
+				if len(requests) == 0 {
+					logFatal(errors.New("no request has a duration within the range"))
+				}
+				if len(requests)*10 < fetched {
+					logWarning(fmt.Sprintf("the duration filter kept %d of %d requests, "+
+						"narrow down the selection to fetch fewer requests", len(requests), fetched))
+				}
+			}
+			if bodySizeMin > 0 || bodySizeMax > 0 {
+				if bodySizeMax > 0 && bodySizeMin > bodySizeMax {
+					logFatal(errors.New("--filter-request-body-size-min must not be greater than --filter-request-body-size-max"))
+				}
+				fetched := len(requests)
+				requests = slices.DeleteFunc(requests, func(request *Request) bool {
+					size := request.BodySize()
+					return bodySizeMin > 0 && size < bodySizeMin ||
+						bodySizeMax > 0 && size > bodySizeMax
+				})
+				if len(requests) == 0 {
+					logFatal(errors.New("no request has a body size within the range"))
+				}
+				if len(requests)*10 < fetched {
+					logWarning(fmt.Sprintf("the body size filter kept %d of %d requests, "+
+						"narrow down the selection to fetch fewer requests", len(requests), fetched))
+				}
+			}
+			if modelFamily != "" {
+				requests = slices.DeleteFunc(requests, func(request *Request) bool {
+					return !strings.HasPrefix(ModelFamily(request.ModelName()), modelFamily)
+				})
+				if len(requests) == 0 {
+					logFatal(errors.New("no request belongs to model family " + modelFamily))
+				}
+			}
+			if len(excludedModels) > 0 {
+				requests = slices.DeleteFunc(requests, func(request *Request) bool {
+					return slices.Contains(excludedModels, request.ModelName())
+				})
+				if len(requests) == 0 {
+					logFatal(errors.New("no request is made with a model other than " + strings.Join(excludedModels, "/")))
+				}
+			}
+			if len(finishReasons) > 0 {
+				for _, finishReason := range finishReasons {
+					if !slices.Contains(availableFinishReasons, finishReason) {
+						logFatal(fmt.Errorf("unsupported finish_reason %q, available values are %s", finishReason, strings.Join(availableFinishReasons, "/")))
+					}
+				}
+				requests = slices.DeleteFunc(requests, func(request *Request) bool {
+					finishReason, err := request.FinishReason()
+					return err != nil || !slices.Contains(finishReasons, finishReason)
+				})
+				if len(requests) == 0 {
+					logFatal(errors.New("no request finished with " + strings.Join(finishReasons, "/")))
+				}
+			}
+			if limit > 0 && int64(len(requests)) > limit {
+				requests = requests[:limit]
+			}
+			if sample < 0 {
+				logFatal(errors.New("--sample must be a positive number of requests"))
+			}
+			if sample == 0 && trainTestSplit == 0 && cmd.Flags().Changed("seed") {
+				logFatal(errors.New("--seed must be used together with --sample or --train-test-split"))
+			} else if !cmd.Flags().Changed("seed") {
+				seed = time.Now().UnixNano()
+			}
+			if sample > 0 {
+				eligible := len(requests)
+				requests = sampleRequests(requests, sample, seed)
+				logSample(len(requests), eligible, seed)
+			}
+			if strictSchema && !validateSchema {
+				logFatal(errors.New("--strict must be used together with --response-schema-validate"))
+			}
+			if validateSchema {
+				var invalid int
+				for _, request := range requests {
+					violations, err := request.ResponseSchemaViolations()
+					if err != nil {
+						invalid++
+						logWarning(fmt.Sprintf("response of %s: %v", request.Ident(), err))
+						continue
+					}
+					if len(violations) > 0 {
+						invalid++
+					}
+					for _, violation := range violations {
+						logWarning(fmt.Sprintf("response of %s does not conform to the schema: %s", request.Ident(), violation))
+					}
+				}
+				if invalid > 0 && strictSchema {
+					logFatal(fmt.Errorf("%d of %d responses do not conform to the schema", invalid, len(requests)))
+				}
+			}
+			if len(excludedHeaders) > 0 {
+				for _, request := range requests {
+					request.ExcludeHeaders(excludedHeaders)
+				}
+			}
+			if anonymizeUIDs {
+				for _, request := range requests {
+					request.AnonymizeUID()
+				}
+			}
+			if curl {
+				for _, name := range []string{apiKeyEnv, baseURLEnv} {
+					if name == "" {
+						continue
+					}
+					if err := checkEnvName(name); err != nil {
+						logFatal(err)
+					}
+				}
+				curlStream, closeCurl := openStream("stdout")
+				defer closeCurl()
+				for _, request := range requests {
+					if err := writeCurlCommand(curlStream, request, contentType, apiKeyEnv, baseURLEnv, canonicalHeaders); err != nil {
+						logFatal(err)
+					}
+				}
+				return
+			}
+			if extractPointer != "" {
+				if !slices.Contains(extractSources, extractFrom) {
+					logFatal(fmt.Errorf("unsupported --extract-from %q, available values are %s", extractFrom, strings.Join(extractSources, "/")))
+				}
+				if _, err := jsonpointer.Parse(extractPointer); err != nil {
+					logFatal(err)
+				}
+				extractStream, closeExtract := openStream(output)
+				defer closeExtract()
+				for _, request := range requests {
+					missing, err := writeExtracted(extractStream, request, extractPointer, extractFrom)
 					if err != nil {
 						logFatal(err)
 					}
-					defer file.Close()
-					outputStream = file
+					if missing != nil {
+						logWarning(fmt.Sprintf("%s: %v", request.Ident(), missing))
+					}
+				}
+				return
+			}
+			if injectTurn != "" {
+				for _, request := range requests {
+					if err := injectAssistantTurn(request, injectTurn); err != nil {
+						logFatal(err)
+					}
+					// Requests answered with a known-correct reply are good cases.
+					request.Category = sql.NullString{String: "goodcase", Valid: true}
+				}
+			}
+			if completionPrefix != "" {
+				for _, request := range requests {
+					if err := addCompletionPrefix(request, completionPrefix); err != nil {
+						logFatal(err)
+					}
+				}
+			}
+			if stripBase64 || hashBase64 {
+				for _, request := range requests {
+					if request.RequestBody.Valid {
+						request.RequestBody.String = stripDataURIs(request.RequestBody.String, hashBase64)
+					}
+				}
+			}
+			if stripTools {
+				for _, request := range requests {
+					if request.RequestBody.Valid && gjson.Valid(request.RequestBody.String) {
+						request.RequestBody.String = stripToolDefinitions(request.RequestBody.String)
+					}
+				}
+			}
+			if stripParts {
+				for _, request := range requests {
+					if request.RequestBody.Valid && gjson.Valid(request.RequestBody.String) {
+						var discarded int
+						request.RequestBody.String, discarded = stripContentParts(request.RequestBody.String)
+						if discarded > 0 {
+							logWarning(fmt.Sprintf("%s: discarded %d non-text content parts, such as image_url, with --strip-content-parts", request.Ident(), discarded))
+						}
+					}
+				}
+			}
+			if normalizeJSON {
+				for _, request := range requests {
+					request.NormalizeJSON()
+				}
+			}
+			for _, request := range requests {
+				request.PrettyHeaders = prettyHeaders
+				request.CanonicalHeaders = canonicalHeaders
+				request.BinaryMode = binaryMode
+			}
+			for _, request := range requests {
+				if request.IsChat() {
+					switch {
+					case goodCase:
+						request.Category = sql.NullString{String: "goodcase", Valid: true}
+					case badCase:
+						request.Category = sql.NullString{String: "badcase", Valid: true}
+					}
+					request.Tags.Add(tags...)
+				}
+			}
+			if trainTestSplit > 0 {
+				var stratified bool
+				splitSets, stratified = splitTrainTest(requests, trainTestSplit, seed)
+				logSplit(splitSets, stratified, seed)
+			}
+			if diffAgainst != "" {
+				previous, err := os.ReadFile(diffAgainst)
+				if err != nil {
+					logFatal(err)
+				}
+				unified, err := requests[0].Diff(diffAgainst, previous)
+				if err != nil {
+					logFatal(err)
+				}
+				writeColoredDiff(os.Stdout, unified)
+				return
+			}
+			if tokensPerFile > 0 {
+				// A single output file is split as if it were the only bucket of
+				// its directory.
+				if directory == "" {
+					var base string
+					if isS3URL(output) {
+						slash := strings.LastIndex(output, "/")
+						directory, base = output[:slash+1], output[slash+1:]
+					} else {
+						directory, base = filepath.Dir(output), filepath.Base(output)
+					}
+					filename = func(*Request) string {
+						return base
+					}
+				}
+				filename = splitFilesByTokens(requests, filename, tokensPerFile)
+			}
+			if dryRun {
+				reportExport(requests, directory, output, filename, !bucketed)
+				return
+			}
+			if format == "parquet" {
+				outputStream, closeOutput := openStream(output)
+				defer closeOutput()
+				if err := writeParquet(outputStream, requests); err != nil {
+					logFatal(err)
+				}
+				return
+			}
+			if merge {
+				outputStream, closeOutput := openStream(output)
+				defer closeOutput()
+				encoder := json.NewEncoder(outputStream)
+				encoder.SetIndent("", strings.Repeat(" ", indent))
+				encoder.SetEscapeHTML(escapeHTML)
+				if err := encoder.Encode(requests); err != nil {
+					logFatal(err)
+				}
+				return
+			}
+			if directory != "" {
+				var (
+					files  = make(map[string]exportFile)
+					counts = make(map[string]int)
+				)
+				closeFile := func(file exportFile) {
+					if err := file.Close(); err != nil {
+						logFatal(err)
+					}
+					if bucketed {
+						logExportCount(file, counts[file.Name()])
+					} else {
+						logExport(file)
+					}
 				}
+				for _, request := range requests {
+					path := exportPath(directory, filename(request))
+					file, opened := files[path]
+					if !opened {
+						var err error
+						if file, err = createExportFile(path); err != nil {
+							logFatal(err)
+						}
+						files[path] = file
+					}
+					if err := encode(file, request, escapeHTML); err != nil {
+						logFatal(err)
+					}
+					counts[file.Name()]++
+					if !bucketed {
+						closeFile(file)
+						delete(files, path)
+					}
+				}
+				paths := make([]string, 0, len(files))
+				for path := range files {
+					paths = append(paths, path)
+				}
+				slices.Sort(paths)
+				for _, path := range paths {
+					closeFile(files[path])
+				}
+				return
 			}
-			encoder := json.NewEncoder(outputStream)
-			encoder.SetIndent("", "    ")
-			encoder.SetEscapeHTML(escapeHTML)
-			if err = encoder.Encode(request); err != nil {
-				logFatal(err)
+			outputStream, closeOutput := openStream(output)
+			defer closeOutput()
+			for _, request := range requests {
+				if err := encode(outputStream, request, escapeHTML); err != nil {
+					logFatal(err)
+				}
 			}
 		},
 	}
@@ -90,20 +823,665 @@ func exportCommand() *cobra.Command {
 	flags.Int64Var(&id, "id", 0, "row id")
 	flags.StringVar(&chatcmpl, "chatcmpl", "", "chatcmpl")
 	flags.StringVar(&requestID, "requestid", "", "request id returned from Moonshot AI")
-	flags.StringVarP(&output, "output", "o", "stdout", "output file path")
+	flags.StringVar(&chatcmplFile, "chatcmpl-file", "", "file containing chatcmpl ids, one per line")
+	flags.StringVar(&uidFile, "filter-uid-file", "", "export requests of the users whose uids are listed in the file, one per line")
+	flags.StringVar(&chatcmplRegex, "chatcmpl-regex", "", "export requests whose chatcmpl matches the regular expression")
+	flags.StringVarP(&output, "output", "o", "stdout", "output file path, or an s3://bucket/key URL, a URL ending with a slash is a prefix under which each request is uploaded")
 	flags.StringVar(&directory, "directory", "", "output directory")
+	flags.BoolVar(&follow, "follow", false, "with --chatcmpl, write the response body as it is received, also while a streaming request is in flight, until it is stored")
 	flags.BoolVar(&escapeHTML, "escape-html", false, "specifies whether problematic HTML characters should be escaped")
 	flags.BoolVar(&goodCase, "good", false, "good case")
 	flags.BoolVar(&badCase, "bad", false, "bad case")
-	flags.StringArrayVar(&tags, "tag", nil, "tags describe the current case")
+	flags.StringVar(&extractPointer, "extract", "", "print only the value at the JSON pointer, such as /messages/0/content, of each request on a line as compact JSON, an empty line if there is none")
+	flags.StringVar(&extractFrom, "extract-from", "request", "body the --extract pointer refers to, \"request\" or \"response\"")
+	flags.StringVar(&completionPrefix, "add-completion-prefix", "", "append a partial assistant message such as '{\"role\":\"assistant\",\"content\":\"...\"}', which seeds the reply, to the messages of the exported requests")
+	flags.StringVar(&injectTurn, "inject-turn", "", "append an assistant message such as '{\"role\":\"assistant\",\"content\":\"...\"}' to the messages of the exported requests, which are marked as good cases")
+	flags.StringArrayVar(&tags, "tag", nil, "tags describe the current case, which are exported only, use the tag command to save tags")
 	flags.BoolVar(&curl, "curl", false, "export curl command")
-	cmd.MarkFlagsOneRequired("id", "chatcmpl", "requestid")
+	flags.StringVar(&contentType, "content-type", "", "override Content-Type of the exported curl command")
+	flags.StringVar(&apiKeyEnv, "api-key-env", "MOONSHOT_API_KEY", "environment variable referenced by the Authorization header of the exported curl command")
+	flags.StringVar(&playgroundURL, "playground-url", defaultPlaygroundURL, "with --format playground-link, the playground that the links open")
+	flags.StringVar(&baseURLEnv, "base-url-env", "", "environment variable used as the base URL of the exported curl command in place of the recorded endpoint")
+	flags.StringVar(&idRange, "id-range", "", "export requests with row id in the range, such as 100-200, 100- or -200")
+	flags.StringVar(&uid, "uid", "", "export requests made by the user id")
+	flags.DurationVar(&durationMin, "filter-duration-min", 0, "only export requests that took at least the duration, such as 5s")
+	flags.DurationVar(&durationMax, "filter-duration-max", 0, "only export requests that took at most the duration, such as 500ms")
+	flags.Int64Var(&bodySizeMin, "filter-request-body-size-min", 0, "only export requests whose body is at least the number of bytes")
+	flags.Int64Var(&bodySizeMax, "filter-request-body-size-max", 0, "only export requests whose body is at most the number of bytes")
+	flags.StringSliceVar(&filterTagsAny, "filter-tag-any", nil, "export requests tagged with any of the comma-separated tags")
+	flags.StringSliceVar(&filterTagsAll, "filter-tag-all", nil, "export requests tagged with all of the comma-separated tags")
+	flags.BoolVar(&categoryUnset, "filter-category-unset", false, "export requests categorized as neither goodcase nor badcase")
+	flags.BoolVar(&hasSystemPrompt, "filter-has-system-prompt", false, "export requests whose first message is a system message")
+	flags.BoolVar(&noSystemPrompt, "filter-no-system-prompt", false, "export requests whose first message is not a system message")
+	flags.StringArrayVar(&promptContains, "filter-system-prompt-contains", nil, "export requests whose system prompt, the first message, contains the text, case-insensitive for ASCII letters, given several times any of them matches")
+	flags.StringVar(&hashPrefix, "hash", "", "export requests whose body hash starts with the hexadecimal prefix")
+	flags.IntVar(&minConversation, "filter-conversation-length-min", 0, "export chat requests whose conversation has at least N user turns")
+	flags.IntVar(&maxConversation, "filter-conversation-length-max", 0, "export chat requests whose conversation has at most N user turns, 0 means no upper bound")
+	flags.Int64Var(&limit, "limit", 0, "export at most N requests, counted after every filter has run, 0 means no limit")
+	flags.Int64Var(&afterID, "after-id", 0, "export requests made strictly after the row id, as an incremental cursor")
+	flags.StringVar(&afterChatcmpl, "after-chatcmpl", "", "export requests made strictly after the chatcmpl, as an incremental cursor")
+	flags.StringVar(&since, "since", "", "export requests made at or after this time, YYYY-mm-dd or YYYY-mm-dd HH:MM:SS")
+	flags.StringVar(&until, "until", "", "export requests made before this time, YYYY-mm-dd or YYYY-mm-dd HH:MM:SS")
+	flags.StringVar(&atTime, "at-time", "", "with --uid, export the single request of the user closest to the time in RFC3339 format")
+	flags.BoolVar(&merge, "merge", false, "write all exported requests as a single JSON array")
+	flags.IntVar(&indent, "indent", 4, "number of spaces used for indentation of the merged JSON array, 0 means no indentation")
+	// --output-format is accepted as an alias of --format.
+	cmd.SetGlobalNormalizationFunc(func(_ *pflag.FlagSet, name string) pflag.NormalizedName {
+		if name == "output-format" {
+			name = "format"
+		}
+		return pflag.NormalizedName(name)
+	})
+	flags.StringVar(&format, "format", "json", "output format, inferred from the extension of --output if not given, \"json\", \"ndjson\" which writes one compact JSON object per line, \"openai-fine-tune-v2\" which writes one training example per line with the system message as a field of its own, \"transcript\" which writes the conversation as plain text, \"typescript\" which writes interfaces inferred from the bodies, \"langchain-messages\" which writes the conversation as LangChain messages, \"playground-link\" which writes a link opening the conversation in the playground of --playground-url, \"test-script\" which writes a shell script that sends the request with curl and checks the status of the response, \"markdown-issue\" which writes a bug report with the conversation, the observed response and a curl command to reproduce it, \"sdk-go\" which writes a Go program that sends the request with net/http, or \"parquet\" which writes a single Parquet file with a row per request and a column per database column")
+	flags.StringSliceVar(&splitBy, "split-by", nil, "with --format ndjson and --directory, write one file per \"model\", \"date\" or \"uid\", several keys as \"date,model\" write one file per model under a directory per date")
+	flags.BoolVar(&anonymizeUIDs, "anonymize-uid", false, "replace the uid of the user, also in the Msh-Uid response header and in the file names of --split-by uid, with a pseudonym that is the same for all requests of the user")
+	flags.BoolVar(&toClipboard, "clipboard", false, "write the exported JSON or curl command to the system clipboard")
+	flags.BoolVar(&dryRun, "dry-run", false, "print the files that would be written without writing them")
+	flags.StringVar(&diffAgainst, "diff-against", "", "show the difference between a previously exported file and the current request")
+	flags.Int64Var(&minTokens, "filter-min-tokens", 0, "only export requests with at least N total tokens")
+	flags.Int64Var(&maxTokens, "filter-max-tokens", 0, "only export requests with at most N total tokens")
+	flags.StringVar(&modelFamily, "filter-model-family", "", "only export requests whose model family starts with the prefix, such as moonshot-v1")
+	flags.StringArrayVar(&excludedModels, "filter-model-not", nil, "do not export requests made with the model, such as moonshot-v1-8k, can be repeated to exclude each of the models")
+	flags.StringVar(&pathPrefix, "filter-path-prefix", "", "only export requests whose path starts with the prefix, such as /v1/chat")
+	flags.StringArrayVar(&filterUIDs, "filter-uid", nil, "only export requests made by the user id, can be repeated to export requests of any of the users, needs a selector such as --since/--until, with which the users are matched by the query")
+	flags.StringSliceVar(&finishReasons, "filter-finish-reason", nil, "only export requests finished with the reasons, such as length, stop or tool_calls")
+	flags.BoolVar(&estimateTokens, "estimate-tokens", false, "estimate prompt tokens for requests without usage, such as interrupted streaming requests")
+	flags.BoolVar(&normalizeJSON, "normalize-json", false, "sort the keys and normalize the numbers of JSON bodies, so that equivalent bodies are exported identically")
+	flags.BoolVar(&prettyHeaders, "pretty-headers", false, "export headers as objects keyed by the header names instead of raw header strings")
+	flags.BoolVar(&assertContent, "assert-content", false, "with --format test-script, also check that the response contains the beginning of the original reply")
+	flags.BoolVar(&canonicalHeaders, "canonical-headers", false, "export request headers as forwarded, with canonical names in sorted order, instead of in the order and casing they were received, which are not recorded for HTTP/2 requests, header blocks larger than 64 KB and requests following a chunked body larger than 64 KB on the same connection, whose headers are always exported as forwarded")
+	flags.StringArrayVar(&excludedHeaders, "exclude-header", nil, "remove the header, case-insensitive, from the exported request and response headers and from the curl command, can be repeated to remove each of the headers")
+	flags.BoolVar(&validateSchema, "response-schema-validate", false, "check the responses of chat completions against the embedded schema of Moonshot AI responses and warn about violations, to catch changes of the response format")
+	flags.BoolVar(&strictSchema, "strict", false, "with --response-schema-validate, fail instead of exporting if a response does not conform to the schema")
+	flags.StringVar(&binaryMode, "binary-mode", binaryBase64, "how bodies that are not valid UTF-8 are exported, \"base64\"/\"hex\" encodes them and marks the encoding in body_encoding, \"skip\" leaves them out")
+	flags.BoolVar(&stripBase64, "strip-base64", false, "replace base64 data URIs in message content with a placeholder noting the media type and size")
+	flags.BoolVar(&hashBase64, "hash-base64", false, "like --strip-base64, and include the SHA-256 of the data in the placeholder so identical images are recognizable")
+	flags.IntVar(&sample, "sample", 0, "export a random sample of N of the selected requests, in the order of their ids")
+	flags.Float64Var(&trainTestSplit, "train-test-split", 0, "with --directory, write the given ratio of the requests to train.jsonl and the rest to test.jsonl, stratified by category if there are both good and bad cases")
+	flags.Int64Var(&tokensPerFile, "max-tokens-per-file", 0, "with JSON lines, start a new file, numbered such as output-002.jsonl, once the total tokens of the requests in a file would exceed N")
+	flags.Int64Var(&seed, "seed", 0, "seed of --sample and --train-test-split, the same seed picks the same requests, a random seed is used and reported if it is not set")
+	flags.BoolVar(&stripTools, "strip-tools", false, "remove tools and tool_choice, tool messages and the tool_calls of assistant messages from request bodies, for fine-tuning without tools")
+	flags.BoolVar(&hideReasoning, "hide-reasoning", false, "with --format transcript or markdown-issue, leave out the reasoning of assistant messages, such as reasoning_content of reasoning models, and keep only their final content")
+	flags.BoolVar(&stripParts, "strip-content-parts", false, "replace message content made of parts with the text of its text parts joined by newlines, discarding the other parts such as image_url, for tools expecting string content")
+	cmd.MarkFlagsOneRequired("id", "chatcmpl", "requestid", "chatcmpl-file", "filter-uid-file", "id-range", "uid", "since", "until", "filter-path-prefix", "filter-tag-any", "filter-tag-all", "chatcmpl-regex", "filter-category-unset", "filter-has-system-prompt", "filter-no-system-prompt", "filter-system-prompt-contains", "hash", "filter-conversation-length-min", "filter-conversation-length-max", "after-id", "after-chatcmpl")
+	cmd.MarkFlagsMutuallyExclusive("id", "chatcmpl-file", "id-range")
+	cmd.MarkFlagsMutuallyExclusive("id", "chatcmpl-file", "uid")
+	cmd.MarkFlagsMutuallyExclusive("chatcmpl", "chatcmpl-file", "id-range")
+	cmd.MarkFlagsMutuallyExclusive("chatcmpl", "chatcmpl-file", "uid")
+	cmd.MarkFlagsMutuallyExclusive("requestid", "chatcmpl-file", "id-range")
+	cmd.MarkFlagsMutuallyExclusive("requestid", "chatcmpl-file", "uid")
+	for _, timeRange := range []string{"since", "until", "filter-path-prefix", "filter-tag-any", "filter-tag-all", "chatcmpl-regex", "filter-category-unset", "filter-has-system-prompt", "filter-no-system-prompt", "filter-system-prompt-contains", "hash", "filter-conversation-length-min", "filter-conversation-length-max", "after-id", "after-chatcmpl"} {
+		cmd.MarkFlagsMutuallyExclusive("id", "chatcmpl-file", timeRange)
+		cmd.MarkFlagsMutuallyExclusive("chatcmpl", "chatcmpl-file", timeRange)
+		cmd.MarkFlagsMutuallyExclusive("requestid", "chatcmpl-file", timeRange)
+		cmd.MarkFlagsMutuallyExclusive("filter-uid-file", timeRange)
+		cmd.MarkFlagsMutuallyExclusive("at-time", timeRange)
+		cmd.MarkFlagsMutuallyExclusive("diff-against", timeRange)
+	}
+	for _, flag := range []string{"id", "chatcmpl", "requestid", "chatcmpl-file", "id-range", "uid", "at-time", "diff-against"} {
+		cmd.MarkFlagsMutuallyExclusive("filter-uid-file", flag)
+	}
+	cmd.MarkFlagsMutuallyExclusive("at-time", "id-range")
+	cmd.MarkFlagsMutuallyExclusive("after-id", "after-chatcmpl")
+	cmd.MarkFlagsMutuallyExclusive("filter-has-system-prompt", "filter-no-system-prompt")
+	cmd.MarkFlagsMutuallyExclusive("filter-system-prompt-contains", "filter-no-system-prompt")
+	cmd.MarkFlagsMutuallyExclusive("merge", "directory")
+	cmd.MarkFlagsMutuallyExclusive("merge", "curl")
+	cmd.MarkFlagsMutuallyExclusive("inject-turn", "bad")
+	cmd.MarkFlagsMutuallyExclusive("inject-turn", "curl")
+	cmd.MarkFlagsMutuallyExclusive("add-completion-prefix", "inject-turn")
+	cmd.MarkFlagsMutuallyExclusive("add-completion-prefix", "curl")
+	for _, flag := range []string{"curl", "merge", "directory", "format", "diff-against", "dry-run", "add-completion-prefix", "inject-turn"} {
+		cmd.MarkFlagsMutuallyExclusive("extract", flag)
+	}
+	cmd.MarkFlagsMutuallyExclusive("strip-tools", "curl")
+	cmd.MarkFlagsMutuallyExclusive("strip-content-parts", "curl")
+	cmd.MarkFlagsMutuallyExclusive("sample", "limit")
+	for _, flag := range []string{"merge", "curl", "clipboard", "extract", "follow"} {
+		cmd.MarkFlagsMutuallyExclusive("max-tokens-per-file", flag)
+	}
+	cmd.MarkFlagsMutuallyExclusive("train-test-split", "split-by")
+	cmd.MarkFlagsMutuallyExclusive("train-test-split", "merge")
+	cmd.MarkFlagsMutuallyExclusive("train-test-split", "curl")
+	for _, flag := range []string{"directory", "curl", "merge", "dry-run", "format"} {
+		cmd.MarkFlagsMutuallyExclusive("follow", flag)
+	}
+	cmd.MarkFlagsMutuallyExclusive("merge", "diff-against")
+	cmd.MarkFlagsMutuallyExclusive("merge", "format")
 	cmd.MarkFlagsMutuallyExclusive("good", "bad")
+	cmd.MarkFlagsMutuallyExclusive("strip-base64", "hash-base64")
+	cmd.MarkFlagsMutuallyExclusive("strip-base64", "curl")
+	cmd.MarkFlagsMutuallyExclusive("hash-base64", "curl")
+	cmd.MarkFlagsMutuallyExclusive("pretty-headers", "curl")
+	cmd.MarkFlagsMutuallyExclusive("clipboard", "output")
+	cmd.MarkFlagsMutuallyExclusive("clipboard", "directory")
+	cmd.MarkFlagsMutuallyExclusive("clipboard", "diff-against")
+	cmd.MarkFlagsMutuallyExclusive("clipboard", "dry-run")
+	cmd.MarkFlagsMutuallyExclusive("dry-run", "curl")
+	cmd.MarkFlagsMutuallyExclusive("dry-run", "diff-against")
+	cmd.MarkFlagsMutuallyExclusive("diff-against", "chatcmpl-file")
+	cmd.MarkFlagsMutuallyExclusive("diff-against", "id-range")
+	cmd.MarkFlagsMutuallyExclusive("diff-against", "uid")
+	cmd.MarkFlagsMutuallyExclusive("diff-against", "curl")
+	cmd.MarkFlagsMutuallyExclusive("diff-against", "directory")
 	cmd.MarkPersistentFlagFilename("output")
+	cmd.MarkPersistentFlagFilename("chatcmpl-file")
+	cmd.MarkPersistentFlagFilename("filter-uid-file")
+	cmd.MarkPersistentFlagFilename("diff-against")
 	cmd.MarkPersistentFlagDirname("directory")
 	return cmd
 }
 
+// injectAssistantTurn appends the assistant message turn to the messages of the
+// request body, which must end with a user message.
+func injectAssistantTurn(request *Request, turn string) error {
+	return appendAssistantMessage(request, turn, "--inject-turn")
+}
+
+// addCompletionPrefix appends the assistant message prefix to the messages of
+// the request body as a partial message, whose content the model continues
+// rather than starting a reply of its own.
+func addCompletionPrefix(request *Request, prefix string) error {
+	if gjson.Valid(prefix) && gjson.Parse(prefix).IsObject() {
+		if content := gjson.Get(prefix, "content"); content.Type != gjson.String {
+			return errors.New("--add-completion-prefix: expects the content of the message to be a string")
+		}
+		if partial, err := sjson.Set(prefix, "partial", true); err == nil {
+			prefix = partial
+		}
+	}
+	return appendAssistantMessage(request, prefix, "--add-completion-prefix")
+}
+
+// appendAssistantMessage appends the assistant message to the messages of the
+// request body, which must end with a user message, errors are prefixed with
+// the flag the message is given with.
+func appendAssistantMessage(request *Request, message string, flag string) error {
+	if !gjson.Valid(message) || !gjson.Parse(message).IsObject() {
+		return errors.New(flag + ": expects a JSON object such as {\"role\":\"assistant\",\"content\":\"...\"}")
+	}
+	if role := gjson.Get(message, "role").String(); role != "assistant" {
+		return fmt.Errorf("%s: expects an assistant message, got role %q", flag, role)
+	}
+	if !request.IsChat() || !gjson.Valid(request.RequestBody.String) {
+		return errors.New(flag + ": " + request.Ident() + " is not a chat completions request")
+	}
+	if role := gjson.Get(request.RequestBody.String, "messages.@reverse.0.role").String(); role != "user" {
+		return fmt.Errorf("%s: the last message of %s is from %q rather than the user", flag, request.Ident(), role)
+	}
+	body, err := sjson.SetRaw(request.RequestBody.String, "messages.-1", message)
+	if err != nil {
+		return err
+	}
+	request.RequestBody.String = body
+	return nil
+}
+
+// reportExport prints where each request would be exported, if checkCollision is
+// set, requests written to a path already taken by another request in a directory
+// export are marked as collisions.
+func reportExport(requests []*Request, directory string, output string, filename func(*Request) string, checkCollision bool) {
+	t.AppendHeader(table.Row{"id", "ident", "path", "note"})
+	seen := make(map[string]struct{}, len(requests))
+	for _, request := range requests {
+		path := output
+		if directory != "" {
+			path = exportPath(directory, filename(request))
+		}
+		var note string
+		if _, collided := seen[path]; collided && directory != "" && checkCollision {
+			note = "collision"
+		}
+		seen[path] = struct{}{}
+		t.AppendRow(table.Row{strconv.FormatInt(request.ID, 10), request.Ident(), path, note})
+	}
+	t.AppendFooter(table.Row{"total", strconv.Itoa(len(requests))})
+	t.Render()
+}
+
+var availableFinishReasons = []string{"stop", "length", "tool_calls", "content_filter"}
+
+// exportFile is a local file or an object uploaded to S3 when closed.
+type exportFile interface {
+	io.WriteCloser
+	Name() string
+}
+
+// exportPath joins the directory, which may be an S3 prefix, and the filename.
+func exportPath(directory string, filename string) string {
+	if isS3URL(directory) {
+		return strings.TrimSuffix(directory, "/") + "/" + filename
+	}
+	return filepath.Join(directory, filename)
+}
+
+func createExportFile(path string) (exportFile, error) {
+	if isS3URL(path) {
+		if _, _, err := parseS3URL(path); err != nil {
+			return nil, err
+		}
+		return &s3Object{url: path}, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	return os.Create(path)
+}
+
+// openOutput opens the output stream, which is either "stdout", "stderr", the
+// path of a file to be created or an s3://bucket/key URL to upload to.
+func openOutput(output string) (w io.Writer, closeFunc func()) {
+	switch output {
+	case "stdout":
+		return os.Stdout, func() {}
+	case "stderr":
+		return os.Stderr, func() {}
+	default:
+		file, err := createExportFile(output)
+		if err != nil {
+			logFatal(err)
+		}
+		if isS3URL(output) {
+			return file, func() {
+				if err := file.Close(); err != nil {
+					logFatal(err)
+				}
+				logExport(file)
+			}
+		}
+		return file, func() { file.Close() }
+	}
+}
+
+// parseIDRange parses ranges such as "100-200", "100-" and "-200", a missing
+// bound is returned as 0, which means unbounded.
+func parseIDRange(idRange string) (idFrom, idTo int64, err error) {
+	from, to, found := strings.Cut(idRange, "-")
+	if !found {
+		return 0, 0, fmt.Errorf("invalid id range %q, expects from-to", idRange)
+	}
+	if from != "" {
+		if idFrom, err = strconv.ParseInt(strings.TrimSpace(from), 10, 64); err != nil {
+			return 0, 0, fmt.Errorf("invalid id range %q: %w", idRange, err)
+		}
+	}
+	if to != "" {
+		if idTo, err = strconv.ParseInt(strings.TrimSpace(to), 10, 64); err != nil {
+			return 0, 0, fmt.Errorf("invalid id range %q: %w", idRange, err)
+		}
+	}
+	if idFrom > 0 && idTo > 0 && idFrom > idTo {
+		return 0, 0, fmt.Errorf("invalid id range %q, from is greater than to", idRange)
+	}
+	return idFrom, idTo, nil
+}
+
+// filterTokens keeps requests whose total tokens are within [minTokens, maxTokens],
+// a zero bound is not checked, requests without usage are dropped unless
+// estimate is set.
+func filterTokens(requests []*Request, minTokens, maxTokens int64, estimate bool) []*Request {
+	filtered := make([]*Request, 0, len(requests))
+	for _, request := range requests {
+		tokens, ok := request.TotalTokens()
+		if !ok {
+			if !estimate {
+				continue
+			}
+			tokens = request.PromptTokensEstimate()
+		}
+		if minTokens > 0 && tokens < minTokens {
+			continue
+		}
+		if maxTokens > 0 && tokens > maxTokens {
+			continue
+		}
+		filtered = append(filtered, request)
+	}
+	return filtered
+}
+
+// splitFilesByTokens numbers the files named by filename, such as
+// output-001.jsonl and output-002.jsonl, so that the total tokens of the
+// requests in each file do not exceed maxTokens. A request with more tokens
+// than that is written to a file of its own.
+func splitFilesByTokens(requests []*Request, filename func(*Request) string, maxTokens int64) func(*Request) string {
+	type split struct {
+		part   int
+		tokens int64
+	}
+	var (
+		splits = make(map[string]*split)
+		names  = make(map[*Request]string, len(requests))
+	)
+	for _, request := range requests {
+		tokens, ok := request.TotalTokens()
+		if !ok {
+			tokens = request.PromptTokensEstimate()
+		}
+		if tokens > maxTokens {
+			logWarning(fmt.Sprintf("%s has %d tokens, more than --max-tokens-per-file, and is written to a file of its own", request.Ident(), tokens))
+		}
+		name := filename(request)
+		current, ok := splits[name]
+		if !ok {
+			current = &split{part: 1}
+			splits[name] = current
+		} else if current.tokens+tokens > maxTokens {
+			current.part++
+			current.tokens = 0
+		}
+		current.tokens += tokens
+		ext := filepath.Ext(name)
+		names[request] = fmt.Sprintf("%s-%03d%s", strings.TrimSuffix(name, ext), current.part, ext)
+	}
+	return func(request *Request) string {
+		return names[request]
+	}
+}
+
+// sampleRequests picks n of the requests at random, with a partial shuffle by
+// the seed, and keeps them in their original order.
+func sampleRequests(requests []*Request, n int, seed int64) []*Request {
+	if n >= len(requests) {
+		return requests
+	}
+	rng := rand.New(rand.NewSource(seed))
+	for i := 0; i < n; i++ {
+		j := i + rng.Intn(len(requests)-i)
+		requests[i], requests[j] = requests[j], requests[i]
+	}
+	sampled := requests[:n]
+	slices.SortFunc(sampled, func(a, b *Request) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return sampled
+}
+
+// splitTrainTest assigns the ratio of the requests to the "train" set and the
+// rest to the "test" set at random by the seed. Good and bad cases are split
+// separately if there are both, so that both sets have the same proportion.
+func splitTrainTest(requests []*Request, ratio float64, seed int64) (sets map[*Request]string, stratified bool) {
+	strata := make(map[string][]*Request)
+	for _, request := range requests {
+		strata[request.Category.String] = append(strata[request.Category.String], request)
+	}
+	stratified = len(strata["goodcase"]) > 0 && len(strata["badcase"]) > 0
+	if !stratified {
+		strata = map[string][]*Request{"": requests}
+	}
+	categories := make([]string, 0, len(strata))
+	for category := range strata {
+		categories = append(categories, category)
+	}
+	slices.Sort(categories)
+	rng := rand.New(rand.NewSource(seed))
+	sets = make(map[*Request]string, len(requests))
+	for _, category := range categories {
+		stratum := slices.Clone(strata[category])
+		rng.Shuffle(len(stratum), func(i, j int) {
+			stratum[i], stratum[j] = stratum[j], stratum[i]
+		})
+		train := int(math.Round(float64(len(stratum)) * ratio))
+		for i, request := range stratum {
+			if i < train {
+				sets[request] = "train"
+			} else {
+				sets[request] = "test"
+			}
+		}
+	}
+	return sets, stratified
+}
+
+// stripDataURIs replaces base64 data URIs in the content parts of messages, such
+// as image_url.url, with a placeholder noting the media type and the size of the
+// decoded data, the SHA-256 of the data is included if hash is set.
+func stripDataURIs(body string, hash bool) string {
+	messages := gjson.Get(body, "messages")
+	if !messages.IsArray() {
+		return body
+	}
+	for i, message := range messages.Array() {
+		content := message.Get("content")
+		if !content.IsArray() {
+			continue
+		}
+		for j, part := range content.Array() {
+			part.ForEach(func(key, value gjson.Result) bool {
+				path := fmt.Sprintf("messages.%d.content.%d.%s", i, j, key.String())
+				if value.IsObject() {
+					path += ".url"
+					value = value.Get("url")
+				}
+				if placeholder, ok := dataURIPlaceholder(value.String(), hash); ok {
+					if stripped, err := sjson.Set(body, path, placeholder); err == nil {
+						body = stripped
+					}
+				}
+				return true
+			})
+		}
+	}
+	return body
+}
+
+// stripToolDefinitions removes the tools offered in the body and the turns in
+// which they are called, that is tool messages and the tool_calls of assistant
+// messages, assistant messages left without content are removed as well.
+func stripToolDefinitions(body string) string {
+	for _, key := range []string{"tools", "tool_choice"} {
+		if stripped, err := sjson.Delete(body, key); err == nil {
+			body = stripped
+		}
+	}
+	messages := gjson.Get(body, "messages")
+	if !messages.IsArray() {
+		return body
+	}
+	kept := make([]string, 0, len(messages.Array()))
+	for _, message := range messages.Array() {
+		role := message.Get("role").String()
+		if role == "tool" {
+			continue
+		}
+		raw := message.Raw
+		if message.Get("tool_calls").Exists() {
+			if role == "assistant" && message.Get("content").String() == "" {
+				continue
+			}
+			if stripped, err := sjson.Delete(raw, "tool_calls"); err == nil {
+				raw = stripped
+			}
+		}
+		kept = append(kept, raw)
+	}
+	if stripped, err := sjson.SetRaw(body, "messages", "["+strings.Join(kept, ",")+"]"); err == nil {
+		body = stripped
+	}
+	return body
+}
+
+// stripContentParts replaces the content of messages made of parts with the
+// text of its text parts joined by newlines, and returns the number of other
+// parts, such as image_url, which are discarded.
+func stripContentParts(body string) (string, int) {
+	messages := gjson.Get(body, "messages")
+	if !messages.IsArray() {
+		return body, 0
+	}
+	var discarded int
+	for i, message := range messages.Array() {
+		content := message.Get("content")
+		if !content.IsArray() {
+			continue
+		}
+		texts := make([]string, 0, len(content.Array()))
+		for _, part := range content.Array() {
+			if part.Get("type").String() == "text" {
+				texts = append(texts, part.Get("text").String())
+			} else {
+				discarded++
+			}
+		}
+		if stripped, err := sjson.Set(body, fmt.Sprintf("messages.%d.content", i), strings.Join(texts, "\n")); err == nil {
+			body = stripped
+		}
+	}
+	return body, discarded
+}
+
+// dataURIPlaceholder returns the placeholder of a base64 data URI such as
+// "data:image/png;base64,...", ok is false if uri is not one.
+func dataURIPlaceholder(uri string, hash bool) (placeholder string, ok bool) {
+	rest, found := strings.CutPrefix(uri, "data:")
+	if !found {
+		return "", false
+	}
+	mediaType, encoded, found := strings.Cut(rest, ";base64,")
+	if !found {
+		return "", false
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", false
+	}
+	if mediaType == "" {
+		mediaType = "text/plain"
+	}
+	if hash {
+		sum := sha256.Sum256(data)
+		return fmt.Sprintf("[base64 %s, %d bytes, sha256 %s]", mediaType, len(data), hex.EncodeToString(sum[:])), true
+	}
+	return fmt.Sprintf("[base64 %s, %d bytes]", mediaType, len(data)), true
+}
+
+func encodeRequest(w io.Writer, request *Request, escapeHTML bool) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "    ")
+	encoder.SetEscapeHTML(escapeHTML)
+	return encoder.Encode(request)
+}
+
+// encodeLangChainMessages writes the conversation as LangChain messages, which
+// can be loaded with langchain_core.messages.messages_from_dict.
+func encodeLangChainMessages(w io.Writer, request *Request, escapeHTML bool) error {
+	messages, err := request.ToLangChainMessage()
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "    ")
+	encoder.SetEscapeHTML(escapeHTML)
+	return encoder.Encode(messages)
+}
+
+func writeColoredDiff(w io.Writer, unified string) {
+	if unified == "" {
+		return
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(unified, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "---"), strings.HasPrefix(line, "+++"):
+			line = boldWhite(line)
+		case strings.HasPrefix(line, "@@"):
+			line = cyan(line)
+		case strings.HasPrefix(line, "-"):
+			line = red(line)
+		case strings.HasPrefix(line, "+"):
+			line = green(line)
+		}
+		io.WriteString(w, line+"\n")
+	}
+}
+
+// readIdentFile reads identifiers such as chatcmpl from the file, one per line,
+// blank lines and lines starting with "#" are ignored.
+func readIdentFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var (
+		idents  []string
+		scanner = bufio.NewScanner(file)
+	)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		idents = append(idents, line)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	return idents, nil
+}
+
+// encodeNDJSON writes the request as a single line of compact JSON.
+func encodeNDJSON(w io.Writer, request *Request, escapeHTML bool) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(escapeHTML)
+	return encoder.Encode(request)
+}
+
+// genBucketFilename returns the NDJSON file the request belongs to when exporting
+// to a directory, requests are split by model, by date or by the uid of the
+// user, or all written to the same file if splitBy is empty. With multiple keys,
+// the buckets of the former keys are directories, such as
+// 2024-08-01/moonshot-v1-8k.ndjson.
+func genBucketFilename(request *Request, splitBy []string, ext string) string {
+	if len(splitBy) == 0 {
+		return "moonpalace" + ext
+	}
+	buckets := make([]string, 0, len(splitBy))
+	for _, key := range splitBy {
+		switch key {
+		case "model":
+			bucket := request.ModelName()
+			if bucket == "" {
+				bucket = "unknown"
+			}
+			buckets = append(buckets, strings.NewReplacer("/", "-", "\\", "-", ":", "-").Replace(bucket))
+		case "uid":
+			bucket := request.MoonshotUID.String
+			if bucket == "" {
+				bucket = "unknown"
+			}
+			buckets = append(buckets, strings.NewReplacer("/", "-", "\\", "-", ":", "-").Replace(bucket))
+		case "date":
+			// The day is the calendar day of the local time at which the
+			// request is made, as created_at is stored.
+			buckets = append(buckets, request.CreatedAt.Format(time.DateOnly))
+		}
+	}
+	return path.Join(buckets...) + ext
+}
+
+// outputFormats maps the extensions of output files to the formats inferred
+// from them when --format is not given, which are the extensions of the files
+// written by each format when exporting to a directory.
+var outputFormats = map[string]string{
+	".json":    "json",
+	".ndjson":  "ndjson",
+	".jsonl":   "jsonl",
+	".txt":     "transcript",
+	".ts":      "typescript",
+	".url":     "playground-link",
+	".sh":      "test-script",
+	".md":      "markdown-issue",
+	".go":      "sdk-go",
+	".parquet": "parquet",
+}
+
+// formatOfOutput returns the format inferred from the extension of the output
+// file or S3 object key, ok is false if the extension is unknown.
+func formatOfOutput(output string) (format string, ok bool) {
+	format, ok = outputFormats[strings.ToLower(filepath.Ext(output))]
+	return format, ok
+}
+
 func genFilename(request *Request) (filename string) {
 	if ident := request.Ident(); strings.HasPrefix(ident, "chatcmpl=") {
 		filename = strings.TrimPrefix(ident, "chatcmpl=") + ".json"
@@ -129,45 +1507,99 @@ func genFilename(request *Request) (filename string) {
 	return filename
 }
 
-func writeCurlCommand(w io.Writer, request *Request) error {
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+func checkEnvName(name string) error {
+	if !envNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid environment variable name %q", name)
+	}
+	return nil
+}
+
+// exportedHeaderFields returns the header lines of the request to reproduce it,
+// in the order and casing they were received unless canonical is set or they
+// were not recorded so. The recorded Content-Type is replaced with contentType
+// if it is not empty.
+func exportedHeaderFields(request *Request, contentType string, canonical bool) []headerField {
+	fields := request.RawHeader()
+	if canonical || fields == nil {
+		header := request.Header()
+		if contentType != "" {
+			header.Set("Content-Type", contentType)
+		}
+		fields = fields[:0]
+		for k, vv := range header {
+			for _, v := range vv {
+				fields = append(fields, headerField{Name: k, Value: v})
+			}
+		}
+	} else if contentType != "" {
+		fields = slices.DeleteFunc(fields, func(field headerField) bool {
+			return strings.EqualFold(field.Name, "Content-Type")
+		})
+		fields = append(fields, headerField{Name: "Content-Type", Value: contentType})
+	}
+	return fields
+}
+
+// writeCurlCommand writes the request as a curl command, the recorded
+// Content-Type is replaced with contentType if it is not empty. The API key is
+// read from the apiKeyEnv variable, and the recorded endpoint is replaced with
+// the baseURLEnv variable if it is not empty. Headers are written in the order
+// and casing they were received unless canonical is set or they were not
+// recorded so.
+func writeCurlCommand(w io.Writer, request *Request, contentType, apiKeyEnv, baseURLEnv string, canonical bool) error {
+	if request.IsRequestBodyTruncated() {
+		return errors.New("request body is truncated, unable to export curl command of " + request.Ident())
+	}
 	escape := func(s string) string {
 		return strings.ReplaceAll(s, "'", `'"'"'`)
 	}
+	url := "'" + escape(request.Url()) + "'"
+	if baseURLEnv != "" {
+		path := request.RequestPath
+		if request.RequestQuery != "" {
+			path += "?" + request.RequestQuery
+		}
+		url = `"$` + baseURLEnv + `"'` + escape(path) + "'"
+	}
 	if _, err := io.WriteString(w,
 		"curl -X '"+
 			escape(request.RequestMethod)+
-			"' '"+
-			escape(request.Url())+
-			"' \\\n\t",
+			"' "+
+			url+
+			" \\\n\t",
 	); err != nil {
 		return err
 	}
 	if _, err := io.WriteString(w,
-		`-H "Authorization: Bearer $MOONSHOT_API_KEY"`+"\\\n\t",
+		`-H "Authorization: Bearer $`+apiKeyEnv+`"`+"\\\n\t",
 	); err != nil {
 		return err
 	}
-	if request.RequestHeader.Valid {
-		mimeHeader, _ := textproto.
-			NewReader(bufio.NewReader(strings.NewReader(request.RequestHeader.String + "\r\n\r\n"))).
-			ReadMIMEHeader()
-		mimeHeader.Del("Content-Length")
-		mimeHeader.Del("X-Unix-Micro")
-		for k, vv := range mimeHeader {
-			for _, v := range vv {
-				if _, err := io.WriteString(w,
-					"-H '"+
-						escape(k)+
-						": "+
-						escape(v)+
-						"' \\\n\t",
-				); err != nil {
-					return err
-				}
-			}
+	fields := exportedHeaderFields(request, contentType, canonical)
+	if request.IsMultipart() {
+		// curl generates the boundary of the form, which is in the Content-Type.
+		fields = slices.DeleteFunc(fields, func(field headerField) bool {
+			return strings.EqualFold(field.Name, "Content-Type")
+		})
+	}
+	for _, field := range fields {
+		if _, err := io.WriteString(w,
+			"-H '"+
+				escape(field.Name)+
+				": "+
+				escape(field.Value)+
+				"' \\\n\t",
+		); err != nil {
+			return err
 		}
 	}
-	if request.RequestBody.Valid {
+	if request.IsMultipart() {
+		if err := writeCurlForm(w, request, escape); err != nil {
+			return err
+		}
+	} else if request.RequestBody.Valid {
 		if _, err := io.WriteString(w,
 			"-d '"+
 				escape(request.RequestBody.String)+
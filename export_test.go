@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+// Numbers in captured bodies are exported byte for byte, even those that do not
+// fit in an int64 or a float64.
+func TestExport_NumberRoundTrip(t *testing.T) {
+	const (
+		seed        = "123456789012345678901234567890"
+		temperature = "0.1000000000000000000001"
+		body        = `{"model":"moonshot-v1-8k","seed":` + seed + `,"temperature":` + temperature + `,"messages":[{"role":"user","content":"hi"}]}`
+	)
+	openTestDatabase(t)
+	var received []byte
+	startTestUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		received, _ = io.ReadAll(r.Body)
+		writeTestCompletion(w, r)
+	})
+	base := startTestProxy(t, testProxy())
+	response, err := http.Post(base+"/v1/chat/completions", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if string(received) != body {
+		t.Errorf("upstream received %s, want %s", received, body)
+	}
+	request := waitForRequest(t, 1)
+	if request.RequestBody.String != body {
+		t.Errorf("stored %s, want %s", request.RequestBody.String, body)
+	}
+	for _, normalize := range []bool{false, true} {
+		if normalize {
+			request.NormalizeJSON()
+		}
+		var exported bytes.Buffer
+		if err = encodeRequest(&exported, request, false); err != nil {
+			t.Fatal(err)
+		}
+		for path, want := range map[string]string{
+			"request.body.seed":        seed,
+			"request.body.temperature": temperature,
+		} {
+			if got := gjson.GetBytes(exported.Bytes(), path).Raw; got != want {
+				t.Errorf("normalize=%t: exported %s is %s, want %s", normalize, path, got, want)
+			}
+		}
+	}
+}
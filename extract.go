@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/MoonshotAI/moonpalace/jsonpointer"
+	"github.com/tidwall/gjson"
+)
+
+// extractSources are the bodies export --extract resolves pointers against.
+var extractSources = []string{"request", "response"}
+
+// writeExtracted writes the value at the JSON pointer of the request or
+// response body on a line as compact JSON, so that strings with line breaks
+// stay on a line of their own. An empty line is written if there is no value at
+// the pointer, so that each request has a line, and missing tells why, while
+// err is an error writing to w.
+func writeExtracted(w io.Writer, request *Request, pointer string, from string) (missing error, err error) {
+	body := request.RequestBody.String
+	if from == "response" {
+		body = request.ResponseBody.String
+		if request.ResponseContentType.String == "text/event-stream" && !gjson.Valid(body) {
+			body = mergeCompletion(body)
+		}
+	}
+	value, missing := jsonpointer.Get([]byte(body), pointer)
+	if missing != nil {
+		_, err = io.WriteString(w, "\n")
+		return fmt.Errorf("%s body: %w", from, missing), err
+	}
+	var line bytes.Buffer
+	if err = json.Compact(&line, value); err != nil {
+		return nil, err
+	}
+	line.WriteByte('\n')
+	_, err = line.WriteTo(w)
+	return nil, err
+}
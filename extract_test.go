@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/MoonshotAI/moonpalace/jsonpointer"
+)
+
+func TestWriteExtracted(t *testing.T) {
+	request := &Request{
+		RequestBody: sql.NullString{String: `{"messages":[{"role":"user","content":"line 1\nline 2"}],"n": 2, "tools" : [ {"type":"function"} ]}`, Valid: true},
+	}
+	for pointer, want := range map[string]string{
+		"/messages/0/content": `"line 1\nline 2"` + "\n",
+		"/n":                  "2\n",
+		"/tools":              `[{"type":"function"}]` + "\n",
+		"/messages/1":         "\n",
+	} {
+		var buf bytes.Buffer
+		missing, err := writeExtracted(&buf, request, pointer, "request")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != want {
+			t.Errorf("%s: got %q, want %q", pointer, buf.String(), want)
+		}
+		if (want == "\n") != errors.Is(missing, jsonpointer.ErrNotFound) {
+			t.Errorf("%s: unexpected missing %v", pointer, missing)
+		}
+	}
+	// Bodies that are not JSON are reported as such rather than as missing.
+	request.RequestBody.String = "not json"
+	missing, err := writeExtracted(new(bytes.Buffer), request, "/n", "request")
+	if err != nil || missing == nil || errors.Is(missing, jsonpointer.ErrNotFound) {
+		t.Errorf("unexpected errors %v, %v for a body that is not JSON", missing, err)
+	}
+}
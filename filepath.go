@@ -37,11 +37,16 @@ func getPalaceDir() string {
 	return workingDir
 }
 
-func getPalaceSqlite() string {
+// getPalaceSqlite returns the path of the database, which is created if create
+// is set, or else an empty path is returned if it does not exist.
+func getPalaceSqlite(create bool) string {
 	palaceDir := getPalaceDir()
 	sqlitePath := filepath.Join(palaceDir, "moonpalace.sqlite")
 	if _, err := os.Stat(sqlitePath); err != nil {
 		if os.IsNotExist(err) {
+			if !create {
+				return ""
+			}
 			if _, err = os.Create(sqlitePath); err != nil {
 				logFatal(err)
 			}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// fineTuneV2Example is a training example of the OpenAI fine-tuning v2 format,
+// in which the system message is a field of its own rather than the first of
+// the messages.
+type fineTuneV2Example struct {
+	System   string            `json:"system"`
+	Messages []json.RawMessage `json:"messages"`
+	Tools    json.RawMessage   `json:"tools,omitempty"`
+}
+
+// ToFineTuneV2 converts the chat request and the reply in its response to a
+// training example, the request must have exactly one system message, whose
+// content is text.
+func (r *Request) ToFineTuneV2() (*fineTuneV2Example, error) {
+	if !r.IsChat() || !gjson.Valid(r.RequestBody.String) {
+		return nil, errors.New("not a chat request: " + r.Ident())
+	}
+	example := &fineTuneV2Example{Messages: make([]json.RawMessage, 0)}
+	var (
+		systems int
+		err     error
+	)
+	gjson.Get(r.RequestBody.String, "messages").ForEach(func(index, message gjson.Result) bool {
+		if message.Get("role").String() != "system" {
+			example.Messages = append(example.Messages, json.RawMessage(message.Raw))
+			return true
+		}
+		if systems++; systems > 1 {
+			err = fmt.Errorf("%s has more than one system message", r.Ident())
+			return false
+		}
+		if content := message.Get("content"); content.Type == gjson.String {
+			example.System = content.String()
+		} else {
+			err = fmt.Errorf("messages[%d]: the content of the system message of %s is not text", index.Int(), r.Ident())
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if systems == 0 {
+		return nil, errors.New(r.Ident() + " has no system message, which the openai-fine-tune-v2 format requires")
+	}
+	response := r.ResponseBody.String
+	if r.ResponseContentType.String == "text/event-stream" && !gjson.Valid(response) {
+		response = mergeCompletion(response)
+	}
+	reply := gjson.Get(response, "choices.0.message")
+	if !reply.Exists() {
+		// Merged streaming responses keep the message in delta.
+		reply = gjson.Get(response, "choices.0.delta")
+	}
+	if !reply.IsObject() {
+		return nil, errors.New("no assistant message found in the response of " + r.Ident())
+	}
+	message, err := sjson.Set(reply.Raw, "role", "assistant")
+	if err != nil {
+		return nil, err
+	}
+	example.Messages = append(example.Messages, json.RawMessage(message))
+	if tools := gjson.Get(r.RequestBody.String, "tools"); tools.IsArray() {
+		example.Tools = json.RawMessage(tools.Raw)
+	}
+	return example, nil
+}
+
+// encodeFineTuneV2 writes the training example of the request as a line of
+// compact JSON.
+func encodeFineTuneV2(w io.Writer, request *Request, escapeHTML bool) error {
+	example, err := request.ToFineTuneV2()
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(escapeHTML)
+	return encoder.Encode(example)
+}
@@ -0,0 +1,61 @@
+// Package gzipbody handles request bodies compressed by clients with gzip, which
+// are decompressed to be inspected and stored, while the bytes sent by the
+// client are forwarded as they are unless the body is modified.
+package gzipbody
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+)
+
+// MaxDecompressedSize is the size bodies are allowed to decompress to, so that
+// a small body does not expand without bound.
+const MaxDecompressedSize = 64 << 20
+
+// ErrTooLarge is returned by Decompress for bodies decompressing to more than
+// MaxDecompressedSize bytes.
+var ErrTooLarge = errors.New("gzipbody: decompressed body is too large")
+
+// Decompress returns the decompressed body, multiple gzip members are
+// concatenated as defined by the gzip format.
+func Decompress(compressed []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	decompressed, err := io.ReadAll(io.LimitReader(reader, MaxDecompressedSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(decompressed) > MaxDecompressedSize {
+		return nil, ErrTooLarge
+	}
+	return decompressed, nil
+}
+
+// Compress compresses the body with the default compression level.
+func Compress(body []byte) ([]byte, error) {
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+	if _, err := writer.Write(body); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// Forward returns the bytes to be forwarded upstream, which are the compressed
+// bytes received from the client if body is still equal to the decompressed
+// body, or body compressed again if it has been modified, such as by rewriting
+// the model.
+func Forward(compressed, decompressed, body []byte) ([]byte, error) {
+	if bytes.Equal(decompressed, body) {
+		return compressed, nil
+	}
+	return Compress(body)
+}
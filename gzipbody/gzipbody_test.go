@@ -0,0 +1,71 @@
+package gzipbody
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"testing"
+)
+
+const testBody = `{"model":"moonshot-v1-8k","messages":[{"role":"user","content":"你好"}]}`
+
+func gzipBytes(t *testing.T, body string) []byte {
+	t.Helper()
+	var buffer bytes.Buffer
+	writer, _ := gzip.NewWriterLevel(&buffer, gzip.BestSpeed)
+	writer.Write([]byte(body))
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
+
+func TestDecompress(t *testing.T) {
+	decompressed, err := Decompress(gzipBytes(t, testBody))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(decompressed) != testBody {
+		t.Errorf("Decompress: got %q, want %q", decompressed, testBody)
+	}
+	if _, err = Decompress([]byte(testBody)); err == nil {
+		t.Errorf("Decompress: expects error for uncompressed body")
+	}
+}
+
+func TestDecompress_TooLarge(t *testing.T) {
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+	zeros := make([]byte, 1<<20)
+	for i := 0; i <= MaxDecompressedSize/len(zeros); i++ {
+		writer.Write(zeros)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Decompress(buffer.Bytes()); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Decompress: got %v, want ErrTooLarge", err)
+	}
+}
+
+func TestForward(t *testing.T) {
+	compressed := gzipBytes(t, testBody)
+	decompressed, err := Decompress(compressed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := Forward(compressed, decompressed, bytes.Clone(decompressed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(body, compressed) {
+		t.Errorf("unmodified body is not forwarded as the compressed body sent by the client")
+	}
+	modified := bytes.Replace(decompressed, []byte("moonshot-v1-8k"), []byte("kimi"), 1)
+	if body, err = Forward(compressed, decompressed, modified); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := Decompress(body); err != nil || !bytes.Equal(got, modified) {
+		t.Errorf("forwarded modified body: got %q (%v), want %q", got, err, modified)
+	}
+}
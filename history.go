@@ -0,0 +1,124 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MoonshotAI/moonpalace/diff"
+	"github.com/mattn/go-sqlite3"
+	"github.com/spf13/cobra"
+)
+
+func historyCommand() *cobra.Command {
+	var (
+		chatcmpl string
+		hash     string
+		replayOf int64
+	)
+	cmd := &cobra.Command{
+		Use:         "history",
+		Short:       "Show how responses to the same request body changed over time",
+		Annotations: map[string]string{databaseAnnotation: databaseRead},
+		Run: func(cmd *cobra.Command, args []string) {
+			if replayOf != 0 {
+				requests, err := persistence.GetReplayChain(replayOf)
+				if err != nil {
+					logFatal(err)
+				}
+				if len(requests) == 0 {
+					logFatal(sql.ErrNoRows)
+				}
+				for _, request := range requests {
+					if request.ResponseContentType.String == "text/event-stream" {
+						request.ResponseBody.String = mergeCompletion(request.ResponseBody.String)
+					}
+				}
+				fmt.Printf("%s #%d, %d requests\n", boldWhite("replays of"), replayOf, len(requests))
+				renderHistory(requests)
+				return
+			}
+			if chatcmpl != "" {
+				request, err := persistence.GetRequest(0, chatcmpl, "", "", "")
+				if err != nil {
+					if errors.Is(err, sql.ErrNoRows) {
+						logFatal(sql.ErrNoRows)
+					}
+					logFatal(err)
+				}
+				if hash = request.BodyHash(); hash == "" {
+					logFatal(errors.New("the request body of " + request.Ident() + " is truncated, its hash is unknown"))
+				}
+			}
+			hash, err := checkHashPrefix(hash)
+			if err != nil {
+				logFatal(err)
+			}
+			requests, err := persistence.ListRequests(0, true, "request_body_hash = '"+hash+"'")
+			if err != nil {
+				if sqliteErr := new(sqlite3.Error); errors.As(err, sqliteErr) {
+					logFatal(sqliteErr)
+				}
+				logFatal(err)
+			}
+			if len(requests) == 0 {
+				logFatal(errors.New("no request found with body hash " + hash))
+			}
+			slices.Reverse(requests)
+			fmt.Printf("%s %s, %d requests\n", boldWhite("body hash"), hash, len(requests))
+			renderHistory(requests)
+		},
+	}
+	flags := cmd.PersistentFlags()
+	flags.StringVar(&chatcmpl, "chatcmpl", "", "find requests with the same body as this chatcmpl")
+	flags.StringVar(&hash, "hash", "", "find requests with the body hash")
+	flags.Int64Var(&replayOf, "replay-of", 0, "show the request of the row id and its replays stored with replay --store-result")
+	cmd.MarkFlagsOneRequired("chatcmpl", "hash", "replay-of")
+	cmd.MarkFlagsMutuallyExclusive("chatcmpl", "hash", "replay-of")
+	return cmd
+}
+
+// renderHistory prints the reply of the first request, and the difference of
+// each reply from the previous one.
+func renderHistory(requests []*Request) {
+	var previous *Request
+	for _, request := range requests {
+		fmt.Printf("\n%s %s %s %s\n",
+			boldYellowf("#%d", request.ID),
+			request.CreatedAt.Format(time.DateTime),
+			request.ModelName(),
+			request.Status(),
+		)
+		if request.SystemFingerprint.Valid {
+			fmt.Println("system_fingerprint: " + request.SystemFingerprint.String)
+		}
+		reply, err := request.AssistantReply()
+		if err != nil {
+			fmt.Println(red(err.Error()))
+			continue
+		}
+		if previous == nil {
+			fmt.Println(reply)
+		} else {
+			previousReply, _ := previous.AssistantReply()
+			unified := diff.Unified(
+				"#"+strconv.FormatInt(previous.ID, 10),
+				"#"+strconv.FormatInt(request.ID, 10),
+				strings.Split(previousReply, "\n"),
+				strings.Split(reply, "\n"),
+				1,
+			)
+			if unified == "" {
+				fmt.Println(green("unchanged"))
+			} else {
+				writeColoredDiff(os.Stdout, unified)
+			}
+		}
+		previous = request
+	}
+}
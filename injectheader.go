@@ -0,0 +1,59 @@
+package main
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// injectedHeader is a header added to every forwarded request, whose value may
+// contain the $UUID and $TIMESTAMP variables.
+type injectedHeader struct {
+	Name  string
+	Value string
+}
+
+// parseInjectHeaders parses headers in the form of "Name: Value", as given to
+// --inject-header.
+func parseInjectHeaders(headers []string) ([]injectedHeader, error) {
+	injected := make([]injectedHeader, 0, len(headers))
+	for _, header := range headers {
+		name, value, ok := strings.Cut(header, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.ContainsAny(name, " \t\r\n") {
+			return nil, fmt.Errorf("--inject-header expects 'Name: Value', got %q", header)
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return nil, errors.New("--inject-header value must not contain line breaks")
+		}
+		injected = append(injected, injectedHeader{
+			Name:  http.CanonicalHeaderKey(name),
+			Value: strings.TrimSpace(value),
+		})
+	}
+	return injected, nil
+}
+
+// expand substitutes $UUID with a random version 4 UUID and $TIMESTAMP with
+// the Unix time in seconds.
+func (h injectedHeader) expand(now time.Time) string {
+	if !strings.Contains(h.Value, "$") {
+		return h.Value
+	}
+	return strings.NewReplacer(
+		"$UUID", newUUID(),
+		"$TIMESTAMP", strconv.FormatInt(now.Unix(), 10),
+	).Replace(h.Value)
+}
+
+func newUUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,103 @@
+// Package jsonpointer resolves JSON Pointers, as defined by RFC 6901, such as
+// /messages/0/content, against JSON documents.
+package jsonpointer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrNotFound is returned when the document has no value at the pointer.
+var ErrNotFound = errors.New("jsonpointer: no value found")
+
+// Parse splits the pointer into its unescaped reference tokens, the empty
+// pointer refers to the whole document and has no tokens.
+func Parse(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("jsonpointer: %q does not start with a slash", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		for j := 0; j < len(token); j++ {
+			if token[j] == '~' && (j+1 == len(token) || token[j+1] != '0' && token[j+1] != '1') {
+				return nil, fmt.Errorf("jsonpointer: %q has an invalid escape, only ~0 and ~1 are allowed", pointer)
+			}
+		}
+		// ~1 is unescaped first, so that ~01 becomes ~1 rather than /.
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// Get returns the value of the document at the pointer, as it is written in
+// the document, or an error wrapping ErrNotFound if there is none.
+func Get(document []byte, pointer string) (json.RawMessage, error) {
+	tokens, err := Parse(pointer)
+	if err != nil {
+		return nil, err
+	}
+	value := json.RawMessage(document)
+	if !json.Valid(value) {
+		return nil, errors.New("jsonpointer: document is not valid JSON")
+	}
+	for i, token := range tokens {
+		at := "/" + strings.Join(tokens[:i+1], "/")
+		switch firstByte(value) {
+		case '{':
+			var object map[string]json.RawMessage
+			if err = json.Unmarshal(value, &object); err != nil {
+				return nil, err
+			}
+			field, ok := object[token]
+			if !ok {
+				return nil, fmt.Errorf("%w at %s", ErrNotFound, at)
+			}
+			value = field
+		case '[':
+			var array []json.RawMessage
+			if err = json.Unmarshal(value, &array); err != nil {
+				return nil, err
+			}
+			index, err := parseIndex(token)
+			if err != nil {
+				return nil, fmt.Errorf("jsonpointer: %s: %w", at, err)
+			}
+			if index >= len(array) {
+				return nil, fmt.Errorf("%w at %s, the array has %d elements", ErrNotFound, at, len(array))
+			}
+			value = array[index]
+		default:
+			return nil, fmt.Errorf("%w at %s, the parent is neither an object nor an array", ErrNotFound, at)
+		}
+	}
+	return value, nil
+}
+
+// parseIndex parses an array index, which is written in decimal without
+// leading zeros.
+func parseIndex(token string) (int, error) {
+	if token == "-" {
+		return 0, errors.New("- refers to the element after the last one, which does not exist")
+	}
+	if token == "" || len(token) > 1 && token[0] == '0' || strings.Trim(token, "0123456789") != "" {
+		return 0, fmt.Errorf("%q is not an array index", token)
+	}
+	return strconv.Atoi(token)
+}
+
+func firstByte(value json.RawMessage) byte {
+	for _, b := range value {
+		switch b {
+		case ' ', '\t', '\r', '\n':
+		default:
+			return b
+		}
+	}
+	return 0
+}
@@ -0,0 +1,85 @@
+package jsonpointer
+
+import (
+	"errors"
+	"testing"
+)
+
+// testDocument is the example document of RFC 6901, section 5.
+const testDocument = `{
+	"foo": ["bar", "baz"],
+	"": 0,
+	"a/b": 1,
+	"c%d": 2,
+	"e^f": 3,
+	"g|h": 4,
+	"i\\j": 5,
+	"k\"l": 6,
+	" ": 7,
+	"m~n": 8,
+	"messages": [{"role": "user", "content": "hi"}]
+}`
+
+func TestGet(t *testing.T) {
+	type testcase struct {
+		pointer, want string
+	}
+	var testcases = []testcase{
+		{pointer: "/foo", want: `["bar", "baz"]`},
+		{pointer: "/foo/0", want: `"bar"`},
+		{pointer: "/", want: `0`},
+		{pointer: "/a~1b", want: `1`},
+		{pointer: "/c%d", want: `2`},
+		{pointer: "/e^f", want: `3`},
+		{pointer: "/g|h", want: `4`},
+		{pointer: "/i\\j", want: `5`},
+		{pointer: "/k\"l", want: `6`},
+		{pointer: "/ ", want: `7`},
+		{pointer: "/m~0n", want: `8`},
+		{pointer: "/messages/0/content", want: `"hi"`},
+	}
+	for _, testcase := range testcases {
+		t.Run(testcase.pointer, func(t *testing.T) {
+			value, err := Get([]byte(testDocument), testcase.pointer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(value) != testcase.want {
+				t.Errorf("Get(%q): got %s, want %s", testcase.pointer, value, testcase.want)
+			}
+		})
+	}
+	whole, err := Get([]byte(`[1]`), "")
+	if err != nil || string(whole) != `[1]` {
+		t.Errorf("Get(\"\"): got %s, %v", whole, err)
+	}
+}
+
+func TestGet_NotFound(t *testing.T) {
+	for _, pointer := range []string{"/missing", "/foo/2", "/foo/0/bar", "/messages/0/name"} {
+		if _, err := Get([]byte(testDocument), pointer); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Get(%q): expected ErrNotFound, got %v", pointer, err)
+		}
+	}
+}
+
+func TestGet_Errors(t *testing.T) {
+	for _, pointer := range []string{"foo", "/m~2n", "/m~", "/foo/01", "/foo/-", "/foo/x"} {
+		if _, err := Get([]byte(testDocument), pointer); err == nil || errors.Is(err, ErrNotFound) {
+			t.Errorf("Get(%q): expected an error other than ErrNotFound, got %v", pointer, err)
+		}
+	}
+	if _, err := Get([]byte(`{"a":`), "/a"); err == nil {
+		t.Error("expected an error for an invalid document")
+	}
+}
+
+func TestParse(t *testing.T) {
+	tokens, err := Parse("/~01/a~1b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != 2 || tokens[0] != "~1" || tokens[1] != "a/b" {
+		t.Errorf("Parse: got %q", tokens)
+	}
+}
@@ -0,0 +1,265 @@
+// Package jsonschema validates JSON documents against the subset of JSON Schema
+// needed to describe API responses: type, enum, properties, required,
+// additionalProperties, items and anyOf. Other keywords are ignored.
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Schema is a compiled JSON schema.
+type Schema struct {
+	Types                []string
+	Enum                 []any
+	Properties           map[string]*Schema
+	Required             []string
+	AdditionalProperties *Schema
+	NoAdditional         bool
+	Items                *Schema
+	AnyOf                []*Schema
+}
+
+// Violation is a place where the document does not conform to the schema.
+type Violation struct {
+	// Path is the location of the offending value, such as
+	// $.choices[0].message.role.
+	Path    string
+	Message string
+}
+
+func (v Violation) String() string {
+	return v.Path + ": " + v.Message
+}
+
+var knownTypes = []string{"object", "array", "string", "number", "integer", "boolean", "null"}
+
+// Compile parses the schema.
+func Compile(data []byte) (*Schema, error) {
+	var raw any
+	if err := unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("jsonschema: %w", err)
+	}
+	return compile(raw, "$")
+}
+
+func compile(raw any, path string) (*Schema, error) {
+	object, ok := raw.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("jsonschema: %s: schema must be an object", path)
+	}
+	s := new(Schema)
+	switch types := object["type"].(type) {
+	case nil:
+	case string:
+		s.Types = []string{types}
+	case []any:
+		for _, t := range types {
+			name, ok := t.(string)
+			if !ok {
+				return nil, fmt.Errorf("jsonschema: %s: type must be a string or an array of strings", path)
+			}
+			s.Types = append(s.Types, name)
+		}
+	default:
+		return nil, fmt.Errorf("jsonschema: %s: type must be a string or an array of strings", path)
+	}
+	for _, t := range s.Types {
+		if !slices.Contains(knownTypes, t) {
+			return nil, fmt.Errorf("jsonschema: %s: unknown type %q", path, t)
+		}
+	}
+	if enum, ok := object["enum"]; ok {
+		if s.Enum, ok = enum.([]any); !ok {
+			return nil, fmt.Errorf("jsonschema: %s: enum must be an array", path)
+		}
+	}
+	if properties, ok := object["properties"]; ok {
+		fields, ok := properties.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("jsonschema: %s: properties must be an object", path)
+		}
+		s.Properties = make(map[string]*Schema, len(fields))
+		for name, field := range fields {
+			property, err := compile(field, path+"."+name)
+			if err != nil {
+				return nil, err
+			}
+			s.Properties[name] = property
+		}
+	}
+	if required, ok := object["required"]; ok {
+		names, ok := required.([]any)
+		if !ok {
+			return nil, fmt.Errorf("jsonschema: %s: required must be an array of strings", path)
+		}
+		for _, name := range names {
+			field, ok := name.(string)
+			if !ok {
+				return nil, fmt.Errorf("jsonschema: %s: required must be an array of strings", path)
+			}
+			s.Required = append(s.Required, field)
+		}
+	}
+	switch additional := object["additionalProperties"].(type) {
+	case nil:
+	case bool:
+		s.NoAdditional = !additional
+	default:
+		schema, err := compile(additional, path+".additionalProperties")
+		if err != nil {
+			return nil, err
+		}
+		s.AdditionalProperties = schema
+	}
+	if items, ok := object["items"]; ok {
+		schema, err := compile(items, path+"[]")
+		if err != nil {
+			return nil, err
+		}
+		s.Items = schema
+	}
+	if anyOf, ok := object["anyOf"]; ok {
+		alternatives, ok := anyOf.([]any)
+		if !ok || len(alternatives) == 0 {
+			return nil, fmt.Errorf("jsonschema: %s: anyOf must be a non-empty array", path)
+		}
+		for i, alternative := range alternatives {
+			schema, err := compile(alternative, path+".anyOf["+strconv.Itoa(i)+"]")
+			if err != nil {
+				return nil, err
+			}
+			s.AnyOf = append(s.AnyOf, schema)
+		}
+	}
+	return s, nil
+}
+
+// Validate checks the document against the schema and returns the violations
+// found, ordered by path, or an error if the document is not valid JSON.
+func (s *Schema) Validate(document []byte) ([]Violation, error) {
+	var value any
+	if err := unmarshal(document, &value); err != nil {
+		return nil, fmt.Errorf("jsonschema: %w", err)
+	}
+	violations := s.validate(value, "$", nil)
+	slices.SortStableFunc(violations, func(a, b Violation) int { return strings.Compare(a.Path, b.Path) })
+	return violations, nil
+}
+
+func (s *Schema) validate(value any, path string, violations []Violation) []Violation {
+	if len(s.Types) > 0 && !slices.ContainsFunc(s.Types, func(t string) bool { return isType(value, t) }) {
+		return append(violations, Violation{path, fmt.Sprintf("expected %s, got %s", strings.Join(s.Types, " or "), typeOf(value))})
+	}
+	if s.Enum != nil && !slices.ContainsFunc(s.Enum, func(e any) bool { return equal(e, value) }) {
+		violations = append(violations, Violation{path, fmt.Sprintf("value %s is not one of the allowed values", encode(value))})
+	}
+	if s.AnyOf != nil && !slices.ContainsFunc(s.AnyOf, func(alternative *Schema) bool {
+		return len(alternative.validate(value, path, nil)) == 0
+	}) {
+		violations = append(violations, Violation{path, "value matches none of the alternatives"})
+	}
+	switch value := value.(type) {
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := value[name]; !ok {
+				violations = append(violations, Violation{path, fmt.Sprintf("missing required property %q", name)})
+			}
+		}
+		names := make([]string, 0, len(value))
+		for name := range value {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			field := path + "." + name
+			if property, ok := s.Properties[name]; ok {
+				violations = property.validate(value[name], field, violations)
+			} else if s.AdditionalProperties != nil {
+				violations = s.AdditionalProperties.validate(value[name], field, violations)
+			} else if s.NoAdditional {
+				violations = append(violations, Violation{field, "unexpected property"})
+			}
+		}
+	case []any:
+		if s.Items != nil {
+			for i, item := range value {
+				violations = s.Items.validate(item, path+"["+strconv.Itoa(i)+"]", violations)
+			}
+		}
+	}
+	return violations
+}
+
+func isType(value any, t string) bool {
+	switch t {
+	case "integer":
+		n, ok := value.(json.Number)
+		if !ok {
+			return false
+		}
+		if _, err := n.Int64(); err == nil {
+			return true
+		}
+		f, err := n.Float64()
+		return err == nil && f == float64(int64(f))
+	case "number":
+		_, ok := value.(json.Number)
+		return ok
+	default:
+		return typeOf(value) == t
+	}
+}
+
+func typeOf(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+func equal(a, b any) bool {
+	if x, ok := a.(json.Number); ok {
+		y, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		fx, errx := x.Float64()
+		fy, erry := y.Float64()
+		return errx == nil && erry == nil && fx == fy
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func encode(value any) string {
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+func unmarshal(data []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	if decoder.More() {
+		return errors.New("unexpected data after the JSON value")
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package jsonschema
+
+import (
+	"slices"
+	"testing"
+)
+
+const testSchema = `{
+	"type": "object",
+	"required": ["id", "object", "choices"],
+	"properties": {
+		"id": {"type": "string"},
+		"object": {"enum": ["chat.completion"]},
+		"created": {"type": "integer"},
+		"choices": {
+			"type": "array",
+			"items": {
+				"type": "object",
+				"required": ["index", "message"],
+				"additionalProperties": false,
+				"properties": {
+					"index": {"type": "integer"},
+					"message": {
+						"type": "object",
+						"properties": {
+							"content": {"type": ["string", "null"]}
+						}
+					},
+					"finish_reason": {"anyOf": [{"type": "null"}, {"enum": ["stop", "length"]}]}
+				}
+			}
+		},
+		"usage": {
+			"type": "object",
+			"additionalProperties": {"type": "integer"}
+		}
+	}
+}`
+
+func TestSchema_Validate(t *testing.T) {
+	schema, err := Compile([]byte(testSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	type testcase struct {
+		name, document string
+		want           []string
+	}
+	var testcases = []testcase{
+		{
+			name:     "valid",
+			document: `{"id":"chatcmpl-1","object":"chat.completion","created":1700000000,"choices":[{"index":0,"message":{"content":null},"finish_reason":"stop"}],"usage":{"total_tokens":10}}`,
+		},
+		{
+			name:     "integer written as float",
+			document: `{"id":"chatcmpl-1","object":"chat.completion","created":1.7e9,"choices":[]}`,
+		},
+		{
+			name:     "missing required",
+			document: `{"object":"chat.completion"}`,
+			want: []string{
+				`$: missing required property "id"`,
+				`$: missing required property "choices"`,
+			},
+		},
+		{
+			name:     "wrong types",
+			document: `{"id":1,"object":"chat.completion","created":1.5,"choices":{},"usage":{"total_tokens":"10"}}`,
+			want: []string{
+				"$.choices: expected array, got object",
+				"$.created: expected integer, got number",
+				"$.id: expected string, got number",
+				"$.usage.total_tokens: expected integer, got string",
+			},
+		},
+		{
+			name:     "nested",
+			document: `{"id":"chatcmpl-1","object":"completion","choices":[{"index":0,"message":{"content":1},"finish_reason":"tool_calls","logprobs":null}]}`,
+			want: []string{
+				"$.choices[0].finish_reason: value matches none of the alternatives",
+				"$.choices[0].logprobs: unexpected property",
+				"$.choices[0].message.content: expected string or null, got number",
+				`$.object: value "completion" is not one of the allowed values`,
+			},
+		},
+	}
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			violations, err := schema.Validate([]byte(testcase.document))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, violation := range violations {
+				got = append(got, violation.String())
+			}
+			if !slices.Equal(got, testcase.want) {
+				t.Errorf("violations:\ngot  %q\nwant %q", got, testcase.want)
+			}
+		})
+	}
+	if _, err = schema.Validate([]byte(`{"id":"x"} {}`)); err == nil {
+		t.Error("expected an error for trailing data")
+	}
+}
+
+func TestCompile_Errors(t *testing.T) {
+	for _, schema := range []string{
+		`[]`,
+		`{"type":"float"}`,
+		`{"type":1}`,
+		`{"properties":{"a":[]}}`,
+		`{"required":[1]}`,
+		`{"anyOf":[]}`,
+	} {
+		if _, err := Compile([]byte(schema)); err == nil {
+			t.Errorf("Compile(%s): expected an error", schema)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+// Package langchain converts Moonshot AI chat messages to LangChain messages,
+// serialized in the form of langchain_core.messages.messages_to_dict, such as
+//
+//	{"type": "human", "data": {"type": "human", "content": "Hello", ...}}
+//
+// which can be loaded with messages_from_dict as HumanMessage, AIMessage,
+// SystemMessage, ToolMessage or ChatMessage.
+package langchain
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Message is a LangChain message serialized with messages_to_dict.
+type Message map[string]any
+
+// types maps the roles of Moonshot AI messages to the types of LangChain
+// messages, other roles are converted to ChatMessage.
+var types = map[string]string{
+	"system":    "system",
+	"user":      "human",
+	"assistant": "ai",
+	"tool":      "tool",
+}
+
+type toolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type chatMessage struct {
+	Role       string          `json:"role"`
+	Content    json.RawMessage `json:"content"`
+	Name       string          `json:"name"`
+	ToolCalls  []toolCall      `json:"tool_calls"`
+	ToolCallID string          `json:"tool_call_id"`
+	Partial    bool            `json:"partial"`
+}
+
+// FromChat converts a Moonshot AI chat message, such as
+// {"role": "user", "content": "Hello"}, to a LangChain message.
+func FromChat(message []byte) (Message, error) {
+	var chat chatMessage
+	if err := json.Unmarshal(message, &chat); err != nil {
+		return nil, fmt.Errorf("langchain: invalid message: %w", err)
+	}
+	if chat.Role == "" {
+		return nil, errors.New("langchain: message has no role")
+	}
+	// Content is either a string or a list of content parts, both of which are
+	// accepted by LangChain, null content of tool calls becomes an empty string.
+	var content any = ""
+	if len(chat.Content) > 0 {
+		if err := unmarshal(chat.Content, &content); err != nil {
+			return nil, fmt.Errorf("langchain: invalid content: %w", err)
+		}
+		if content == nil {
+			content = ""
+		}
+	}
+	typ, ok := types[chat.Role]
+	if !ok {
+		typ = "chat"
+	}
+	data := map[string]any{
+		"type":              typ,
+		"content":           content,
+		"additional_kwargs": map[string]any{},
+		"response_metadata": map[string]any{},
+		"name":              nil,
+		"id":                nil,
+	}
+	if chat.Name != "" {
+		data["name"] = chat.Name
+	}
+	switch typ {
+	case "ai":
+		toolCalls, invalidToolCalls := convertToolCalls(chat.ToolCalls)
+		data["tool_calls"] = toolCalls
+		data["invalid_tool_calls"] = invalidToolCalls
+		if len(chat.ToolCalls) > 0 {
+			data["additional_kwargs"] = map[string]any{"tool_calls": chat.ToolCalls}
+		}
+		if chat.Partial {
+			data["additional_kwargs"].(map[string]any)["partial"] = true
+		}
+	case "tool":
+		data["tool_call_id"] = chat.ToolCallID
+	case "chat":
+		data["role"] = chat.Role
+	}
+	return Message{"type": typ, "data": data}, nil
+}
+
+// convertToolCalls converts tool calls to the tool_calls of AIMessage, tool
+// calls whose arguments are not a valid JSON object are reported in
+// invalid_tool_calls with the arguments as they are.
+func convertToolCalls(toolCalls []toolCall) (valid, invalid []map[string]any) {
+	valid, invalid = []map[string]any{}, []map[string]any{}
+	for _, call := range toolCalls {
+		args := make(map[string]any)
+		if call.Function.Arguments == "" || unmarshal([]byte(call.Function.Arguments), &args) == nil {
+			valid = append(valid, map[string]any{
+				"name": call.Function.Name,
+				"args": args,
+				"id":   call.ID,
+				"type": "tool_call",
+			})
+			continue
+		}
+		invalid = append(invalid, map[string]any{
+			"name":  call.Function.Name,
+			"args":  call.Function.Arguments,
+			"id":    call.ID,
+			"error": "arguments are not a valid JSON object",
+			"type":  "invalid_tool_call",
+		})
+	}
+	return valid, invalid
+}
+
+// unmarshal decodes numbers as json.Number, so that they are encoded back as
+// they are sent, large integers such as ids would lose precision as float64.
+func unmarshal(data []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	if decoder.More() {
+		return errors.New("invalid character after top-level value")
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package langchain
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+const conversation = `[
+	{"role": "system", "content": "You are Kimi."},
+	{"role": "user", "content": "What is the weather in Beijing?"},
+	{"role": "assistant", "content": null, "tool_calls": [{"id": "get_weather:0", "type": "function", "function": {"name": "get_weather", "arguments": "{\"city\": \"Beijing\"}"}}]},
+	{"role": "tool", "tool_call_id": "get_weather:0", "name": "get_weather", "content": "Sunny, 25°C"},
+	{"role": "assistant", "content": "It is sunny in Beijing."},
+	{"role": "user", "content": [{"type": "text", "text": "And this picture?"}, {"type": "image_url", "image_url": {"url": "data:image/png;base64,AA=="}}]},
+	{"role": "assistant", "content": "The picture shows", "partial": true}
+]`
+
+const expected = `[
+	{"type": "system", "data": {"type": "system", "content": "You are Kimi.", "additional_kwargs": {}, "response_metadata": {}, "name": null, "id": null}},
+	{"type": "human", "data": {"type": "human", "content": "What is the weather in Beijing?", "additional_kwargs": {}, "response_metadata": {}, "name": null, "id": null}},
+	{"type": "ai", "data": {"type": "ai", "content": "", "additional_kwargs": {"tool_calls": [{"id": "get_weather:0", "type": "function", "function": {"name": "get_weather", "arguments": "{\"city\": \"Beijing\"}"}}]}, "response_metadata": {}, "name": null, "id": null, "tool_calls": [{"name": "get_weather", "args": {"city": "Beijing"}, "id": "get_weather:0", "type": "tool_call"}], "invalid_tool_calls": []}},
+	{"type": "tool", "data": {"type": "tool", "content": "Sunny, 25°C", "additional_kwargs": {}, "response_metadata": {}, "name": "get_weather", "id": null, "tool_call_id": "get_weather:0"}},
+	{"type": "ai", "data": {"type": "ai", "content": "It is sunny in Beijing.", "additional_kwargs": {}, "response_metadata": {}, "name": null, "id": null, "tool_calls": [], "invalid_tool_calls": []}},
+	{"type": "human", "data": {"type": "human", "content": [{"type": "text", "text": "And this picture?"}, {"type": "image_url", "image_url": {"url": "data:image/png;base64,AA=="}}], "additional_kwargs": {}, "response_metadata": {}, "name": null, "id": null}},
+	{"type": "ai", "data": {"type": "ai", "content": "The picture shows", "additional_kwargs": {"partial": true}, "response_metadata": {}, "name": null, "id": null, "tool_calls": [], "invalid_tool_calls": []}}
+]`
+
+func TestFromChat(t *testing.T) {
+	var messages []json.RawMessage
+	if err := json.Unmarshal([]byte(conversation), &messages); err != nil {
+		t.Fatal(err)
+	}
+	converted := make([]Message, 0, len(messages))
+	for _, message := range messages {
+		langchainMessage, err := FromChat(message)
+		if err != nil {
+			t.Fatal(err)
+		}
+		converted = append(converted, langchainMessage)
+	}
+	// Both are compared after a round trip through JSON, which is the form
+	// loaded by messages_from_dict.
+	data, err := json.Marshal(converted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got, want any
+	if err = json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if err = json.Unmarshal([]byte(expected), &want); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FromChat:\ngot  %s\nwant %s", data, expected)
+	}
+}
+
+func TestFromChat_Errors(t *testing.T) {
+	for _, message := range []string{
+		`{"content": "no role"}`,
+		`"not an object"`,
+	} {
+		if _, err := FromChat([]byte(message)); err == nil {
+			t.Errorf("FromChat(%s): expects error", message)
+		}
+	}
+	message, err := FromChat([]byte(`{"role": "assistant", "tool_calls": [{"id": "a:0", "function": {"name": "a", "arguments": "{\"x\":"}}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := message["data"].(map[string]any)
+	if invalid := data["invalid_tool_calls"].([]map[string]any); len(invalid) != 1 || invalid[0]["args"] != `{"x":` {
+		t.Errorf("FromChat: expects the truncated arguments in invalid_tool_calls, got %v", data["invalid_tool_calls"])
+	}
+}
+
+func TestFromChat_Numbers(t *testing.T) {
+	// Numbers are kept as sent, neither rounded to float64 nor reformatted.
+	const (
+		content = `[{"order_id":12345678901234567891,"text":"order","type":"text","weight":0.10000000000000001}]`
+		args    = `{"order_id":9007199254740993,"amount":1.10}`
+	)
+	message, err := FromChat([]byte(`{"role": "assistant", "content": ` + content + `, "tool_calls": [{"id": "refund:0", "type": "function", "function": {"name": "refund", "arguments": ` + strconv.Quote(args) + `}}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := message["data"].(map[string]any)
+	if got, err := json.Marshal(data["content"]); err != nil || string(got) != content {
+		t.Errorf("FromChat: content want %s, got %s (%v)", content, got, err)
+	}
+	// Keys of maps are encoded in sorted order.
+	const wantArgs = `{"amount":1.10,"order_id":9007199254740993}`
+	toolCalls := data["tool_calls"].([]map[string]any)
+	if got, err := json.Marshal(toolCalls[0]["args"]); err != nil || string(got) != wantArgs {
+		t.Errorf("FromChat: args want %s, got %s (%v)", wantArgs, got, err)
+	}
+}
@@ -1,13 +1,19 @@
 package main
 
 import (
+	"bytes"
+	"cmp"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -17,6 +23,7 @@ import (
 	"github.com/mattn/go-runewidth"
 	"github.com/mattn/go-sqlite3"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var t table.Writer
@@ -45,35 +52,136 @@ var style = table.Style{
 
 func listCommand() *cobra.Command {
 	var (
-		n          int64
-		verbose    bool
-		chatOnly   bool
-		predicates []string
-		export     string
-		escapeHTML bool
+		n           int64
+		verbose     bool
+		chatOnly    bool
+		predicates  []string
+		export      string
+		escapeHTML  bool
+		csvOutput   bool
+		sortBy      string
+		rateLimited bool
+		showLatency bool
+		latencyWarn time.Duration
+		latencyErr  time.Duration
+		jsonlOutput bool
+		follow      bool
+		groupBy     []string
+		afterID     int64
+		afterChat   string
+		toolCalls   bool
+		hashPrefix  string
+		showCost    bool
+		pricesPath  string
 	)
 	cmd := &cobra.Command{
-		Use:   "list",
-		Short: "Query Moonshot AI requests based on conditions",
+		Use:         "list",
+		Short:       "Query Moonshot AI requests based on conditions",
+		Annotations: map[string]string{databaseAnnotation: databaseRead},
 		Run: func(cmd *cobra.Command, args []string) {
-			var predicate string
+			if afterID > 0 || afterChat != "" {
+				after, err := resolveAfterID(afterID, afterChat)
+				if err != nil {
+					logFatal(err)
+				}
+				predicates = append(predicates, "id > "+strconv.FormatInt(after, 10))
+			}
+			var predicate, selected string
 			if parsed, err := Predicates(predicates).Parse(); err != nil {
 				logFatal(fmt.Errorf("predicate: %w", err))
 			} else {
 				predicate = parsed
 			}
+			if toolCalls {
+				selected = andConditions(selected, toolCallCondition)
+			}
+			if hashPrefix != "" {
+				prefix, err := checkHashPrefix(hashPrefix)
+				if err != nil {
+					logFatal(err)
+				}
+				selected = andConditions(selected, "request_body_hash like '"+prefix+"%'")
+			}
+			predicate = andConditions(predicate, selected)
+			if follow && !jsonlOutput {
+				logFatal(errors.New("--follow is only supported with --jsonl"))
+			}
+			var prices priceTable
+			if showCost {
+				if len(groupBy) > 0 {
+					logFatal(errors.New("--show-cost is not supported with --group-by"))
+				}
+				var err error
+				if prices, err = loadPriceTable(pricesPath); err != nil {
+					logFatal(err)
+				}
+			}
+			if showLatency && latencyWarn > latencyErr {
+				logFatal(errors.New("--latency-warn must not be greater than --latency-error"))
+			}
+			if len(groupBy) > 0 {
+				for _, key := range groupBy {
+					if !slices.Contains(groupKeyNames(), key) {
+						logFatal(fmt.Errorf("unsupported group key %q, available keys are %s", key, strings.Join(groupKeyNames(), "/")))
+					}
+				}
+				groups, err := persistence.GroupRequests(groupBy, chatOnly, predicate)
+				if err != nil {
+					logFatal(err)
+				}
+				header, rows := groupRows(groupBy, groups)
+				switch {
+				case jsonlOutput:
+					for _, row := range rows {
+						if err = writeJSONLine(os.Stdout, header, row); err != nil {
+							logFatal(err)
+						}
+					}
+				case csvOutput:
+					if err = writeCSV(os.Stdout, header, rows); err != nil {
+						logFatal(err)
+					}
+				default:
+					t.AppendHeader(header)
+					t.AppendRows(rows)
+					t.Render()
+				}
+				return
+			}
 			// If an export request is needed and the n value is not set,
 			// then there is no limit to the number of queries.
 			if export != "" && !cmd.Flags().Changed("n") {
 				n = 0
 			}
-			requests, err := persistence.ListRequests(n, chatOnly, predicate)
+			// Requests are sorted by conversation length once they are all
+			// fetched, the n longest conversations are then listed.
+			limit := n
+			switch sortBy {
+			case "id":
+			case "conversation_length":
+				limit = 0
+			default:
+				logFatal(fmt.Errorf("unsupported sort key %q, available keys are \"id\"/\"conversation_length\"", sortBy))
+			}
+			requests, err := persistence.ListRequests(limit, chatOnly, predicate)
 			if err != nil {
 				if sqliteErr := new(sqlite3.Error); errors.As(err, sqliteErr) {
 					logFatal(sqliteErr)
 				}
 				logFatal(err)
 			}
+			if sortBy == "conversation_length" {
+				slices.SortStableFunc(requests, func(a, b *Request) int {
+					return cmp.Compare(b.ConversationLength(), a.ConversationLength())
+				})
+				if n > 0 && int64(len(requests)) > n {
+					requests = requests[:n]
+				}
+			}
+			// When following, requests are written in the order they are made.
+			if follow {
+				slices.Reverse(requests)
+			}
 			if export != "" {
 				for _, request := range requests {
 					var file *os.File
@@ -81,10 +189,7 @@ func listCommand() *cobra.Command {
 					if err != nil {
 						logFatal(err)
 					}
-					encoder := json.NewEncoder(file)
-					encoder.SetIndent("", "    ")
-					encoder.SetEscapeHTML(escapeHTML)
-					if err = encoder.Encode(request); err != nil {
+					if err = encodeRequest(file, request, escapeHTML); err != nil {
 						logFatal(err)
 					}
 					logExport(file)
@@ -92,51 +197,180 @@ func listCommand() *cobra.Command {
 				}
 				return
 			}
+			var (
+				header table.Row
+				rows   = make([]table.Row, 0, len(requests))
+				// Colors are not written to CSV or JSON Lines.
+				colored = !csvOutput && !jsonlOutput
+			)
 			if verbose {
-				t.AppendHeader(table.Row{
+				header = table.Row{
 					"id",
 					"url",
 					"method",
 					"status",
+					"finish_reason",
 					"chatcmpl",
 					"request_id",
 					"user_id",
 					"server_timing",
 					"content_type",
 					"requested_at",
-				})
+				}
 			} else {
-				t.AppendHeader(table.Row{
+				header = table.Row{
 					"id",
 					"status",
+					"finish_reason",
 					"chatcmpl",
 					"request_id",
 					"requested_at",
-				})
+				}
 			}
-			for _, request := range requests {
+			if showLatency {
+				header = append(header, "latency")
+			}
+			if rateLimited {
+				header = append(header, "remaining_requests", "remaining_tokens")
+			}
+			if showCost {
+				header = append(header, "cost")
+			}
+			var (
+				totalCost float64
+				unpriced  []string
+			)
+			toRow := func(request *Request) table.Row {
+				var (
+					row             table.Row
+					finishReason, _ = request.FinishReason()
+				)
+				if colored && request.HasToolCallResponse() {
+					finishReason = cyan(finishReason)
+				}
 				if verbose {
-					t.AppendRow(table.Row{
+					row = table.Row{
 						strconv.FormatInt(request.ID, 10),
 						request.Url(),
 						request.RequestMethod,
 						strconv.FormatInt(request.ResponseStatusCode.Int64, 10),
+						finishReason,
 						request.ChatCmpl(),
 						request.MoonshotRequestID.String,
 						request.MoonshotUID.String,
 						strconv.FormatInt(request.MoonshotServerTiming.Int64, 10),
 						request.ResponseContentType.String,
 						request.CreatedAt.Format(time.DateTime),
-					})
+					}
 				} else {
-					t.AppendRow(table.Row{
+					row = table.Row{
 						strconv.FormatInt(request.ID, 10),
 						http.StatusText(int(request.ResponseStatusCode.Int64)),
+						finishReason,
 						request.ChatCmpl(),
 						request.MoonshotRequestID.String,
 						request.CreatedAt.Format(time.DateTime),
-					})
+					}
+				}
+				if showLatency {
+					var latency string
+					if request.Latency.Valid {
+						duration := time.Duration(request.Latency.Int64)
+						latency = strconv.FormatFloat(duration.Seconds(), 'f', 2, 64) + "s"
+						if colored {
+							switch {
+							case duration >= latencyErr:
+								latency = red(latency)
+							case duration >= latencyWarn:
+								latency = yellow(latency)
+							default:
+								latency = green(latency)
+							}
+						}
+					}
+					row = append(row, latency)
+				}
+				if rateLimited {
+					rateLimits := request.RateLimits()
+					row = append(row, rateLimits["remaining_requests"], rateLimits["remaining_tokens"])
+					// Highlight requests that are throttled or close to being throttled.
+					if colored && request.IsNearRateLimit(rateLimitRatio) {
+						for i, cell := range row {
+							row[i] = red(cell)
+						}
+					}
+				}
+				if showCost {
+					// Requests without usage, such as failed requests, have no cost.
+					var cost string
+					if modelPrices, ok := prices.Lookup(request.ModelName()); !ok {
+						if model := request.ModelName(); model != "" && !slices.Contains(unpriced, model) {
+							unpriced = append(unpriced, model)
+						}
+					} else if requestCost, err := request.TotalCost(modelPrices.Prompt, modelPrices.Completion); err == nil {
+						cost = formatCost(requestCost)
+						totalCost += requestCost
+					}
+					row = append(row, cost)
+				}
+				return row
+			}
+			for _, request := range requests {
+				rows = append(rows, toRow(request))
+			}
+			if jsonlOutput {
+				for _, row := range rows {
+					if err = writeJSONLine(os.Stdout, header, row); err != nil {
+						logFatal(err)
+					}
+				}
+				if !follow {
+					return
+				}
+				var lastID int64
+				for _, request := range requests {
+					lastID = max(lastID, request.ID)
 				}
+				for {
+					time.Sleep(followInterval)
+					if parsed, err := Predicates(append(predicates, "id > "+strconv.FormatInt(lastID, 10))).Parse(); err != nil {
+						logFatal(fmt.Errorf("predicate: %w", err))
+					} else {
+						predicate = andConditions(parsed, selected)
+					}
+					if requests, err = persistence.ListRequests(0, chatOnly, predicate); err != nil {
+						logFatal(err)
+					}
+					slices.Reverse(requests)
+					for _, request := range requests {
+						if err = writeJSONLine(os.Stdout, header, toRow(request)); err != nil {
+							logFatal(err)
+						}
+						lastID = request.ID
+					}
+				}
+			}
+			if csvOutput {
+				if err = writeCSV(os.Stdout, header, rows); err != nil {
+					logFatal(err)
+				}
+				return
+			}
+			if len(unpriced) > 0 {
+				logWarning("no prices of " + strings.Join(unpriced, ", ") + " in " + pricesPath)
+			}
+			t.AppendHeader(header)
+			t.AppendRows(rows)
+			if showCost {
+				footer := make(table.Row, len(header))
+				for i := range footer {
+					footer[i] = ""
+				}
+				footer[0], footer[len(footer)-1] = "total", formatCost(totalCost)
+				t.AppendFooter(footer)
+				t.SetColumnConfigs([]table.ColumnConfig{
+					{Name: "cost", Align: text.AlignRight, AlignFooter: text.AlignRight},
+				})
 			}
 			t.Render()
 		},
@@ -145,13 +379,112 @@ func listCommand() *cobra.Command {
 	flags.Int64VarP(&n, "n", "n", 10, "number of results to return")
 	flags.BoolVarP(&verbose, "verbose", "v", false, "verbose output")
 	flags.BoolVar(&chatOnly, "chatonly", false, "chat only output")
-	flags.StringArrayVarP(&predicates, "predicate", "p", nil, "predicate is used to set the conditions for query requests")
+	// --select is accepted as an alias of --predicate.
+	cmd.SetGlobalNormalizationFunc(func(_ *pflag.FlagSet, name string) pflag.NormalizedName {
+		if name == "select" {
+			name = "predicate"
+		}
+		return pflag.NormalizedName(name)
+	})
+	flags.StringArrayVarP(&predicates, "predicate", "p", nil, "predicate is used to set the conditions for query requests, --select is an alias")
+	flags.Int64Var(&afterID, "after-id", 0, "list requests made strictly after the row id")
+	flags.StringVar(&afterChat, "after-chatcmpl", "", "list requests made strictly after the chatcmpl")
+	flags.BoolVar(&toolCalls, "tool-calls", false, "list requests offering tools or functions only, responses with tool calls are highlighted")
+	flags.StringVar(&hashPrefix, "hash", "", "list requests whose body hash starts with the hexadecimal prefix")
 	flags.StringVar(&export, "export", "", "export requests to directory")
 	flags.BoolVar(&escapeHTML, "escape-html", false, "specifies whether problematic HTML characters should be escaped")
+	flags.StringVar(&sortBy, "sort-by", "id", "sort requests in descending order by \"id\"/\"conversation_length\", -n is applied after sorting")
+	flags.BoolVar(&rateLimited, "rate-limited", false, "show remaining rate limits and highlight requests throttled or with less than 10% of the limit remaining")
+	flags.BoolVar(&showLatency, "show-latency", false, "show the latency, colored yellow from --latency-warn and red from --latency-error")
+	flags.DurationVar(&latencyWarn, "latency-warn", 1*time.Second, "latency from which requests are considered slow")
+	flags.DurationVar(&latencyErr, "latency-error", 5*time.Second, "latency from which requests are considered too slow")
+	flags.BoolVar(&showCost, "show-cost", false, "show the estimated cost of each request and the total, with the prices per 1000 tokens of --price-table")
+	flags.StringVar(&pricesPath, "price-table", defaultPriceTablePath(), "path of the TOML file of the prices per 1000 tokens of each model, such as [moonshot-v1-8k] prompt_price = 0.012 and completion_price = 0.012")
+	flags.BoolVar(&csvOutput, "csv", false, "output in CSV format, with a header line")
+	flags.BoolVar(&jsonlOutput, "jsonl", false, "output one JSON object per line, keyed by the column names")
+	flags.BoolVarP(&follow, "follow", "f", false, "with --jsonl, keep writing requests as they are captured")
+	flags.StringSliceVar(&groupBy, "group-by", nil, "print one row per group with the number of requests, errors and tokens instead of one row per request, grouped by "+strings.Join(groupKeyNames(), "/")+", -n does not apply")
+	cmd.MarkFlagsMutuallyExclusive("csv", "export", "jsonl")
+	cmd.MarkFlagsMutuallyExclusive("after-id", "after-chatcmpl")
+	for _, flag := range []string{"export", "follow", "verbose", "sort-by", "rate-limited", "show-latency"} {
+		cmd.MarkFlagsMutuallyExclusive("group-by", flag)
+	}
 	cmd.MarkPersistentFlagDirname("export")
 	return cmd
 }
 
+// groupRows returns the header and rows of the groups, with the keys in the
+// order given followed by the aggregated columns.
+func groupRows(keys []string, groups []*RequestGroup) (table.Row, []table.Row) {
+	header := make(table.Row, 0, len(keys)+6)
+	for _, key := range keys {
+		header = append(header, key)
+	}
+	header = append(header, "requests", "errors", "error_rate", "prompt_tokens", "completion_tokens", "total_tokens")
+	rows := make([]table.Row, 0, len(groups))
+	for _, group := range groups {
+		row := make(table.Row, 0, len(header))
+		for _, key := range keys {
+			row = append(row, group.Key(key))
+		}
+		row = append(row,
+			strconv.FormatInt(group.Requests, 10),
+			strconv.FormatInt(group.Errors, 10),
+			strconv.FormatFloat(group.ErrorRate()*100, 'f', 1, 64)+"%",
+			strconv.FormatInt(group.PromptTokens, 10),
+			strconv.FormatInt(group.CompletionTokens, 10),
+			strconv.FormatInt(group.TotalTokens, 10),
+		)
+		rows = append(rows, row)
+	}
+	return header, rows
+}
+
+// rateLimitRatio is the ratio of the remaining requests or tokens to the limit
+// below which a request is considered near the rate limit.
+const rateLimitRatio = 0.1
+
+// followInterval is how often the database is polled for new requests when
+// following.
+const followInterval = time.Second
+
+// writeJSONLine writes the row as a JSON object with the columns in the order of
+// the header, in a single write so that each line is flushed as a whole.
+func writeJSONLine(w io.Writer, header table.Row, row table.Row) error {
+	var line bytes.Buffer
+	line.WriteByte('{')
+	for i, column := range header {
+		if i > 0 {
+			line.WriteByte(',')
+		}
+		key, _ := json.Marshal(fmt.Sprint(column))
+		value, _ := json.Marshal(fmt.Sprint(row[i]))
+		line.Write(key)
+		line.WriteByte(':')
+		line.Write(value)
+	}
+	line.WriteString("}\n")
+	_, err := w.Write(line.Bytes())
+	return err
+}
+
+// writeCSV writes the header and rows in CSV format, fields containing commas,
+// quotes or newlines are quoted as described in RFC 4180.
+func writeCSV(w io.Writer, header table.Row, rows []table.Row) error {
+	csvWriter := csv.NewWriter(w)
+	for _, row := range append([]table.Row{header}, rows...) {
+		record := make([]string, len(row))
+		for i, field := range row {
+			record[i] = fmt.Sprint(field)
+		}
+		if err := csvWriter.Write(record); err != nil {
+			return err
+		}
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
 func inspectCommand() *cobra.Command {
 	var columns = map[string]struct{}{
 		"metadata":        {},
@@ -176,27 +509,46 @@ func inspectCommand() *cobra.Command {
 		id                          int64
 		chatcmpl                    string
 		requestID                   string
+		uid                         string
+		atTime                      string
 		printColumns                []string
 		printRequest, printResponse bool
+		printReply                  bool
 		mergeEventStream            bool
+		maxDisplayBytes             int
+		full                        bool
 	)
 	cmd := &cobra.Command{
-		Use:   "inspect",
-		Short: "Inspect the specific content of a Moonshot AI request",
+		Use:         "inspect",
+		Short:       "Inspect the specific content of a Moonshot AI request",
+		Annotations: map[string]string{databaseAnnotation: databaseRead},
 		Run: func(cmd *cobra.Command, args []string) {
-			request, err := persistence.GetRequest(id, chatcmpl, requestID)
+			request, err := selectRequest(id, chatcmpl, requestID, uid, atTime)
 			if err != nil {
 				if errors.Is(err, sql.ErrNoRows) {
 					logFatal(sql.ErrNoRows)
 				}
 				logFatal(err)
 			}
+			if full {
+				maxDisplayBytes = 0
+			}
 			switch {
 			case printRequest:
-				request.PrintRequest(os.Stdout)
+				request.PrintRequest(os.Stdout, maxDisplayBytes)
 				return
 			case printResponse:
-				request.PrintResponse(os.Stdout, mergeEventStream)
+				request.PrintResponse(os.Stdout, mergeEventStream, maxDisplayBytes)
+				return
+			case printReply:
+				if mergeEventStream && request.ResponseContentType.String == "text/event-stream" {
+					request.ResponseBody.String = mergeCompletion(request.ResponseBody.String)
+				}
+				reply, err := request.AssistantReply()
+				if err != nil {
+					logFatal(err)
+				}
+				fmt.Println(reply)
 				return
 			}
 			header := make(table.Row, 0, 2)
@@ -220,7 +572,7 @@ func inspectCommand() *cobra.Command {
 			}
 			t.AppendHeader(header)
 			row := make(table.Row, 0, len(header))
-			inspection := request.Inspection()
+			inspection := request.Inspection(maxDisplayBytes)
 			for _, column := range header {
 				switch column {
 				case "error":
@@ -245,12 +597,19 @@ func inspectCommand() *cobra.Command {
 	flags.Int64Var(&id, "id", 0, "row id")
 	flags.StringVar(&chatcmpl, "chatcmpl", "", "chatcmpl")
 	flags.StringVar(&requestID, "requestid", "", "request id returned from Moonshot AI")
+	flags.StringVar(&uid, "uid", "", "user id, used together with --at-time")
+	flags.StringVar(&atTime, "at-time", "", "select the request of the user closest to the time in RFC3339 format")
 	flags.StringSliceVar(&printColumns, "print", []string{"metadata"}, "columns to print, available columns are "+columnsBuilder.String())
 	flags.BoolVar(&printRequest, "print-request", false, "print the request information in HTTP format")
 	flags.BoolVar(&printResponse, "print-response", false, "print the response information in HTTP format")
+	flags.BoolVar(&printReply, "print-reply", false, "print the content of the assistant message in the response")
 	flags.BoolVar(&mergeEventStream, "merge-event-stream", false, "merge response event stream")
-	cmd.MarkFlagsOneRequired("id", "chatcmpl", "requestid")
-	cmd.MarkFlagsMutuallyExclusive("print", "print-request", "print-response")
+	flags.IntVar(&maxDisplayBytes, "max-display-bytes", 8192, "truncate request and response bodies to N bytes, 0 means no limit")
+	flags.BoolVar(&full, "full", false, "print request and response bodies in full, the same as --max-display-bytes 0")
+	cmd.MarkFlagsOneRequired("id", "chatcmpl", "requestid", "at-time")
+	cmd.MarkFlagsRequiredTogether("uid", "at-time")
+	cmd.MarkFlagsMutuallyExclusive("print", "print-request", "print-response", "print-reply")
+	cmd.MarkFlagsMutuallyExclusive("max-display-bytes", "full")
 	return cmd
 }
 
@@ -259,18 +618,15 @@ func cleanupCommand() *cobra.Command {
 		before string
 	)
 	cmd := &cobra.Command{
-		Use:   "cleanup",
-		Short: "Cleanup Moonshot AI requests",
+		Use:         "cleanup",
+		Short:       "Cleanup Moonshot AI requests",
+		Annotations: map[string]string{databaseAnnotation: databaseRead},
 		Run: func(cmd *cobra.Command, args []string) {
-			_, errParseDateOnly := time.Parse(time.DateOnly, before)
-			_, errParseDateTime := time.Parse(time.DateTime, before)
-			if errParseDateOnly != nil && errParseDateTime != nil {
-				logFatal(
-					fmt.Errorf(
-						"the date(time) format is either YYYY-mm-dd or YYYY-mm-dd HH:MM:SS, got %s",
-						before,
-					),
-				)
+			if err := checkDateTime(before); err != nil {
+				logFatal(err)
+			}
+			if err := checkWritable("clean up requests"); err != nil {
+				logFatal(err)
 			}
 			result, err := persistence.Cleanup(before)
 			if err != nil {
@@ -293,3 +649,58 @@ func cleanupCommand() *cobra.Command {
 	)
 	return cmd
 }
+
+// toolCallCondition is the SQL counterpart of Request.IsToolCall.
+const toolCallCondition = "json_valid(request_body) and (json_type(request_body, '$.tools') is not null or json_type(request_body, '$.functions') is not null)"
+
+// checkHashPrefix checks that prefix is a prefix of a body hash, which is a
+// SHA-256 in lower case hexadecimal, and returns it in lower case.
+func checkHashPrefix(prefix string) (string, error) {
+	prefix = strings.ToLower(prefix)
+	if len(prefix) > sha256.Size*2 || strings.Trim(prefix, "0123456789abcdef") != "" {
+		return "", fmt.Errorf("--hash expects a prefix of a hexadecimal SHA-256, got %q", prefix)
+	}
+	return prefix, nil
+}
+
+// andConditions joins the non-empty SQL conditions with "and".
+func andConditions(conditions ...string) string {
+	var joined []string
+	for _, condition := range conditions {
+		if condition != "" {
+			joined = append(joined, "("+condition+")")
+		}
+	}
+	return strings.Join(joined, " and ")
+}
+
+// resolveAfterID returns the row id after which requests are selected, which is
+// afterID itself or the id of the request of afterChatcmpl. Rows are created in
+// the order of their ids, so ids are a gap-free cursor unlike timestamps.
+func resolveAfterID(afterID int64, afterChatcmpl string) (int64, error) {
+	if afterChatcmpl == "" {
+		return afterID, nil
+	}
+	request, err := persistence.GetRequest(0, afterChatcmpl, "", "", "")
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, errors.New("no request found for chatcmpl " + afterChatcmpl)
+		}
+		return 0, err
+	}
+	return request.ID, nil
+}
+
+// checkDateTime checks that value is in the format of the created_at column, or
+// only the date part of it.
+func checkDateTime(value string) error {
+	_, errParseDateOnly := time.Parse(time.DateOnly, value)
+	_, errParseDateTime := time.Parse(time.DateTime, value)
+	if errParseDateOnly != nil && errParseDateTime != nil {
+		return fmt.Errorf(
+			"the date(time) format is either YYYY-mm-dd or YYYY-mm-dd HH:MM:SS, got %s",
+			value,
+		)
+	}
+	return nil
+}
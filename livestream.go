@@ -0,0 +1,142 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"time"
+)
+
+// liveStreamInterval is how often the chunks of a streaming response are
+// written to moonshot_live_streams while it is in flight.
+const liveStreamInterval = 500 * time.Millisecond
+
+// liveStreamBacklog is how many writes of chunks may wait for the database,
+// further chunks are sent along with the next write.
+const liveStreamBacklog = 16
+
+// liveStream writes the chunks of a streaming response as they are received,
+// so that export --follow is able to tail a long generation. The row is removed
+// once the request is stored, the stored response body starts with the chunks
+// written so far. Chunks are written in the background, so that the response
+// is never held up by the database.
+type liveStream struct {
+	moonshotID string
+	flushed    int
+	flushedAt  time.Time
+	chunks     chan string
+	written    chan struct{}
+}
+
+// update sends the chunks of body received since the last write, at most once
+// per liveStreamInterval, chunks are only written once the chatcmpl is known.
+func (s *liveStream) update(moonshotID string, body []byte) {
+	if moonshotID == "" || s.flushed == len(body) {
+		return
+	}
+	if s.chunks == nil {
+		s.moonshotID = moonshotID
+		s.chunks = make(chan string, liveStreamBacklog)
+		s.written = make(chan struct{})
+		go s.write()
+	} else if time.Since(s.flushedAt) < liveStreamInterval {
+		return
+	}
+	select {
+	case s.chunks <- string(body[s.flushed:]):
+		s.flushed, s.flushedAt = len(body), time.Now()
+	default:
+	}
+}
+
+// write writes the chunks sent by update, the first of which starts the row.
+// Once a write fails, the following chunks are dropped, as the row would miss
+// the failed one.
+func (s *liveStream) write() {
+	defer close(s.written)
+	var (
+		started bool
+		failed  bool
+	)
+	for chunk := range s.chunks {
+		if failed {
+			continue
+		}
+		var err error
+		if started {
+			err = persistence.AppendLiveStream(s.moonshotID, chunk)
+		} else {
+			err = persistence.StartLiveStream(s.moonshotID, chunk)
+			started = true
+		}
+		if err != nil {
+			serverErrorLogger.Printf("live stream %s: %v\n", s.moonshotID, err)
+			failed = true
+		}
+	}
+}
+
+// finish waits for the pending chunks to be written and removes the row, after
+// the request is stored.
+func (s *liveStream) finish() {
+	if s.chunks == nil {
+		return
+	}
+	close(s.chunks)
+	<-s.written
+	if err := persistence.FinishLiveStream(s.moonshotID); err != nil {
+		serverErrorLogger.Printf("live stream %s: %v\n", s.moonshotID, err)
+	}
+}
+
+// followStreamInterval is how often export --follow checks for new chunks.
+const followStreamInterval = 200 * time.Millisecond
+
+// followResponse writes the response body of the request of chatcmpl to w as it
+// grows, until the request is stored. Requests already stored are written at
+// once.
+func followResponse(w io.Writer, chatcmpl string) error {
+	var written int
+	write := func(body string) error {
+		if len(body) <= written {
+			return nil
+		}
+		_, err := io.WriteString(w, body[written:])
+		written = len(body)
+		return err
+	}
+	for {
+		request, err := persistence.GetRequest(0, chatcmpl, "", "", "")
+		if err == nil {
+			// The stored body starts with the chunks written so far.
+			return write(request.ResponseBody.String)
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		body, err := persistence.GetLiveStream(chatcmpl)
+		switch {
+		case err == nil:
+			if err = write(body); err != nil {
+				return err
+			}
+		case !errors.Is(err, sql.ErrNoRows):
+			return err
+		default:
+			// The request may have been stored since it was looked up, the row
+			// is only removed after it is.
+			if _, err = persistence.GetRequest(0, chatcmpl, "", "", ""); err == nil {
+				continue
+			} else if !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+			if written == 0 {
+				return errors.New("no request is captured or in flight for chatcmpl " + chatcmpl)
+			}
+			// Requests are not stored while capture is paused with the admin
+			// API, there is nothing more to follow.
+			return errors.New("the response of chatcmpl " + chatcmpl + " ended without being stored")
+		}
+		time.Sleep(followStreamInterval)
+	}
+}
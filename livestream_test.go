@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestLiveStream(t *testing.T) {
+	openTestDatabase(t)
+	var live liveStream
+	live.update("chatcmpl-live", []byte("data: 1\n\n"))
+	live.flushedAt = time.Time{}
+	live.update("chatcmpl-live", []byte("data: 1\n\ndata: 2\n\n"))
+	close(live.chunks)
+	<-live.written
+	chunks, err := persistence.GetLiveStream("chatcmpl-live")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chunks != "data: 1\n\ndata: 2\n\n" {
+		t.Errorf("unexpected chunks %q", chunks)
+	}
+}
+
+func TestFollowResponse_NotStored(t *testing.T) {
+	openTestDatabase(t)
+	if err := persistence.StartLiveStream("chatcmpl-paused", "data: 1\n\n"); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	var buf bytes.Buffer
+	go func() { done <- followResponse(&buf, "chatcmpl-paused") }()
+	time.Sleep(2 * followStreamInterval)
+	// As when capture is paused, the row is removed without the request being stored.
+	if err := persistence.FinishLiveStream("chatcmpl-paused"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("expected an error for a response that was not stored")
+		}
+	case <-time.After(10 * followStreamInterval):
+		t.Fatal("followResponse did not return once the live stream was finished")
+	}
+	if buf.String() != "data: 1\n\n" {
+		t.Errorf("unexpected output %q", buf.String())
+	}
+}
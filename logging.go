@@ -8,6 +8,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/fatih/color"
@@ -18,6 +19,40 @@ var (
 	serverErrorLogger = log.New(getPalaceServerErrorLog(), "", log.LstdFlags)
 )
 
+// logLevel is the least severe level of the messages logged, which is changed
+// at runtime with the admin API to quiet a busy proxy.
+type logLevel int32
+
+const (
+	// logLevelInfo logs every request.
+	logLevelInfo logLevel = iota
+	// logLevelWarn logs warnings and requests with warnings or errors.
+	logLevelWarn
+	// logLevelError logs failed requests only.
+	logLevelError
+)
+
+var logLevelNames = []string{"info", "warn", "error"}
+
+func (l logLevel) String() string {
+	return logLevelNames[l]
+}
+
+func parseLogLevel(name string) (logLevel, error) {
+	for i, levelName := range logLevelNames {
+		if strings.EqualFold(name, levelName) {
+			return logLevel(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unsupported log level %q, available levels are %s", name, strings.Join(logLevelNames, "/"))
+}
+
+var currentLogLevel atomic.Int32
+
+func loggable(level logLevel) bool {
+	return level >= logLevel(currentLogLevel.Load())
+}
+
 var (
 	boldWhite   = color.New(color.FgHiWhite, color.Bold).SprintFunc()
 	boldGreen   = color.New(color.FgGreen, color.Bold).SprintFunc()
@@ -27,6 +62,8 @@ var (
 	boldRed     = color.New(color.FgRed, color.Bold).SprintFunc()
 	green       = color.New(color.FgHiGreen).SprintFunc()
 	red         = color.New(color.FgRed).SprintFunc()
+	yellow      = color.New(color.FgYellow).SprintFunc()
+	cyan        = color.New(color.FgCyan).SprintFunc()
 )
 
 const asciiMoonPalace = `
@@ -66,6 +103,16 @@ func logRequest(
 	requestHeader http.Header,
 	responseHeader http.Header,
 ) {
+	level := logLevelInfo
+	switch {
+	case err != nil:
+		level = logLevelError
+	case len(warnings) > 0:
+		level = logLevelWarn
+	}
+	if !loggable(level) {
+		return
+	}
 	if query != "" {
 		path += "?" + query
 	}
@@ -167,16 +214,93 @@ func _boolToInt(b bool) int {
 }
 
 func logNewRow(id int64) {
+	if !loggable(logLevelInfo) {
+		return
+	}
 	logger.Println(
 		boldWhite("  New Row Inserted:"),
 		boldGreenf("last_insert_id=%d", id),
 	)
 }
 
-func logExport(file *os.File) {
+func logWebhookFailure(err error) {
+	logger.Println(boldRed("  Error Webhook Failed:"), err.Error())
+}
+
+func logOTLPFailure(err error) {
+	logger.Println(boldRed("  OTLP Export Failed:"), err.Error())
+}
+
+func logExport(file exportFile) {
 	logger.Println("export to", boldGreen(file.Name()), "successfully")
 }
 
+func logExportCount(file exportFile, n int) {
+	logger.Println("export", n, "requests to", boldGreen(file.Name()), "successfully")
+}
+
+func logResponseCacheHit(method, path string, id int64) {
+	if !loggable(logLevelInfo) {
+		return
+	}
+	logger.Printf("%s %s %s\n",
+		boldYellowf("%-6s", method),
+		boldWhite(path),
+		green("served from response cache, id="+strconv.FormatInt(id, 10)),
+	)
+}
+
+func logResponseCacheStats(hits, misses int64) {
+	logger.Printf("response cache: %d hits, %d misses\n", hits, misses)
+}
+
+func logAdminStarts(addr string) {
+	logger.Printf("admin API listening on http://%s\n", addr)
+}
+
+func logCaptureToggled(enabled bool) {
+	if enabled {
+		logger.Println(green("capture resumed with the admin API"))
+	} else {
+		logger.Println(boldYellow("capture paused with the admin API, requests are forwarded without being stored"))
+	}
+}
+
+func logLogLevelChanged(level logLevel) {
+	logger.Printf("log level set to %s with the admin API\n", level)
+}
+
+func logAdminCleanup(before string, deleted int64) {
+	logger.Printf("cleaned up %d requests made before %s with the admin API\n", deleted, before)
+}
+
+func logSample(n, eligible int, seed int64) {
+	logger.Printf("sampled %d of %d eligible requests (%.1f%%) with --seed %d",
+		n, eligible, float64(n)*100/float64(eligible), seed)
+}
+
+func logSplit(sets map[*Request]string, stratified bool, seed int64) {
+	var train int
+	for _, set := range sets {
+		if set == "train" {
+			train++
+		}
+	}
+	var by string
+	if stratified {
+		by = " stratified by category"
+	}
+	logger.Printf("split %d requests into %d for training and %d for testing%s with --seed %d",
+		len(sets), train, len(sets)-train, by, seed)
+}
+
+func logWarning(message string) {
+	if !loggable(logLevelWarn) {
+		return
+	}
+	fmt.Fprintln(os.Stderr, boldYellow("[WARNING] "+message))
+}
+
 func logFatal(err error) {
 	if errorMsg := err.Error(); errorMsg != "" {
 		for _, line := range strings.Split(errorMsg, "\n") {
@@ -11,6 +11,15 @@ func init() {
 		inspectCommand(),
 		cleanupCommand(),
 		exportCommand(),
+		statsCommand(),
+		replayCommand(),
+		historyCommand(),
+		auditCommand(),
+		browseCommand(),
+		noteCommand(),
+		tagCommand(),
+		compareCommand(),
+		reindexCommand(),
 	)
 }
 
@@ -21,9 +30,16 @@ var (
 		Short:         "MoonPalace is a command-line tool for debugging the Moonshot AI HTTP API",
 		SilenceErrors: true,
 		SilenceUsage:  true,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			openPersistence(cmd)
+		},
 	}
 )
 
+func init() {
+	MoonPalace.PersistentFlags().BoolVar(&readOnly, "read-only", false, "open the database in read-only mode and refuse commands that modify it, such as tagging requests, for shared databases")
+}
+
 func main() {
 	if err := MoonPalace.Execute(); err != nil {
 		logFatal(err)
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// writeMarkdownIssue writes the request as a bug report in Markdown, with the
+// identifiers asked for by Moonshot AI support, the parameters and messages of
+// the request, the observed response and a curl command to reproduce it. The
+// reasoning of assistant messages is written in a collapsed section before
+// their content, unless hideReasoning is set.
+func writeMarkdownIssue(w io.Writer, request *Request, apiKeyEnv, baseURLEnv string, canonicalHeaders, hideReasoning bool) error {
+	var issue strings.Builder
+	issue.WriteString("# Bad case: " + request.Ident() + "\n\n")
+	row := func(name, value string) {
+		if value != "" {
+			issue.WriteString("| " + name + " | " + markdownCell(value) + " |\n")
+		}
+	}
+	issue.WriteString("| | |\n| --- | --- |\n")
+	if chatcmpl := request.ChatCmpl(); chatcmpl != "" {
+		row("chatcmpl", "`"+chatcmpl+"`")
+	}
+	if request.MoonshotRequestID.String != "" {
+		row("Msh-Request-Id", "`"+request.MoonshotRequestID.String+"`")
+	}
+	row("model", request.ModelName())
+	row("endpoint", request.RequestMethod+" "+request.RequestPath)
+	row("status", request.Status())
+	if finishReason, err := request.FinishReason(); err == nil {
+		row("finish_reason", finishReason)
+	}
+	row("requested_at", request.CreatedAt.Format(time.DateTime))
+	if request.Latency.Valid {
+		row("latency", strconv.FormatInt(request.Latency.Int64/int64(time.Millisecond), 10)+"ms")
+	}
+	if request.MoonshotServerTiming.Valid {
+		row("server_timing", strconv.FormatInt(request.MoonshotServerTiming.Int64, 10)+"ms")
+	}
+	row("system_fingerprint", request.SystemFingerprint.String)
+
+	if body := gjson.Parse(request.RequestBody.String); body.IsObject() {
+		var names []string
+		values := make(map[string]string)
+		body.ForEach(func(key, value gjson.Result) bool {
+			if name := key.String(); name != "messages" && name != "model" {
+				names = append(names, name)
+				values[name] = value.Raw
+			}
+			return true
+		})
+		if len(names) > 0 {
+			sort.Strings(names)
+			issue.WriteString("\n## Parameters\n\n| parameter | value |\n| --- | --- |\n")
+			for _, name := range names {
+				issue.WriteString("| " + markdownCell(name) + " | `" + markdownCell(values[name]) + "` |\n")
+			}
+		}
+	}
+
+	if turns := transcriptTurns(request); len(turns) > 0 {
+		issue.WriteString("\n## Conversation\n")
+		for _, turn := range turns {
+			issue.WriteString("\n**" + turn.Role + "**\n\n")
+			if turn.Reasoning != "" && !hideReasoning {
+				issue.WriteString(reasoningDetails(turn.Reasoning))
+			}
+			issue.WriteString(fenced("", turn.Content))
+		}
+	}
+
+	issue.WriteString("\n## Observed response\n\n")
+	if reply, ok := transcriptReply(request); ok && !request.HasError() {
+		if reply.Reasoning != "" && !hideReasoning {
+			issue.WriteString(reasoningDetails(reply.Reasoning))
+		}
+		issue.WriteString(fenced("", reply.Content))
+	} else if request.ResponseBody.Valid {
+		issue.WriteString(fenced("json", formatJSON(request.ResponseBody.String)))
+	} else if request.Error.Valid {
+		issue.WriteString(fenced("", request.Error.String))
+	} else {
+		issue.WriteString("No response was recorded.\n")
+	}
+
+	var usage []string
+	for _, field := range []string{"prompt_tokens", "completion_tokens", "cached_tokens", "total_tokens"} {
+		if tokens, ok := request.usage(field); ok {
+			usage = append(usage, field+": "+strconv.FormatInt(tokens, 10))
+		}
+	}
+	if len(usage) > 0 {
+		issue.WriteString("\n## Usage\n\n")
+		for _, field := range usage {
+			issue.WriteString("- " + field + "\n")
+		}
+	}
+
+	issue.WriteString("\n## Reproduction\n\n")
+	var curl bytes.Buffer
+	if err := writeCurlCommand(&curl, request, "", apiKeyEnv, baseURLEnv, canonicalHeaders); err != nil {
+		issue.WriteString("The request cannot be reproduced with curl: " + err.Error() + ".\n")
+	} else {
+		issue.WriteString(fenced("sh", curl.String()))
+	}
+	issue.WriteString("\n")
+	_, err := io.WriteString(w, issue.String())
+	return err
+}
+
+// reasoningDetails wraps the reasoning of an assistant message in a section
+// which is collapsed when the Markdown is rendered.
+func reasoningDetails(reasoning string) string {
+	return "<details>\n<summary>Reasoning</summary>\n\n" + fenced("", reasoning) + "\n</details>\n\n"
+}
+
+// markdownCell escapes the pipes and line breaks of a table cell.
+func markdownCell(value string) string {
+	return strings.NewReplacer("|", `\|`, "\r\n", "<br>", "\n", "<br>").Replace(value)
+}
+
+// fenced wraps text in a code block, whose fence is longer than any run of
+// backticks in the text.
+func fenced(lang, text string) string {
+	longest, run := 0, 0
+	for _, char := range text {
+		if char == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	fence := strings.Repeat("`", max(3, longest+1))
+	return fence + lang + "\n" + strings.TrimRight(text, "\n") + "\n" + fence + "\n"
+}
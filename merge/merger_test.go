@@ -85,3 +85,30 @@ func TestMerger_MergeObject(t *testing.T) {
 		t.Errorf("\nwant: %s\ngot:  %s", string(wantjs), string(objectjs))
 	}
 }
+
+func TestMerger_MergeObject_Numbers(t *testing.T) {
+	var (
+		object = make(map[string]any)
+		chunks = []string{
+			`{"created":12345678901234567891,"choices":[{"index":0,"delta":{"content":"a"},"logprob":-0.10000000000000001}]}`,
+			`{"created":12345678901234567891,"choices":[{"index":0,"delta":{"content":"b"}}],"usage":{"total_tokens":9007199254740993}}`,
+		}
+	)
+	for _, chunk := range chunks {
+		var next map[string]any
+		decoder := json.NewDecoder(strings.NewReader(chunk))
+		decoder.UseNumber()
+		if err := decoder.Decode(&next); err != nil {
+			t.Fatal(err)
+		}
+		merger.MergeObject(object, next)
+	}
+	const want = `{"choices":[{"delta":{"content":"ab"},"index":0,"logprob":-0.10000000000000001}],"created":12345678901234567891,"usage":{"total_tokens":9007199254740993}}`
+	got, err := json.Marshal(object)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("\nwant: %s\ngot:  %s", want, got)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// multipartPart is a part of a multipart/form-data request body, such as the
+// file uploaded to /v1/files.
+type multipartPart struct {
+	Name        string
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// IsMultipart reports whether the request body is multipart/form-data, which is
+// stored as received so that its parts can be recovered with MultipartParts.
+func (r *Request) IsMultipart() bool {
+	return r.RequestContentType.String == "multipart/form-data"
+}
+
+// MultipartParts parses the request body with the boundary of the stored
+// Content-Type header.
+func (r *Request) MultipartParts() ([]multipartPart, error) {
+	if !r.IsMultipart() {
+		return nil, errors.New("request body is not multipart/form-data")
+	}
+	if r.IsRequestBodyTruncated() {
+		return nil, errors.New("request body is truncated, unable to parse multipart parts of " + r.Ident())
+	}
+	_, params, err := mime.ParseMediaType(r.RequestHeaders().Get("Content-Type"))
+	if err != nil {
+		return nil, err
+	}
+	if params["boundary"] == "" {
+		return nil, errors.New("no multipart boundary in the Content-Type header of " + r.Ident())
+	}
+	reader := multipart.NewReader(strings.NewReader(r.RequestBody.String), params["boundary"])
+	var parts []multipartPart
+	for {
+		part, err := reader.NextRawPart()
+		if err == io.EOF {
+			return parts, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(part)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, multipartPart{
+			Name:        part.FormName(),
+			Filename:    part.FileName(),
+			ContentType: part.Header.Get("Content-Type"),
+			Data:        data,
+		})
+	}
+}
+
+// multipartPartMarshaler describes a part in the export, only the values of
+// fields that are not files are included, files are kept in the body.
+type multipartPartMarshaler struct {
+	Name        string `json:"name"`
+	Filename    string `json:"filename,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Size        int    `json:"size"`
+	Value       string `json:"value,omitempty"`
+}
+
+func marshalMultipartParts(parts []multipartPart) []multipartPartMarshaler {
+	marshaled := make([]multipartPartMarshaler, len(parts))
+	for i, part := range parts {
+		marshaled[i] = multipartPartMarshaler{
+			Name:        part.Name,
+			Filename:    part.Filename,
+			ContentType: part.ContentType,
+			Size:        len(part.Data),
+		}
+		if part.Filename == "" && utf8.Valid(part.Data) {
+			marshaled[i].Value = string(part.Data)
+		}
+	}
+	return marshaled
+}
+
+// writeCurlForm writes the parts as curl -F options, files are extracted to a
+// temporary directory, which is left for the command to upload them from.
+func writeCurlForm(w io.Writer, request *Request, escape func(string) string) error {
+	parts, err := request.MultipartParts()
+	if err != nil {
+		return err
+	}
+	var dir string
+	for i, part := range parts {
+		var option string
+		if part.Filename == "" {
+			// --form-string does not treat values starting with @ or < as files.
+			option = "--form-string '" + escape(part.Name+"="+string(part.Data)) + "'"
+		} else {
+			if dir == "" {
+				if dir, err = os.MkdirTemp("", "moonpalace-multipart-"); err != nil {
+					return err
+				}
+				logWarning("files of " + request.Ident() + " are extracted to " + dir)
+			}
+			path := filepath.Join(dir, strconv.Itoa(i)+"-"+filepath.Base(part.Filename))
+			if err = os.WriteFile(path, part.Data, 0o644); err != nil {
+				return err
+			}
+			value := part.Name + "=@" + path + ";filename=" + strconv.Quote(part.Filename)
+			if part.ContentType != "" {
+				value += ";type=" + part.ContentType
+			}
+			option = "-F '" + escape(value) + "'"
+		}
+		if i < len(parts)-1 {
+			option += " \\\n\t"
+		}
+		if _, err = io.WriteString(w, option); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func noteCommand() *cobra.Command {
+	var (
+		id        int64
+		chatcmpl  string
+		requestID string
+		clearNote bool
+	)
+	cmd := &cobra.Command{
+		Use:         "note [text]",
+		Short:       "Set, print or clear the note of a Moonshot AI request",
+		Annotations: map[string]string{databaseAnnotation: databaseRead},
+		Args:        cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			request, err := selectRequest(id, chatcmpl, requestID, "", "")
+			if err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					logFatal(sql.ErrNoRows)
+				}
+				logFatal(err)
+			}
+			switch {
+			case clearNote:
+				if len(args) > 0 {
+					logFatal(errors.New("--clear does not accept a note"))
+				}
+				request.Note = sql.NullString{}
+			case len(args) > 0:
+				// An empty note clears the note as well.
+				request.Note = sql.NullString{String: args[0], Valid: args[0] != ""}
+			default:
+				if request.Note.Valid {
+					fmt.Println(request.Note.String)
+				}
+				return
+			}
+			if err = checkWritable("set the note"); err != nil {
+				logFatal(err)
+			}
+			if err = persistence.SetNote(request.ID, request.Note); err != nil {
+				logFatal(err)
+			}
+		},
+	}
+	flags := cmd.PersistentFlags()
+	flags.Int64Var(&id, "id", 0, "row id")
+	flags.StringVar(&chatcmpl, "chatcmpl", "", "chatcmpl")
+	flags.StringVar(&requestID, "requestid", "", "request id returned from Moonshot AI")
+	flags.BoolVar(&clearNote, "clear", false, "clear the note")
+	cmd.MarkFlagsOneRequired("id", "chatcmpl", "requestid")
+	return cmd
+}
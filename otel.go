@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var otelClient = &http.Client{
+	Timeout: 10 * time.Second,
+}
+
+// otelTracesPath is appended to the OTLP endpoint unless it is already there,
+// as OTEL_EXPORTER_OTLP_ENDPOINT does.
+const otelTracesPath = "/v1/traces"
+
+// otelTracesURL returns the URL to which spans are posted.
+func otelTracesURL(endpoint string) string {
+	endpoint = strings.TrimSuffix(endpoint, "/")
+	if strings.HasSuffix(endpoint, otelTracesPath) {
+		return endpoint
+	}
+	return endpoint + otelTracesPath
+}
+
+// traceContext identifies the span of a proxied request, which is the child of
+// the span given in the traceparent header sent by the client, if any.
+type traceContext struct {
+	TraceID      string
+	SpanID       string
+	ParentSpanID string
+}
+
+// newTraceContext continues the trace of the W3C traceparent header, such as
+// 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01, or starts a new
+// trace if the header is missing or malformed.
+func newTraceContext(traceparent string) *traceContext {
+	tc := &traceContext{SpanID: randomHex(8)}
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) == 4 && len(parts[0]) == 2 && parts[0] != "ff" &&
+		isNonZeroHex(parts[1], 32) && isNonZeroHex(parts[2], 16) {
+		tc.TraceID, tc.ParentSpanID = strings.ToLower(parts[1]), strings.ToLower(parts[2])
+	} else {
+		tc.TraceID = randomHex(16)
+	}
+	return tc
+}
+
+// Traceparent returns the traceparent header propagated to the endpoint, whose
+// parent is the span of the proxied request.
+func (tc *traceContext) Traceparent() string {
+	return "00-" + tc.TraceID + "-" + tc.SpanID + "-01"
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func isNonZeroHex(s string, length int) bool {
+	if len(s) != length || strings.Trim(s, "0") == "" {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// requestSpan is the span of a proxied request.
+type requestSpan struct {
+	*traceContext
+	Name       string
+	Start, End time.Time
+	Attributes map[string]any
+	// Error is the status message of a failed request, the span is marked as
+	// an error if it is not empty.
+	Error string
+}
+
+type otlpAnyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+// otlpAttributes converts the attributes in the JSON encoding of OTLP, where
+// 64-bit integers are strings.
+func otlpAttributes(attributes map[string]any) []otlpKeyValue {
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	keyValues := make([]otlpKeyValue, 0, len(attributes))
+	for _, key := range keys {
+		value := attributes[key]
+		var anyValue otlpAnyValue
+		switch v := value.(type) {
+		case string:
+			if v == "" {
+				continue
+			}
+			anyValue.StringValue = &v
+		case int:
+			s := strconv.Itoa(v)
+			anyValue.IntValue = &s
+		case int64:
+			s := strconv.FormatInt(v, 10)
+			anyValue.IntValue = &s
+		case float64:
+			anyValue.DoubleValue = &v
+		case bool:
+			anyValue.BoolValue = &v
+		default:
+			s := fmt.Sprint(v)
+			anyValue.StringValue = &s
+		}
+		keyValues = append(keyValues, otlpKeyValue{Key: key, Value: anyValue})
+	}
+	return keyValues
+}
+
+const (
+	otlpSpanKindServer = 2
+	// Spans of successful requests are left unset rather than OK, as
+	// instrumentations are supposed to do.
+	otlpStatusCodeUnset = 0
+	otlpStatusCodeError = 2
+)
+
+// exportSpan posts the span to the OTLP/HTTP collector in the JSON encoding.
+func exportSpan(endpoint string, span *requestSpan) error {
+	type otlpStatus struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+	type otlpSpan struct {
+		TraceID           string         `json:"traceId"`
+		SpanID            string         `json:"spanId"`
+		ParentSpanID      string         `json:"parentSpanId,omitempty"`
+		Name              string         `json:"name"`
+		Kind              int            `json:"kind"`
+		StartTimeUnixNano string         `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+		Attributes        []otlpKeyValue `json:"attributes"`
+		Status            otlpStatus     `json:"status"`
+	}
+	status := otlpStatus{Code: otlpStatusCodeUnset}
+	if span.Error != "" {
+		status = otlpStatus{Code: otlpStatusCodeError, Message: span.Error}
+	}
+	payload, err := json.Marshal(map[string]any{
+		"resourceSpans": []any{map[string]any{
+			"resource": map[string]any{
+				"attributes": otlpAttributes(map[string]any{"service.name": "moonpalace"}),
+			},
+			"scopeSpans": []any{map[string]any{
+				"scope": map[string]any{"name": "github.com/MoonshotAI/moonpalace"},
+				"spans": []otlpSpan{{
+					TraceID:           span.TraceID,
+					SpanID:            span.SpanID,
+					ParentSpanID:      span.ParentSpanID,
+					Name:              span.Name,
+					Kind:              otlpSpanKindServer,
+					StartTimeUnixNano: strconv.FormatInt(span.Start.UnixNano(), 10),
+					EndTimeUnixNano:   strconv.FormatInt(span.End.UnixNano(), 10),
+					Attributes:        otlpAttributes(span.Attributes),
+					Status:            status,
+				}},
+			}},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+	response, err := otelClient.Post(otelTracesURL(endpoint), "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("OTLP collector responded with %s", response.Status)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/MoonshotAI/moonpalace/parquet"
+)
+
+// parquetField maps the type of a field of Request to a Parquet column, value
+// returns the value of the column, or nil for null.
+type parquetField struct {
+	typ      parquet.Type
+	optional bool
+	value    func(field reflect.Value) any
+}
+
+var parquetFields = map[reflect.Type]parquetField{
+	reflect.TypeFor[string](): {parquet.String, false, func(field reflect.Value) any {
+		return field.String()
+	}},
+	reflect.TypeFor[int64](): {parquet.Int64, false, func(field reflect.Value) any {
+		return field.Int()
+	}},
+	reflect.TypeFor[sql.NullString](): {parquet.String, true, func(field reflect.Value) any {
+		if v := field.Interface().(sql.NullString); v.Valid {
+			return v.String
+		}
+		return nil
+	}},
+	reflect.TypeFor[sql.NullInt64](): {parquet.Int64, true, func(field reflect.Value) any {
+		if v := field.Interface().(sql.NullInt64); v.Valid {
+			return v.Int64
+		}
+		return nil
+	}},
+	reflect.TypeFor[sql.NullFloat64](): {parquet.Double, true, func(field reflect.Value) any {
+		if v := field.Interface().(sql.NullFloat64); v.Valid {
+			return v.Float64
+		}
+		return nil
+	}},
+	reflect.TypeFor[SqliteTime](): {parquet.Timestamp, false, func(field reflect.Value) any {
+		return field.Interface().(SqliteTime).Time
+	}},
+	// Tags are stored as they are in the database, a JSON array of strings.
+	reflect.TypeFor[Tags](): {parquet.String, true, func(field reflect.Value) any {
+		value, _ := field.Interface().(Tags).Value()
+		return value
+	}},
+}
+
+// parquetColumn is a column of the Parquet export, which is a field of Request
+// named by its db tag.
+type parquetColumn struct {
+	parquet.Column
+	index int
+	value func(field reflect.Value) any
+}
+
+func parquetColumns() ([]parquetColumn, error) {
+	requestType := reflect.TypeFor[Request]()
+	columns := make([]parquetColumn, 0, requestType.NumField())
+	for i := 0; i < requestType.NumField(); i++ {
+		field := requestType.Field(i)
+		name := field.Tag.Get("db")
+		if name == "" || name == "-" {
+			continue
+		}
+		mapped, ok := parquetFields[field.Type]
+		if !ok {
+			return nil, fmt.Errorf("no Parquet column type for field %s of type %s", field.Name, field.Type)
+		}
+		columns = append(columns, parquetColumn{
+			Column: parquet.Column{Name: name, Type: mapped.typ, Optional: mapped.optional},
+			index:  i,
+			value:  mapped.value,
+		})
+	}
+	return columns, nil
+}
+
+// writeParquet writes the requests as a Parquet file with a row per request and
+// a column per column of the database.
+func writeParquet(w io.Writer, requests []*Request) error {
+	columns, err := parquetColumns()
+	if err != nil {
+		return err
+	}
+	schema := make([]parquet.Column, len(columns))
+	for i, column := range columns {
+		schema[i] = column.Column
+	}
+	writer := parquet.NewWriter(w, schema)
+	row := make([]any, len(columns))
+	for _, request := range requests {
+		value := reflect.ValueOf(request).Elem()
+		for i, column := range columns {
+			row[i] = column.value(value.Field(column.index))
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
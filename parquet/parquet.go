@@ -0,0 +1,373 @@
+// Package parquet writes flat tables as Parquet files. Rows are written in row
+// groups of bounded size, each column of which is a single uncompressed data
+// page in the PLAIN encoding, which every Parquet reader is able to load.
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// Type is the type of the values of a column.
+type Type int
+
+const (
+	// Int64 columns hold int64 values.
+	Int64 Type = iota
+	// Double columns hold float64 values.
+	Double
+	// String columns hold UTF-8 string values.
+	String
+	// Timestamp columns hold time.Time values, stored as milliseconds since the
+	// Unix epoch in UTC.
+	Timestamp
+)
+
+// Column describes a column of the table, values of optional columns may be
+// nil.
+type Column struct {
+	Name     string
+	Type     Type
+	Optional bool
+}
+
+// Row groups are written once they have rowGroupRows rows or their values
+// take rowGroupBytes, so that the rows of large tables are not all buffered.
+const (
+	rowGroupRows  = 10000
+	rowGroupBytes = 64 << 20
+)
+
+// Writer buffers the rows of a row group and writes it once it is full, the
+// footer of the file, which describes where the columns of each row group are,
+// is written when the writer is closed.
+type Writer struct {
+	out     *countingWriter
+	columns []Column
+	values  [][]any
+	rows    int64
+	size    int
+	groups  []rowGroup
+	// maxRows is the number of rows of a row group, rowGroupRows unless
+	// changed by tests.
+	maxRows int64
+}
+
+type rowGroup struct {
+	chunks []columnChunk
+	rows   int64
+}
+
+// NewWriter returns a writer of a table with the columns to w.
+func NewWriter(w io.Writer, columns []Column) *Writer {
+	return &Writer{
+		out:     &countingWriter{w: w},
+		columns: columns,
+		values:  make([][]any, len(columns)),
+		maxRows: rowGroupRows,
+	}
+}
+
+// Write appends a row, whose values are in the order of the columns.
+func (w *Writer) Write(row []any) error {
+	if w.columns == nil {
+		return errors.New("parquet: writer is closed")
+	}
+	if len(row) != len(w.columns) {
+		return fmt.Errorf("parquet: row has %d values, expects %d", len(row), len(w.columns))
+	}
+	values := make([]any, len(row))
+	size := 0
+	for i, column := range w.columns {
+		value := row[i]
+		if value == nil {
+			if !column.Optional {
+				return fmt.Errorf("parquet: column %s is not optional", column.Name)
+			}
+			continue
+		}
+		var ok bool
+		switch column.Type {
+		case Int64:
+			_, ok = value.(int64)
+			size += 8
+		case Double:
+			_, ok = value.(float64)
+			size += 8
+		case String:
+			var s string
+			s, ok = value.(string)
+			size += 4 + len(s)
+		case Timestamp:
+			var t time.Time
+			if t, ok = value.(time.Time); ok {
+				value = t.UnixMilli()
+			}
+			size += 8
+		}
+		if !ok {
+			return fmt.Errorf("parquet: unexpected value of type %T in column %s", value, column.Name)
+		}
+		values[i] = value
+	}
+	for i, value := range values {
+		w.values[i] = append(w.values[i], value)
+	}
+	w.rows++
+	w.size += size
+	if w.rows >= w.maxRows || w.size >= rowGroupBytes {
+		return w.flush()
+	}
+	return nil
+}
+
+// flush writes the buffered rows as a row group.
+func (w *Writer) flush() error {
+	if w.rows == 0 {
+		return nil
+	}
+	if w.out.n == 0 {
+		if _, err := io.WriteString(w.out, magic); err != nil {
+			return err
+		}
+	}
+	group := rowGroup{rows: w.rows}
+	for i, column := range w.columns {
+		chunk, err := writeColumnChunk(w.out, column, w.values[i])
+		if err != nil {
+			return err
+		}
+		group.chunks = append(group.chunks, chunk)
+		w.values[i] = w.values[i][:0]
+	}
+	w.groups = append(w.groups, group)
+	w.rows, w.size = 0, 0
+	return nil
+}
+
+// Close writes the remaining rows and the footer, it does not close the
+// underlying writer.
+func (w *Writer) Close() error {
+	if w.columns == nil {
+		return errors.New("parquet: writer is closed")
+	}
+	if err := w.flush(); err != nil {
+		return err
+	}
+	if w.out.n == 0 {
+		if _, err := io.WriteString(w.out, magic); err != nil {
+			return err
+		}
+	}
+	footer := w.fileMetaData()
+	if _, err := w.out.Write(footer); err != nil {
+		return err
+	}
+	if err := binary.Write(w.out, binary.LittleEndian, uint32(len(footer))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w.out, magic); err != nil {
+		return err
+	}
+	w.columns, w.values, w.groups = nil, nil, nil
+	return nil
+}
+
+const magic = "PAR1"
+
+// Physical types, converted types and encodings defined by parquet.thrift.
+const (
+	typeInt64     = 2
+	typeDouble    = 5
+	typeByteArray = 6
+
+	convertedUTF8            = 0
+	convertedTimestampMillis = 9
+
+	repetitionRequired = 0
+	repetitionOptional = 1
+
+	encodingPlain = 0
+	encodingRLE   = 3
+
+	pageTypeData = 0
+)
+
+func physicalType(t Type) int32 {
+	switch t {
+	case Double:
+		return typeDouble
+	case String:
+		return typeByteArray
+	default:
+		return typeInt64
+	}
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+type columnChunk struct {
+	column Column
+	offset int64
+	size   int64
+}
+
+// writeColumnChunk writes the values of a column as a single data page.
+func writeColumnChunk(out *countingWriter, column Column, values []any) (columnChunk, error) {
+	var page bytes.Buffer
+	if column.Optional {
+		levels := definitionLevels(values)
+		binary.Write(&page, binary.LittleEndian, uint32(len(levels)))
+		page.Write(levels)
+	}
+	for _, value := range values {
+		switch v := value.(type) {
+		case nil:
+		case int64:
+			binary.Write(&page, binary.LittleEndian, v)
+		case float64:
+			binary.Write(&page, binary.LittleEndian, math.Float64bits(v))
+		case string:
+			binary.Write(&page, binary.LittleEndian, uint32(len(v)))
+			page.WriteString(v)
+		}
+	}
+	if page.Len() > math.MaxInt32 {
+		return columnChunk{}, fmt.Errorf("parquet: column %s exceeds the size of a page", column.Name)
+	}
+	var header thriftWriter
+	header.structBegin()
+	header.i32(1, pageTypeData)
+	header.i32(2, int32(page.Len()))
+	header.i32(3, int32(page.Len()))
+	header.fieldStructBegin(5)
+	header.i32(1, int32(len(values)))
+	header.i32(2, encodingPlain)
+	header.i32(3, encodingRLE)
+	header.i32(4, encodingRLE)
+	header.structEnd()
+	header.structEnd()
+	chunk := columnChunk{column: column, offset: out.n}
+	if _, err := out.Write(header.buf.Bytes()); err != nil {
+		return chunk, err
+	}
+	if _, err := out.Write(page.Bytes()); err != nil {
+		return chunk, err
+	}
+	chunk.size = out.n - chunk.offset
+	return chunk, nil
+}
+
+// definitionLevels encodes whether each value is present, 1, or null, 0, with
+// the RLE/bit-packing hybrid encoding of bit width 1, as a run per repeated
+// level.
+func definitionLevels(values []any) []byte {
+	var levels []byte
+	for i := 0; i < len(values); {
+		present := values[i] != nil
+		n := 1
+		for i+n < len(values) && (values[i+n] != nil) == present {
+			n++
+		}
+		levels = binary.AppendUvarint(levels, uint64(n)<<1)
+		if present {
+			levels = append(levels, 1)
+		} else {
+			levels = append(levels, 0)
+		}
+		i += n
+	}
+	return levels
+}
+
+func (w *Writer) fileMetaData() []byte {
+	var meta thriftWriter
+	meta.structBegin()
+	meta.i32(1, 1)
+	meta.listBegin(2, thriftStruct, len(w.columns)+1)
+	meta.structBegin()
+	meta.binary(4, "schema")
+	meta.i32(5, int32(len(w.columns)))
+	meta.structEnd()
+	for _, column := range w.columns {
+		meta.structBegin()
+		meta.i32(1, physicalType(column.Type))
+		if column.Optional {
+			meta.i32(3, repetitionOptional)
+		} else {
+			meta.i32(3, repetitionRequired)
+		}
+		meta.binary(4, column.Name)
+		switch column.Type {
+		case String:
+			meta.i32(6, convertedUTF8)
+			// LogicalType STRING
+			meta.fieldStructBegin(10)
+			meta.fieldStructBegin(1)
+			meta.structEnd()
+			meta.structEnd()
+		case Timestamp:
+			meta.i32(6, convertedTimestampMillis)
+			// LogicalType TIMESTAMP(isAdjustedToUTC=true, unit=MILLIS)
+			meta.fieldStructBegin(10)
+			meta.fieldStructBegin(8)
+			meta.bool(1, true)
+			meta.fieldStructBegin(2)
+			meta.fieldStructBegin(1)
+			meta.structEnd()
+			meta.structEnd()
+			meta.structEnd()
+			meta.structEnd()
+		}
+		meta.structEnd()
+	}
+	var rows int64
+	for _, group := range w.groups {
+		rows += group.rows
+	}
+	meta.i64(3, rows)
+	meta.listBegin(4, thriftStruct, len(w.groups))
+	for _, group := range w.groups {
+		meta.structBegin()
+		meta.listBegin(1, thriftStruct, len(group.chunks))
+		var totalSize int64
+		for _, chunk := range group.chunks {
+			totalSize += chunk.size
+			meta.structBegin()
+			meta.i64(2, chunk.offset)
+			meta.fieldStructBegin(3)
+			meta.i32(1, physicalType(chunk.column.Type))
+			meta.listBegin(2, thriftI32, 2)
+			meta.i32Elem(encodingPlain)
+			meta.i32Elem(encodingRLE)
+			meta.listBegin(3, thriftBinary, 1)
+			meta.binaryElem(chunk.column.Name)
+			meta.i32(4, 0) // UNCOMPRESSED
+			meta.i64(5, group.rows)
+			meta.i64(6, chunk.size)
+			meta.i64(7, chunk.size)
+			meta.i64(9, chunk.offset)
+			meta.structEnd()
+			meta.structEnd()
+		}
+		meta.i64(2, totalSize)
+		meta.i64(3, group.rows)
+		meta.structEnd()
+	}
+	meta.structEnd()
+	return meta.buf.Bytes()
+}
@@ -0,0 +1,358 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// compactReader decodes Thrift compact structs as maps keyed by field ids, so
+// that the metadata can be checked without a Parquet reader.
+type compactReader struct {
+	t    *testing.T
+	data []byte
+}
+
+func (r *compactReader) byte() byte {
+	if len(r.data) == 0 {
+		r.t.Fatal("unexpected end of thrift data")
+	}
+	b := r.data[0]
+	r.data = r.data[1:]
+	return b
+}
+
+func (r *compactReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		r.t.Fatal("invalid varint")
+	}
+	r.data = r.data[n:]
+	return v
+}
+
+func (r *compactReader) varint() int64 {
+	v := r.uvarint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (r *compactReader) value(typ byte) any {
+	switch typ {
+	case thriftTrue:
+		return true
+	case thriftFalse:
+		return false
+	case thriftI32, thriftI64:
+		return r.varint()
+	case thriftBinary:
+		n := r.uvarint()
+		s := string(r.data[:n])
+		r.data = r.data[n:]
+		return s
+	case thriftList:
+		header := r.byte()
+		n, elemType := uint64(header>>4), header&0x0f
+		if n == 15 {
+			n = r.uvarint()
+		}
+		list := make([]any, n)
+		for i := range list {
+			list[i] = r.value(elemType)
+		}
+		return list
+	case thriftStruct:
+		return r.readStruct()
+	}
+	r.t.Fatalf("unexpected thrift type %d", typ)
+	return nil
+}
+
+func (r *compactReader) readStruct() map[int16]any {
+	fields := make(map[int16]any)
+	var last int16
+	for {
+		header := r.byte()
+		if header == 0 {
+			return fields
+		}
+		id := last + int16(header>>4)
+		if header>>4 == 0 {
+			id = int16(r.varint())
+		}
+		fields[id] = r.value(header & 0x0f)
+		last = id
+	}
+}
+
+func TestWriter(t *testing.T) {
+	columns := []Column{
+		{Name: "id", Type: Int64},
+		{Name: "body", Type: String, Optional: true},
+		{Name: "otps", Type: Double, Optional: true},
+		{Name: "created_at", Type: Timestamp},
+	}
+	createdAt := time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC)
+	rows := [][]any{
+		{int64(1), "你好", 12.5, createdAt},
+		{int64(2), nil, nil, createdAt.Add(time.Second)},
+		{int64(3), nil, 3.0, createdAt.Add(2 * time.Second)},
+		{int64(4), "", nil, createdAt.Add(3 * time.Second)},
+	}
+	var file bytes.Buffer
+	writer := NewWriter(&file, columns)
+	for _, row := range rows {
+		if err := writer.Write(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data := file.Bytes()
+	if string(data[:4]) != magic || string(data[len(data)-4:]) != magic {
+		t.Fatal("missing magic number")
+	}
+	footerSize := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	footer := &compactReader{t: t, data: data[len(data)-8-footerSize : len(data)-8]}
+	meta := footer.readStruct()
+	if meta[3] != int64(len(rows)) {
+		t.Errorf("num_rows = %v, want %d", meta[3], len(rows))
+	}
+	schema := meta[2].([]any)
+	if len(schema) != len(columns)+1 {
+		t.Fatalf("schema has %d elements, want %d", len(schema), len(columns)+1)
+	}
+	for i, column := range columns {
+		element := schema[i+1].(map[int16]any)
+		if element[4] != column.Name {
+			t.Errorf("schema element %d is %v, want %s", i+1, element[4], column.Name)
+		}
+	}
+	timestamp := schema[4].(map[int16]any)
+	if timestamp[6] != int64(convertedTimestampMillis) {
+		t.Errorf("created_at has converted type %v, want TIMESTAMP_MILLIS", timestamp[6])
+	}
+	chunks := meta[4].([]any)[0].(map[int16]any)[1].([]any)
+	want := [][]any{
+		{int64(1), int64(2), int64(3), int64(4)},
+		{"你好", nil, nil, ""},
+		{12.5, nil, 3.0, nil},
+		{createdAt.UnixMilli(), createdAt.UnixMilli() + 1000, createdAt.UnixMilli() + 2000, createdAt.UnixMilli() + 3000},
+	}
+	for i, column := range columns {
+		columnMeta := chunks[i].(map[int16]any)[3].(map[int16]any)
+		offset := columnMeta[9].(int64)
+		page := &compactReader{t: t, data: data[offset:]}
+		header := page.readStruct()
+		if header[5].(map[int16]any)[1] != int64(len(rows)) {
+			t.Errorf("%s: page has %v values, want %d", column.Name, header[5].(map[int16]any)[1], len(rows))
+		}
+		values := readPlain(t, page.data[:header[2].(int64)], column, len(rows))
+		if !reflect.DeepEqual(values, want[i]) {
+			t.Errorf("%s: values %v, want %v", column.Name, values, want[i])
+		}
+	}
+}
+
+// readPlain decodes a data page with definition levels encoded as RLE runs.
+func readPlain(t *testing.T, page []byte, column Column, n int) []any {
+	present := make([]bool, n)
+	if column.Optional {
+		size := binary.LittleEndian.Uint32(page)
+		levels := &compactReader{t: t, data: page[4 : 4+size]}
+		page = page[4+size:]
+		for i := 0; i < n; {
+			run := int(levels.uvarint() >> 1)
+			level := levels.byte()
+			for j := 0; j < run; j++ {
+				present[i+j] = level == 1
+			}
+			i += run
+		}
+	} else {
+		for i := range present {
+			present[i] = true
+		}
+	}
+	values := make([]any, n)
+	for i := range values {
+		if !present[i] {
+			continue
+		}
+		switch column.Type {
+		case Int64, Timestamp:
+			values[i] = int64(binary.LittleEndian.Uint64(page))
+			page = page[8:]
+		case Double:
+			values[i] = math.Float64frombits(binary.LittleEndian.Uint64(page))
+			page = page[8:]
+		case String:
+			size := binary.LittleEndian.Uint32(page)
+			values[i] = string(page[4 : 4+size])
+			page = page[4+size:]
+		}
+	}
+	if len(page) != 0 {
+		t.Errorf("%s: %d bytes left in page", column.Name, len(page))
+	}
+	return values
+}
+
+func TestWriter_Errors(t *testing.T) {
+	writer := NewWriter(new(bytes.Buffer), []Column{{Name: "id", Type: Int64}})
+	for _, row := range [][]any{
+		{},
+		{nil},
+		{"1"},
+	} {
+		if err := writer.Write(row); err == nil {
+			t.Errorf("Write(%v): expects error", row)
+		}
+	}
+}
+
+func TestWriter_Empty(t *testing.T) {
+	var file bytes.Buffer
+	if err := NewWriter(&file, []Column{{Name: "id", Type: Int64}}).Close(); err != nil {
+		t.Fatal(err)
+	}
+	data := file.Bytes()
+	footerSize := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	meta := (&compactReader{t: t, data: data[len(data)-8-footerSize : len(data)-8]}).readStruct()
+	if meta[3] != int64(0) || len(meta[4].([]any)) != 0 {
+		t.Errorf("expects no rows and no row groups, got %v", meta)
+	}
+}
+
+func TestWriter_RowGroups(t *testing.T) {
+	column := Column{Name: "id", Type: Int64, Optional: true}
+	var file bytes.Buffer
+	writer := NewWriter(&file, []Column{column})
+	writer.maxRows = 2
+	rows := []any{int64(1), nil, int64(3), int64(4), nil}
+	for _, value := range rows {
+		if err := writer.Write([]any{value}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data := file.Bytes()
+	footerSize := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	meta := (&compactReader{t: t, data: data[len(data)-8-footerSize : len(data)-8]}).readStruct()
+	if meta[3] != int64(len(rows)) {
+		t.Errorf("num_rows = %v, want %d", meta[3], len(rows))
+	}
+	groups := meta[4].([]any)
+	if len(groups) != 3 {
+		t.Fatalf("%d row groups, want 3", len(groups))
+	}
+	var values []any
+	for _, group := range groups {
+		group := group.(map[int16]any)
+		n := int(group[3].(int64))
+		columnMeta := group[1].([]any)[0].(map[int16]any)[3].(map[int16]any)
+		if columnMeta[5] != int64(n) {
+			t.Errorf("column chunk has %v values, row group has %d rows", columnMeta[5], n)
+		}
+		page := &compactReader{t: t, data: data[columnMeta[9].(int64):]}
+		header := page.readStruct()
+		values = append(values, readPlain(t, page.data[:header[2].(int64)], column, n)...)
+	}
+	if !reflect.DeepEqual(values, rows) {
+		t.Errorf("values %v, want %v", values, rows)
+	}
+}
+
+// pyarrowScript prints the rows of the file as a JSON array, with timestamps as
+// milliseconds since the Unix epoch, as the DuckDB query does.
+const pyarrowScript = `
+import json, sys
+import pyarrow.parquet as pq
+table = pq.read_table(sys.argv[1])
+index = table.schema.get_field_index("created_at")
+table = table.set_column(index, "created_at", table.column(index).cast("int64"))
+print(json.dumps(table.to_pylist()))
+`
+
+// readExternal reads the file with the DuckDB CLI or pyarrow, whichever is
+// installed, so that the files are known to be loaded by real readers and not
+// only by the decoder of these tests.
+func readExternal(t *testing.T, path string) []map[string]any {
+	var cmd *exec.Cmd
+	if _, err := exec.LookPath("duckdb"); err == nil {
+		query := "select id, body, otps, epoch_ms(created_at) as created_at from read_parquet('" + path + "') order by id"
+		cmd = exec.Command("duckdb", "-json", "-c", query)
+	} else if exec.Command("python3", "-c", "import pyarrow.parquet").Run() == nil {
+		cmd = exec.Command("python3", "-c", pyarrowScript, path)
+	} else {
+		t.Skip("neither the DuckDB CLI nor pyarrow is installed")
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("%s: %v\n%s", cmd.Path, err, stderr.String())
+	}
+	var rows []map[string]any
+	if err = json.Unmarshal(out, &rows); err != nil {
+		t.Fatalf("%s: %v\n%s", cmd.Path, err, out)
+	}
+	return rows
+}
+
+func TestWriter_ExternalReader(t *testing.T) {
+	columns := []Column{
+		{Name: "id", Type: Int64},
+		{Name: "body", Type: String, Optional: true},
+		{Name: "otps", Type: Double, Optional: true},
+		{Name: "created_at", Type: Timestamp},
+	}
+	createdAt := time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC)
+	rows := [][]any{
+		{int64(1), "你好", 12.5, createdAt},
+		{int64(2), nil, nil, createdAt.Add(time.Second)},
+		{int64(3), nil, 3.0, createdAt.Add(2 * time.Second)},
+		{int64(4), "", nil, createdAt.Add(3 * time.Second)},
+		{int64(5), `{"model":"moonshot-v1-8k"}`, 0.25, createdAt.Add(4 * time.Second)},
+	}
+	path := filepath.Join(t.TempDir(), "requests.parquet")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer := NewWriter(file, columns)
+	// Several row groups, the last of which is not full.
+	writer.maxRows = 2
+	for _, row := range rows {
+		if err = writer.Write(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = file.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want := make([]map[string]any, len(rows))
+	for i, row := range rows {
+		want[i] = map[string]any{
+			"id":         float64(row[0].(int64)),
+			"body":       row[1],
+			"otps":       row[2],
+			"created_at": float64(row[3].(time.Time).UnixMilli()),
+		}
+	}
+	if got := readExternal(t, path); !reflect.DeepEqual(got, want) {
+		t.Errorf("rows %v, want %v", got, want)
+	}
+}
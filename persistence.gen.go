@@ -63,15 +63,33 @@ func (__imp *implPersistence) Close() error {
 var (
 	_ = (*template.Template)(nil)
 
-	__PersistenceBaseTemplate = template.Must(template.New("PersistenceBaseTemplate").Funcs(template.FuncMap{"bindvars": __rt.BindVars, "fields": tableFields}).Parse(""))
-
-	sqlTmpladdTTFTField     = template.Must(__PersistenceBaseTemplate.New("addTTFTField").Parse("alter table moonshot_requests add response_ttft integer;\r\n"))
-	sqlTmpladdTPOTField     = template.Must(__PersistenceBaseTemplate.New("addTPOTField").Parse("alter table moonshot_requests add response_tpot integer;\r\n"))
-	sqlTmpladdOTPSField     = template.Must(__PersistenceBaseTemplate.New("addOTPSField").Parse("alter table moonshot_requests add response_otps real;\r\n"))
-	sqlTmpladdLatencyField  = template.Must(__PersistenceBaseTemplate.New("addLatencyField").Parse("alter table moonshot_requests add latency integer;\r\n"))
-	sqlTmpladdEndpointField = template.Must(__PersistenceBaseTemplate.New("addEndpointField").Parse("alter table moonshot_requests add endpoint text;\r\n"))
-	sqlTmplPersistence      = template.Must(__PersistenceBaseTemplate.New("Persistence").Parse("insert into moonshot_requests ( request_method, request_path, request_query, created_at {{ if .requestContentType }},request_content_type{{ end }} {{ if .requestID }},request_id{{ end }} {{ if .moonshotID }},moonshot_id{{ end }} {{ if .moonshotGID }},moonshot_gid{{ end }} {{ if .moonshotUID }},moonshot_uid{{ end }} {{ if .moonshotRequestID }},moonshot_request_id{{ end }} {{ if .moonshotServerTiming }},moonshot_server_timing{{ end }} {{ if .responseStatusCode }},response_status_code{{ end }} {{ if .responseContentType }},response_content_type{{ end }} {{ if .requestHeader }},request_header{{ end }} {{ if .requestBody }},request_body{{ end }} {{ if .responseHeader }},response_header{{ end }} {{ if .responseBody }},response_body{{ end }} {{ if .programError }},error{{ end }} {{ if .responseTTFT }},response_ttft{{ end }} {{ if .responseTPOT }},response_tpot{{ end }} {{ if .responseOTPS }},response_otps{{ end }} {{ if .latency }},latency{{ end }} {{ if .endpoint }},endpoint{{ end }} ) values ( :requestMethod, :requestPath, :requestQuery, :createdAt {{ if .requestContentType }},:requestContentType{{ end }} {{ if .requestID }},:requestID{{ end }} {{ if .moonshotID }},:moonshotID{{ end }} {{ if .moonshotGID }},:moonshotGID{{ end }} {{ if .moonshotUID }},:moonshotUID{{ end }} {{ if .moonshotRequestID }},:moonshotRequestID{{ end }} {{ if .moonshotServerTiming }},:moonshotServerTiming{{ end }} {{ if .responseStatusCode }},:responseStatusCode{{ end }} {{ if .responseContentType }},:responseContentType{{ end }} {{ if .requestHeader }},:requestHeader{{ end }} {{ if .requestBody }},:requestBody{{ end }} {{ if .responseHeader }},:responseHeader{{ end }} {{ if .responseBody }},:responseBody{{ end }} {{ if .programError }},:programError{{ end }} {{ if .responseTTFT }},:responseTTFT{{ end }} {{ if .responseTPOT }},:responseTPOT{{ end }} {{ if .responseOTPS }},:responseOTPS{{ end }} {{ if .latency }},:latency{{ end }} {{ if .endpoint }},:endpoint{{ end }} );\r\nselect last_insert_rowid();\r\n"))
-	sqlTmplGetRequest       = template.Must(__PersistenceBaseTemplate.New("GetRequest").Parse("select * from moonshot_requests where 1 = 1 {{ if .id }} and id = :id {{ end }} {{ if .chatcmpl }} and moonshot_id = :chatcmpl {{ end }} {{ if .requestid }} and moonshot_request_id = :requestid {{ end }} ;\r\n"))
+	__PersistenceBaseTemplate = template.Must(template.New("PersistenceBaseTemplate").Funcs(template.FuncMap{"bindvars": __rt.BindVars, "fields": tableFields, "groupBy": groupByColumns, "groupColumns": groupColumns}).Parse(""))
+
+	sqlTmpladdTTFTField              = template.Must(__PersistenceBaseTemplate.New("addTTFTField").Parse("alter table moonshot_requests add response_ttft integer;\r\n"))
+	sqlTmpladdTPOTField              = template.Must(__PersistenceBaseTemplate.New("addTPOTField").Parse("alter table moonshot_requests add response_tpot integer;\r\n"))
+	sqlTmpladdOTPSField              = template.Must(__PersistenceBaseTemplate.New("addOTPSField").Parse("alter table moonshot_requests add response_otps real;\r\n"))
+	sqlTmpladdLatencyField           = template.Must(__PersistenceBaseTemplate.New("addLatencyField").Parse("alter table moonshot_requests add latency integer;\r\n"))
+	sqlTmpladdEndpointField          = template.Must(__PersistenceBaseTemplate.New("addEndpointField").Parse("alter table moonshot_requests add endpoint text;\r\n"))
+	sqlTmpladdModelField             = template.Must(__PersistenceBaseTemplate.New("addModelField").Parse("alter table moonshot_requests add model text;\r\n"))
+	sqlTmpladdSystemFingerprintField = template.Must(__PersistenceBaseTemplate.New("addSystemFingerprintField").Parse("alter table moonshot_requests add system_fingerprint text;\r\n"))
+	sqlTmpladdRequestBodySizeField   = template.Must(__PersistenceBaseTemplate.New("addRequestBodySizeField").Parse("alter table moonshot_requests add request_body_size integer;\r\n"))
+	sqlTmpladdRateLimitField         = template.Must(__PersistenceBaseTemplate.New("addRateLimitField").Parse("alter table moonshot_requests add rate_limit text;\r\n"))
+	sqlTmpladdOriginalModelField     = template.Must(__PersistenceBaseTemplate.New("addOriginalModelField").Parse("alter table moonshot_requests add original_model text;\r\n"))
+	sqlTmpladdParentIDField          = template.Must(__PersistenceBaseTemplate.New("addParentIDField").Parse("alter table moonshot_requests add parent_id integer;\r\n"))
+	sqlTmpladdTagsField              = template.Must(__PersistenceBaseTemplate.New("addTagsField").Parse("alter table moonshot_requests add tags text;\r\n"))
+	sqlTmpladdCategoryField          = template.Must(__PersistenceBaseTemplate.New("addCategoryField").Parse("alter table moonshot_requests add category text;\r\n"))
+	sqlTmpladdNoteField              = template.Must(__PersistenceBaseTemplate.New("addNoteField").Parse("alter table moonshot_requests add note text;\r\n"))
+	sqlTmpladdTraceIDField           = template.Must(__PersistenceBaseTemplate.New("addTraceIDField").Parse("alter table moonshot_requests add trace_id text;\r\n"))
+	sqlTmpladdRawRequestHeaderField  = template.Must(__PersistenceBaseTemplate.New("addRawRequestHeaderField").Parse("alter table moonshot_requests add raw_request_header text;\r\n"))
+	sqlTmpladdRequestBodyHashField   = template.Must(__PersistenceBaseTemplate.New("addRequestBodyHashField").Parse("alter table moonshot_requests add request_body_hash text;\r\n"))
+	sqlTmplbackfillRequestBodyHash   = template.Must(__PersistenceBaseTemplate.New("backfillRequestBodyHash").Parse("update moonshot_requests set request_body_hash = hash_body(request_body) where request_body is not null and request_body_size is null;\r\n"))
+	sqlTmpladdRequestBodyHashIndex   = template.Must(__PersistenceBaseTemplate.New("addRequestBodyHashIndex").Parse("create index if not exists moonshot_requests_request_body_hash on moonshot_requests (request_body_hash);\r\n"))
+	sqlTmpladdCredentialHashField    = template.Must(__PersistenceBaseTemplate.New("addCredentialHashField").Parse("alter table moonshot_requests add credential_hash text;\r\n"))
+	sqlTmplPersistence               = template.Must(__PersistenceBaseTemplate.New("Persistence").Parse("insert into moonshot_requests ( request_method, request_path, request_query, created_at {{ if .requestContentType }},request_content_type{{ end }} {{ if .requestID }},request_id{{ end }} {{ if .moonshotID }},moonshot_id{{ end }} {{ if .moonshotGID }},moonshot_gid{{ end }} {{ if .moonshotUID }},moonshot_uid{{ end }} {{ if .moonshotRequestID }},moonshot_request_id{{ end }} {{ if .moonshotServerTiming }},moonshot_server_timing{{ end }} {{ if .responseStatusCode }},response_status_code{{ end }} {{ if .responseContentType }},response_content_type{{ end }} {{ if .requestHeader }},request_header{{ end }} {{ if .requestBody }},request_body{{ end }} {{ if .responseHeader }},response_header{{ end }} {{ if .responseBody }},response_body{{ end }} {{ if .programError }},error{{ end }} {{ if .responseTTFT }},response_ttft{{ end }} {{ if .responseTPOT }},response_tpot{{ end }} {{ if .responseOTPS }},response_otps{{ end }} {{ if .latency }},latency{{ end }} {{ if .endpoint }},endpoint{{ end }} {{ if .model }},model{{ end }} {{ if .systemFingerprint }},system_fingerprint{{ end }} {{ if .requestBodySize }},request_body_size{{ end }} {{ if .rateLimit }},rate_limit{{ end }} {{ if .originalModel }},original_model{{ end }} {{ if .traceID }},trace_id{{ end }} {{ if .rawRequestHeader }},raw_request_header{{ end }} {{ if .requestBodyHash }},request_body_hash{{ end }} {{ if .credentialHash }},credential_hash{{ end }} ) values ( :requestMethod, :requestPath, :requestQuery, :createdAt {{ if .requestContentType }},:requestContentType{{ end }} {{ if .requestID }},:requestID{{ end }} {{ if .moonshotID }},:moonshotID{{ end }} {{ if .moonshotGID }},:moonshotGID{{ end }} {{ if .moonshotUID }},:moonshotUID{{ end }} {{ if .moonshotRequestID }},:moonshotRequestID{{ end }} {{ if .moonshotServerTiming }},:moonshotServerTiming{{ end }} {{ if .responseStatusCode }},:responseStatusCode{{ end }} {{ if .responseContentType }},:responseContentType{{ end }} {{ if .requestHeader }},:requestHeader{{ end }} {{ if .requestBody }},:requestBody{{ end }} {{ if .responseHeader }},:responseHeader{{ end }} {{ if .responseBody }},:responseBody{{ end }} {{ if .programError }},:programError{{ end }} {{ if .responseTTFT }},:responseTTFT{{ end }} {{ if .responseTPOT }},:responseTPOT{{ end }} {{ if .responseOTPS }},:responseOTPS{{ end }} {{ if .latency }},:latency{{ end }} {{ if .endpoint }},:endpoint{{ end }} {{ if .model }},:model{{ end }} {{ if .systemFingerprint }},:systemFingerprint{{ end }} {{ if .requestBodySize }},:requestBodySize{{ end }} {{ if .rateLimit }},:rateLimit{{ end }} {{ if .originalModel }},:originalModel{{ end }} {{ if .traceID }},:traceID{{ end }} {{ if .rawRequestHeader }},:rawRequestHeader{{ end }} {{ if .requestBodyHash }},:requestBodyHash{{ end }} {{ if .credentialHash }},:credentialHash{{ end }} );\r\nselect last_insert_rowid();\r\n"))
+	sqlTmplGetReplayChain            = template.Must(__PersistenceBaseTemplate.New("GetReplayChain").Parse("with recursive chain(id) as ( select id from moonshot_requests where id = :originalID union select moonshot_requests.id from moonshot_requests join chain on moonshot_requests.parent_id = chain.id ) select * from moonshot_requests where id in (select id from chain) order by id;\r\n"))
+	sqlTmplGetRequest                = template.Must(__PersistenceBaseTemplate.New("GetRequest").Parse("select * from moonshot_requests where 1 = 1 {{ if .id }} and id = :id {{ end }} {{ if .chatcmpl }} and moonshot_id = :chatcmpl {{ end }} {{ if .requestid }} and moonshot_request_id = :requestid {{ end }} {{ if .uid }} and moonshot_uid = :uid {{ end }} {{ if .atTime }} and abs(julianday(created_at) - julianday(:atTime)) = ( select min(abs(julianday(created_at) - julianday(:atTime))) from moonshot_requests where moonshot_uid = :uid ) {{ end }} ;\r\n"))
+	sqlTmplGetCachedResponse         = template.Must(__PersistenceBaseTemplate.New("GetCachedResponse").Parse("select * from moonshot_requests where request_body_hash = :hash and credential_hash = :credential and request_method = :method and request_path = :path and request_query = :query and response_status_code between 200 and 299 and error is null and response_body is not null {{ if .since }} and created_at >= :since {{ end }} order by id desc limit 1;\r\n"))
+	sqlTmplGetRequestsByRange        = template.Must(__PersistenceBaseTemplate.New("GetRequestsByRange").Parse("select * from moonshot_requests where 1 = 1 {{ if .idFrom }} and id >= :idFrom {{ end }} {{ if .idTo }} and id <= :idTo {{ end }} {{ if .uid }} and moonshot_uid = :uid {{ end }} {{ if .uids }} and moonshot_uid in (:uids) {{ end }} {{ if .since }} and created_at >= :since {{ end }} {{ if .until }} and created_at < :until {{ end }} {{ if .pathPrefix }} and request_path like :pathPrefix || '%' escape '\\' {{ end }} {{ if .tagsAny }} and exists ( select 1 from json_each(tags) where value in (:tagsAny) ) {{ end }} {{ if .tagsAll }} and ( select count(distinct value) from json_each(tags) where value in (:tagsAll) ) = {{ len .tagsAll }} {{ end }} {{ if .categoryUnset }} and (category is null or category = '') {{ end }} {{ if .hasSystemPrompt }} and iif(json_valid(request_body), json_extract(request_body, '$.messages[0].role'), null) = 'system' {{ end }} {{ if .noSystemPrompt }} and coalesce(iif(json_valid(request_body), json_extract(request_body, '$.messages[0].role'), null), '') != 'system' {{ end }} {{ if .hashPrefix }} and request_body_hash like :hashPrefix || '%' {{ end }} {{ if .systemPromptContains }} and iif(json_valid(request_body), json_extract(request_body, '$.messages[0].role'), null) = 'system' and exists ( select 1 from json_each(:systemPromptContains) where iif(json_valid(request_body), json_extract(request_body, '$.messages[0].content'), null) like '%' || value || '%' escape '\\' ) {{ end }} order by id {{ if .limit }} limit :limit {{ end }} ;\r\n"))
 )
 
 func (__imp *implPersistence) createTable() error {
@@ -82,7 +100,7 @@ func (__imp *implPersistence) createTable() error {
 
 	argListcreateTable = __rt.Arguments{}
 
-	querycreateTable := "create table if not exists moonshot_requests ( id                     integer not null constraint moonshot_requests_pk primary key autoincrement, request_method         text    not null, request_path           text    not null, request_query          text    not null, request_content_type   text, request_id             text, moonshot_id            text, moonshot_gid           text, moonshot_uid           text, moonshot_request_id    text, moonshot_server_timing integer, response_status_code   integer, response_content_type  text, request_header         text, request_body           text, response_header        text, response_body          text, error                  text, response_ttft          integer, response_tpot          integer, response_otps          real, latency                integer, endpoint               text, created_at             text    default (datetime('now', 'localtime')) not null ); create table if not exists moonshot_caches ( id                     integer not null constraint moonshot_requests_pk primary key autoincrement, cache_id               text    not null, hash                   text    not null, n_bytes                integer not null, k_ident                text    not null, created_at             text    default (datetime('now', 'localtime')) not null, updated_at             text )\r\n"
+	querycreateTable := "create table if not exists moonshot_requests ( id                     integer not null constraint moonshot_requests_pk primary key autoincrement, request_method         text    not null, request_path           text    not null, request_query          text    not null, request_content_type   text, request_id             text, moonshot_id            text, moonshot_gid           text, moonshot_uid           text, moonshot_request_id    text, moonshot_server_timing integer, response_status_code   integer, response_content_type  text, request_header         text, request_body           text, response_header        text, response_body          text, error                  text, response_ttft          integer, response_tpot          integer, response_otps          real, latency                integer, endpoint               text, model                  text, system_fingerprint     text, request_body_size      integer, rate_limit             text, original_model         text, parent_id              integer, tags                   text, category               text, note                   text, trace_id               text, raw_request_header     text, request_body_hash      text, credential_hash        text, created_at             text    default (datetime('now', 'localtime')) not null ); create table if not exists moonshot_caches ( id                     integer not null constraint moonshot_requests_pk primary key autoincrement, cache_id               text    not null, hash                   text    not null, n_bytes                integer not null, k_ident                text    not null, created_at             text    default (datetime('now', 'localtime')) not null, updated_at             text ); create table if not exists moonshot_live_streams ( moonshot_id            text    not null constraint moonshot_live_streams_pk primary key, response_body          text    not null ); create table if not exists benchmark_runs ( id                     integer not null constraint benchmark_runs_pk primary key autoincrement, request_id             integer not null, replay_id              integer, compare_model          text    not null, score                  real    not null, threshold              real    not null, created_at             text    default (datetime('now', 'localtime')) not null )\r\n"
 
 	txcreateTable, errcreateTable := __imp.__core.Beginx()
 	if errcreateTable != nil {
@@ -434,302 +452,2327 @@ func (__imp *implPersistence) addEndpointField() error {
 	return nil
 }
 
+func (__imp *implPersistence) addModelField() error {
+	var (
+		erraddModelField     error
+		argListaddModelField = make(__rt.Arguments, 0, 8)
+	)
+
+	argListaddModelField = __rt.Arguments{}
+
+	sqladdModelField := __rt.GetBuffer()
+	defer __rt.PutBuffer(sqladdModelField)
+	defer sqladdModelField.Reset()
+
+	if erraddModelField = sqlTmpladdModelField.Execute(sqladdModelField, map[string]any{}); erraddModelField != nil {
+		return fmt.Errorf("error executing %s template: %w", strconv.Quote("addModelField"), erraddModelField)
+	}
+
+	queryaddModelField := sqladdModelField.String()
+
+	txaddModelField, erraddModelField := __imp.__core.Beginx()
+	if erraddModelField != nil {
+		return fmt.Errorf("error creating %s transaction: %w", strconv.Quote("addModelField"), erraddModelField)
+	}
+	if !__imp.__withTx {
+		defer txaddModelField.Rollback()
+	}
+
+	offsetaddModelField := 0
+	argsaddModelField := __rt.MergeArgs(argListaddModelField...)
+
+	sqlSliceaddModelField := __rt.Split(queryaddModelField, ";")
+	for indexaddModelField, splitSqladdModelField := range sqlSliceaddModelField {
+		_ = indexaddModelField
+
+		countaddModelField := __rt.Count(splitSqladdModelField, "?")
+
+		_, erraddModelField = txaddModelField.Exec(splitSqladdModelField, argsaddModelField[offsetaddModelField:offsetaddModelField+countaddModelField]...)
+
+		if erraddModelField != nil {
+			return fmt.Errorf("error executing %s sql: \n\n%s\n\n%w", strconv.Quote("addModelField"), splitSqladdModelField, erraddModelField)
+		}
+
+		offsetaddModelField += countaddModelField
+	}
+
+	if !__imp.__withTx {
+		if erraddModelField := txaddModelField.Commit(); erraddModelField != nil {
+			return fmt.Errorf("error committing %s transaction: %w", strconv.Quote("addModelField"), erraddModelField)
+		}
+	}
+
+	return nil
+}
+
+func (__imp *implPersistence) addSystemFingerprintField() error {
+	var (
+		erraddSystemFingerprintField     error
+		argListaddSystemFingerprintField = make(__rt.Arguments, 0, 8)
+	)
+
+	argListaddSystemFingerprintField = __rt.Arguments{}
+
+	sqladdSystemFingerprintField := __rt.GetBuffer()
+	defer __rt.PutBuffer(sqladdSystemFingerprintField)
+	defer sqladdSystemFingerprintField.Reset()
+
+	if erraddSystemFingerprintField = sqlTmpladdSystemFingerprintField.Execute(sqladdSystemFingerprintField, map[string]any{}); erraddSystemFingerprintField != nil {
+		return fmt.Errorf("error executing %s template: %w", strconv.Quote("addSystemFingerprintField"), erraddSystemFingerprintField)
+	}
+
+	queryaddSystemFingerprintField := sqladdSystemFingerprintField.String()
+
+	txaddSystemFingerprintField, erraddSystemFingerprintField := __imp.__core.Beginx()
+	if erraddSystemFingerprintField != nil {
+		return fmt.Errorf("error creating %s transaction: %w", strconv.Quote("addSystemFingerprintField"), erraddSystemFingerprintField)
+	}
+	if !__imp.__withTx {
+		defer txaddSystemFingerprintField.Rollback()
+	}
+
+	offsetaddSystemFingerprintField := 0
+	argsaddSystemFingerprintField := __rt.MergeArgs(argListaddSystemFingerprintField...)
+
+	sqlSliceaddSystemFingerprintField := __rt.Split(queryaddSystemFingerprintField, ";")
+	for indexaddSystemFingerprintField, splitSqladdSystemFingerprintField := range sqlSliceaddSystemFingerprintField {
+		_ = indexaddSystemFingerprintField
+
+		countaddSystemFingerprintField := __rt.Count(splitSqladdSystemFingerprintField, "?")
+
+		_, erraddSystemFingerprintField = txaddSystemFingerprintField.Exec(splitSqladdSystemFingerprintField, argsaddSystemFingerprintField[offsetaddSystemFingerprintField:offsetaddSystemFingerprintField+countaddSystemFingerprintField]...)
+
+		if erraddSystemFingerprintField != nil {
+			return fmt.Errorf("error executing %s sql: \n\n%s\n\n%w", strconv.Quote("addSystemFingerprintField"), splitSqladdSystemFingerprintField, erraddSystemFingerprintField)
+		}
+
+		offsetaddSystemFingerprintField += countaddSystemFingerprintField
+	}
+
+	if !__imp.__withTx {
+		if erraddSystemFingerprintField := txaddSystemFingerprintField.Commit(); erraddSystemFingerprintField != nil {
+			return fmt.Errorf("error committing %s transaction: %w", strconv.Quote("addSystemFingerprintField"), erraddSystemFingerprintField)
+		}
+	}
+
+	return nil
+}
+
+func (__imp *implPersistence) addRequestBodySizeField() error {
+	var (
+		erraddRequestBodySizeField     error
+		argListaddRequestBodySizeField = make(__rt.Arguments, 0, 8)
+	)
+
+	argListaddRequestBodySizeField = __rt.Arguments{}
+
+	sqladdRequestBodySizeField := __rt.GetBuffer()
+	defer __rt.PutBuffer(sqladdRequestBodySizeField)
+	defer sqladdRequestBodySizeField.Reset()
+
+	if erraddRequestBodySizeField = sqlTmpladdRequestBodySizeField.Execute(sqladdRequestBodySizeField, map[string]any{}); erraddRequestBodySizeField != nil {
+		return fmt.Errorf("error executing %s template: %w", strconv.Quote("addRequestBodySizeField"), erraddRequestBodySizeField)
+	}
+
+	queryaddRequestBodySizeField := sqladdRequestBodySizeField.String()
+
+	txaddRequestBodySizeField, erraddRequestBodySizeField := __imp.__core.Beginx()
+	if erraddRequestBodySizeField != nil {
+		return fmt.Errorf("error creating %s transaction: %w", strconv.Quote("addRequestBodySizeField"), erraddRequestBodySizeField)
+	}
+	if !__imp.__withTx {
+		defer txaddRequestBodySizeField.Rollback()
+	}
+
+	offsetaddRequestBodySizeField := 0
+	argsaddRequestBodySizeField := __rt.MergeArgs(argListaddRequestBodySizeField...)
+
+	sqlSliceaddRequestBodySizeField := __rt.Split(queryaddRequestBodySizeField, ";")
+	for indexaddRequestBodySizeField, splitSqladdRequestBodySizeField := range sqlSliceaddRequestBodySizeField {
+		_ = indexaddRequestBodySizeField
+
+		countaddRequestBodySizeField := __rt.Count(splitSqladdRequestBodySizeField, "?")
+
+		_, erraddRequestBodySizeField = txaddRequestBodySizeField.Exec(splitSqladdRequestBodySizeField, argsaddRequestBodySizeField[offsetaddRequestBodySizeField:offsetaddRequestBodySizeField+countaddRequestBodySizeField]...)
+
+		if erraddRequestBodySizeField != nil {
+			return fmt.Errorf("error executing %s sql: \n\n%s\n\n%w", strconv.Quote("addRequestBodySizeField"), splitSqladdRequestBodySizeField, erraddRequestBodySizeField)
+		}
+
+		offsetaddRequestBodySizeField += countaddRequestBodySizeField
+	}
+
+	if !__imp.__withTx {
+		if erraddRequestBodySizeField := txaddRequestBodySizeField.Commit(); erraddRequestBodySizeField != nil {
+			return fmt.Errorf("error committing %s transaction: %w", strconv.Quote("addRequestBodySizeField"), erraddRequestBodySizeField)
+		}
+	}
+
+	return nil
+}
+
+func (__imp *implPersistence) addRateLimitField() error {
+	var (
+		erraddRateLimitField     error
+		argListaddRateLimitField = make(__rt.Arguments, 0, 8)
+	)
+
+	argListaddRateLimitField = __rt.Arguments{}
+
+	sqladdRateLimitField := __rt.GetBuffer()
+	defer __rt.PutBuffer(sqladdRateLimitField)
+	defer sqladdRateLimitField.Reset()
+
+	if erraddRateLimitField = sqlTmpladdRateLimitField.Execute(sqladdRateLimitField, map[string]any{}); erraddRateLimitField != nil {
+		return fmt.Errorf("error executing %s template: %w", strconv.Quote("addRateLimitField"), erraddRateLimitField)
+	}
+
+	queryaddRateLimitField := sqladdRateLimitField.String()
+
+	txaddRateLimitField, erraddRateLimitField := __imp.__core.Beginx()
+	if erraddRateLimitField != nil {
+		return fmt.Errorf("error creating %s transaction: %w", strconv.Quote("addRateLimitField"), erraddRateLimitField)
+	}
+	if !__imp.__withTx {
+		defer txaddRateLimitField.Rollback()
+	}
+
+	offsetaddRateLimitField := 0
+	argsaddRateLimitField := __rt.MergeArgs(argListaddRateLimitField...)
+
+	sqlSliceaddRateLimitField := __rt.Split(queryaddRateLimitField, ";")
+	for indexaddRateLimitField, splitSqladdRateLimitField := range sqlSliceaddRateLimitField {
+		_ = indexaddRateLimitField
+
+		countaddRateLimitField := __rt.Count(splitSqladdRateLimitField, "?")
+
+		_, erraddRateLimitField = txaddRateLimitField.Exec(splitSqladdRateLimitField, argsaddRateLimitField[offsetaddRateLimitField:offsetaddRateLimitField+countaddRateLimitField]...)
+
+		if erraddRateLimitField != nil {
+			return fmt.Errorf("error executing %s sql: \n\n%s\n\n%w", strconv.Quote("addRateLimitField"), splitSqladdRateLimitField, erraddRateLimitField)
+		}
+
+		offsetaddRateLimitField += countaddRateLimitField
+	}
+
+	if !__imp.__withTx {
+		if erraddRateLimitField := txaddRateLimitField.Commit(); erraddRateLimitField != nil {
+			return fmt.Errorf("error committing %s transaction: %w", strconv.Quote("addRateLimitField"), erraddRateLimitField)
+		}
+	}
+
+	return nil
+}
+
+func (__imp *implPersistence) addOriginalModelField() error {
+	var (
+		erraddOriginalModelField     error
+		argListaddOriginalModelField = make(__rt.Arguments, 0, 8)
+	)
+
+	argListaddOriginalModelField = __rt.Arguments{}
+
+	sqladdOriginalModelField := __rt.GetBuffer()
+	defer __rt.PutBuffer(sqladdOriginalModelField)
+	defer sqladdOriginalModelField.Reset()
+
+	if erraddOriginalModelField = sqlTmpladdOriginalModelField.Execute(sqladdOriginalModelField, map[string]any{}); erraddOriginalModelField != nil {
+		return fmt.Errorf("error executing %s template: %w", strconv.Quote("addOriginalModelField"), erraddOriginalModelField)
+	}
+
+	queryaddOriginalModelField := sqladdOriginalModelField.String()
+
+	txaddOriginalModelField, erraddOriginalModelField := __imp.__core.Beginx()
+	if erraddOriginalModelField != nil {
+		return fmt.Errorf("error creating %s transaction: %w", strconv.Quote("addOriginalModelField"), erraddOriginalModelField)
+	}
+	if !__imp.__withTx {
+		defer txaddOriginalModelField.Rollback()
+	}
+
+	offsetaddOriginalModelField := 0
+	argsaddOriginalModelField := __rt.MergeArgs(argListaddOriginalModelField...)
+
+	sqlSliceaddOriginalModelField := __rt.Split(queryaddOriginalModelField, ";")
+	for indexaddOriginalModelField, splitSqladdOriginalModelField := range sqlSliceaddOriginalModelField {
+		_ = indexaddOriginalModelField
+
+		countaddOriginalModelField := __rt.Count(splitSqladdOriginalModelField, "?")
+
+		_, erraddOriginalModelField = txaddOriginalModelField.Exec(splitSqladdOriginalModelField, argsaddOriginalModelField[offsetaddOriginalModelField:offsetaddOriginalModelField+countaddOriginalModelField]...)
+
+		if erraddOriginalModelField != nil {
+			return fmt.Errorf("error executing %s sql: \n\n%s\n\n%w", strconv.Quote("addOriginalModelField"), splitSqladdOriginalModelField, erraddOriginalModelField)
+		}
+
+		offsetaddOriginalModelField += countaddOriginalModelField
+	}
+
+	if !__imp.__withTx {
+		if erraddOriginalModelField := txaddOriginalModelField.Commit(); erraddOriginalModelField != nil {
+			return fmt.Errorf("error committing %s transaction: %w", strconv.Quote("addOriginalModelField"), erraddOriginalModelField)
+		}
+	}
+
+	return nil
+}
+
+func (__imp *implPersistence) addParentIDField() error {
+	var (
+		erraddParentIDField     error
+		argListaddParentIDField = make(__rt.Arguments, 0, 8)
+	)
+
+	argListaddParentIDField = __rt.Arguments{}
+
+	sqladdParentIDField := __rt.GetBuffer()
+	defer __rt.PutBuffer(sqladdParentIDField)
+	defer sqladdParentIDField.Reset()
+
+	if erraddParentIDField = sqlTmpladdParentIDField.Execute(sqladdParentIDField, map[string]any{}); erraddParentIDField != nil {
+		return fmt.Errorf("error executing %s template: %w", strconv.Quote("addParentIDField"), erraddParentIDField)
+	}
+
+	queryaddParentIDField := sqladdParentIDField.String()
+
+	txaddParentIDField, erraddParentIDField := __imp.__core.Beginx()
+	if erraddParentIDField != nil {
+		return fmt.Errorf("error creating %s transaction: %w", strconv.Quote("addParentIDField"), erraddParentIDField)
+	}
+	if !__imp.__withTx {
+		defer txaddParentIDField.Rollback()
+	}
+
+	offsetaddParentIDField := 0
+	argsaddParentIDField := __rt.MergeArgs(argListaddParentIDField...)
+
+	sqlSliceaddParentIDField := __rt.Split(queryaddParentIDField, ";")
+	for indexaddParentIDField, splitSqladdParentIDField := range sqlSliceaddParentIDField {
+		_ = indexaddParentIDField
+
+		countaddParentIDField := __rt.Count(splitSqladdParentIDField, "?")
+
+		_, erraddParentIDField = txaddParentIDField.Exec(splitSqladdParentIDField, argsaddParentIDField[offsetaddParentIDField:offsetaddParentIDField+countaddParentIDField]...)
+
+		if erraddParentIDField != nil {
+			return fmt.Errorf("error executing %s sql: \n\n%s\n\n%w", strconv.Quote("addParentIDField"), splitSqladdParentIDField, erraddParentIDField)
+		}
+
+		offsetaddParentIDField += countaddParentIDField
+	}
+
+	if !__imp.__withTx {
+		if erraddParentIDField := txaddParentIDField.Commit(); erraddParentIDField != nil {
+			return fmt.Errorf("error committing %s transaction: %w", strconv.Quote("addParentIDField"), erraddParentIDField)
+		}
+	}
+
+	return nil
+}
+
+func (__imp *implPersistence) addTagsField() error {
+	var (
+		erraddTagsField     error
+		argListaddTagsField = make(__rt.Arguments, 0, 8)
+	)
+
+	argListaddTagsField = __rt.Arguments{}
+
+	sqladdTagsField := __rt.GetBuffer()
+	defer __rt.PutBuffer(sqladdTagsField)
+	defer sqladdTagsField.Reset()
+
+	if erraddTagsField = sqlTmpladdTagsField.Execute(sqladdTagsField, map[string]any{}); erraddTagsField != nil {
+		return fmt.Errorf("error executing %s template: %w", strconv.Quote("addTagsField"), erraddTagsField)
+	}
+
+	queryaddTagsField := sqladdTagsField.String()
+
+	txaddTagsField, erraddTagsField := __imp.__core.Beginx()
+	if erraddTagsField != nil {
+		return fmt.Errorf("error creating %s transaction: %w", strconv.Quote("addTagsField"), erraddTagsField)
+	}
+	if !__imp.__withTx {
+		defer txaddTagsField.Rollback()
+	}
+
+	offsetaddTagsField := 0
+	argsaddTagsField := __rt.MergeArgs(argListaddTagsField...)
+
+	sqlSliceaddTagsField := __rt.Split(queryaddTagsField, ";")
+	for indexaddTagsField, splitSqladdTagsField := range sqlSliceaddTagsField {
+		_ = indexaddTagsField
+
+		countaddTagsField := __rt.Count(splitSqladdTagsField, "?")
+
+		_, erraddTagsField = txaddTagsField.Exec(splitSqladdTagsField, argsaddTagsField[offsetaddTagsField:offsetaddTagsField+countaddTagsField]...)
+
+		if erraddTagsField != nil {
+			return fmt.Errorf("error executing %s sql: \n\n%s\n\n%w", strconv.Quote("addTagsField"), splitSqladdTagsField, erraddTagsField)
+		}
+
+		offsetaddTagsField += countaddTagsField
+	}
+
+	if !__imp.__withTx {
+		if erraddTagsField := txaddTagsField.Commit(); erraddTagsField != nil {
+			return fmt.Errorf("error committing %s transaction: %w", strconv.Quote("addTagsField"), erraddTagsField)
+		}
+	}
+
+	return nil
+}
+
+func (__imp *implPersistence) addCategoryField() error {
+	var (
+		erraddCategoryField     error
+		argListaddCategoryField = make(__rt.Arguments, 0, 8)
+	)
+
+	argListaddCategoryField = __rt.Arguments{}
+
+	sqladdCategoryField := __rt.GetBuffer()
+	defer __rt.PutBuffer(sqladdCategoryField)
+	defer sqladdCategoryField.Reset()
+
+	if erraddCategoryField = sqlTmpladdCategoryField.Execute(sqladdCategoryField, map[string]any{}); erraddCategoryField != nil {
+		return fmt.Errorf("error executing %s template: %w", strconv.Quote("addCategoryField"), erraddCategoryField)
+	}
+
+	queryaddCategoryField := sqladdCategoryField.String()
+
+	txaddCategoryField, erraddCategoryField := __imp.__core.Beginx()
+	if erraddCategoryField != nil {
+		return fmt.Errorf("error creating %s transaction: %w", strconv.Quote("addCategoryField"), erraddCategoryField)
+	}
+	if !__imp.__withTx {
+		defer txaddCategoryField.Rollback()
+	}
+
+	offsetaddCategoryField := 0
+	argsaddCategoryField := __rt.MergeArgs(argListaddCategoryField...)
+
+	sqlSliceaddCategoryField := __rt.Split(queryaddCategoryField, ";")
+	for indexaddCategoryField, splitSqladdCategoryField := range sqlSliceaddCategoryField {
+		_ = indexaddCategoryField
+
+		countaddCategoryField := __rt.Count(splitSqladdCategoryField, "?")
+
+		_, erraddCategoryField = txaddCategoryField.Exec(splitSqladdCategoryField, argsaddCategoryField[offsetaddCategoryField:offsetaddCategoryField+countaddCategoryField]...)
+
+		if erraddCategoryField != nil {
+			return fmt.Errorf("error executing %s sql: \n\n%s\n\n%w", strconv.Quote("addCategoryField"), splitSqladdCategoryField, erraddCategoryField)
+		}
+
+		offsetaddCategoryField += countaddCategoryField
+	}
+
+	if !__imp.__withTx {
+		if erraddCategoryField := txaddCategoryField.Commit(); erraddCategoryField != nil {
+			return fmt.Errorf("error committing %s transaction: %w", strconv.Quote("addCategoryField"), erraddCategoryField)
+		}
+	}
+
+	return nil
+}
+
+func (__imp *implPersistence) addNoteField() error {
+	var (
+		erraddNoteField     error
+		argListaddNoteField = make(__rt.Arguments, 0, 8)
+	)
+
+	argListaddNoteField = __rt.Arguments{}
+
+	sqladdNoteField := __rt.GetBuffer()
+	defer __rt.PutBuffer(sqladdNoteField)
+	defer sqladdNoteField.Reset()
+
+	if erraddNoteField = sqlTmpladdNoteField.Execute(sqladdNoteField, map[string]any{}); erraddNoteField != nil {
+		return fmt.Errorf("error executing %s template: %w", strconv.Quote("addNoteField"), erraddNoteField)
+	}
+
+	queryaddNoteField := sqladdNoteField.String()
+
+	txaddNoteField, erraddNoteField := __imp.__core.Beginx()
+	if erraddNoteField != nil {
+		return fmt.Errorf("error creating %s transaction: %w", strconv.Quote("addNoteField"), erraddNoteField)
+	}
+	if !__imp.__withTx {
+		defer txaddNoteField.Rollback()
+	}
+
+	offsetaddNoteField := 0
+	argsaddNoteField := __rt.MergeArgs(argListaddNoteField...)
+
+	sqlSliceaddNoteField := __rt.Split(queryaddNoteField, ";")
+	for indexaddNoteField, splitSqladdNoteField := range sqlSliceaddNoteField {
+		_ = indexaddNoteField
+
+		countaddNoteField := __rt.Count(splitSqladdNoteField, "?")
+
+		_, erraddNoteField = txaddNoteField.Exec(splitSqladdNoteField, argsaddNoteField[offsetaddNoteField:offsetaddNoteField+countaddNoteField]...)
+
+		if erraddNoteField != nil {
+			return fmt.Errorf("error executing %s sql: \n\n%s\n\n%w", strconv.Quote("addNoteField"), splitSqladdNoteField, erraddNoteField)
+		}
+
+		offsetaddNoteField += countaddNoteField
+	}
+
+	if !__imp.__withTx {
+		if erraddNoteField := txaddNoteField.Commit(); erraddNoteField != nil {
+			return fmt.Errorf("error committing %s transaction: %w", strconv.Quote("addNoteField"), erraddNoteField)
+		}
+	}
+
+	return nil
+}
+
+func (__imp *implPersistence) addTraceIDField() error {
+	var (
+		erraddTraceIDField     error
+		argListaddTraceIDField = make(__rt.Arguments, 0, 8)
+	)
+
+	argListaddTraceIDField = __rt.Arguments{}
+
+	sqladdTraceIDField := __rt.GetBuffer()
+	defer __rt.PutBuffer(sqladdTraceIDField)
+	defer sqladdTraceIDField.Reset()
+
+	if erraddTraceIDField = sqlTmpladdTraceIDField.Execute(sqladdTraceIDField, map[string]any{}); erraddTraceIDField != nil {
+		return fmt.Errorf("error executing %s template: %w", strconv.Quote("addTraceIDField"), erraddTraceIDField)
+	}
+
+	queryaddTraceIDField := sqladdTraceIDField.String()
+
+	txaddTraceIDField, erraddTraceIDField := __imp.__core.Beginx()
+	if erraddTraceIDField != nil {
+		return fmt.Errorf("error creating %s transaction: %w", strconv.Quote("addTraceIDField"), erraddTraceIDField)
+	}
+	if !__imp.__withTx {
+		defer txaddTraceIDField.Rollback()
+	}
+
+	offsetaddTraceIDField := 0
+	argsaddTraceIDField := __rt.MergeArgs(argListaddTraceIDField...)
+
+	sqlSliceaddTraceIDField := __rt.Split(queryaddTraceIDField, ";")
+	for indexaddTraceIDField, splitSqladdTraceIDField := range sqlSliceaddTraceIDField {
+		_ = indexaddTraceIDField
+
+		countaddTraceIDField := __rt.Count(splitSqladdTraceIDField, "?")
+
+		_, erraddTraceIDField = txaddTraceIDField.Exec(splitSqladdTraceIDField, argsaddTraceIDField[offsetaddTraceIDField:offsetaddTraceIDField+countaddTraceIDField]...)
+
+		if erraddTraceIDField != nil {
+			return fmt.Errorf("error executing %s sql: \n\n%s\n\n%w", strconv.Quote("addTraceIDField"), splitSqladdTraceIDField, erraddTraceIDField)
+		}
+
+		offsetaddTraceIDField += countaddTraceIDField
+	}
+
+	if !__imp.__withTx {
+		if erraddTraceIDField := txaddTraceIDField.Commit(); erraddTraceIDField != nil {
+			return fmt.Errorf("error committing %s transaction: %w", strconv.Quote("addTraceIDField"), erraddTraceIDField)
+		}
+	}
+
+	return nil
+}
+
+func (__imp *implPersistence) addRawRequestHeaderField() error {
+	var (
+		erraddRawRequestHeaderField     error
+		argListaddRawRequestHeaderField = make(__rt.Arguments, 0, 8)
+	)
+
+	argListaddRawRequestHeaderField = __rt.Arguments{}
+
+	sqladdRawRequestHeaderField := __rt.GetBuffer()
+	defer __rt.PutBuffer(sqladdRawRequestHeaderField)
+	defer sqladdRawRequestHeaderField.Reset()
+
+	if erraddRawRequestHeaderField = sqlTmpladdRawRequestHeaderField.Execute(sqladdRawRequestHeaderField, map[string]any{}); erraddRawRequestHeaderField != nil {
+		return fmt.Errorf("error executing %s template: %w", strconv.Quote("addRawRequestHeaderField"), erraddRawRequestHeaderField)
+	}
+
+	queryaddRawRequestHeaderField := sqladdRawRequestHeaderField.String()
+
+	txaddRawRequestHeaderField, erraddRawRequestHeaderField := __imp.__core.Beginx()
+	if erraddRawRequestHeaderField != nil {
+		return fmt.Errorf("error creating %s transaction: %w", strconv.Quote("addRawRequestHeaderField"), erraddRawRequestHeaderField)
+	}
+	if !__imp.__withTx {
+		defer txaddRawRequestHeaderField.Rollback()
+	}
+
+	offsetaddRawRequestHeaderField := 0
+	argsaddRawRequestHeaderField := __rt.MergeArgs(argListaddRawRequestHeaderField...)
+
+	sqlSliceaddRawRequestHeaderField := __rt.Split(queryaddRawRequestHeaderField, ";")
+	for indexaddRawRequestHeaderField, splitSqladdRawRequestHeaderField := range sqlSliceaddRawRequestHeaderField {
+		_ = indexaddRawRequestHeaderField
+
+		countaddRawRequestHeaderField := __rt.Count(splitSqladdRawRequestHeaderField, "?")
+
+		_, erraddRawRequestHeaderField = txaddRawRequestHeaderField.Exec(splitSqladdRawRequestHeaderField, argsaddRawRequestHeaderField[offsetaddRawRequestHeaderField:offsetaddRawRequestHeaderField+countaddRawRequestHeaderField]...)
+
+		if erraddRawRequestHeaderField != nil {
+			return fmt.Errorf("error executing %s sql: \n\n%s\n\n%w", strconv.Quote("addRawRequestHeaderField"), splitSqladdRawRequestHeaderField, erraddRawRequestHeaderField)
+		}
+
+		offsetaddRawRequestHeaderField += countaddRawRequestHeaderField
+	}
+
+	if !__imp.__withTx {
+		if erraddRawRequestHeaderField := txaddRawRequestHeaderField.Commit(); erraddRawRequestHeaderField != nil {
+			return fmt.Errorf("error committing %s transaction: %w", strconv.Quote("addRawRequestHeaderField"), erraddRawRequestHeaderField)
+		}
+	}
+
+	return nil
+}
+
+func (__imp *implPersistence) addRequestBodyHashField() error {
+	var (
+		erraddRequestBodyHashField     error
+		argListaddRequestBodyHashField = make(__rt.Arguments, 0, 8)
+	)
+
+	argListaddRequestBodyHashField = __rt.Arguments{}
+
+	sqladdRequestBodyHashField := __rt.GetBuffer()
+	defer __rt.PutBuffer(sqladdRequestBodyHashField)
+	defer sqladdRequestBodyHashField.Reset()
+
+	if erraddRequestBodyHashField = sqlTmpladdRequestBodyHashField.Execute(sqladdRequestBodyHashField, map[string]any{}); erraddRequestBodyHashField != nil {
+		return fmt.Errorf("error executing %s template: %w", strconv.Quote("addRequestBodyHashField"), erraddRequestBodyHashField)
+	}
+
+	queryaddRequestBodyHashField := sqladdRequestBodyHashField.String()
+
+	txaddRequestBodyHashField, erraddRequestBodyHashField := __imp.__core.Beginx()
+	if erraddRequestBodyHashField != nil {
+		return fmt.Errorf("error creating %s transaction: %w", strconv.Quote("addRequestBodyHashField"), erraddRequestBodyHashField)
+	}
+	if !__imp.__withTx {
+		defer txaddRequestBodyHashField.Rollback()
+	}
+
+	offsetaddRequestBodyHashField := 0
+	argsaddRequestBodyHashField := __rt.MergeArgs(argListaddRequestBodyHashField...)
+
+	sqlSliceaddRequestBodyHashField := __rt.Split(queryaddRequestBodyHashField, ";")
+	for indexaddRequestBodyHashField, splitSqladdRequestBodyHashField := range sqlSliceaddRequestBodyHashField {
+		_ = indexaddRequestBodyHashField
+
+		countaddRequestBodyHashField := __rt.Count(splitSqladdRequestBodyHashField, "?")
+
+		_, erraddRequestBodyHashField = txaddRequestBodyHashField.Exec(splitSqladdRequestBodyHashField, argsaddRequestBodyHashField[offsetaddRequestBodyHashField:offsetaddRequestBodyHashField+countaddRequestBodyHashField]...)
+
+		if erraddRequestBodyHashField != nil {
+			return fmt.Errorf("error executing %s sql: \n\n%s\n\n%w", strconv.Quote("addRequestBodyHashField"), splitSqladdRequestBodyHashField, erraddRequestBodyHashField)
+		}
+
+		offsetaddRequestBodyHashField += countaddRequestBodyHashField
+	}
+
+	if !__imp.__withTx {
+		if erraddRequestBodyHashField := txaddRequestBodyHashField.Commit(); erraddRequestBodyHashField != nil {
+			return fmt.Errorf("error committing %s transaction: %w", strconv.Quote("addRequestBodyHashField"), erraddRequestBodyHashField)
+		}
+	}
+
+	return nil
+}
+
+func (__imp *implPersistence) backfillRequestBodyHash() error {
+	var (
+		errbackfillRequestBodyHash     error
+		argListbackfillRequestBodyHash = make(__rt.Arguments, 0, 8)
+	)
+
+	argListbackfillRequestBodyHash = __rt.Arguments{}
+
+	sqlbackfillRequestBodyHash := __rt.GetBuffer()
+	defer __rt.PutBuffer(sqlbackfillRequestBodyHash)
+	defer sqlbackfillRequestBodyHash.Reset()
+
+	if errbackfillRequestBodyHash = sqlTmplbackfillRequestBodyHash.Execute(sqlbackfillRequestBodyHash, map[string]any{}); errbackfillRequestBodyHash != nil {
+		return fmt.Errorf("error executing %s template: %w", strconv.Quote("backfillRequestBodyHash"), errbackfillRequestBodyHash)
+	}
+
+	querybackfillRequestBodyHash := sqlbackfillRequestBodyHash.String()
+
+	txbackfillRequestBodyHash, errbackfillRequestBodyHash := __imp.__core.Beginx()
+	if errbackfillRequestBodyHash != nil {
+		return fmt.Errorf("error creating %s transaction: %w", strconv.Quote("backfillRequestBodyHash"), errbackfillRequestBodyHash)
+	}
+	if !__imp.__withTx {
+		defer txbackfillRequestBodyHash.Rollback()
+	}
+
+	offsetbackfillRequestBodyHash := 0
+	argsbackfillRequestBodyHash := __rt.MergeArgs(argListbackfillRequestBodyHash...)
+
+	sqlSlicebackfillRequestBodyHash := __rt.Split(querybackfillRequestBodyHash, ";")
+	for indexbackfillRequestBodyHash, splitSqlbackfillRequestBodyHash := range sqlSlicebackfillRequestBodyHash {
+		_ = indexbackfillRequestBodyHash
+
+		countbackfillRequestBodyHash := __rt.Count(splitSqlbackfillRequestBodyHash, "?")
+
+		_, errbackfillRequestBodyHash = txbackfillRequestBodyHash.Exec(splitSqlbackfillRequestBodyHash, argsbackfillRequestBodyHash[offsetbackfillRequestBodyHash:offsetbackfillRequestBodyHash+countbackfillRequestBodyHash]...)
+
+		if errbackfillRequestBodyHash != nil {
+			return fmt.Errorf("error executing %s sql: \n\n%s\n\n%w", strconv.Quote("backfillRequestBodyHash"), splitSqlbackfillRequestBodyHash, errbackfillRequestBodyHash)
+		}
+
+		offsetbackfillRequestBodyHash += countbackfillRequestBodyHash
+	}
+
+	if !__imp.__withTx {
+		if errbackfillRequestBodyHash := txbackfillRequestBodyHash.Commit(); errbackfillRequestBodyHash != nil {
+			return fmt.Errorf("error committing %s transaction: %w", strconv.Quote("backfillRequestBodyHash"), errbackfillRequestBodyHash)
+		}
+	}
+
+	return nil
+}
+
+func (__imp *implPersistence) listIndexes() ([]string, error) {
+	var (
+		v0listIndexes      []string
+		errlistIndexes     error
+		argListlistIndexes = make(__rt.Arguments, 0, 8)
+	)
+
+	argListlistIndexes = __rt.Arguments{}
+
+	querylistIndexes := "select name from pragma_index_list('moonshot_requests');\r\n"
+
+	txlistIndexes, errlistIndexes := __imp.__core.Beginx()
+	if errlistIndexes != nil {
+		return v0listIndexes, fmt.Errorf("error creating %s transaction: %w", strconv.Quote("listIndexes"), errlistIndexes)
+	}
+	if !__imp.__withTx {
+		defer txlistIndexes.Rollback()
+	}
+
+	offsetlistIndexes := 0
+	argslistIndexes := __rt.MergeArgs(argListlistIndexes...)
+
+	sqlSlicelistIndexes := __rt.Split(querylistIndexes, ";")
+	for indexlistIndexes, splitSqllistIndexes := range sqlSlicelistIndexes {
+		_ = indexlistIndexes
+
+		countlistIndexes := __rt.Count(splitSqllistIndexes, "?")
+
+		if indexlistIndexes < len(sqlSlicelistIndexes)-1 {
+			_, errlistIndexes = txlistIndexes.Exec(splitSqllistIndexes, argslistIndexes[offsetlistIndexes:offsetlistIndexes+countlistIndexes]...)
+		} else {
+			errlistIndexes = txlistIndexes.Select(&v0listIndexes, splitSqllistIndexes, argslistIndexes[offsetlistIndexes:offsetlistIndexes+countlistIndexes]...)
+		}
+
+		if errlistIndexes != nil {
+			return v0listIndexes, fmt.Errorf("error executing %s sql: \n\n%s\n\n%w", strconv.Quote("listIndexes"), splitSqllistIndexes, errlistIndexes)
+		}
+
+		offsetlistIndexes += countlistIndexes
+	}
+
+	if !__imp.__withTx {
+		if errlistIndexes := txlistIndexes.Commit(); errlistIndexes != nil {
+			return v0listIndexes, fmt.Errorf("error committing %s transaction: %w", strconv.Quote("listIndexes"), errlistIndexes)
+		}
+	}
+
+	return v0listIndexes, nil
+}
+
+func (__imp *implPersistence) addRequestBodyHashIndex() error {
+	var (
+		erraddRequestBodyHashIndex     error
+		argListaddRequestBodyHashIndex = make(__rt.Arguments, 0, 8)
+	)
+
+	argListaddRequestBodyHashIndex = __rt.Arguments{}
+
+	sqladdRequestBodyHashIndex := __rt.GetBuffer()
+	defer __rt.PutBuffer(sqladdRequestBodyHashIndex)
+	defer sqladdRequestBodyHashIndex.Reset()
+
+	if erraddRequestBodyHashIndex = sqlTmpladdRequestBodyHashIndex.Execute(sqladdRequestBodyHashIndex, map[string]any{}); erraddRequestBodyHashIndex != nil {
+		return fmt.Errorf("error executing %s template: %w", strconv.Quote("addRequestBodyHashIndex"), erraddRequestBodyHashIndex)
+	}
+
+	queryaddRequestBodyHashIndex := sqladdRequestBodyHashIndex.String()
+
+	txaddRequestBodyHashIndex, erraddRequestBodyHashIndex := __imp.__core.Beginx()
+	if erraddRequestBodyHashIndex != nil {
+		return fmt.Errorf("error creating %s transaction: %w", strconv.Quote("addRequestBodyHashIndex"), erraddRequestBodyHashIndex)
+	}
+	if !__imp.__withTx {
+		defer txaddRequestBodyHashIndex.Rollback()
+	}
+
+	offsetaddRequestBodyHashIndex := 0
+	argsaddRequestBodyHashIndex := __rt.MergeArgs(argListaddRequestBodyHashIndex...)
+
+	sqlSliceaddRequestBodyHashIndex := __rt.Split(queryaddRequestBodyHashIndex, ";")
+	for indexaddRequestBodyHashIndex, splitSqladdRequestBodyHashIndex := range sqlSliceaddRequestBodyHashIndex {
+		_ = indexaddRequestBodyHashIndex
+
+		countaddRequestBodyHashIndex := __rt.Count(splitSqladdRequestBodyHashIndex, "?")
+
+		_, erraddRequestBodyHashIndex = txaddRequestBodyHashIndex.Exec(splitSqladdRequestBodyHashIndex, argsaddRequestBodyHashIndex[offsetaddRequestBodyHashIndex:offsetaddRequestBodyHashIndex+countaddRequestBodyHashIndex]...)
+
+		if erraddRequestBodyHashIndex != nil {
+			return fmt.Errorf("error executing %s sql: \n\n%s\n\n%w", strconv.Quote("addRequestBodyHashIndex"), splitSqladdRequestBodyHashIndex, erraddRequestBodyHashIndex)
+		}
+
+		offsetaddRequestBodyHashIndex += countaddRequestBodyHashIndex
+	}
+
+	if !__imp.__withTx {
+		if erraddRequestBodyHashIndex := txaddRequestBodyHashIndex.Commit(); erraddRequestBodyHashIndex != nil {
+			return fmt.Errorf("error committing %s transaction: %w", strconv.Quote("addRequestBodyHashIndex"), erraddRequestBodyHashIndex)
+		}
+	}
+
+	return nil
+}
+
+func (__imp *implPersistence) addCredentialHashField() error {
+	var (
+		erraddCredentialHashField     error
+		argListaddCredentialHashField = make(__rt.Arguments, 0, 8)
+	)
+
+	argListaddCredentialHashField = __rt.Arguments{}
+
+	sqladdCredentialHashField := __rt.GetBuffer()
+	defer __rt.PutBuffer(sqladdCredentialHashField)
+	defer sqladdCredentialHashField.Reset()
+
+	if erraddCredentialHashField = sqlTmpladdCredentialHashField.Execute(sqladdCredentialHashField, map[string]any{}); erraddCredentialHashField != nil {
+		return fmt.Errorf("error executing %s template: %w", strconv.Quote("addCredentialHashField"), erraddCredentialHashField)
+	}
+
+	queryaddCredentialHashField := sqladdCredentialHashField.String()
+
+	txaddCredentialHashField, erraddCredentialHashField := __imp.__core.Beginx()
+	if erraddCredentialHashField != nil {
+		return fmt.Errorf("error creating %s transaction: %w", strconv.Quote("addCredentialHashField"), erraddCredentialHashField)
+	}
+	if !__imp.__withTx {
+		defer txaddCredentialHashField.Rollback()
+	}
+
+	offsetaddCredentialHashField := 0
+	argsaddCredentialHashField := __rt.MergeArgs(argListaddCredentialHashField...)
+
+	sqlSliceaddCredentialHashField := __rt.Split(queryaddCredentialHashField, ";")
+	for indexaddCredentialHashField, splitSqladdCredentialHashField := range sqlSliceaddCredentialHashField {
+		_ = indexaddCredentialHashField
+
+		countaddCredentialHashField := __rt.Count(splitSqladdCredentialHashField, "?")
+
+		_, erraddCredentialHashField = txaddCredentialHashField.Exec(splitSqladdCredentialHashField, argsaddCredentialHashField[offsetaddCredentialHashField:offsetaddCredentialHashField+countaddCredentialHashField]...)
+
+		if erraddCredentialHashField != nil {
+			return fmt.Errorf("error executing %s sql: \n\n%s\n\n%w", strconv.Quote("addCredentialHashField"), splitSqladdCredentialHashField, erraddCredentialHashField)
+		}
+
+		offsetaddCredentialHashField += countaddCredentialHashField
+	}
+
+	if !__imp.__withTx {
+		if erraddCredentialHashField := txaddCredentialHashField.Commit(); erraddCredentialHashField != nil {
+			return fmt.Errorf("error committing %s transaction: %w", strconv.Quote("addCredentialHashField"), erraddCredentialHashField)
+		}
+	}
+
+	return nil
+}
+
 func (__imp *implPersistence) Cleanup(before string) (sql.Result, error) {
 	var (
-		v0Cleanup  sql.Result
-		errCleanup error
+		v0Cleanup  sql.Result
+		errCleanup error
+	)
+
+	queryCleanup := "delete from moonshot_requests where created_at < :before;\r\n"
+
+	txCleanup, errCleanup := __imp.__core.Beginx()
+	if errCleanup != nil {
+		return v0Cleanup, fmt.Errorf("error creating %s transaction: %w", strconv.Quote("Cleanup"), errCleanup)
+	}
+	if !__imp.__withTx {
+		defer txCleanup.Rollback()
+	}
+
+	argsCleanup := __rt.MergeNamedArgs(map[string]any{
+		"before": before,
+	})
+
+	sqlSliceCleanup := __rt.Split(queryCleanup, ";")
+	for indexCleanup, splitSqlCleanup := range sqlSliceCleanup {
+		_ = indexCleanup
+
+		var listArgsCleanup []interface{}
+
+		splitSqlCleanup, listArgsCleanup, errCleanup = sqlx.Named(splitSqlCleanup, argsCleanup)
+		if errCleanup != nil {
+			return v0Cleanup, fmt.Errorf("error building %s query: %w", strconv.Quote("Cleanup"), errCleanup)
+		}
+
+		splitSqlCleanup, listArgsCleanup, errCleanup = sqlx.In(splitSqlCleanup, listArgsCleanup...)
+		if errCleanup != nil {
+			return v0Cleanup, fmt.Errorf("error building %s query: %w", strconv.Quote("Cleanup"), errCleanup)
+		}
+
+		v0Cleanup, errCleanup = txCleanup.Exec(splitSqlCleanup, listArgsCleanup...)
+
+		if errCleanup != nil {
+			return v0Cleanup, fmt.Errorf("error executing %s sql: \n\n%s\n\n%w", strconv.Quote("Cleanup"), splitSqlCleanup, errCleanup)
+		}
+	}
+
+	if !__imp.__withTx {
+		if errCleanup := txCleanup.Commit(); errCleanup != nil {
+			return v0Cleanup, fmt.Errorf("error committing %s transaction: %w", strconv.Quote("Cleanup"), errCleanup)
+		}
+	}
+
+	return v0Cleanup, nil
+}
+
+func (__imp *implPersistence) Persistence(requestID string, requestContentType string, requestMethod string, requestPath string, requestQuery string, moonshotID string, moonshotGID string, moonshotUID string, moonshotRequestID string, moonshotServerTiming int, responseStatusCode int, responseContentType string, requestHeader string, requestBody string, responseHeader string, responseBody string, programError string, responseTTFT int, responseTPOT int, responseOTPS float64, createdAt string, latency time.Duration, endpoint string, model string, systemFingerprint string, requestBodySize int, rateLimit string, originalModel string, traceID string, rawRequestHeader string, requestBodyHash string, credentialHash string) (int64, error) {
+	var (
+		v0Persistence  int64
+		errPersistence error
+	)
+
+	sqlPersistence := __rt.GetBuffer()
+	defer __rt.PutBuffer(sqlPersistence)
+	defer sqlPersistence.Reset()
+
+	if errPersistence = sqlTmplPersistence.Execute(sqlPersistence, map[string]any{
+		"requestID":            requestID,
+		"requestContentType":   requestContentType,
+		"requestMethod":        requestMethod,
+		"requestPath":          requestPath,
+		"requestQuery":         requestQuery,
+		"moonshotID":           moonshotID,
+		"moonshotGID":          moonshotGID,
+		"moonshotUID":          moonshotUID,
+		"moonshotRequestID":    moonshotRequestID,
+		"moonshotServerTiming": moonshotServerTiming,
+		"responseStatusCode":   responseStatusCode,
+		"responseContentType":  responseContentType,
+		"requestHeader":        requestHeader,
+		"requestBody":          requestBody,
+		"responseHeader":       responseHeader,
+		"responseBody":         responseBody,
+		"programError":         programError,
+		"responseTTFT":         responseTTFT,
+		"responseTPOT":         responseTPOT,
+		"responseOTPS":         responseOTPS,
+		"createdAt":            createdAt,
+		"latency":              latency,
+		"endpoint":             endpoint,
+		"model":                model,
+		"systemFingerprint":    systemFingerprint,
+		"requestBodySize":      requestBodySize,
+		"rateLimit":            rateLimit,
+		"originalModel":        originalModel,
+		"traceID":              traceID,
+		"rawRequestHeader":     rawRequestHeader,
+		"requestBodyHash":      requestBodyHash,
+		"credentialHash":       credentialHash,
+	}); errPersistence != nil {
+		return v0Persistence, fmt.Errorf("error executing %s template: %w", strconv.Quote("Persistence"), errPersistence)
+	}
+
+	queryPersistence := sqlPersistence.String()
+
+	txPersistence, errPersistence := __imp.__core.Beginx()
+	if errPersistence != nil {
+		return v0Persistence, fmt.Errorf("error creating %s transaction: %w", strconv.Quote("Persistence"), errPersistence)
+	}
+	if !__imp.__withTx {
+		defer txPersistence.Rollback()
+	}
+
+	argsPersistence := __rt.MergeNamedArgs(map[string]any{
+		"requestID":            requestID,
+		"requestContentType":   requestContentType,
+		"requestMethod":        requestMethod,
+		"requestPath":          requestPath,
+		"requestQuery":         requestQuery,
+		"moonshotID":           moonshotID,
+		"moonshotGID":          moonshotGID,
+		"moonshotUID":          moonshotUID,
+		"moonshotRequestID":    moonshotRequestID,
+		"moonshotServerTiming": moonshotServerTiming,
+		"responseStatusCode":   responseStatusCode,
+		"responseContentType":  responseContentType,
+		"requestHeader":        requestHeader,
+		"requestBody":          requestBody,
+		"responseHeader":       responseHeader,
+		"responseBody":         responseBody,
+		"programError":         programError,
+		"responseTTFT":         responseTTFT,
+		"responseTPOT":         responseTPOT,
+		"responseOTPS":         responseOTPS,
+		"createdAt":            createdAt,
+		"latency":              latency,
+		"endpoint":             endpoint,
+		"model":                model,
+		"systemFingerprint":    systemFingerprint,
+		"requestBodySize":      requestBodySize,
+		"rateLimit":            rateLimit,
+		"originalModel":        originalModel,
+		"traceID":              traceID,
+		"rawRequestHeader":     rawRequestHeader,
+		"requestBodyHash":      requestBodyHash,
+		"credentialHash":       credentialHash,
+	})
+
+	sqlSlicePersistence := __rt.Split(queryPersistence, ";")
+	for indexPersistence, splitSqlPersistence := range sqlSlicePersistence {
+		_ = indexPersistence
+
+		var listArgsPersistence []interface{}
+
+		splitSqlPersistence, listArgsPersistence, errPersistence = sqlx.Named(splitSqlPersistence, argsPersistence)
+		if errPersistence != nil {
+			return v0Persistence, fmt.Errorf("error building %s query: %w", strconv.Quote("Persistence"), errPersistence)
+		}
+
+		splitSqlPersistence, listArgsPersistence, errPersistence = sqlx.In(splitSqlPersistence, listArgsPersistence...)
+		if errPersistence != nil {
+			return v0Persistence, fmt.Errorf("error building %s query: %w", strconv.Quote("Persistence"), errPersistence)
+		}
+
+		if indexPersistence < len(sqlSlicePersistence)-1 {
+			_, errPersistence = txPersistence.Exec(splitSqlPersistence, listArgsPersistence...)
+		} else {
+			errPersistence = txPersistence.Get(&v0Persistence, splitSqlPersistence, listArgsPersistence...)
+		}
+
+		if errPersistence != nil {
+			return v0Persistence, fmt.Errorf("error executing %s sql: \n\n%s\n\n%w", strconv.Quote("Persistence"), splitSqlPersistence, errPersistence)
+		}
+	}
+
+	if !__imp.__withTx {
+		if errPersistence := txPersistence.Commit(); errPersistence != nil {
+			return v0Persistence, fmt.Errorf("error committing %s transaction: %w", strconv.Quote("Persistence"), errPersistence)
+		}
+	}
+
+	return v0Persistence, nil
+}
+
+func (__imp *implPersistence) InsertRequest(request *Request) (int64, error) {
+	var (
+		v0InsertRequest      int64
+		errInsertRequest     error
+		argListInsertRequest = make(__rt.Arguments, 0, 8)
+	)
+
+	__InsertRequestBindFunc := func(arg any) string {
+		argListInsertRequest = append(argListInsertRequest, arg)
+		return __rt.BindVars(len(__rt.MergeArgs(arg)))
+	}
+	sqlTmplInsertRequest := template.Must(template.New("InsertRequest").Funcs(template.FuncMap{"bind": __InsertRequestBindFunc, "bindvars": __rt.BindVars, "fields": tableFields, "groupBy": groupByColumns, "groupColumns": groupColumns}).Parse("insert into moonshot_requests ( request_method, request_path, request_query, request_content_type, request_id, moonshot_id, moonshot_gid, moonshot_uid, moonshot_request_id, moonshot_server_timing, response_status_code, response_content_type, request_header, request_body, response_header, response_body, error, response_ttft, response_tpot, response_otps, latency, endpoint, model, system_fingerprint, request_body_size, rate_limit, original_model, parent_id, tags, category, note, trace_id, raw_request_header, request_body_hash, credential_hash, created_at ) values ({{ bind .request }});\r\nselect last_insert_rowid();\r\n"))
+
+	sqlInsertRequest := __rt.GetBuffer()
+	defer __rt.PutBuffer(sqlInsertRequest)
+	defer sqlInsertRequest.Reset()
+
+	if errInsertRequest = sqlTmplInsertRequest.Execute(sqlInsertRequest, map[string]any{
+		"request": request,
+	}); errInsertRequest != nil {
+		return v0InsertRequest, fmt.Errorf("error executing %s template: %w", strconv.Quote("InsertRequest"), errInsertRequest)
+	}
+
+	queryInsertRequest := sqlInsertRequest.String()
+
+	txInsertRequest, errInsertRequest := __imp.__core.Beginx()
+	if errInsertRequest != nil {
+		return v0InsertRequest, fmt.Errorf("error creating %s transaction: %w", strconv.Quote("InsertRequest"), errInsertRequest)
+	}
+	if !__imp.__withTx {
+		defer txInsertRequest.Rollback()
+	}
+
+	offsetInsertRequest := 0
+	argsInsertRequest := __rt.MergeArgs(argListInsertRequest...)
+
+	sqlSliceInsertRequest := __rt.Split(queryInsertRequest, ";")
+	for indexInsertRequest, splitSqlInsertRequest := range sqlSliceInsertRequest {
+		_ = indexInsertRequest
+
+		countInsertRequest := __rt.Count(splitSqlInsertRequest, "?")
+
+		if indexInsertRequest < len(sqlSliceInsertRequest)-1 {
+			_, errInsertRequest = txInsertRequest.Exec(splitSqlInsertRequest, argsInsertRequest[offsetInsertRequest:offsetInsertRequest+countInsertRequest]...)
+		} else {
+			errInsertRequest = txInsertRequest.Get(&v0InsertRequest, splitSqlInsertRequest, argsInsertRequest[offsetInsertRequest:offsetInsertRequest+countInsertRequest]...)
+		}
+
+		if errInsertRequest != nil {
+			return v0InsertRequest, fmt.Errorf("error executing %s sql: \n\n%s\n\n%w", strconv.Quote("InsertRequest"), splitSqlInsertRequest, errInsertRequest)
+		}
+
+		offsetInsertRequest += countInsertRequest
+	}
+
+	if !__imp.__withTx {
+		if errInsertRequest := txInsertRequest.Commit(); errInsertRequest != nil {
+			return v0InsertRequest, fmt.Errorf("error committing %s transaction: %w", strconv.Quote("InsertRequest"), errInsertRequest)
+		}
+	}
+
+	return v0InsertRequest, nil
+}
+
+func (__imp *implPersistence) GetReplayChain(originalID int64) ([]*Request, error) {
+	var (
+		v0GetReplayChain  []*Request
+		errGetReplayChain error
+	)
+
+	sqlGetReplayChain := __rt.GetBuffer()
+	defer __rt.PutBuffer(sqlGetReplayChain)
+	defer sqlGetReplayChain.Reset()
+
+	if errGetReplayChain = sqlTmplGetReplayChain.Execute(sqlGetReplayChain, map[string]any{
+		"originalID": originalID,
+	}); errGetReplayChain != nil {
+		return v0GetReplayChain, fmt.Errorf("error executing %s template: %w", strconv.Quote("GetReplayChain"), errGetReplayChain)
+	}
+
+	queryGetReplayChain := sqlGetReplayChain.String()
+
+	txGetReplayChain, errGetReplayChain := __imp.__core.Beginx()
+	if errGetReplayChain != nil {
+		return v0GetReplayChain, fmt.Errorf("error creating %s transaction: %w", strconv.Quote("GetReplayChain"), errGetReplayChain)
+	}
+	if !__imp.__withTx {
+		defer txGetReplayChain.Rollback()
+	}
+
+	argsGetReplayChain := __rt.MergeNamedArgs(map[string]any{
+		"originalID": originalID,
+	})
+
+	sqlSliceGetReplayChain := __rt.Split(queryGetReplayChain, ";")
+	for indexGetReplayChain, splitSqlGetReplayChain := range sqlSliceGetReplayChain {
+		_ = indexGetReplayChain
+
+		var listArgsGetReplayChain []interface{}
+
+		splitSqlGetReplayChain, listArgsGetReplayChain, errGetReplayChain = sqlx.Named(splitSqlGetReplayChain, argsGetReplayChain)
+		if errGetReplayChain != nil {
+			return v0GetReplayChain, fmt.Errorf("error building %s query: %w", strconv.Quote("GetReplayChain"), errGetReplayChain)
+		}
+
+		splitSqlGetReplayChain, listArgsGetReplayChain, errGetReplayChain = sqlx.In(splitSqlGetReplayChain, listArgsGetReplayChain...)
+		if errGetReplayChain != nil {
+			return v0GetReplayChain, fmt.Errorf("error building %s query: %w", strconv.Quote("GetReplayChain"), errGetReplayChain)
+		}
+
+		if indexGetReplayChain < len(sqlSliceGetReplayChain)-1 {
+			_, errGetReplayChain = txGetReplayChain.Exec(splitSqlGetReplayChain, listArgsGetReplayChain...)
+		} else {
+			errGetReplayChain = txGetReplayChain.Select(&v0GetReplayChain, splitSqlGetReplayChain, listArgsGetReplayChain...)
+		}
+
+		if errGetReplayChain != nil {
+			return v0GetReplayChain, fmt.Errorf("error executing %s sql: \n\n%s\n\n%w", strconv.Quote("GetReplayChain"), splitSqlGetReplayChain, errGetReplayChain)
+		}
+	}
+
+	if !__imp.__withTx {
+		if errGetReplayChain := txGetReplayChain.Commit(); errGetReplayChain != nil {
+			return v0GetReplayChain, fmt.Errorf("error committing %s transaction: %w", strconv.Quote("GetReplayChain"), errGetReplayChain)
+		}
+	}
+
+	return v0GetReplayChain, nil
+}
+
+func (__imp *implPersistence) ListRequests(n int64, chatOnly bool, predicate string) ([]*Request, error) {
+	var (
+		v0ListRequests      []*Request
+		errListRequests     error
+		argListListRequests = make(__rt.Arguments, 0, 8)
+	)
+
+	__ListRequestsBindFunc := func(arg any) string {
+		argListListRequests = append(argListListRequests, arg)
+		return __rt.BindVars(len(__rt.MergeArgs(arg)))
+	}
+	sqlTmplListRequests := template.Must(template.New("ListRequests").Funcs(template.FuncMap{"bind": __ListRequestsBindFunc, "bindvars": __rt.BindVars, "fields": tableFields, "groupBy": groupByColumns, "groupColumns": groupColumns}).Parse("select * from ( select {{ fields \"response_body\" }}, iif( response_content_type = 'text/event-stream' and response_body is not null, merge_cmpl(response_body), response_body ) as response_body from moonshot_requests ) where 1 = 1 {{ if .chatOnly }} and request_path like '%/chat/completions' {{ end }} {{ if .predicate }} and ({{ .predicate }}) {{ end }} order by id desc {{ if .n }} limit {{ bind .n }} {{ end }} ;\r\n"))
+
+	sqlListRequests := __rt.GetBuffer()
+	defer __rt.PutBuffer(sqlListRequests)
+	defer sqlListRequests.Reset()
+
+	if errListRequests = sqlTmplListRequests.Execute(sqlListRequests, map[string]any{
+		"n":         n,
+		"chatOnly":  chatOnly,
+		"predicate": predicate,
+	}); errListRequests != nil {
+		return v0ListRequests, fmt.Errorf("error executing %s template: %w", strconv.Quote("ListRequests"), errListRequests)
+	}
+
+	queryListRequests := sqlListRequests.String()
+
+	txListRequests, errListRequests := __imp.__core.Beginx()
+	if errListRequests != nil {
+		return v0ListRequests, fmt.Errorf("error creating %s transaction: %w", strconv.Quote("ListRequests"), errListRequests)
+	}
+	if !__imp.__withTx {
+		defer txListRequests.Rollback()
+	}
+
+	offsetListRequests := 0
+	argsListRequests := __rt.MergeArgs(argListListRequests...)
+
+	sqlSliceListRequests := __rt.Split(queryListRequests, ";")
+	for indexListRequests, splitSqlListRequests := range sqlSliceListRequests {
+		_ = indexListRequests
+
+		countListRequests := __rt.Count(splitSqlListRequests, "?")
+
+		if indexListRequests < len(sqlSliceListRequests)-1 {
+			_, errListRequests = txListRequests.Exec(splitSqlListRequests, argsListRequests[offsetListRequests:offsetListRequests+countListRequests]...)
+		} else {
+			errListRequests = txListRequests.Select(&v0ListRequests, splitSqlListRequests, argsListRequests[offsetListRequests:offsetListRequests+countListRequests]...)
+		}
+
+		if errListRequests != nil {
+			return v0ListRequests, fmt.Errorf("error executing %s sql: \n\n%s\n\n%w", strconv.Quote("ListRequests"), splitSqlListRequests, errListRequests)
+		}
+
+		offsetListRequests += countListRequests
+	}
+
+	if !__imp.__withTx {
+		if errListRequests := txListRequests.Commit(); errListRequests != nil {
+			return v0ListRequests, fmt.Errorf("error committing %s transaction: %w", strconv.Quote("ListRequests"), errListRequests)
+		}
+	}
+
+	return v0ListRequests, nil
+}
+
+func (__imp *implPersistence) GroupRequests(keys []string, chatOnly bool, predicate string) ([]*RequestGroup, error) {
+	var (
+		v0GroupRequests      []*RequestGroup
+		errGroupRequests     error
+		argListGroupRequests = make(__rt.Arguments, 0, 8)
+	)
+
+	__GroupRequestsBindFunc := func(arg any) string {
+		argListGroupRequests = append(argListGroupRequests, arg)
+		return __rt.BindVars(len(__rt.MergeArgs(arg)))
+	}
+	sqlTmplGroupRequests := template.Must(template.New("GroupRequests").Funcs(template.FuncMap{"bind": __GroupRequestsBindFunc, "bindvars": __rt.BindVars, "fields": tableFields, "groupBy": groupByColumns, "groupColumns": groupColumns}).Parse("select {{ groupColumns .keys }}, count(*) as requests, sum(iif(response_status_code is null or response_status_code >= 400 or error is not null, 1, 0)) as errors, coalesce(sum(iif(json_valid(response_body), json_extract(response_body, '$.usage.prompt_tokens'), null)), 0) as prompt_tokens, coalesce(sum(iif(json_valid(response_body), json_extract(response_body, '$.usage.completion_tokens'), null)), 0) as completion_tokens, coalesce(sum(iif(json_valid(response_body), json_extract(response_body, '$.usage.total_tokens'), null)), 0) as total_tokens from ( select {{ fields \"response_body\" }}, iif( response_content_type = 'text/event-stream' and response_body is not null, merge_cmpl(response_body), response_body ) as response_body from moonshot_requests ) where 1 = 1 {{ if .chatOnly }} and request_path like '%/chat/completions' {{ end }} {{ if .predicate }} and ({{ .predicate }}) {{ end }} group by {{ groupBy .keys }} order by {{ groupBy .keys }};\r\n"))
+
+	sqlGroupRequests := __rt.GetBuffer()
+	defer __rt.PutBuffer(sqlGroupRequests)
+	defer sqlGroupRequests.Reset()
+
+	if errGroupRequests = sqlTmplGroupRequests.Execute(sqlGroupRequests, map[string]any{
+		"keys":      keys,
+		"chatOnly":  chatOnly,
+		"predicate": predicate,
+	}); errGroupRequests != nil {
+		return v0GroupRequests, fmt.Errorf("error executing %s template: %w", strconv.Quote("GroupRequests"), errGroupRequests)
+	}
+
+	queryGroupRequests := sqlGroupRequests.String()
+
+	txGroupRequests, errGroupRequests := __imp.__core.Beginx()
+	if errGroupRequests != nil {
+		return v0GroupRequests, fmt.Errorf("error creating %s transaction: %w", strconv.Quote("GroupRequests"), errGroupRequests)
+	}
+	if !__imp.__withTx {
+		defer txGroupRequests.Rollback()
+	}
+
+	offsetGroupRequests := 0
+	argsGroupRequests := __rt.MergeArgs(argListGroupRequests...)
+
+	sqlSliceGroupRequests := __rt.Split(queryGroupRequests, ";")
+	for indexGroupRequests, splitSqlGroupRequests := range sqlSliceGroupRequests {
+		_ = indexGroupRequests
+
+		countGroupRequests := __rt.Count(splitSqlGroupRequests, "?")
+
+		if indexGroupRequests < len(sqlSliceGroupRequests)-1 {
+			_, errGroupRequests = txGroupRequests.Exec(splitSqlGroupRequests, argsGroupRequests[offsetGroupRequests:offsetGroupRequests+countGroupRequests]...)
+		} else {
+			errGroupRequests = txGroupRequests.Select(&v0GroupRequests, splitSqlGroupRequests, argsGroupRequests[offsetGroupRequests:offsetGroupRequests+countGroupRequests]...)
+		}
+
+		if errGroupRequests != nil {
+			return v0GroupRequests, fmt.Errorf("error executing %s sql: \n\n%s\n\n%w", strconv.Quote("GroupRequests"), splitSqlGroupRequests, errGroupRequests)
+		}
+
+		offsetGroupRequests += countGroupRequests
+	}
+
+	if !__imp.__withTx {
+		if errGroupRequests := txGroupRequests.Commit(); errGroupRequests != nil {
+			return v0GroupRequests, fmt.Errorf("error committing %s transaction: %w", strconv.Quote("GroupRequests"), errGroupRequests)
+		}
+	}
+
+	return v0GroupRequests, nil
+}
+
+func (__imp *implPersistence) GetRequest(id int64, chatcmpl string, requestid string, uid string, atTime string) (*Request, error) {
+	var (
+		v0GetRequest  = new(Request)
+		errGetRequest error
+	)
+
+	sqlGetRequest := __rt.GetBuffer()
+	defer __rt.PutBuffer(sqlGetRequest)
+	defer sqlGetRequest.Reset()
+
+	if errGetRequest = sqlTmplGetRequest.Execute(sqlGetRequest, map[string]any{
+		"id":        id,
+		"chatcmpl":  chatcmpl,
+		"requestid": requestid,
+		"uid":       uid,
+		"atTime":    atTime,
+	}); errGetRequest != nil {
+		return v0GetRequest, fmt.Errorf("error executing %s template: %w", strconv.Quote("GetRequest"), errGetRequest)
+	}
+
+	queryGetRequest := sqlGetRequest.String()
+
+	txGetRequest, errGetRequest := __imp.__core.Beginx()
+	if errGetRequest != nil {
+		return v0GetRequest, fmt.Errorf("error creating %s transaction: %w", strconv.Quote("GetRequest"), errGetRequest)
+	}
+	if !__imp.__withTx {
+		defer txGetRequest.Rollback()
+	}
+
+	argsGetRequest := __rt.MergeNamedArgs(map[string]any{
+		"id":        id,
+		"chatcmpl":  chatcmpl,
+		"requestid": requestid,
+		"uid":       uid,
+		"atTime":    atTime,
+	})
+
+	sqlSliceGetRequest := __rt.Split(queryGetRequest, ";")
+	for indexGetRequest, splitSqlGetRequest := range sqlSliceGetRequest {
+		_ = indexGetRequest
+
+		var listArgsGetRequest []interface{}
+
+		splitSqlGetRequest, listArgsGetRequest, errGetRequest = sqlx.Named(splitSqlGetRequest, argsGetRequest)
+		if errGetRequest != nil {
+			return v0GetRequest, fmt.Errorf("error building %s query: %w", strconv.Quote("GetRequest"), errGetRequest)
+		}
+
+		splitSqlGetRequest, listArgsGetRequest, errGetRequest = sqlx.In(splitSqlGetRequest, listArgsGetRequest...)
+		if errGetRequest != nil {
+			return v0GetRequest, fmt.Errorf("error building %s query: %w", strconv.Quote("GetRequest"), errGetRequest)
+		}
+
+		if indexGetRequest < len(sqlSliceGetRequest)-1 {
+			_, errGetRequest = txGetRequest.Exec(splitSqlGetRequest, listArgsGetRequest...)
+		} else {
+			errGetRequest = txGetRequest.Get(v0GetRequest, splitSqlGetRequest, listArgsGetRequest...)
+		}
+
+		if errGetRequest != nil {
+			return v0GetRequest, fmt.Errorf("error executing %s sql: \n\n%s\n\n%w", strconv.Quote("GetRequest"), splitSqlGetRequest, errGetRequest)
+		}
+	}
+
+	if !__imp.__withTx {
+		if errGetRequest := txGetRequest.Commit(); errGetRequest != nil {
+			return v0GetRequest, fmt.Errorf("error committing %s transaction: %w", strconv.Quote("GetRequest"), errGetRequest)
+		}
+	}
+
+	return v0GetRequest, nil
+}
+
+func (__imp *implPersistence) GetCachedResponse(hash string, credential string, method string, path string, query string, since string) (*Request, error) {
+	var (
+		v0GetCachedResponse  = new(Request)
+		errGetCachedResponse error
+	)
+
+	sqlGetCachedResponse := __rt.GetBuffer()
+	defer __rt.PutBuffer(sqlGetCachedResponse)
+	defer sqlGetCachedResponse.Reset()
+
+	if errGetCachedResponse = sqlTmplGetCachedResponse.Execute(sqlGetCachedResponse, map[string]any{
+		"hash":       hash,
+		"credential": credential,
+		"method":     method,
+		"path":       path,
+		"query":      query,
+		"since":      since,
+	}); errGetCachedResponse != nil {
+		return v0GetCachedResponse, fmt.Errorf("error executing %s template: %w", strconv.Quote("GetCachedResponse"), errGetCachedResponse)
+	}
+
+	queryGetCachedResponse := sqlGetCachedResponse.String()
+
+	txGetCachedResponse, errGetCachedResponse := __imp.__core.Beginx()
+	if errGetCachedResponse != nil {
+		return v0GetCachedResponse, fmt.Errorf("error creating %s transaction: %w", strconv.Quote("GetCachedResponse"), errGetCachedResponse)
+	}
+	if !__imp.__withTx {
+		defer txGetCachedResponse.Rollback()
+	}
+
+	argsGetCachedResponse := __rt.MergeNamedArgs(map[string]any{
+		"hash":       hash,
+		"credential": credential,
+		"method":     method,
+		"path":       path,
+		"query":      query,
+		"since":      since,
+	})
+
+	sqlSliceGetCachedResponse := __rt.Split(queryGetCachedResponse, ";")
+	for indexGetCachedResponse, splitSqlGetCachedResponse := range sqlSliceGetCachedResponse {
+		_ = indexGetCachedResponse
+
+		var listArgsGetCachedResponse []interface{}
+
+		splitSqlGetCachedResponse, listArgsGetCachedResponse, errGetCachedResponse = sqlx.Named(splitSqlGetCachedResponse, argsGetCachedResponse)
+		if errGetCachedResponse != nil {
+			return v0GetCachedResponse, fmt.Errorf("error building %s query: %w", strconv.Quote("GetCachedResponse"), errGetCachedResponse)
+		}
+
+		splitSqlGetCachedResponse, listArgsGetCachedResponse, errGetCachedResponse = sqlx.In(splitSqlGetCachedResponse, listArgsGetCachedResponse...)
+		if errGetCachedResponse != nil {
+			return v0GetCachedResponse, fmt.Errorf("error building %s query: %w", strconv.Quote("GetCachedResponse"), errGetCachedResponse)
+		}
+
+		if indexGetCachedResponse < len(sqlSliceGetCachedResponse)-1 {
+			_, errGetCachedResponse = txGetCachedResponse.Exec(splitSqlGetCachedResponse, listArgsGetCachedResponse...)
+		} else {
+			errGetCachedResponse = txGetCachedResponse.Get(v0GetCachedResponse, splitSqlGetCachedResponse, listArgsGetCachedResponse...)
+		}
+
+		if errGetCachedResponse != nil {
+			return v0GetCachedResponse, fmt.Errorf("error executing %s sql: \n\n%s\n\n%w", strconv.Quote("GetCachedResponse"), splitSqlGetCachedResponse, errGetCachedResponse)
+		}
+	}
+
+	if !__imp.__withTx {
+		if errGetCachedResponse := txGetCachedResponse.Commit(); errGetCachedResponse != nil {
+			return v0GetCachedResponse, fmt.Errorf("error committing %s transaction: %w", strconv.Quote("GetCachedResponse"), errGetCachedResponse)
+		}
+	}
+
+	return v0GetCachedResponse, nil
+}
+
+func (__imp *implPersistence) HasRequests() (bool, error) {
+	var (
+		v0HasRequests      bool
+		errHasRequests     error
+		argListHasRequests = make(__rt.Arguments, 0, 8)
+	)
+
+	argListHasRequests = __rt.Arguments{}
+
+	queryHasRequests := "select exists (select 1 from moonshot_requests);\r\n"
+
+	txHasRequests, errHasRequests := __imp.__core.Beginx()
+	if errHasRequests != nil {
+		return v0HasRequests, fmt.Errorf("error creating %s transaction: %w", strconv.Quote("HasRequests"), errHasRequests)
+	}
+	if !__imp.__withTx {
+		defer txHasRequests.Rollback()
+	}
+
+	offsetHasRequests := 0
+	argsHasRequests := __rt.MergeArgs(argListHasRequests...)
+
+	sqlSliceHasRequests := __rt.Split(queryHasRequests, ";")
+	for indexHasRequests, splitSqlHasRequests := range sqlSliceHasRequests {
+		_ = indexHasRequests
+
+		countHasRequests := __rt.Count(splitSqlHasRequests, "?")
+
+		if indexHasRequests < len(sqlSliceHasRequests)-1 {
+			_, errHasRequests = txHasRequests.Exec(splitSqlHasRequests, argsHasRequests[offsetHasRequests:offsetHasRequests+countHasRequests]...)
+		} else {
+			errHasRequests = txHasRequests.Get(&v0HasRequests, splitSqlHasRequests, argsHasRequests[offsetHasRequests:offsetHasRequests+countHasRequests]...)
+		}
+
+		if errHasRequests != nil {
+			return v0HasRequests, fmt.Errorf("error executing %s sql: \n\n%s\n\n%w", strconv.Quote("HasRequests"), splitSqlHasRequests, errHasRequests)
+		}
+
+		offsetHasRequests += countHasRequests
+	}
+
+	if !__imp.__withTx {
+		if errHasRequests := txHasRequests.Commit(); errHasRequests != nil {
+			return v0HasRequests, fmt.Errorf("error committing %s transaction: %w", strconv.Quote("HasRequests"), errHasRequests)
+		}
+	}
+
+	return v0HasRequests, nil
+}
+
+func (__imp *implPersistence) CountRequests() (int64, error) {
+	var (
+		v0CountRequests      int64
+		errCountRequests     error
+		argListCountRequests = make(__rt.Arguments, 0, 8)
+	)
+
+	argListCountRequests = __rt.Arguments{}
+
+	queryCountRequests := "select count(*) from moonshot_requests;\r\n"
+
+	txCountRequests, errCountRequests := __imp.__core.Beginx()
+	if errCountRequests != nil {
+		return v0CountRequests, fmt.Errorf("error creating %s transaction: %w", strconv.Quote("CountRequests"), errCountRequests)
+	}
+	if !__imp.__withTx {
+		defer txCountRequests.Rollback()
+	}
+
+	offsetCountRequests := 0
+	argsCountRequests := __rt.MergeArgs(argListCountRequests...)
+
+	sqlSliceCountRequests := __rt.Split(queryCountRequests, ";")
+	for indexCountRequests, splitSqlCountRequests := range sqlSliceCountRequests {
+		_ = indexCountRequests
+
+		countCountRequests := __rt.Count(splitSqlCountRequests, "?")
+
+		if indexCountRequests < len(sqlSliceCountRequests)-1 {
+			_, errCountRequests = txCountRequests.Exec(splitSqlCountRequests, argsCountRequests[offsetCountRequests:offsetCountRequests+countCountRequests]...)
+		} else {
+			errCountRequests = txCountRequests.Get(&v0CountRequests, splitSqlCountRequests, argsCountRequests[offsetCountRequests:offsetCountRequests+countCountRequests]...)
+		}
+
+		if errCountRequests != nil {
+			return v0CountRequests, fmt.Errorf("error executing %s sql: \n\n%s\n\n%w", strconv.Quote("CountRequests"), splitSqlCountRequests, errCountRequests)
+		}
+
+		offsetCountRequests += countCountRequests
+	}
+
+	if !__imp.__withTx {
+		if errCountRequests := txCountRequests.Commit(); errCountRequests != nil {
+			return v0CountRequests, fmt.Errorf("error committing %s transaction: %w", strconv.Quote("CountRequests"), errCountRequests)
+		}
+	}
+
+	return v0CountRequests, nil
+}
+
+func (__imp *implPersistence) CountRequestsAtTime(uid string, atTime string) (int64, error) {
+	var (
+		v0CountRequestsAtTime  int64
+		errCountRequestsAtTime error
+	)
+
+	queryCountRequestsAtTime := "select count(*) from moonshot_requests where moonshot_uid = :uid and abs(julianday(created_at) - julianday(:atTime)) = ( select min(abs(julianday(created_at) - julianday(:atTime))) from moonshot_requests where moonshot_uid = :uid );\r\n"
+
+	txCountRequestsAtTime, errCountRequestsAtTime := __imp.__core.Beginx()
+	if errCountRequestsAtTime != nil {
+		return v0CountRequestsAtTime, fmt.Errorf("error creating %s transaction: %w", strconv.Quote("CountRequestsAtTime"), errCountRequestsAtTime)
+	}
+	if !__imp.__withTx {
+		defer txCountRequestsAtTime.Rollback()
+	}
+
+	argsCountRequestsAtTime := __rt.MergeNamedArgs(map[string]any{
+		"uid":    uid,
+		"atTime": atTime,
+	})
+
+	sqlSliceCountRequestsAtTime := __rt.Split(queryCountRequestsAtTime, ";")
+	for indexCountRequestsAtTime, splitSqlCountRequestsAtTime := range sqlSliceCountRequestsAtTime {
+		_ = indexCountRequestsAtTime
+
+		var listArgsCountRequestsAtTime []interface{}
+
+		splitSqlCountRequestsAtTime, listArgsCountRequestsAtTime, errCountRequestsAtTime = sqlx.Named(splitSqlCountRequestsAtTime, argsCountRequestsAtTime)
+		if errCountRequestsAtTime != nil {
+			return v0CountRequestsAtTime, fmt.Errorf("error building %s query: %w", strconv.Quote("CountRequestsAtTime"), errCountRequestsAtTime)
+		}
+
+		splitSqlCountRequestsAtTime, listArgsCountRequestsAtTime, errCountRequestsAtTime = sqlx.In(splitSqlCountRequestsAtTime, listArgsCountRequestsAtTime...)
+		if errCountRequestsAtTime != nil {
+			return v0CountRequestsAtTime, fmt.Errorf("error building %s query: %w", strconv.Quote("CountRequestsAtTime"), errCountRequestsAtTime)
+		}
+
+		if indexCountRequestsAtTime < len(sqlSliceCountRequestsAtTime)-1 {
+			_, errCountRequestsAtTime = txCountRequestsAtTime.Exec(splitSqlCountRequestsAtTime, listArgsCountRequestsAtTime...)
+		} else {
+			errCountRequestsAtTime = txCountRequestsAtTime.Get(&v0CountRequestsAtTime, splitSqlCountRequestsAtTime, listArgsCountRequestsAtTime...)
+		}
+
+		if errCountRequestsAtTime != nil {
+			return v0CountRequestsAtTime, fmt.Errorf("error executing %s sql: \n\n%s\n\n%w", strconv.Quote("CountRequestsAtTime"), splitSqlCountRequestsAtTime, errCountRequestsAtTime)
+		}
+	}
+
+	if !__imp.__withTx {
+		if errCountRequestsAtTime := txCountRequestsAtTime.Commit(); errCountRequestsAtTime != nil {
+			return v0CountRequestsAtTime, fmt.Errorf("error committing %s transaction: %w", strconv.Quote("CountRequestsAtTime"), errCountRequestsAtTime)
+		}
+	}
+
+	return v0CountRequestsAtTime, nil
+}
+
+func (__imp *implPersistence) GetRequestsByChatcmpls(chatcmpls []string) ([]*Request, error) {
+	var (
+		v0GetRequestsByChatcmpls  []*Request
+		errGetRequestsByChatcmpls error
+	)
+
+	queryGetRequestsByChatcmpls := "select * from moonshot_requests where moonshot_id in (:chatcmpls) order by id;\r\n"
+
+	txGetRequestsByChatcmpls, errGetRequestsByChatcmpls := __imp.__core.Beginx()
+	if errGetRequestsByChatcmpls != nil {
+		return v0GetRequestsByChatcmpls, fmt.Errorf("error creating %s transaction: %w", strconv.Quote("GetRequestsByChatcmpls"), errGetRequestsByChatcmpls)
+	}
+	if !__imp.__withTx {
+		defer txGetRequestsByChatcmpls.Rollback()
+	}
+
+	argsGetRequestsByChatcmpls := __rt.MergeNamedArgs(map[string]any{
+		"chatcmpls": chatcmpls,
+	})
+
+	sqlSliceGetRequestsByChatcmpls := __rt.Split(queryGetRequestsByChatcmpls, ";")
+	for indexGetRequestsByChatcmpls, splitSqlGetRequestsByChatcmpls := range sqlSliceGetRequestsByChatcmpls {
+		_ = indexGetRequestsByChatcmpls
+
+		var listArgsGetRequestsByChatcmpls []interface{}
+
+		splitSqlGetRequestsByChatcmpls, listArgsGetRequestsByChatcmpls, errGetRequestsByChatcmpls = sqlx.Named(splitSqlGetRequestsByChatcmpls, argsGetRequestsByChatcmpls)
+		if errGetRequestsByChatcmpls != nil {
+			return v0GetRequestsByChatcmpls, fmt.Errorf("error building %s query: %w", strconv.Quote("GetRequestsByChatcmpls"), errGetRequestsByChatcmpls)
+		}
+
+		splitSqlGetRequestsByChatcmpls, listArgsGetRequestsByChatcmpls, errGetRequestsByChatcmpls = sqlx.In(splitSqlGetRequestsByChatcmpls, listArgsGetRequestsByChatcmpls...)
+		if errGetRequestsByChatcmpls != nil {
+			return v0GetRequestsByChatcmpls, fmt.Errorf("error building %s query: %w", strconv.Quote("GetRequestsByChatcmpls"), errGetRequestsByChatcmpls)
+		}
+
+		if indexGetRequestsByChatcmpls < len(sqlSliceGetRequestsByChatcmpls)-1 {
+			_, errGetRequestsByChatcmpls = txGetRequestsByChatcmpls.Exec(splitSqlGetRequestsByChatcmpls, listArgsGetRequestsByChatcmpls...)
+		} else {
+			errGetRequestsByChatcmpls = txGetRequestsByChatcmpls.Select(&v0GetRequestsByChatcmpls, splitSqlGetRequestsByChatcmpls, listArgsGetRequestsByChatcmpls...)
+		}
+
+		if errGetRequestsByChatcmpls != nil {
+			return v0GetRequestsByChatcmpls, fmt.Errorf("error executing %s sql: \n\n%s\n\n%w", strconv.Quote("GetRequestsByChatcmpls"), splitSqlGetRequestsByChatcmpls, errGetRequestsByChatcmpls)
+		}
+	}
+
+	if !__imp.__withTx {
+		if errGetRequestsByChatcmpls := txGetRequestsByChatcmpls.Commit(); errGetRequestsByChatcmpls != nil {
+			return v0GetRequestsByChatcmpls, fmt.Errorf("error committing %s transaction: %w", strconv.Quote("GetRequestsByChatcmpls"), errGetRequestsByChatcmpls)
+		}
+	}
+
+	return v0GetRequestsByChatcmpls, nil
+}
+
+func (__imp *implPersistence) GetRequestsByUIDs(uids []string) ([]*Request, error) {
+	var (
+		v0GetRequestsByUIDs  []*Request
+		errGetRequestsByUIDs error
+	)
+
+	queryGetRequestsByUIDs := "select * from moonshot_requests where moonshot_uid in (:uids) order by id;\r\n"
+
+	txGetRequestsByUIDs, errGetRequestsByUIDs := __imp.__core.Beginx()
+	if errGetRequestsByUIDs != nil {
+		return v0GetRequestsByUIDs, fmt.Errorf("error creating %s transaction: %w", strconv.Quote("GetRequestsByUIDs"), errGetRequestsByUIDs)
+	}
+	if !__imp.__withTx {
+		defer txGetRequestsByUIDs.Rollback()
+	}
+
+	argsGetRequestsByUIDs := __rt.MergeNamedArgs(map[string]any{
+		"uids": uids,
+	})
+
+	sqlSliceGetRequestsByUIDs := __rt.Split(queryGetRequestsByUIDs, ";")
+	for indexGetRequestsByUIDs, splitSqlGetRequestsByUIDs := range sqlSliceGetRequestsByUIDs {
+		_ = indexGetRequestsByUIDs
+
+		var listArgsGetRequestsByUIDs []interface{}
+
+		splitSqlGetRequestsByUIDs, listArgsGetRequestsByUIDs, errGetRequestsByUIDs = sqlx.Named(splitSqlGetRequestsByUIDs, argsGetRequestsByUIDs)
+		if errGetRequestsByUIDs != nil {
+			return v0GetRequestsByUIDs, fmt.Errorf("error building %s query: %w", strconv.Quote("GetRequestsByUIDs"), errGetRequestsByUIDs)
+		}
+
+		splitSqlGetRequestsByUIDs, listArgsGetRequestsByUIDs, errGetRequestsByUIDs = sqlx.In(splitSqlGetRequestsByUIDs, listArgsGetRequestsByUIDs...)
+		if errGetRequestsByUIDs != nil {
+			return v0GetRequestsByUIDs, fmt.Errorf("error building %s query: %w", strconv.Quote("GetRequestsByUIDs"), errGetRequestsByUIDs)
+		}
+
+		if indexGetRequestsByUIDs < len(sqlSliceGetRequestsByUIDs)-1 {
+			_, errGetRequestsByUIDs = txGetRequestsByUIDs.Exec(splitSqlGetRequestsByUIDs, listArgsGetRequestsByUIDs...)
+		} else {
+			errGetRequestsByUIDs = txGetRequestsByUIDs.Select(&v0GetRequestsByUIDs, splitSqlGetRequestsByUIDs, listArgsGetRequestsByUIDs...)
+		}
+
+		if errGetRequestsByUIDs != nil {
+			return v0GetRequestsByUIDs, fmt.Errorf("error executing %s sql: \n\n%s\n\n%w", strconv.Quote("GetRequestsByUIDs"), splitSqlGetRequestsByUIDs, errGetRequestsByUIDs)
+		}
+	}
+
+	if !__imp.__withTx {
+		if errGetRequestsByUIDs := txGetRequestsByUIDs.Commit(); errGetRequestsByUIDs != nil {
+			return v0GetRequestsByUIDs, fmt.Errorf("error committing %s transaction: %w", strconv.Quote("GetRequestsByUIDs"), errGetRequestsByUIDs)
+		}
+	}
+
+	return v0GetRequestsByUIDs, nil
+}
+
+func (__imp *implPersistence) GetRequestsByRange(idFrom int64, idTo int64, uid string, uids []string, since string, until string, pathPrefix string, tagsAny []string, tagsAll []string, categoryUnset bool, hasSystemPrompt bool, noSystemPrompt bool, hashPrefix string, systemPromptContains string, limit int64) ([]*Request, error) {
+	var (
+		v0GetRequestsByRange  []*Request
+		errGetRequestsByRange error
+	)
+
+	sqlGetRequestsByRange := __rt.GetBuffer()
+	defer __rt.PutBuffer(sqlGetRequestsByRange)
+	defer sqlGetRequestsByRange.Reset()
+
+	if errGetRequestsByRange = sqlTmplGetRequestsByRange.Execute(sqlGetRequestsByRange, map[string]any{
+		"idFrom":               idFrom,
+		"idTo":                 idTo,
+		"uid":                  uid,
+		"uids":                 uids,
+		"since":                since,
+		"until":                until,
+		"pathPrefix":           pathPrefix,
+		"tagsAny":              tagsAny,
+		"tagsAll":              tagsAll,
+		"categoryUnset":        categoryUnset,
+		"hasSystemPrompt":      hasSystemPrompt,
+		"noSystemPrompt":       noSystemPrompt,
+		"hashPrefix":           hashPrefix,
+		"systemPromptContains": systemPromptContains,
+		"limit":                limit,
+	}); errGetRequestsByRange != nil {
+		return v0GetRequestsByRange, fmt.Errorf("error executing %s template: %w", strconv.Quote("GetRequestsByRange"), errGetRequestsByRange)
+	}
+
+	queryGetRequestsByRange := sqlGetRequestsByRange.String()
+
+	txGetRequestsByRange, errGetRequestsByRange := __imp.__core.Beginx()
+	if errGetRequestsByRange != nil {
+		return v0GetRequestsByRange, fmt.Errorf("error creating %s transaction: %w", strconv.Quote("GetRequestsByRange"), errGetRequestsByRange)
+	}
+	if !__imp.__withTx {
+		defer txGetRequestsByRange.Rollback()
+	}
+
+	argsGetRequestsByRange := __rt.MergeNamedArgs(map[string]any{
+		"idFrom":               idFrom,
+		"idTo":                 idTo,
+		"uid":                  uid,
+		"uids":                 uids,
+		"since":                since,
+		"until":                until,
+		"pathPrefix":           pathPrefix,
+		"tagsAny":              tagsAny,
+		"tagsAll":              tagsAll,
+		"categoryUnset":        categoryUnset,
+		"hasSystemPrompt":      hasSystemPrompt,
+		"noSystemPrompt":       noSystemPrompt,
+		"hashPrefix":           hashPrefix,
+		"systemPromptContains": systemPromptContains,
+		"limit":                limit,
+	})
+
+	sqlSliceGetRequestsByRange := __rt.Split(queryGetRequestsByRange, ";")
+	for indexGetRequestsByRange, splitSqlGetRequestsByRange := range sqlSliceGetRequestsByRange {
+		_ = indexGetRequestsByRange
+
+		var listArgsGetRequestsByRange []interface{}
+
+		splitSqlGetRequestsByRange, listArgsGetRequestsByRange, errGetRequestsByRange = sqlx.Named(splitSqlGetRequestsByRange, argsGetRequestsByRange)
+		if errGetRequestsByRange != nil {
+			return v0GetRequestsByRange, fmt.Errorf("error building %s query: %w", strconv.Quote("GetRequestsByRange"), errGetRequestsByRange)
+		}
+
+		splitSqlGetRequestsByRange, listArgsGetRequestsByRange, errGetRequestsByRange = sqlx.In(splitSqlGetRequestsByRange, listArgsGetRequestsByRange...)
+		if errGetRequestsByRange != nil {
+			return v0GetRequestsByRange, fmt.Errorf("error building %s query: %w", strconv.Quote("GetRequestsByRange"), errGetRequestsByRange)
+		}
+
+		if indexGetRequestsByRange < len(sqlSliceGetRequestsByRange)-1 {
+			_, errGetRequestsByRange = txGetRequestsByRange.Exec(splitSqlGetRequestsByRange, listArgsGetRequestsByRange...)
+		} else {
+			errGetRequestsByRange = txGetRequestsByRange.Select(&v0GetRequestsByRange, splitSqlGetRequestsByRange, listArgsGetRequestsByRange...)
+		}
+
+		if errGetRequestsByRange != nil {
+			return v0GetRequestsByRange, fmt.Errorf("error executing %s sql: \n\n%s\n\n%w", strconv.Quote("GetRequestsByRange"), splitSqlGetRequestsByRange, errGetRequestsByRange)
+		}
+	}
+
+	if !__imp.__withTx {
+		if errGetRequestsByRange := txGetRequestsByRange.Commit(); errGetRequestsByRange != nil {
+			return v0GetRequestsByRange, fmt.Errorf("error committing %s transaction: %w", strconv.Quote("GetRequestsByRange"), errGetRequestsByRange)
+		}
+	}
+
+	return v0GetRequestsByRange, nil
+}
+
+func (__imp *implPersistence) StartLiveStream(moonshotID string, chunks string) error {
+	var (
+		errStartLiveStream error
+	)
+
+	queryStartLiveStream := "insert or replace into moonshot_live_streams (moonshot_id, response_body) values (:moonshotID, :chunks);\r\n"
+
+	txStartLiveStream, errStartLiveStream := __imp.__core.Beginx()
+	if errStartLiveStream != nil {
+		return fmt.Errorf("error creating %s transaction: %w", strconv.Quote("StartLiveStream"), errStartLiveStream)
+	}
+	if !__imp.__withTx {
+		defer txStartLiveStream.Rollback()
+	}
+
+	argsStartLiveStream := __rt.MergeNamedArgs(map[string]any{
+		"moonshotID": moonshotID,
+		"chunks":     chunks,
+	})
+
+	sqlSliceStartLiveStream := __rt.Split(queryStartLiveStream, ";")
+	for indexStartLiveStream, splitSqlStartLiveStream := range sqlSliceStartLiveStream {
+		_ = indexStartLiveStream
+
+		var listArgsStartLiveStream []interface{}
+
+		splitSqlStartLiveStream, listArgsStartLiveStream, errStartLiveStream = sqlx.Named(splitSqlStartLiveStream, argsStartLiveStream)
+		if errStartLiveStream != nil {
+			return fmt.Errorf("error building %s query: %w", strconv.Quote("StartLiveStream"), errStartLiveStream)
+		}
+
+		splitSqlStartLiveStream, listArgsStartLiveStream, errStartLiveStream = sqlx.In(splitSqlStartLiveStream, listArgsStartLiveStream...)
+		if errStartLiveStream != nil {
+			return fmt.Errorf("error building %s query: %w", strconv.Quote("StartLiveStream"), errStartLiveStream)
+		}
+
+		_, errStartLiveStream = txStartLiveStream.Exec(splitSqlStartLiveStream, listArgsStartLiveStream...)
+
+		if errStartLiveStream != nil {
+			return fmt.Errorf("error executing %s sql: \n\n%s\n\n%w", strconv.Quote("StartLiveStream"), splitSqlStartLiveStream, errStartLiveStream)
+		}
+	}
+
+	if !__imp.__withTx {
+		if errStartLiveStream := txStartLiveStream.Commit(); errStartLiveStream != nil {
+			return fmt.Errorf("error committing %s transaction: %w", strconv.Quote("StartLiveStream"), errStartLiveStream)
+		}
+	}
+
+	return nil
+}
+
+func (__imp *implPersistence) AppendLiveStream(moonshotID string, chunks string) error {
+	var (
+		errAppendLiveStream error
+	)
+
+	queryAppendLiveStream := "update moonshot_live_streams set response_body = response_body || :chunks where moonshot_id = :moonshotID;\r\n"
+
+	txAppendLiveStream, errAppendLiveStream := __imp.__core.Beginx()
+	if errAppendLiveStream != nil {
+		return fmt.Errorf("error creating %s transaction: %w", strconv.Quote("AppendLiveStream"), errAppendLiveStream)
+	}
+	if !__imp.__withTx {
+		defer txAppendLiveStream.Rollback()
+	}
+
+	argsAppendLiveStream := __rt.MergeNamedArgs(map[string]any{
+		"moonshotID": moonshotID,
+		"chunks":     chunks,
+	})
+
+	sqlSliceAppendLiveStream := __rt.Split(queryAppendLiveStream, ";")
+	for indexAppendLiveStream, splitSqlAppendLiveStream := range sqlSliceAppendLiveStream {
+		_ = indexAppendLiveStream
+
+		var listArgsAppendLiveStream []interface{}
+
+		splitSqlAppendLiveStream, listArgsAppendLiveStream, errAppendLiveStream = sqlx.Named(splitSqlAppendLiveStream, argsAppendLiveStream)
+		if errAppendLiveStream != nil {
+			return fmt.Errorf("error building %s query: %w", strconv.Quote("AppendLiveStream"), errAppendLiveStream)
+		}
+
+		splitSqlAppendLiveStream, listArgsAppendLiveStream, errAppendLiveStream = sqlx.In(splitSqlAppendLiveStream, listArgsAppendLiveStream...)
+		if errAppendLiveStream != nil {
+			return fmt.Errorf("error building %s query: %w", strconv.Quote("AppendLiveStream"), errAppendLiveStream)
+		}
+
+		_, errAppendLiveStream = txAppendLiveStream.Exec(splitSqlAppendLiveStream, listArgsAppendLiveStream...)
+
+		if errAppendLiveStream != nil {
+			return fmt.Errorf("error executing %s sql: \n\n%s\n\n%w", strconv.Quote("AppendLiveStream"), splitSqlAppendLiveStream, errAppendLiveStream)
+		}
+	}
+
+	if !__imp.__withTx {
+		if errAppendLiveStream := txAppendLiveStream.Commit(); errAppendLiveStream != nil {
+			return fmt.Errorf("error committing %s transaction: %w", strconv.Quote("AppendLiveStream"), errAppendLiveStream)
+		}
+	}
+
+	return nil
+}
+
+func (__imp *implPersistence) FinishLiveStream(moonshotID string) error {
+	var (
+		errFinishLiveStream error
+	)
+
+	queryFinishLiveStream := "delete from moonshot_live_streams where moonshot_id = :moonshotID;\r\n"
+
+	txFinishLiveStream, errFinishLiveStream := __imp.__core.Beginx()
+	if errFinishLiveStream != nil {
+		return fmt.Errorf("error creating %s transaction: %w", strconv.Quote("FinishLiveStream"), errFinishLiveStream)
+	}
+	if !__imp.__withTx {
+		defer txFinishLiveStream.Rollback()
+	}
+
+	argsFinishLiveStream := __rt.MergeNamedArgs(map[string]any{
+		"moonshotID": moonshotID,
+	})
+
+	sqlSliceFinishLiveStream := __rt.Split(queryFinishLiveStream, ";")
+	for indexFinishLiveStream, splitSqlFinishLiveStream := range sqlSliceFinishLiveStream {
+		_ = indexFinishLiveStream
+
+		var listArgsFinishLiveStream []interface{}
+
+		splitSqlFinishLiveStream, listArgsFinishLiveStream, errFinishLiveStream = sqlx.Named(splitSqlFinishLiveStream, argsFinishLiveStream)
+		if errFinishLiveStream != nil {
+			return fmt.Errorf("error building %s query: %w", strconv.Quote("FinishLiveStream"), errFinishLiveStream)
+		}
+
+		splitSqlFinishLiveStream, listArgsFinishLiveStream, errFinishLiveStream = sqlx.In(splitSqlFinishLiveStream, listArgsFinishLiveStream...)
+		if errFinishLiveStream != nil {
+			return fmt.Errorf("error building %s query: %w", strconv.Quote("FinishLiveStream"), errFinishLiveStream)
+		}
+
+		_, errFinishLiveStream = txFinishLiveStream.Exec(splitSqlFinishLiveStream, listArgsFinishLiveStream...)
+
+		if errFinishLiveStream != nil {
+			return fmt.Errorf("error executing %s sql: \n\n%s\n\n%w", strconv.Quote("FinishLiveStream"), splitSqlFinishLiveStream, errFinishLiveStream)
+		}
+	}
+
+	if !__imp.__withTx {
+		if errFinishLiveStream := txFinishLiveStream.Commit(); errFinishLiveStream != nil {
+			return fmt.Errorf("error committing %s transaction: %w", strconv.Quote("FinishLiveStream"), errFinishLiveStream)
+		}
+	}
+
+	return nil
+}
+
+func (__imp *implPersistence) ClearLiveStreams() error {
+	var (
+		errClearLiveStreams     error
+		argListClearLiveStreams = make(__rt.Arguments, 0, 8)
+	)
+
+	argListClearLiveStreams = __rt.Arguments{}
+
+	queryClearLiveStreams := "delete from moonshot_live_streams;\r\n"
+
+	txClearLiveStreams, errClearLiveStreams := __imp.__core.Beginx()
+	if errClearLiveStreams != nil {
+		return fmt.Errorf("error creating %s transaction: %w", strconv.Quote("ClearLiveStreams"), errClearLiveStreams)
+	}
+	if !__imp.__withTx {
+		defer txClearLiveStreams.Rollback()
+	}
+
+	offsetClearLiveStreams := 0
+	argsClearLiveStreams := __rt.MergeArgs(argListClearLiveStreams...)
+
+	sqlSliceClearLiveStreams := __rt.Split(queryClearLiveStreams, ";")
+	for indexClearLiveStreams, splitSqlClearLiveStreams := range sqlSliceClearLiveStreams {
+		_ = indexClearLiveStreams
+
+		countClearLiveStreams := __rt.Count(splitSqlClearLiveStreams, "?")
+
+		_, errClearLiveStreams = txClearLiveStreams.Exec(splitSqlClearLiveStreams, argsClearLiveStreams[offsetClearLiveStreams:offsetClearLiveStreams+countClearLiveStreams]...)
+
+		if errClearLiveStreams != nil {
+			return fmt.Errorf("error executing %s sql: \n\n%s\n\n%w", strconv.Quote("ClearLiveStreams"), splitSqlClearLiveStreams, errClearLiveStreams)
+		}
+
+		offsetClearLiveStreams += countClearLiveStreams
+	}
+
+	if !__imp.__withTx {
+		if errClearLiveStreams := txClearLiveStreams.Commit(); errClearLiveStreams != nil {
+			return fmt.Errorf("error committing %s transaction: %w", strconv.Quote("ClearLiveStreams"), errClearLiveStreams)
+		}
+	}
+
+	return nil
+}
+
+func (__imp *implPersistence) GetLiveStream(moonshotID string) (string, error) {
+	var (
+		v0GetLiveStream  string
+		errGetLiveStream error
 	)
 
-	queryCleanup := "delete from moonshot_requests where created_at < :before;\r\n"
+	queryGetLiveStream := "select response_body from moonshot_live_streams where moonshot_id = :moonshotID;\r\n"
 
-	txCleanup, errCleanup := __imp.__core.Beginx()
-	if errCleanup != nil {
-		return v0Cleanup, fmt.Errorf("error creating %s transaction: %w", strconv.Quote("Cleanup"), errCleanup)
+	txGetLiveStream, errGetLiveStream := __imp.__core.Beginx()
+	if errGetLiveStream != nil {
+		return v0GetLiveStream, fmt.Errorf("error creating %s transaction: %w", strconv.Quote("GetLiveStream"), errGetLiveStream)
 	}
 	if !__imp.__withTx {
-		defer txCleanup.Rollback()
+		defer txGetLiveStream.Rollback()
 	}
 
-	argsCleanup := __rt.MergeNamedArgs(map[string]any{
-		"before": before,
+	argsGetLiveStream := __rt.MergeNamedArgs(map[string]any{
+		"moonshotID": moonshotID,
 	})
 
-	sqlSliceCleanup := __rt.Split(queryCleanup, ";")
-	for indexCleanup, splitSqlCleanup := range sqlSliceCleanup {
-		_ = indexCleanup
+	sqlSliceGetLiveStream := __rt.Split(queryGetLiveStream, ";")
+	for indexGetLiveStream, splitSqlGetLiveStream := range sqlSliceGetLiveStream {
+		_ = indexGetLiveStream
 
-		var listArgsCleanup []interface{}
+		var listArgsGetLiveStream []interface{}
 
-		splitSqlCleanup, listArgsCleanup, errCleanup = sqlx.Named(splitSqlCleanup, argsCleanup)
-		if errCleanup != nil {
-			return v0Cleanup, fmt.Errorf("error building %s query: %w", strconv.Quote("Cleanup"), errCleanup)
+		splitSqlGetLiveStream, listArgsGetLiveStream, errGetLiveStream = sqlx.Named(splitSqlGetLiveStream, argsGetLiveStream)
+		if errGetLiveStream != nil {
+			return v0GetLiveStream, fmt.Errorf("error building %s query: %w", strconv.Quote("GetLiveStream"), errGetLiveStream)
 		}
 
-		splitSqlCleanup, listArgsCleanup, errCleanup = sqlx.In(splitSqlCleanup, listArgsCleanup...)
-		if errCleanup != nil {
-			return v0Cleanup, fmt.Errorf("error building %s query: %w", strconv.Quote("Cleanup"), errCleanup)
+		splitSqlGetLiveStream, listArgsGetLiveStream, errGetLiveStream = sqlx.In(splitSqlGetLiveStream, listArgsGetLiveStream...)
+		if errGetLiveStream != nil {
+			return v0GetLiveStream, fmt.Errorf("error building %s query: %w", strconv.Quote("GetLiveStream"), errGetLiveStream)
 		}
 
-		v0Cleanup, errCleanup = txCleanup.Exec(splitSqlCleanup, listArgsCleanup...)
+		if indexGetLiveStream < len(sqlSliceGetLiveStream)-1 {
+			_, errGetLiveStream = txGetLiveStream.Exec(splitSqlGetLiveStream, listArgsGetLiveStream...)
+		} else {
+			errGetLiveStream = txGetLiveStream.Get(&v0GetLiveStream, splitSqlGetLiveStream, listArgsGetLiveStream...)
+		}
 
-		if errCleanup != nil {
-			return v0Cleanup, fmt.Errorf("error executing %s sql: \n\n%s\n\n%w", strconv.Quote("Cleanup"), splitSqlCleanup, errCleanup)
+		if errGetLiveStream != nil {
+			return v0GetLiveStream, fmt.Errorf("error executing %s sql: \n\n%s\n\n%w", strconv.Quote("GetLiveStream"), splitSqlGetLiveStream, errGetLiveStream)
 		}
 	}
 
 	if !__imp.__withTx {
-		if errCleanup := txCleanup.Commit(); errCleanup != nil {
-			return v0Cleanup, fmt.Errorf("error committing %s transaction: %w", strconv.Quote("Cleanup"), errCleanup)
+		if errGetLiveStream := txGetLiveStream.Commit(); errGetLiveStream != nil {
+			return v0GetLiveStream, fmt.Errorf("error committing %s transaction: %w", strconv.Quote("GetLiveStream"), errGetLiveStream)
 		}
 	}
 
-	return v0Cleanup, nil
+	return v0GetLiveStream, nil
 }
 
-func (__imp *implPersistence) Persistence(requestID string, requestContentType string, requestMethod string, requestPath string, requestQuery string, moonshotID string, moonshotGID string, moonshotUID string, moonshotRequestID string, moonshotServerTiming int, responseStatusCode int, responseContentType string, requestHeader string, requestBody string, responseHeader string, responseBody string, programError string, responseTTFT int, responseTPOT int, responseOTPS float64, createdAt string, latency time.Duration, endpoint string) (int64, error) {
+func (__imp *implPersistence) SetTags(id int64, tags Tags) error {
 	var (
-		v0Persistence  int64
-		errPersistence error
+		errSetTags error
 	)
 
-	sqlPersistence := __rt.GetBuffer()
-	defer __rt.PutBuffer(sqlPersistence)
-	defer sqlPersistence.Reset()
-
-	if errPersistence = sqlTmplPersistence.Execute(sqlPersistence, map[string]any{
-		"requestID":            requestID,
-		"requestContentType":   requestContentType,
-		"requestMethod":        requestMethod,
-		"requestPath":          requestPath,
-		"requestQuery":         requestQuery,
-		"moonshotID":           moonshotID,
-		"moonshotGID":          moonshotGID,
-		"moonshotUID":          moonshotUID,
-		"moonshotRequestID":    moonshotRequestID,
-		"moonshotServerTiming": moonshotServerTiming,
-		"responseStatusCode":   responseStatusCode,
-		"responseContentType":  responseContentType,
-		"requestHeader":        requestHeader,
-		"requestBody":          requestBody,
-		"responseHeader":       responseHeader,
-		"responseBody":         responseBody,
-		"programError":         programError,
-		"responseTTFT":         responseTTFT,
-		"responseTPOT":         responseTPOT,
-		"responseOTPS":         responseOTPS,
-		"createdAt":            createdAt,
-		"latency":              latency,
-		"endpoint":             endpoint,
-	}); errPersistence != nil {
-		return v0Persistence, fmt.Errorf("error executing %s template: %w", strconv.Quote("Persistence"), errPersistence)
-	}
-
-	queryPersistence := sqlPersistence.String()
+	querySetTags := "update moonshot_requests set tags = :tags where id = :id;\r\n"
 
-	txPersistence, errPersistence := __imp.__core.Beginx()
-	if errPersistence != nil {
-		return v0Persistence, fmt.Errorf("error creating %s transaction: %w", strconv.Quote("Persistence"), errPersistence)
+	txSetTags, errSetTags := __imp.__core.Beginx()
+	if errSetTags != nil {
+		return fmt.Errorf("error creating %s transaction: %w", strconv.Quote("SetTags"), errSetTags)
 	}
 	if !__imp.__withTx {
-		defer txPersistence.Rollback()
+		defer txSetTags.Rollback()
 	}
 
-	argsPersistence := __rt.MergeNamedArgs(map[string]any{
-		"requestID":            requestID,
-		"requestContentType":   requestContentType,
-		"requestMethod":        requestMethod,
-		"requestPath":          requestPath,
-		"requestQuery":         requestQuery,
-		"moonshotID":           moonshotID,
-		"moonshotGID":          moonshotGID,
-		"moonshotUID":          moonshotUID,
-		"moonshotRequestID":    moonshotRequestID,
-		"moonshotServerTiming": moonshotServerTiming,
-		"responseStatusCode":   responseStatusCode,
-		"responseContentType":  responseContentType,
-		"requestHeader":        requestHeader,
-		"requestBody":          requestBody,
-		"responseHeader":       responseHeader,
-		"responseBody":         responseBody,
-		"programError":         programError,
-		"responseTTFT":         responseTTFT,
-		"responseTPOT":         responseTPOT,
-		"responseOTPS":         responseOTPS,
-		"createdAt":            createdAt,
-		"latency":              latency,
-		"endpoint":             endpoint,
+	argsSetTags := __rt.MergeNamedArgs(map[string]any{
+		"id":   id,
+		"tags": tags,
 	})
 
-	sqlSlicePersistence := __rt.Split(queryPersistence, ";")
-	for indexPersistence, splitSqlPersistence := range sqlSlicePersistence {
-		_ = indexPersistence
+	sqlSliceSetTags := __rt.Split(querySetTags, ";")
+	for indexSetTags, splitSqlSetTags := range sqlSliceSetTags {
+		_ = indexSetTags
 
-		var listArgsPersistence []interface{}
+		var listArgsSetTags []interface{}
 
-		splitSqlPersistence, listArgsPersistence, errPersistence = sqlx.Named(splitSqlPersistence, argsPersistence)
-		if errPersistence != nil {
-			return v0Persistence, fmt.Errorf("error building %s query: %w", strconv.Quote("Persistence"), errPersistence)
+		splitSqlSetTags, listArgsSetTags, errSetTags = sqlx.Named(splitSqlSetTags, argsSetTags)
+		if errSetTags != nil {
+			return fmt.Errorf("error building %s query: %w", strconv.Quote("SetTags"), errSetTags)
 		}
 
-		splitSqlPersistence, listArgsPersistence, errPersistence = sqlx.In(splitSqlPersistence, listArgsPersistence...)
-		if errPersistence != nil {
-			return v0Persistence, fmt.Errorf("error building %s query: %w", strconv.Quote("Persistence"), errPersistence)
+		splitSqlSetTags, listArgsSetTags, errSetTags = sqlx.In(splitSqlSetTags, listArgsSetTags...)
+		if errSetTags != nil {
+			return fmt.Errorf("error building %s query: %w", strconv.Quote("SetTags"), errSetTags)
 		}
 
-		if indexPersistence < len(sqlSlicePersistence)-1 {
-			_, errPersistence = txPersistence.Exec(splitSqlPersistence, listArgsPersistence...)
-		} else {
-			errPersistence = txPersistence.Get(&v0Persistence, splitSqlPersistence, listArgsPersistence...)
-		}
+		_, errSetTags = txSetTags.Exec(splitSqlSetTags, listArgsSetTags...)
 
-		if errPersistence != nil {
-			return v0Persistence, fmt.Errorf("error executing %s sql: \n\n%s\n\n%w", strconv.Quote("Persistence"), splitSqlPersistence, errPersistence)
+		if errSetTags != nil {
+			return fmt.Errorf("error executing %s sql: \n\n%s\n\n%w", strconv.Quote("SetTags"), splitSqlSetTags, errSetTags)
 		}
 	}
 
 	if !__imp.__withTx {
-		if errPersistence := txPersistence.Commit(); errPersistence != nil {
-			return v0Persistence, fmt.Errorf("error committing %s transaction: %w", strconv.Quote("Persistence"), errPersistence)
+		if errSetTags := txSetTags.Commit(); errSetTags != nil {
+			return fmt.Errorf("error committing %s transaction: %w", strconv.Quote("SetTags"), errSetTags)
 		}
 	}
 
-	return v0Persistence, nil
+	return nil
 }
 
-func (__imp *implPersistence) ListRequests(n int64, chatOnly bool, predicate string) ([]*Request, error) {
+func (__imp *implPersistence) SetCategory(id int64, category sql.NullString) error {
 	var (
-		v0ListRequests      []*Request
-		errListRequests     error
-		argListListRequests = make(__rt.Arguments, 0, 8)
+		errSetCategory error
 	)
 
-	__ListRequestsBindFunc := func(arg any) string {
-		argListListRequests = append(argListListRequests, arg)
-		return __rt.BindVars(len(__rt.MergeArgs(arg)))
+	querySetCategory := "update moonshot_requests set category = :category where id = :id;\r\n"
+
+	txSetCategory, errSetCategory := __imp.__core.Beginx()
+	if errSetCategory != nil {
+		return fmt.Errorf("error creating %s transaction: %w", strconv.Quote("SetCategory"), errSetCategory)
+	}
+	if !__imp.__withTx {
+		defer txSetCategory.Rollback()
 	}
-	sqlTmplListRequests := template.Must(template.New("ListRequests").Funcs(template.FuncMap{"bind": __ListRequestsBindFunc, "bindvars": __rt.BindVars, "fields": tableFields}).Parse("select * from ( select {{ fields \"response_body\" }}, iif( response_content_type = 'text/event-stream' and response_body is not null, merge_cmpl(response_body), response_body ) as response_body from moonshot_requests ) where 1 = 1 {{ if .chatOnly }} and request_path like '%/chat/completions' {{ end }} {{ if .predicate }} and ({{ .predicate }}) {{ end }} order by id desc {{ if .n }} limit {{ bind .n }} {{ end }} ;\r\n"))
 
-	sqlListRequests := __rt.GetBuffer()
-	defer __rt.PutBuffer(sqlListRequests)
-	defer sqlListRequests.Reset()
+	argsSetCategory := __rt.MergeNamedArgs(map[string]any{
+		"id":       id,
+		"category": category,
+	})
 
-	if errListRequests = sqlTmplListRequests.Execute(sqlListRequests, map[string]any{
-		"n":         n,
-		"chatOnly":  chatOnly,
-		"predicate": predicate,
-	}); errListRequests != nil {
-		return v0ListRequests, fmt.Errorf("error executing %s template: %w", strconv.Quote("ListRequests"), errListRequests)
+	sqlSliceSetCategory := __rt.Split(querySetCategory, ";")
+	for indexSetCategory, splitSqlSetCategory := range sqlSliceSetCategory {
+		_ = indexSetCategory
+
+		var listArgsSetCategory []interface{}
+
+		splitSqlSetCategory, listArgsSetCategory, errSetCategory = sqlx.Named(splitSqlSetCategory, argsSetCategory)
+		if errSetCategory != nil {
+			return fmt.Errorf("error building %s query: %w", strconv.Quote("SetCategory"), errSetCategory)
+		}
+
+		splitSqlSetCategory, listArgsSetCategory, errSetCategory = sqlx.In(splitSqlSetCategory, listArgsSetCategory...)
+		if errSetCategory != nil {
+			return fmt.Errorf("error building %s query: %w", strconv.Quote("SetCategory"), errSetCategory)
+		}
+
+		_, errSetCategory = txSetCategory.Exec(splitSqlSetCategory, listArgsSetCategory...)
+
+		if errSetCategory != nil {
+			return fmt.Errorf("error executing %s sql: \n\n%s\n\n%w", strconv.Quote("SetCategory"), splitSqlSetCategory, errSetCategory)
+		}
 	}
 
-	queryListRequests := sqlListRequests.String()
+	if !__imp.__withTx {
+		if errSetCategory := txSetCategory.Commit(); errSetCategory != nil {
+			return fmt.Errorf("error committing %s transaction: %w", strconv.Quote("SetCategory"), errSetCategory)
+		}
+	}
 
-	txListRequests, errListRequests := __imp.__core.Beginx()
-	if errListRequests != nil {
-		return v0ListRequests, fmt.Errorf("error creating %s transaction: %w", strconv.Quote("ListRequests"), errListRequests)
+	return nil
+}
+
+func (__imp *implPersistence) SetDerivedFields(id int64, moonshotID sql.NullString, model sql.NullString, systemFingerprint sql.NullString, rateLimit sql.NullString, requestBodyHash sql.NullString) error {
+	var (
+		errSetDerivedFields error
+	)
+
+	querySetDerivedFields := "update moonshot_requests set moonshot_id        = :moonshotID, model              = :model, system_fingerprint = :systemFingerprint, rate_limit         = :rateLimit, request_body_hash  = :requestBodyHash where id = :id;\r\n"
+
+	txSetDerivedFields, errSetDerivedFields := __imp.__core.Beginx()
+	if errSetDerivedFields != nil {
+		return fmt.Errorf("error creating %s transaction: %w", strconv.Quote("SetDerivedFields"), errSetDerivedFields)
 	}
 	if !__imp.__withTx {
-		defer txListRequests.Rollback()
+		defer txSetDerivedFields.Rollback()
 	}
 
-	offsetListRequests := 0
-	argsListRequests := __rt.MergeArgs(argListListRequests...)
+	argsSetDerivedFields := __rt.MergeNamedArgs(map[string]any{
+		"id":                id,
+		"moonshotID":        moonshotID,
+		"model":             model,
+		"systemFingerprint": systemFingerprint,
+		"rateLimit":         rateLimit,
+		"requestBodyHash":   requestBodyHash,
+	})
 
-	sqlSliceListRequests := __rt.Split(queryListRequests, ";")
-	for indexListRequests, splitSqlListRequests := range sqlSliceListRequests {
-		_ = indexListRequests
+	sqlSliceSetDerivedFields := __rt.Split(querySetDerivedFields, ";")
+	for indexSetDerivedFields, splitSqlSetDerivedFields := range sqlSliceSetDerivedFields {
+		_ = indexSetDerivedFields
 
-		countListRequests := __rt.Count(splitSqlListRequests, "?")
+		var listArgsSetDerivedFields []interface{}
 
-		if indexListRequests < len(sqlSliceListRequests)-1 {
-			_, errListRequests = txListRequests.Exec(splitSqlListRequests, argsListRequests[offsetListRequests:offsetListRequests+countListRequests]...)
-		} else {
-			errListRequests = txListRequests.Select(&v0ListRequests, splitSqlListRequests, argsListRequests[offsetListRequests:offsetListRequests+countListRequests]...)
+		splitSqlSetDerivedFields, listArgsSetDerivedFields, errSetDerivedFields = sqlx.Named(splitSqlSetDerivedFields, argsSetDerivedFields)
+		if errSetDerivedFields != nil {
+			return fmt.Errorf("error building %s query: %w", strconv.Quote("SetDerivedFields"), errSetDerivedFields)
 		}
 
-		if errListRequests != nil {
-			return v0ListRequests, fmt.Errorf("error executing %s sql: \n\n%s\n\n%w", strconv.Quote("ListRequests"), splitSqlListRequests, errListRequests)
+		splitSqlSetDerivedFields, listArgsSetDerivedFields, errSetDerivedFields = sqlx.In(splitSqlSetDerivedFields, listArgsSetDerivedFields...)
+		if errSetDerivedFields != nil {
+			return fmt.Errorf("error building %s query: %w", strconv.Quote("SetDerivedFields"), errSetDerivedFields)
 		}
 
-		offsetListRequests += countListRequests
+		_, errSetDerivedFields = txSetDerivedFields.Exec(splitSqlSetDerivedFields, listArgsSetDerivedFields...)
+
+		if errSetDerivedFields != nil {
+			return fmt.Errorf("error executing %s sql: \n\n%s\n\n%w", strconv.Quote("SetDerivedFields"), splitSqlSetDerivedFields, errSetDerivedFields)
+		}
 	}
 
 	if !__imp.__withTx {
-		if errListRequests := txListRequests.Commit(); errListRequests != nil {
-			return v0ListRequests, fmt.Errorf("error committing %s transaction: %w", strconv.Quote("ListRequests"), errListRequests)
+		if errSetDerivedFields := txSetDerivedFields.Commit(); errSetDerivedFields != nil {
+			return fmt.Errorf("error committing %s transaction: %w", strconv.Quote("SetDerivedFields"), errSetDerivedFields)
 		}
 	}
 
-	return v0ListRequests, nil
+	return nil
 }
 
-func (__imp *implPersistence) GetRequest(id int64, chatcmpl string, requestid string) (*Request, error) {
+func (__imp *implPersistence) SetNote(id int64, note sql.NullString) error {
 	var (
-		v0GetRequest  = new(Request)
-		errGetRequest error
+		errSetNote error
 	)
 
-	sqlGetRequest := __rt.GetBuffer()
-	defer __rt.PutBuffer(sqlGetRequest)
-	defer sqlGetRequest.Reset()
+	querySetNote := "update moonshot_requests set note = :note where id = :id;\r\n"
 
-	if errGetRequest = sqlTmplGetRequest.Execute(sqlGetRequest, map[string]any{
-		"id":        id,
-		"chatcmpl":  chatcmpl,
-		"requestid": requestid,
-	}); errGetRequest != nil {
-		return v0GetRequest, fmt.Errorf("error executing %s template: %w", strconv.Quote("GetRequest"), errGetRequest)
+	txSetNote, errSetNote := __imp.__core.Beginx()
+	if errSetNote != nil {
+		return fmt.Errorf("error creating %s transaction: %w", strconv.Quote("SetNote"), errSetNote)
+	}
+	if !__imp.__withTx {
+		defer txSetNote.Rollback()
 	}
 
-	queryGetRequest := sqlGetRequest.String()
+	argsSetNote := __rt.MergeNamedArgs(map[string]any{
+		"id":   id,
+		"note": note,
+	})
 
-	txGetRequest, errGetRequest := __imp.__core.Beginx()
-	if errGetRequest != nil {
-		return v0GetRequest, fmt.Errorf("error creating %s transaction: %w", strconv.Quote("GetRequest"), errGetRequest)
+	sqlSliceSetNote := __rt.Split(querySetNote, ";")
+	for indexSetNote, splitSqlSetNote := range sqlSliceSetNote {
+		_ = indexSetNote
+
+		var listArgsSetNote []interface{}
+
+		splitSqlSetNote, listArgsSetNote, errSetNote = sqlx.Named(splitSqlSetNote, argsSetNote)
+		if errSetNote != nil {
+			return fmt.Errorf("error building %s query: %w", strconv.Quote("SetNote"), errSetNote)
+		}
+
+		splitSqlSetNote, listArgsSetNote, errSetNote = sqlx.In(splitSqlSetNote, listArgsSetNote...)
+		if errSetNote != nil {
+			return fmt.Errorf("error building %s query: %w", strconv.Quote("SetNote"), errSetNote)
+		}
+
+		_, errSetNote = txSetNote.Exec(splitSqlSetNote, listArgsSetNote...)
+
+		if errSetNote != nil {
+			return fmt.Errorf("error executing %s sql: \n\n%s\n\n%w", strconv.Quote("SetNote"), splitSqlSetNote, errSetNote)
+		}
 	}
+
 	if !__imp.__withTx {
-		defer txGetRequest.Rollback()
+		if errSetNote := txSetNote.Commit(); errSetNote != nil {
+			return fmt.Errorf("error committing %s transaction: %w", strconv.Quote("SetNote"), errSetNote)
+		}
 	}
 
-	argsGetRequest := __rt.MergeNamedArgs(map[string]any{
-		"id":        id,
-		"chatcmpl":  chatcmpl,
-		"requestid": requestid,
+	return nil
+}
+
+func (__imp *implPersistence) InsertBenchmarkRun(requestID int64, replayID sql.NullInt64, compareModel string, score float64, threshold float64) error {
+	var (
+		errInsertBenchmarkRun error
+	)
+
+	queryInsertBenchmarkRun := "insert into benchmark_runs ( request_id, replay_id, compare_model, score, threshold ) values ( :requestID, :replayID, :compareModel, :score, :threshold );\r\n"
+
+	txInsertBenchmarkRun, errInsertBenchmarkRun := __imp.__core.Beginx()
+	if errInsertBenchmarkRun != nil {
+		return fmt.Errorf("error creating %s transaction: %w", strconv.Quote("InsertBenchmarkRun"), errInsertBenchmarkRun)
+	}
+	if !__imp.__withTx {
+		defer txInsertBenchmarkRun.Rollback()
+	}
+
+	argsInsertBenchmarkRun := __rt.MergeNamedArgs(map[string]any{
+		"requestID":    requestID,
+		"replayID":     replayID,
+		"compareModel": compareModel,
+		"score":        score,
+		"threshold":    threshold,
 	})
 
-	sqlSliceGetRequest := __rt.Split(queryGetRequest, ";")
-	for indexGetRequest, splitSqlGetRequest := range sqlSliceGetRequest {
-		_ = indexGetRequest
+	sqlSliceInsertBenchmarkRun := __rt.Split(queryInsertBenchmarkRun, ";")
+	for indexInsertBenchmarkRun, splitSqlInsertBenchmarkRun := range sqlSliceInsertBenchmarkRun {
+		_ = indexInsertBenchmarkRun
 
-		var listArgsGetRequest []interface{}
+		var listArgsInsertBenchmarkRun []interface{}
 
-		splitSqlGetRequest, listArgsGetRequest, errGetRequest = sqlx.Named(splitSqlGetRequest, argsGetRequest)
-		if errGetRequest != nil {
-			return v0GetRequest, fmt.Errorf("error building %s query: %w", strconv.Quote("GetRequest"), errGetRequest)
+		splitSqlInsertBenchmarkRun, listArgsInsertBenchmarkRun, errInsertBenchmarkRun = sqlx.Named(splitSqlInsertBenchmarkRun, argsInsertBenchmarkRun)
+		if errInsertBenchmarkRun != nil {
+			return fmt.Errorf("error building %s query: %w", strconv.Quote("InsertBenchmarkRun"), errInsertBenchmarkRun)
 		}
 
-		splitSqlGetRequest, listArgsGetRequest, errGetRequest = sqlx.In(splitSqlGetRequest, listArgsGetRequest...)
-		if errGetRequest != nil {
-			return v0GetRequest, fmt.Errorf("error building %s query: %w", strconv.Quote("GetRequest"), errGetRequest)
+		splitSqlInsertBenchmarkRun, listArgsInsertBenchmarkRun, errInsertBenchmarkRun = sqlx.In(splitSqlInsertBenchmarkRun, listArgsInsertBenchmarkRun...)
+		if errInsertBenchmarkRun != nil {
+			return fmt.Errorf("error building %s query: %w", strconv.Quote("InsertBenchmarkRun"), errInsertBenchmarkRun)
 		}
 
-		if indexGetRequest < len(sqlSliceGetRequest)-1 {
-			_, errGetRequest = txGetRequest.Exec(splitSqlGetRequest, listArgsGetRequest...)
-		} else {
-			errGetRequest = txGetRequest.Get(v0GetRequest, splitSqlGetRequest, listArgsGetRequest...)
-		}
+		_, errInsertBenchmarkRun = txInsertBenchmarkRun.Exec(splitSqlInsertBenchmarkRun, listArgsInsertBenchmarkRun...)
 
-		if errGetRequest != nil {
-			return v0GetRequest, fmt.Errorf("error executing %s sql: \n\n%s\n\n%w", strconv.Quote("GetRequest"), splitSqlGetRequest, errGetRequest)
+		if errInsertBenchmarkRun != nil {
+			return fmt.Errorf("error executing %s sql: \n\n%s\n\n%w", strconv.Quote("InsertBenchmarkRun"), splitSqlInsertBenchmarkRun, errInsertBenchmarkRun)
 		}
 	}
 
 	if !__imp.__withTx {
-		if errGetRequest := txGetRequest.Commit(); errGetRequest != nil {
-			return v0GetRequest, fmt.Errorf("error committing %s transaction: %w", strconv.Quote("GetRequest"), errGetRequest)
+		if errInsertBenchmarkRun := txInsertBenchmarkRun.Commit(); errInsertBenchmarkRun != nil {
+			return fmt.Errorf("error committing %s transaction: %w", strconv.Quote("InsertBenchmarkRun"), errInsertBenchmarkRun)
 		}
 	}
 
-	return v0GetRequest, nil
+	return nil
 }
 
 func (__imp *implPersistence) SetCache(ctx context.Context, cacheID string, hash string, nBytes int, kIdent string, createdAt string) error {
@@ -4,26 +4,53 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/textproto"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/MoonshotAI/moonpalace/canonical"
+	"github.com/MoonshotAI/moonpalace/diff"
+	"github.com/MoonshotAI/moonpalace/langchain"
 	parser "github.com/MoonshotAI/moonpalace/predicate"
 
 	"github.com/mattn/go-sqlite3"
+	"github.com/spf13/cobra"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+	"github.com/x5iu/defc/sqlx"
 )
 
 var (
-	persistence Persistence
+	persistence *palace
 	tableInfos  []*tableInfo
 )
 
+// palace is the database of captures, whose queries are generated by defc, but
+// for those that need the database handle itself, such as InsertRequestBatch,
+// which reuses a prepared statement.
+type palace struct {
+	queries
+	db *sqlx.DB
+}
+
+// queries embeds Persistence under another name, as Persistence is also the
+// name of the query storing a proxied request.
+type queries = Persistence
+
 const sqlDriver = "moonshot_sqlite3"
 
 func init() {
@@ -35,26 +62,103 @@ func init() {
 			if err := conn.RegisterFunc("regexp", sqliteRegexp, true); err != nil {
 				return err
 			}
+			if err := conn.RegisterFunc("hash_body", hashRequestBody, true); err != nil {
+				return err
+			}
 			return nil
 		},
 	})
-	persistence = NewPersistence(
-		sqlDriver,
-		"file:"+getPalaceSqlite(),
-	)
-	var err error
-	if err = persistence.createTable(); err != nil {
-		logFatal(err)
+}
+
+// Commands that use the database are annotated with databaseAnnotation, whose
+// value is databaseCreate for commands that capture requests, and databaseRead
+// for those that read captured requests and so need an existing database.
+const (
+	databaseAnnotation = "database"
+	databaseCreate     = "create"
+	databaseRead       = "read"
+)
+
+var errNoCaptures = errors.New("no captures found; run `moonpalace start` first")
+
+// readOnly is set by --read-only, the database is then opened in read-only mode
+// and commands refuse to modify it, so that a shared archive is never written.
+var readOnly bool
+
+// checkWritable returns an error if the database is opened with --read-only,
+// action describes the modification refused.
+func checkWritable(action string) error {
+	if readOnly {
+		return errors.New("unable to " + action + ", the database is opened with --read-only")
 	}
-	tableInfos, err = persistence.inspectTable()
-	if err != nil {
+	return nil
+}
+
+// openPersistence opens the database as needed by the command, which is created
+// and migrated on start. Read commands never create an empty database, and fail
+// with errNoCaptures if there is none or it has no requests.
+func openPersistence(cmd *cobra.Command) {
+	mode := cmd.Annotations[databaseAnnotation]
+	if mode == "" {
+		return
+	}
+	if mode == databaseCreate {
+		if err := checkWritable("capture requests"); err != nil {
+			logFatal(err)
+		}
+	}
+	path := getPalaceSqlite(mode == databaseCreate)
+	if path == "" {
+		logFatal(errNoCaptures)
+	}
+	dataSourceName := "file:" + path
+	if readOnly {
+		// Neither tables nor columns are created, queries select the columns
+		// the database already has.
+		dataSourceName += "?mode=ro"
+	}
+	if err := openDatabase(dataSourceName); err != nil {
 		logFatal(err)
 	}
-	for _, alter := range alterFuncs {
-		if err = alter(tableInfos); err != nil {
+	// Following the requests to come, such as with list --follow, is the only
+	// way to read an empty database.
+	if follow := cmd.Flags().Lookup("follow"); mode == databaseRead && (follow == nil || !follow.Changed) {
+		hasRequests, err := persistence.HasRequests()
+		if err != nil {
 			logFatal(err)
 		}
+		if !hasRequests {
+			logFatal(errNoCaptures)
+		}
+	}
+}
+
+// openDatabase opens the database as persistence, whose table is created and
+// migrated unless it is opened with --read-only.
+func openDatabase(dataSourceName string) error {
+	db := sqlx.MustOpen(sqlDriver, dataSourceName)
+	persistence = &palace{
+		queries: NewPersistenceFromDB(db),
+		db:      db,
+	}
+	var err error
+	if !readOnly {
+		if err = persistence.createTable(); err != nil {
+			return err
+		}
+		if tableInfos, err = persistence.inspectTable(); err != nil {
+			return err
+		}
+		for _, alter := range alterFuncs {
+			if err = alter(tableInfos); err != nil {
+				return err
+			}
+		}
 	}
+	// Queries select the columns listed in tableInfos, which must include the
+	// columns just added.
+	tableInfos, err = persistence.inspectTable()
+	return err
 }
 
 var alterFuncs = []func([]*tableInfo) error{
@@ -63,6 +167,20 @@ var alterFuncs = []func([]*tableInfo) error{
 	addOTPSField,
 	addLatencyField,
 	addEndpointField,
+	addModelField,
+	addSystemFingerprintField,
+	addRequestBodySizeField,
+	addRateLimitField,
+	addOriginalModelField,
+	addParentIDField,
+	addTagsField,
+	addCategoryField,
+	addNoteField,
+	addTraceIDField,
+	addRawRequestHeaderField,
+	addRequestBodyHashField,
+	addCredentialHashField,
+	addRequestBodyHashIndex,
 }
 
 func addTTFTField(tableInfos []*tableInfo) error {
@@ -110,6 +228,164 @@ func addEndpointField(tableInfos []*tableInfo) error {
 	return persistence.addEndpointField()
 }
 
+func addModelField(tableInfos []*tableInfo) error {
+	for _, info := range tableInfos {
+		if info.Name == "model" {
+			return nil
+		}
+	}
+	return persistence.addModelField()
+}
+
+func addSystemFingerprintField(tableInfos []*tableInfo) error {
+	for _, info := range tableInfos {
+		if info.Name == "system_fingerprint" {
+			return nil
+		}
+	}
+	return persistence.addSystemFingerprintField()
+}
+
+func addRequestBodySizeField(tableInfos []*tableInfo) error {
+	for _, info := range tableInfos {
+		if info.Name == "request_body_size" {
+			return nil
+		}
+	}
+	return persistence.addRequestBodySizeField()
+}
+
+func addRateLimitField(tableInfos []*tableInfo) error {
+	for _, info := range tableInfos {
+		if info.Name == "rate_limit" {
+			return nil
+		}
+	}
+	return persistence.addRateLimitField()
+}
+
+func addOriginalModelField(tableInfos []*tableInfo) error {
+	for _, info := range tableInfos {
+		if info.Name == "original_model" {
+			return nil
+		}
+	}
+	return persistence.addOriginalModelField()
+}
+
+func addParentIDField(tableInfos []*tableInfo) error {
+	for _, info := range tableInfos {
+		if info.Name == "parent_id" {
+			return nil
+		}
+	}
+	return persistence.addParentIDField()
+}
+
+func addTagsField(tableInfos []*tableInfo) error {
+	for _, info := range tableInfos {
+		if info.Name == "tags" {
+			return nil
+		}
+	}
+	return persistence.addTagsField()
+}
+
+func addCategoryField(tableInfos []*tableInfo) error {
+	for _, info := range tableInfos {
+		if info.Name == "category" {
+			return nil
+		}
+	}
+	return persistence.addCategoryField()
+}
+
+func addNoteField(tableInfos []*tableInfo) error {
+	for _, info := range tableInfos {
+		if info.Name == "note" {
+			return nil
+		}
+	}
+	return persistence.addNoteField()
+}
+
+func addTraceIDField(tableInfos []*tableInfo) error {
+	for _, info := range tableInfos {
+		if info.Name == "trace_id" {
+			return nil
+		}
+	}
+	return persistence.addTraceIDField()
+}
+
+func addRawRequestHeaderField(tableInfos []*tableInfo) error {
+	for _, info := range tableInfos {
+		if info.Name == "raw_request_header" {
+			return nil
+		}
+	}
+	return persistence.addRawRequestHeaderField()
+}
+
+// addRequestBodyHashField adds the column and backfills the hashes of the stored
+// bodies, except for the bodies truncated by --max-body-store, whose hash would
+// not be the one of the whole body.
+func addRequestBodyHashField(tableInfos []*tableInfo) error {
+	for _, info := range tableInfos {
+		if info.Name == "request_body_hash" {
+			return nil
+		}
+	}
+	if err := persistence.addRequestBodyHashField(); err != nil {
+		return err
+	}
+	return persistence.backfillRequestBodyHash()
+}
+
+// addRequestBodyHashIndex indexes the hashes, which --hash and history look up.
+func addRequestBodyHashIndex([]*tableInfo) error {
+	indexes, err := persistence.listIndexes()
+	if err != nil {
+		return err
+	}
+	if slices.Contains(indexes, "moonshot_requests_request_body_hash") {
+		return nil
+	}
+	return persistence.addRequestBodyHashIndex()
+}
+
+// addCredentialHashField adds the column, which is left empty for the requests
+// already stored, so that they are never served by --response-cache.
+func addCredentialHashField(tableInfos []*tableInfo) error {
+	for _, info := range tableInfos {
+		if info.Name == "credential_hash" {
+			return nil
+		}
+	}
+	return persistence.addCredentialHashField()
+}
+
+// selectRequest selects a single request, either by id, chatcmpl or request id,
+// or the request of the user closest to atTime, which is in RFC3339 format.
+func selectRequest(id int64, chatcmpl, requestID, uid, atTime string) (*Request, error) {
+	if atTime != "" {
+		at, err := time.Parse(time.RFC3339, atTime)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time %q, expects RFC3339 format: %w", atTime, err)
+		}
+		// created_at is stored in local time.
+		atTime = at.In(time.Local).Format(time.DateTime)
+		n, err := persistence.CountRequestsAtTime(uid, atTime)
+		if err != nil {
+			return nil, err
+		}
+		if n > 1 {
+			return nil, fmt.Errorf("%d requests of user %s are equally close to %s, use a more precise selector", n, uid, atTime)
+		}
+	}
+	return persistence.GetRequest(id, chatcmpl, requestID, uid, atTime)
+}
+
 type tableInfo struct {
 	CID          int64          `db:"cid"`
 	Name         string         `db:"name"`
@@ -137,8 +413,102 @@ func tableFields(exclude ...string) (fields string) {
 	return strings.Join(fieldList, ",")
 }
 
+// groupKeys are the keys available in list --group-by, in the order of the
+// columns of RequestGroup, each of which is mapped to a SQL expression.
+var groupKeys = []struct{ Name, Expr string }{
+	{"model", "coalesce(model, iif(json_valid(request_body), json_extract(request_body, '$.model'), null))"},
+	{"date", "date(created_at)"},
+	{"uid", "moonshot_uid"},
+	{"category", "category"},
+	{"path", "request_path"},
+	{"finish_reason", predicateFields["finish_reason"]},
+}
+
+func groupKeyNames() []string {
+	names := make([]string, len(groupKeys))
+	for i, key := range groupKeys {
+		names[i] = key.Name
+	}
+	return names
+}
+
+// groupColumns selects every group key, keys not grouped by are selected as
+// null so that all the columns of RequestGroup are present.
+func groupColumns(keys []string) string {
+	columns := make([]string, len(groupKeys))
+	for i, key := range groupKeys {
+		if slices.Contains(keys, key.Name) {
+			columns[i] = key.Expr + " as " + key.Name
+		} else {
+			columns[i] = "null as " + key.Name
+		}
+	}
+	return strings.Join(columns, ", ")
+}
+
+// groupByColumns returns the expressions of the keys in the given order, which
+// are used rather than the aliases since the aliases shadow the columns.
+func groupByColumns(keys []string) string {
+	columns := make([]string, 0, len(keys))
+	for _, name := range keys {
+		for _, key := range groupKeys {
+			if key.Name == name {
+				columns = append(columns, key.Expr)
+			}
+		}
+	}
+	return strings.Join(columns, ", ")
+}
+
+// RequestGroup is a row of GroupRequests, where only the keys grouped by are
+// valid.
+type RequestGroup struct {
+	Model            sql.NullString `db:"model"`
+	Date             sql.NullString `db:"date"`
+	UID              sql.NullString `db:"uid"`
+	Category         sql.NullString `db:"category"`
+	Path             sql.NullString `db:"path"`
+	FinishReason     sql.NullString `db:"finish_reason"`
+	Requests         int64          `db:"requests"`
+	Errors           int64          `db:"errors"`
+	PromptTokens     int64          `db:"prompt_tokens"`
+	CompletionTokens int64          `db:"completion_tokens"`
+	TotalTokens      int64          `db:"total_tokens"`
+}
+
+// Key returns the value of the group key, or "-" if it is null.
+func (g *RequestGroup) Key(name string) string {
+	var value sql.NullString
+	switch name {
+	case "model":
+		value = g.Model
+	case "date":
+		value = g.Date
+	case "uid":
+		value = g.UID
+	case "category":
+		value = g.Category
+	case "path":
+		value = g.Path
+	case "finish_reason":
+		value = g.FinishReason
+	}
+	if !value.Valid || value.String == "" {
+		return "-"
+	}
+	return value.String
+}
+
+// ErrorRate returns the ratio of the requests failed in the group.
+func (g *RequestGroup) ErrorRate() float64 {
+	if g.Requests == 0 {
+		return 0
+	}
+	return float64(g.Errors) / float64(g.Requests)
+}
+
 //go:generate python3 updateln.py
-//go:generate defc generate --features sqlx/future --func fields=tableFields
+//go:generate defc generate --features sqlx/future --func fields=tableFields --func groupColumns=groupColumns --func groupBy=groupByColumns
 type Persistence interface {
 	// createTable exec const
 	/*
@@ -169,6 +539,19 @@ type Persistence interface {
 	       response_otps          real,
 	       latency                integer,
 	       endpoint               text,
+	       model                  text,
+	       system_fingerprint     text,
+	       request_body_size      integer,
+	       rate_limit             text,
+	       original_model         text,
+	       parent_id              integer,
+	       tags                   text,
+	       category               text,
+	       note                   text,
+	       trace_id               text,
+	       raw_request_header     text,
+	       request_body_hash      text,
+	       credential_hash        text,
 	       created_at             text    default (datetime('now', 'localtime')) not null
 	   );
 	   create table if not exists moonshot_caches
@@ -182,6 +565,25 @@ type Persistence interface {
 	       k_ident                text    not null,
 	       created_at             text    default (datetime('now', 'localtime')) not null,
 	       updated_at             text
+	   );
+	   create table if not exists moonshot_live_streams
+	   (
+	       moonshot_id            text    not null
+	               constraint moonshot_live_streams_pk
+	                   primary key,
+	       response_body          text    not null
+	   );
+	   create table if not exists benchmark_runs
+	   (
+	       id                     integer not null
+	               constraint benchmark_runs_pk
+	                   primary key autoincrement,
+	       request_id             integer not null,
+	       replay_id              integer,
+	       compare_model          text    not null,
+	       score                  real    not null,
+	       threshold              real    not null,
+	       created_at             text    default (datetime('now', 'localtime')) not null
 	   )
 	*/
 	createTable() error
@@ -210,6 +612,70 @@ type Persistence interface {
 	// alter table moonshot_requests add endpoint text;
 	addEndpointField() error
 
+	// addModelField exec
+	// alter table moonshot_requests add model text;
+	addModelField() error
+
+	// addSystemFingerprintField exec
+	// alter table moonshot_requests add system_fingerprint text;
+	addSystemFingerprintField() error
+
+	// addRequestBodySizeField exec
+	// alter table moonshot_requests add request_body_size integer;
+	addRequestBodySizeField() error
+
+	// addRateLimitField exec
+	// alter table moonshot_requests add rate_limit text;
+	addRateLimitField() error
+
+	// addOriginalModelField exec
+	// alter table moonshot_requests add original_model text;
+	addOriginalModelField() error
+
+	// addParentIDField exec
+	// alter table moonshot_requests add parent_id integer;
+	addParentIDField() error
+
+	// addTagsField exec
+	// alter table moonshot_requests add tags text;
+	addTagsField() error
+
+	// addCategoryField exec
+	// alter table moonshot_requests add category text;
+	addCategoryField() error
+
+	// addNoteField exec
+	// alter table moonshot_requests add note text;
+	addNoteField() error
+
+	// addTraceIDField exec
+	// alter table moonshot_requests add trace_id text;
+	addTraceIDField() error
+
+	// addRawRequestHeaderField exec
+	// alter table moonshot_requests add raw_request_header text;
+	addRawRequestHeaderField() error
+
+	// addRequestBodyHashField exec
+	// alter table moonshot_requests add request_body_hash text;
+	addRequestBodyHashField() error
+
+	// backfillRequestBodyHash exec
+	// update moonshot_requests set request_body_hash = hash_body(request_body) where request_body is not null and request_body_size is null;
+	backfillRequestBodyHash() error
+
+	// listIndexes query many const
+	// select name from pragma_index_list('moonshot_requests');
+	listIndexes() ([]string, error)
+
+	// addRequestBodyHashIndex exec
+	// create index if not exists moonshot_requests_request_body_hash on moonshot_requests (request_body_hash);
+	addRequestBodyHashIndex() error
+
+	// addCredentialHashField exec
+	// alter table moonshot_requests add credential_hash text;
+	addCredentialHashField() error
+
 	// Cleanup exec named const
 	// delete from moonshot_requests where created_at < :before;
 	Cleanup(before string) (sql.Result, error)
@@ -240,6 +706,15 @@ type Persistence interface {
 	       {{ if .responseOTPS }},response_otps{{ end }}
 	       {{ if .latency }},latency{{ end }}
 	       {{ if .endpoint }},endpoint{{ end }}
+	       {{ if .model }},model{{ end }}
+	       {{ if .systemFingerprint }},system_fingerprint{{ end }}
+	       {{ if .requestBodySize }},request_body_size{{ end }}
+	       {{ if .rateLimit }},rate_limit{{ end }}
+	       {{ if .originalModel }},original_model{{ end }}
+	       {{ if .traceID }},trace_id{{ end }}
+	       {{ if .rawRequestHeader }},raw_request_header{{ end }}
+	       {{ if .requestBodyHash }},request_body_hash{{ end }}
+	       {{ if .credentialHash }},credential_hash{{ end }}
 	   ) values (
 	       :requestMethod,
 	       :requestPath,
@@ -264,6 +739,15 @@ type Persistence interface {
 	       {{ if .responseOTPS }},:responseOTPS{{ end }}
 	       {{ if .latency }},:latency{{ end }}
 	       {{ if .endpoint }},:endpoint{{ end }}
+	       {{ if .model }},:model{{ end }}
+	       {{ if .systemFingerprint }},:systemFingerprint{{ end }}
+	       {{ if .requestBodySize }},:requestBodySize{{ end }}
+	       {{ if .rateLimit }},:rateLimit{{ end }}
+	       {{ if .originalModel }},:originalModel{{ end }}
+	       {{ if .traceID }},:traceID{{ end }}
+	       {{ if .rawRequestHeader }},:rawRequestHeader{{ end }}
+	       {{ if .requestBodyHash }},:requestBodyHash{{ end }}
+	       {{ if .credentialHash }},:credentialHash{{ end }}
 	   );
 	*/
 	// select last_insert_rowid();
@@ -291,8 +775,77 @@ type Persistence interface {
 		createdAt string,
 		latency time.Duration,
 		endpoint string,
+		model string,
+		systemFingerprint string,
+		requestBodySize int,
+		rateLimit string,
+		originalModel string,
+		traceID string,
+		rawRequestHeader string,
+		requestBodyHash string,
+		credentialHash string,
 	) (pid int64, err error)
 
+	// InsertRequest query one bind
+	/*
+	   insert into moonshot_requests (
+	       request_method,
+	       request_path,
+	       request_query,
+	       request_content_type,
+	       request_id,
+	       moonshot_id,
+	       moonshot_gid,
+	       moonshot_uid,
+	       moonshot_request_id,
+	       moonshot_server_timing,
+	       response_status_code,
+	       response_content_type,
+	       request_header,
+	       request_body,
+	       response_header,
+	       response_body,
+	       error,
+	       response_ttft,
+	       response_tpot,
+	       response_otps,
+	       latency,
+	       endpoint,
+	       model,
+	       system_fingerprint,
+	       request_body_size,
+	       rate_limit,
+	       original_model,
+	       parent_id,
+	       tags,
+	       category,
+	       note,
+	       trace_id,
+	       raw_request_header,
+	       request_body_hash,
+	       credential_hash,
+	       created_at
+	   ) values ({{ bind .request }});
+	*/
+	// select last_insert_rowid();
+	InsertRequest(request *Request) (int64, error)
+
+	// GetReplayChain query many named
+	/*
+	   with recursive chain(id) as (
+	       select id from moonshot_requests where id = :originalID
+	       union
+	       select moonshot_requests.id
+	       from moonshot_requests
+	       join chain on moonshot_requests.parent_id = chain.id
+	   )
+	   select *
+	   from moonshot_requests
+	   where id in (select id from chain)
+	   order by id;
+	*/
+	GetReplayChain(originalID int64) ([]*Request, error)
+
 	// ListRequests query many bind
 	/*
 	   select *
@@ -321,6 +874,37 @@ type Persistence interface {
 	*/
 	ListRequests(n int64, chatOnly bool, predicate string) ([]*Request, error)
 
+	// GroupRequests query many bind
+	/*
+	   select
+	       {{ groupColumns .keys }},
+	       count(*) as requests,
+	       sum(iif(response_status_code is null or response_status_code >= 400 or error is not null, 1, 0)) as errors,
+	       coalesce(sum(iif(json_valid(response_body), json_extract(response_body, '$.usage.prompt_tokens'), null)), 0) as prompt_tokens,
+	       coalesce(sum(iif(json_valid(response_body), json_extract(response_body, '$.usage.completion_tokens'), null)), 0) as completion_tokens,
+	       coalesce(sum(iif(json_valid(response_body), json_extract(response_body, '$.usage.total_tokens'), null)), 0) as total_tokens
+	   from (
+	       select
+	           {{ fields "response_body" }},
+	           iif(
+	               response_content_type = 'text/event-stream' and response_body is not null,
+	               merge_cmpl(response_body),
+	               response_body
+	           ) as response_body
+	       from moonshot_requests
+	   )
+	   where 1 = 1
+	     {{ if .chatOnly }}
+	     and request_path like '%/chat/completions'
+	     {{ end }}
+	     {{ if .predicate }}
+	     and ({{ .predicate }})
+	     {{ end }}
+	   group by {{ groupBy .keys }}
+	   order by {{ groupBy .keys }};
+	*/
+	GroupRequests(keys []string, chatOnly bool, predicate string) ([]*RequestGroup, error)
+
 	// GetRequest query one named
 	/*
 	   select *
@@ -332,16 +916,220 @@ type Persistence interface {
 	     {{ if .chatcmpl }}
 	     and moonshot_id = :chatcmpl
 	     {{ end }}
-	     {{ if .requestid }}
-	     and moonshot_request_id = :requestid
+	     {{ if .requestid }}
+	     and moonshot_request_id = :requestid
+	     {{ end }}
+	     {{ if .uid }}
+	     and moonshot_uid = :uid
+	     {{ end }}
+	     {{ if .atTime }}
+	     and abs(julianday(created_at) - julianday(:atTime)) = (
+	         select min(abs(julianday(created_at) - julianday(:atTime)))
+	         from moonshot_requests
+	         where moonshot_uid = :uid
+	     )
+	     {{ end }}
+	   ;
+	*/
+	GetRequest(
+		id int64,
+		chatcmpl string,
+		requestid string,
+		uid string,
+		atTime string,
+	) (*Request, error)
+
+	// GetCachedResponse query one named
+	/*
+	   select *
+	   from moonshot_requests
+	   where request_body_hash = :hash
+	     and credential_hash = :credential
+	     and request_method = :method
+	     and request_path = :path
+	     and request_query = :query
+	     and response_status_code between 200 and 299
+	     and error is null
+	     and response_body is not null
+	     {{ if .since }}
+	     and created_at >= :since
+	     {{ end }}
+	   order by id desc
+	   limit 1;
+	*/
+	GetCachedResponse(hash string, credential string, method string, path string, query string, since string) (*Request, error)
+
+	// HasRequests query one const
+	// select exists (select 1 from moonshot_requests);
+	HasRequests() (bool, error)
+
+	// CountRequests query one const
+	// select count(*) from moonshot_requests;
+	CountRequests() (int64, error)
+
+	// CountRequestsAtTime query one named const
+	/*
+	   select count(*)
+	   from moonshot_requests
+	   where moonshot_uid = :uid
+	     and abs(julianday(created_at) - julianday(:atTime)) = (
+	         select min(abs(julianday(created_at) - julianday(:atTime)))
+	         from moonshot_requests
+	         where moonshot_uid = :uid
+	     );
+	*/
+	CountRequestsAtTime(uid string, atTime string) (int64, error)
+
+	// GetRequestsByChatcmpls query many named const
+	/*
+	   select *
+	   from moonshot_requests
+	   where moonshot_id in (:chatcmpls)
+	   order by id;
+	*/
+	GetRequestsByChatcmpls(chatcmpls []string) ([]*Request, error)
+
+	// GetRequestsByUIDs query many named const
+	/*
+	   select *
+	   from moonshot_requests
+	   where moonshot_uid in (:uids)
+	   order by id;
+	*/
+	GetRequestsByUIDs(uids []string) ([]*Request, error)
+
+	// GetRequestsByRange query many named
+	/*
+	   select *
+	   from moonshot_requests
+	   where 1 = 1
+	     {{ if .idFrom }}
+	     and id >= :idFrom
+	     {{ end }}
+	     {{ if .idTo }}
+	     and id <= :idTo
+	     {{ end }}
+	     {{ if .uid }}
+	     and moonshot_uid = :uid
+	     {{ end }}
+	     {{ if .uids }}
+	     and moonshot_uid in (:uids)
+	     {{ end }}
+	     {{ if .since }}
+	     and created_at >= :since
+	     {{ end }}
+	     {{ if .until }}
+	     and created_at < :until
+	     {{ end }}
+	     {{ if .pathPrefix }}
+	     and request_path like :pathPrefix || '%' escape '\'
+	     {{ end }}
+	     {{ if .tagsAny }}
+	     and exists (
+	         select 1 from json_each(tags) where value in (:tagsAny)
+	     )
+	     {{ end }}
+	     {{ if .tagsAll }}
+	     and (
+	         select count(distinct value) from json_each(tags) where value in (:tagsAll)
+	     ) = {{ len .tagsAll }}
+	     {{ end }}
+	     {{ if .categoryUnset }}
+	     and (category is null or category = '')
+	     {{ end }}
+	     {{ if .hasSystemPrompt }}
+	     and iif(json_valid(request_body), json_extract(request_body, '$.messages[0].role'), null) = 'system'
+	     {{ end }}
+	     {{ if .noSystemPrompt }}
+	     and coalesce(iif(json_valid(request_body), json_extract(request_body, '$.messages[0].role'), null), '') != 'system'
+	     {{ end }}
+	     {{ if .hashPrefix }}
+	     and request_body_hash like :hashPrefix || '%'
+	     {{ end }}
+	     {{ if .systemPromptContains }}
+	     and iif(json_valid(request_body), json_extract(request_body, '$.messages[0].role'), null) = 'system'
+	     and exists (
+	         select 1 from json_each(:systemPromptContains)
+	         where iif(json_valid(request_body), json_extract(request_body, '$.messages[0].content'), null) like '%' || value || '%' escape '\'
+	     )
 	     {{ end }}
+	   order by id
+	   {{ if .limit }}
+	   limit :limit
+	   {{ end }}
 	   ;
 	*/
-	GetRequest(
-		id int64,
-		chatcmpl string,
-		requestid string,
-	) (*Request, error)
+	GetRequestsByRange(
+		idFrom int64,
+		idTo int64,
+		uid string,
+		uids []string,
+		since string,
+		until string,
+		pathPrefix string,
+		tagsAny []string,
+		tagsAll []string,
+		categoryUnset bool,
+		hasSystemPrompt bool,
+		noSystemPrompt bool,
+		hashPrefix string,
+		systemPromptContains string,
+		limit int64,
+	) ([]*Request, error)
+
+	// StartLiveStream exec named const
+	// insert or replace into moonshot_live_streams (moonshot_id, response_body) values (:moonshotID, :chunks);
+	StartLiveStream(moonshotID string, chunks string) error
+
+	// AppendLiveStream exec named const
+	// update moonshot_live_streams set response_body = response_body || :chunks where moonshot_id = :moonshotID;
+	AppendLiveStream(moonshotID string, chunks string) error
+
+	// FinishLiveStream exec named const
+	// delete from moonshot_live_streams where moonshot_id = :moonshotID;
+	FinishLiveStream(moonshotID string) error
+
+	// ClearLiveStreams exec const
+	// delete from moonshot_live_streams;
+	ClearLiveStreams() error
+
+	// GetLiveStream query one named const
+	// select response_body from moonshot_live_streams where moonshot_id = :moonshotID;
+	GetLiveStream(moonshotID string) (string, error)
+
+	// SetTags exec named const
+	// update moonshot_requests set tags = :tags where id = :id;
+	SetTags(id int64, tags Tags) error
+
+	// SetCategory exec named const
+	// update moonshot_requests set category = :category where id = :id;
+	SetCategory(id int64, category sql.NullString) error
+
+	// SetDerivedFields exec named const
+	/*
+	   update moonshot_requests
+	   set moonshot_id        = :moonshotID,
+	       model              = :model,
+	       system_fingerprint = :systemFingerprint,
+	       rate_limit         = :rateLimit,
+	       request_body_hash  = :requestBodyHash
+	   where id = :id;
+	*/
+	SetDerivedFields(id int64, moonshotID sql.NullString, model sql.NullString, systemFingerprint sql.NullString, rateLimit sql.NullString, requestBodyHash sql.NullString) error
+
+	// SetNote exec named const
+	// update moonshot_requests set note = :note where id = :id;
+	SetNote(id int64, note sql.NullString) error
+
+	// InsertBenchmarkRun exec named const
+	/*
+	   insert into benchmark_runs (
+	       request_id, replay_id, compare_model, score, threshold
+	   ) values (
+	       :requestID, :replayID, :compareModel, :score, :threshold
+	   );
+	*/
+	InsertBenchmarkRun(requestID int64, replayID sql.NullInt64, compareModel string, score float64, threshold float64) error
 
 	// SetCache exec named const
 	/*
@@ -393,6 +1181,102 @@ type Persistence interface {
 	RemoveInactiveCaches(kIdent string, before string) ([]string, error)
 }
 
+// sqliteMaxVariables is the most parameters a statement may have in older
+// SQLite builds, longer lists of identifiers are queried in chunks.
+const sqliteMaxVariables = 999
+
+// insertColumns are the columns set by InsertRequestBatch, in the order of the
+// values of Request.ToArgs.
+var insertColumns = []string{
+	"request_method",
+	"request_path",
+	"request_query",
+	"request_content_type",
+	"request_id",
+	"moonshot_id",
+	"moonshot_gid",
+	"moonshot_uid",
+	"moonshot_request_id",
+	"moonshot_server_timing",
+	"response_status_code",
+	"response_content_type",
+	"request_header",
+	"request_body",
+	"response_header",
+	"response_body",
+	"error",
+	"response_ttft",
+	"response_tpot",
+	"response_otps",
+	"latency",
+	"endpoint",
+	"model",
+	"system_fingerprint",
+	"request_body_size",
+	"rate_limit",
+	"original_model",
+	"parent_id",
+	"tags",
+	"category",
+	"note",
+	"trace_id",
+	"raw_request_header",
+	"request_body_hash",
+	"credential_hash",
+	"created_at",
+}
+
+// insertRowsQuery returns the statement inserting rows requests at once.
+func insertRowsQuery(rows int) string {
+	values := "(" + strings.TrimSuffix(strings.Repeat("?,", len(insertColumns)), ",") + ")"
+	return "insert into moonshot_requests (" + strings.Join(insertColumns, ",") + ") values " +
+		strings.TrimSuffix(strings.Repeat(values+",", rows), ",")
+}
+
+// InsertRequestBatch inserts the requests in a single transaction, as many rows
+// at once as the bind variables of a statement allow, with a statement which is
+// prepared once and executed for each chunk of rows.
+func (p *palace) InsertRequestBatch(requests []*Request) error {
+	rowsPerStatement := sqliteMaxVariables / len(insertColumns)
+	tx, err := p.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var (
+		stmt         *sqlx.Stmt
+		preparedRows int
+		args         = make([]any, 0, rowsPerStatement*len(insertColumns))
+	)
+	defer func() {
+		if stmt != nil {
+			stmt.Close()
+		}
+	}()
+	for len(requests) > 0 {
+		chunk := requests[:min(rowsPerStatement, len(requests))]
+		requests = requests[len(chunk):]
+		// Only the last chunk may have fewer rows, and so another statement.
+		if len(chunk) != preparedRows {
+			if stmt != nil {
+				stmt.Close()
+			}
+			if stmt, err = tx.Preparex(insertRowsQuery(len(chunk))); err != nil {
+				return err
+			}
+			preparedRows = len(chunk)
+		}
+		args = args[:0]
+		for _, request := range chunk {
+			args = append(args, request.ToArgs()...)
+		}
+		if _, err = stmt.Exec(args...); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
 type Request struct {
 	ID                   int64           `db:"id"`
 	RequestMethod        string          `db:"request_method"`
@@ -418,23 +1302,52 @@ type Request struct {
 	CreatedAt            SqliteTime      `db:"created_at"`
 	Latency              sql.NullInt64   `db:"latency"`
 	Endpoint             sql.NullString  `db:"endpoint"`
+	Model                sql.NullString  `db:"model"`
+	SystemFingerprint    sql.NullString  `db:"system_fingerprint"`
+	RequestBodySize      sql.NullInt64   `db:"request_body_size"`
+	RateLimit            sql.NullString  `db:"rate_limit"`
+	OriginalModel        sql.NullString  `db:"original_model"`
+	ParentID             sql.NullInt64   `db:"parent_id"`
+	Tags                 Tags            `db:"tags"`
+	Category             sql.NullString  `db:"category"`
+	Note                 sql.NullString  `db:"note"`
+	TraceID              sql.NullString  `db:"trace_id"`
+	RawRequestHeader     sql.NullString  `db:"raw_request_header"`
+	RequestBodyHash      sql.NullString  `db:"request_body_hash"`
+	CredentialHash       sql.NullString  `db:"credential_hash"`
 
 	// Extra Fields
 
-	Category string   `db:"-"`
-	Tags     []string `db:"-"`
+	// PrettyHeaders marshals headers as objects instead of raw header strings.
+	PrettyHeaders bool `db:"-"`
+	// CanonicalHeaders marshals the request header as forwarded, with canonical
+	// names in sorted order, instead of as received.
+	CanonicalHeaders bool `db:"-"`
+	// BinaryMode is one of the binary* modes, which decides how bodies that are
+	// not valid UTF-8 are marshaled, base64 is used if it is empty.
+	BinaryMode string `db:"-"`
 }
 
 func (r *Request) MarshalJSON() ([]byte, error) {
 	type RequestMarshaler struct {
-		Url    string `json:"url"`
-		Header string `json:"header"`
-		Body   any    `json:"body"`
+		Url          string `json:"url"`
+		Header       any    `json:"header"`
+		Body         any    `json:"body"`
+		BodyEncoding string `json:"body_encoding,omitempty"`
+		// Parts describes the parts of multipart/form-data bodies.
+		Parts []multipartPartMarshaler `json:"parts,omitempty"`
 	}
 	type ResponseMarshaler struct {
-		Status string `json:"status"`
-		Header string `json:"header"`
-		Body   any    `json:"body"`
+		Status       string `json:"status"`
+		Header       any    `json:"header"`
+		Body         any    `json:"body"`
+		BodyEncoding string `json:"body_encoding,omitempty"`
+	}
+	var requestHeader, responseHeader any = r.RequestHeader.String, r.ResponseHeader.String
+	if r.PrettyHeaders {
+		requestHeader, responseHeader = flattenHeader(r.RequestHeaders()), flattenHeader(r.ResponseHeaders())
+	} else if !r.CanonicalHeaders && r.RawRequestHeader.Valid {
+		requestHeader = r.RawRequestHeader.String
 	}
 	type Marshaler struct {
 		Metadata map[string]string  `json:"metadata"`
@@ -442,26 +1355,154 @@ func (r *Request) MarshalJSON() ([]byte, error) {
 		Response *ResponseMarshaler `json:"response"`
 		Error    string             `json:"error,omitempty"`
 		Category string             `json:"category,omitempty"`
-		Tags     []string           `json:"tags,omitempty"`
+		Tags     Tags               `json:"tags,omitempty"`
+	}
+	requestBody, requestBodyEncoding := marshalBinaryBody(r.RequestBody.String, r.BinaryMode)
+	responseBody, responseBodyEncoding := marshalBinaryBody(r.ResponseBody.String, r.BinaryMode)
+	var requestParts []multipartPartMarshaler
+	if r.IsMultipart() {
+		if parts, err := r.MultipartParts(); err == nil {
+			requestParts = marshalMultipartParts(parts)
+		}
 	}
 	return json.Marshal(&Marshaler{
 		Metadata: r.Metadata(),
 		Request: &RequestMarshaler{
-			Url:    r.Url(),
-			Header: r.RequestHeader.String,
-			Body:   marshalBody(r.RequestBody.String),
+			Url:          r.Url(),
+			Header:       requestHeader,
+			Body:         requestBody,
+			BodyEncoding: requestBodyEncoding,
+			Parts:        requestParts,
 		},
 		Response: &ResponseMarshaler{
-			Status: r.Status(),
-			Header: r.ResponseHeader.String,
-			Body:   marshalBody(r.ResponseBody.String),
+			Status:       r.Status(),
+			Header:       responseHeader,
+			Body:         responseBody,
+			BodyEncoding: responseBodyEncoding,
 		},
 		Error:    r.Error.String,
-		Category: r.Category,
+		Category: r.Category.String,
 		Tags:     r.Tags,
 	})
 }
 
+// Diff compares a previously exported request with the current one, and returns
+// the difference in the unified format, both are indented in the same way before
+// comparison so that formatting options used in export do not matter.
+func (r *Request) Diff(previousName string, previous []byte) (string, error) {
+	current, err := json.Marshal(r)
+	if err != nil {
+		return "", err
+	}
+	previousLines, err := indentLines(previous)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", previousName, err)
+	}
+	currentLines, err := indentLines(current)
+	if err != nil {
+		return "", err
+	}
+	return diff.Unified(previousName, r.Ident(), previousLines, currentLines, 3), nil
+}
+
+// BodyHash returns the hash of the request body stored at capture time, or
+// computes it for requests of databases that predate the column, except for
+// truncated bodies, for which it returns an empty string.
+func (r *Request) BodyHash() string {
+	if r.RequestBodyHash.Valid {
+		return r.RequestBodyHash.String
+	}
+	// The hash of a truncated body would not match the one of the whole body.
+	if r.IsRequestBodyTruncated() {
+		return ""
+	}
+	return hashRequestBody(r.RequestBody.String)
+}
+
+// hashRequestBody returns the SHA-256 of the body in canonical form, so that
+// equivalent bodies have the same hash, bodies that are not JSON are hashed as
+// they are.
+func hashRequestBody(body string) string {
+	data := []byte(body)
+	if normalized, err := canonical.Compact(data); err == nil {
+		data = normalized
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// hashCredential returns the SHA-256 of the Authorization header, with which
+// responses are only served by --response-cache to the same API key, or an
+// empty string if there is none.
+func hashCredential(authorization string) string {
+	if authorization == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(authorization))
+	return hex.EncodeToString(sum[:])
+}
+
+func indentLines(data []byte) ([]string, error) {
+	indented, err := canonical.Indent(data, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(string(indented), "\n"), nil
+}
+
+// NormalizeJSON rewrites the request and response bodies in canonical form,
+// bodies that are not JSON, such as event streams, are left untouched.
+func (r *Request) NormalizeJSON() {
+	for _, body := range []*sql.NullString{&r.RequestBody, &r.ResponseBody} {
+		if normalized, err := canonical.Compact([]byte(body.String)); err == nil {
+			body.String = string(normalized)
+		}
+	}
+}
+
+// ToArgs implements defc.ToArgs, the order of the values is consistent with
+// insertColumns and the columns listed in InsertRequest.
+func (r *Request) ToArgs() []any {
+	return []any{
+		r.RequestMethod,
+		r.RequestPath,
+		r.RequestQuery,
+		r.RequestContentType,
+		r.RequestID,
+		r.MoonshotID,
+		r.MoonshotGID,
+		r.MoonshotUID,
+		r.MoonshotRequestID,
+		r.MoonshotServerTiming,
+		r.ResponseStatusCode,
+		r.ResponseContentType,
+		r.RequestHeader,
+		r.RequestBody,
+		r.ResponseHeader,
+		r.ResponseBody,
+		r.Error,
+		r.ResponseTTFT,
+		r.ResponseTPOT,
+		r.ResponseOTPS,
+		r.Latency,
+		r.Endpoint,
+		r.Model,
+		r.SystemFingerprint,
+		r.RequestBodySize,
+		r.RateLimit,
+		r.OriginalModel,
+		r.ParentID,
+		r.Tags,
+		r.Category,
+		r.Note,
+		r.TraceID,
+		r.RawRequestHeader,
+		sql.NullString{String: r.BodyHash(), Valid: r.RequestBody.Valid && r.BodyHash() != ""},
+		r.CredentialHash,
+		r.CreatedAt.Format(time.DateTime),
+	}
+}
+
 func (r *Request) Ident() string {
 	if chatcmpl := r.ChatCmpl(); chatcmpl != "" {
 		return "chatcmpl=" + chatcmpl
@@ -476,6 +1517,21 @@ func (r *Request) IsChat() bool {
 	return strings.HasSuffix(r.RequestPath, "/chat/completions")
 }
 
+// IsToolCall reports whether the request offers tools or functions to the model.
+func (r *Request) IsToolCall() bool {
+	if !gjson.Valid(r.RequestBody.String) {
+		return false
+	}
+	results := gjson.GetMany(r.RequestBody.String, "tools", "functions")
+	return results[0].Exists() || results[1].Exists()
+}
+
+// HasToolCallResponse reports whether the model responded with tool calls.
+func (r *Request) HasToolCallResponse() bool {
+	finishReason, err := r.FinishReason()
+	return err == nil && finishReason == "tool_calls"
+}
+
 func (r *Request) HasError() bool {
 	return !r.ResponseStatusCode.Valid || r.ResponseStatusCode.Int64 >= http.StatusBadRequest || r.Error.Valid
 }
@@ -487,6 +1543,151 @@ func (r *Request) ChatCmpl() string {
 	return ""
 }
 
+// ModelName returns the model that served the request, requests captured before
+// the model column was added fall back to the model specified in the request body.
+func (r *Request) ModelName() string {
+	if r.Model.Valid {
+		return r.Model.String
+	}
+	if r.RequestBody.Valid {
+		return gjson.Get(r.RequestBody.String, "model").String()
+	}
+	return ""
+}
+
+var contextLengthSuffix = regexp.MustCompile(`-(\d+k|auto)$`)
+
+// ModelFamily strips the context length suffix from the model name, so that
+// moonshot-v1-8k and moonshot-v1-128k both belong to moonshot-v1.
+func ModelFamily(model string) string {
+	return contextLengthSuffix.ReplaceAllString(model, "")
+}
+
+// ConversationLength returns the number of turns in the conversation, a turn is
+// a user message together with the assistant message answering it, so it is the
+// number of user messages.
+func (r *Request) ConversationLength() int {
+	if !r.IsChat() || !r.RequestBody.Valid {
+		return 0
+	}
+	var turns int
+	gjson.Get(r.RequestBody.String, "messages").ForEach(func(_, message gjson.Result) bool {
+		if message.Get("role").String() == "user" {
+			turns++
+		}
+		return true
+	})
+	return turns
+}
+
+// TotalTokens returns usage.total_tokens reported in the response, streaming
+// responses are merged first, ok is false if no usage is reported.
+func (r *Request) TotalTokens() (tokens int64, ok bool) {
+	return r.usage("total_tokens")
+}
+
+func (r *Request) usage(field string) (tokens int64, ok bool) {
+	if !r.ResponseBody.Valid {
+		return 0, false
+	}
+	body := r.ResponseBody.String
+	if r.ResponseContentType.String == "text/event-stream" {
+		body = mergeCompletion(body)
+	}
+	for _, path := range []string{"usage." + field, "choices.0.usage." + field} {
+		if result := gjson.Get(body, path); result.Exists() {
+			return result.Int(), true
+		}
+	}
+	return 0, false
+}
+
+// TotalCost returns the cost of the request with prices per 1000 tokens, an
+// error is returned if the response reports no usage.
+func (r *Request) TotalCost(pricePerPromptToken, pricePerCompletionToken float64) (float64, error) {
+	promptTokens, ok := r.usage("prompt_tokens")
+	if !ok {
+		return 0, errors.New("prompt_tokens is unavailable")
+	}
+	completionTokens, ok := r.usage("completion_tokens")
+	if !ok {
+		return 0, errors.New("completion_tokens is unavailable")
+	}
+	return (float64(promptTokens)*pricePerPromptToken + float64(completionTokens)*pricePerCompletionToken) / 1000, nil
+}
+
+// PromptTokensEstimate roughly estimates the number of prompt tokens from the
+// messages in the request body, about 4 ASCII characters or 1 other character
+// per token, it is only meant to be used when no usage is reported.
+func (r *Request) PromptTokensEstimate() int64 {
+	if !r.RequestBody.Valid {
+		return 0
+	}
+	var asciiBytes, otherRunes int64
+	count := func(s string) {
+		for _, ch := range s {
+			if ch < utf8.RuneSelf {
+				asciiBytes++
+			} else {
+				otherRunes++
+			}
+		}
+	}
+	gjson.Get(r.RequestBody.String, "messages").ForEach(func(_, message gjson.Result) bool {
+		count(message.Get("content").String())
+		message.Get("tool_calls.#.function.arguments").ForEach(func(_, arguments gjson.Result) bool {
+			count(arguments.String())
+			return true
+		})
+		return true
+	})
+	count(gjson.Get(r.RequestBody.String, "tools").Raw)
+	return (asciiBytes+3)/4 + otherRunes
+}
+
+// RateLimits returns the X-Ratelimit-* headers of the response, keyed by the
+// rest of the header name in snake case, such as "remaining_requests".
+func (r *Request) RateLimits() map[string]string {
+	if !r.RateLimit.Valid {
+		return nil
+	}
+	var rateLimits map[string]string
+	json.Unmarshal([]byte(r.RateLimit.String), &rateLimits)
+	return rateLimits
+}
+
+// IsNearRateLimit reports whether the request was rejected with 429, or the
+// remaining requests or tokens dropped to ratio of the limit or below.
+func (r *Request) IsNearRateLimit(ratio float64) bool {
+	if r.ResponseStatusCode.Int64 == http.StatusTooManyRequests {
+		return true
+	}
+	rateLimits := r.RateLimits()
+	for _, kind := range []string{"requests", "tokens"} {
+		limit, errLimit := strconv.ParseFloat(rateLimits["limit_"+kind], 64)
+		remaining, errRemaining := strconv.ParseFloat(rateLimits["remaining_"+kind], 64)
+		if errLimit == nil && errRemaining == nil && limit > 0 && remaining <= limit*ratio {
+			return true
+		}
+	}
+	return false
+}
+
+// IsRequestBodyTruncated reports whether only part of the request body is stored,
+// request_body_size is recorded only when the body exceeds --max-body-store.
+func (r *Request) IsRequestBodyTruncated() bool {
+	return r.RequestBodySize.Valid
+}
+
+// BodySize returns the size in bytes of the request body as it was received,
+// which is larger than the stored body if it is truncated.
+func (r *Request) BodySize() int64 {
+	if r.IsRequestBodyTruncated() {
+		return r.RequestBodySize.Int64
+	}
+	return int64(len(r.RequestBody.String))
+}
+
 func (r *Request) Url() (url string) {
 	var requestEndpoint string
 	if r.Endpoint.Valid {
@@ -501,6 +1702,135 @@ func (r *Request) Url() (url string) {
 	return url
 }
 
+// Header parses the recorded request header, headers that cannot be sent as is
+// such as Content-Length are removed, so is Content-Encoding as compressed
+// request bodies are stored decompressed.
+func (r *Request) Header() http.Header {
+	header := r.RequestHeaders()
+	header.Del("Content-Length")
+	header.Del("Content-Encoding")
+	header.Del("X-Unix-Micro")
+	return header
+}
+
+// ExcludeHeaders removes the headers of the names, case-insensitive, from the
+// recorded request and response headers.
+func (r *Request) ExcludeHeaders(names []string) {
+	for _, header := range []*sql.NullString{&r.RequestHeader, &r.RawRequestHeader, &r.ResponseHeader} {
+		if !header.Valid {
+			continue
+		}
+		lines := strings.SplitAfter(header.String, "\r\n")
+		lines = slices.DeleteFunc(lines, func(line string) bool {
+			name, _, ok := strings.Cut(line, ":")
+			return ok && slices.ContainsFunc(names, func(excluded string) bool {
+				return strings.EqualFold(strings.TrimSpace(name), excluded)
+			})
+		})
+		header.String = strings.Join(lines, "")
+	}
+}
+
+// anonymizeUID returns a pseudonym of the uid, the same uid always has the same
+// pseudonym so that the requests of a user can still be told apart.
+func anonymizeUID(uid string) string {
+	sum := sha256.Sum256([]byte(uid))
+	return "anon-" + hex.EncodeToString(sum[:8])
+}
+
+// AnonymizeUID replaces the uid of the request, and the Msh-Uid response header
+// it is taken from, with its pseudonym.
+func (r *Request) AnonymizeUID() {
+	if !r.MoonshotUID.Valid || r.MoonshotUID.String == "" {
+		return
+	}
+	pseudonym := anonymizeUID(r.MoonshotUID.String)
+	if r.ResponseHeader.Valid {
+		lines := strings.SplitAfter(r.ResponseHeader.String, "\r\n")
+		for i, line := range lines {
+			if name, _, ok := strings.Cut(line, ":"); ok && strings.EqualFold(strings.TrimSpace(name), "Msh-Uid") {
+				lines[i] = name + ": " + pseudonym + "\r\n"
+			}
+		}
+		r.ResponseHeader.String = strings.Join(lines, "")
+	}
+	r.MoonshotUID.String = pseudonym
+}
+
+// headerField is a header line of the request as it was received.
+type headerField struct {
+	Name, Value string
+}
+
+// rawHeaderExcluded are the headers that Header removes, and those that are
+// never part of the parsed header, in lower case.
+var rawHeaderExcluded = []string{"content-length", "content-encoding", "x-unix-micro", "host", "transfer-encoding"}
+
+// RawHeader returns the header of the request in the order and casing it was
+// received, without the headers removed by Header. It returns nil for requests
+// captured before the raw header was recorded.
+func (r *Request) RawHeader() []headerField {
+	if !r.RawRequestHeader.Valid {
+		return nil
+	}
+	var fields []headerField
+	for _, line := range strings.Split(r.RawRequestHeader.String, "\r\n") {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok || slices.Contains(rawHeaderExcluded, strings.ToLower(name)) {
+			continue
+		}
+		fields = append(fields, headerField{Name: name, Value: strings.TrimSpace(value)})
+	}
+	return fields
+}
+
+// RequestHeaders parses the recorded request header as is.
+func (r *Request) RequestHeaders() http.Header {
+	return parseHeader(r.RequestHeader)
+}
+
+// ResponseHeaders parses the recorded response header as is.
+func (r *Request) ResponseHeaders() http.Header {
+	return parseHeader(r.ResponseHeader)
+}
+
+func parseHeader(raw sql.NullString) http.Header {
+	if !raw.Valid {
+		return make(http.Header)
+	}
+	mimeHeader, _ := textproto.
+		NewReader(bufio.NewReader(strings.NewReader(raw.String + "\r\n\r\n"))).
+		ReadMIMEHeader()
+	header := http.Header(mimeHeader)
+	if header == nil {
+		header = make(http.Header)
+	}
+	return header
+}
+
+// flattenHeader joins the values of each header with ", ", the keys are sorted
+// when the map is marshaled.
+func flattenHeader(header http.Header) map[string]string {
+	flattened := make(map[string]string, len(header))
+	for name, values := range header {
+		flattened[name] = strings.Join(values, ", ")
+	}
+	return flattened
+}
+
+// NetworkLatency returns the part of the latency not spent by Moonshot AI
+// processing the request, as reported in the Server-Timing header, which is the
+// time in transit, ok is false if either is not recorded.
+func (r *Request) NetworkLatency() (latency time.Duration, ok bool) {
+	if !r.Latency.Valid || !r.MoonshotServerTiming.Valid {
+		return 0, false
+	}
+	latency = time.Duration(r.Latency.Int64) - time.Duration(r.MoonshotServerTiming.Int64)*time.Millisecond
+	// Latencies are measured on different clocks, the server one may be
+	// rounded up beyond the total.
+	return max(latency, 0), true
+}
+
 func (r *Request) Status() string {
 	if r.ResponseStatusCode.Int64 == 0 {
 		return ""
@@ -529,6 +1859,9 @@ func (r *Request) Metadata() (metadata map[string]string) {
 	if r.MoonshotServerTiming.Valid {
 		metadata["server_timing"] = strconv.FormatInt(r.MoonshotServerTiming.Int64, 10)
 	}
+	if networkLatency, ok := r.NetworkLatency(); ok {
+		metadata["network_latency"] = strconv.FormatInt(networkLatency.Milliseconds(), 10)
+	}
 	if r.RequestContentType.Valid {
 		metadata["request_content_type"] = r.RequestContentType.String
 	}
@@ -551,17 +1884,160 @@ func (r *Request) Metadata() (metadata map[string]string) {
 	if r.Endpoint.Valid {
 		metadata["endpoint"] = r.Endpoint.String
 	}
+	if r.Model.Valid {
+		metadata["model"] = r.Model.String
+	}
+	if r.SystemFingerprint.Valid {
+		metadata["system_fingerprint"] = r.SystemFingerprint.String
+	}
+	if r.OriginalModel.Valid {
+		metadata["original_model"] = r.OriginalModel.String
+	}
+	if r.ParentID.Valid {
+		metadata["parent_id"] = strconv.FormatInt(r.ParentID.Int64, 10)
+	}
+	if r.Note.Valid {
+		metadata["note"] = r.Note.String
+	}
+	if r.TraceID.Valid {
+		metadata["trace_id"] = r.TraceID.String
+	}
+	if r.RequestBodyHash.Valid {
+		metadata["request_body_hash"] = r.RequestBodyHash.String
+	}
+	if r.IsRequestBodyTruncated() {
+		metadata["request_body_truncated"] = "true"
+		metadata["request_body_size"] = strconv.FormatInt(r.RequestBodySize.Int64, 10)
+	}
+	for name, value := range r.RateLimits() {
+		metadata["ratelimit_"+name] = value
+	}
 	return metadata
 }
 
-func (r *Request) Inspection() (inspection map[string]string) {
+// AssistantReply returns choices[0].message.content of the response, content in
+// the form of content parts is joined from its text parts. Streaming responses
+// must be merged into a completion first, as ListRequests does.
+func (r *Request) AssistantReply() (string, error) {
+	if !r.ResponseBody.Valid {
+		return "", errors.New("response body is empty")
+	}
+	if !gjson.Valid(r.ResponseBody.String) {
+		if r.ResponseContentType.String == "text/event-stream" {
+			return "", errors.New("streaming response has not been merged into a completion")
+		}
+		return "", errors.New("response body is not a valid JSON")
+	}
+	content := gjson.Get(r.ResponseBody.String, "choices.0.message.content")
+	if !content.Exists() {
+		// Merged streaming responses keep the message in delta.
+		content = gjson.Get(r.ResponseBody.String, "choices.0.delta.content")
+	}
+	switch {
+	case !content.Exists():
+		return "", errors.New("no assistant message found in response body")
+	case content.IsArray():
+		var reply strings.Builder
+		content.ForEach(func(_, part gjson.Result) bool {
+			if part.Get("type").String() == "text" {
+				reply.WriteString(part.Get("text").String())
+			}
+			return true
+		})
+		return reply.String(), nil
+	default:
+		return content.String(), nil
+	}
+}
+
+// ToLangChainMessage converts the messages of a chat request and the reply in
+// the response to LangChain messages, as {"messages": [...]}, the reply has the
+// model, finish reason and usage in its response_metadata.
+func (r *Request) ToLangChainMessage() (map[string]any, error) {
+	if !r.IsChat() {
+		return nil, errors.New("not a chat request: " + r.Ident())
+	}
+	messages := make([]langchain.Message, 0)
+	var err error
+	gjson.Get(r.RequestBody.String, "messages").ForEach(func(index, message gjson.Result) bool {
+		var converted langchain.Message
+		if converted, err = langchain.FromChat([]byte(message.Raw)); err != nil {
+			err = fmt.Errorf("messages[%d]: %w", index.Int(), err)
+			return false
+		}
+		messages = append(messages, converted)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := r.ResponseBody.String
+	if r.ResponseContentType.String == "text/event-stream" && !gjson.Valid(response) {
+		response = mergeCompletion(response)
+	}
+	reply := gjson.Get(response, "choices.0.message")
+	if !reply.Exists() {
+		// Merged streaming responses keep the message in delta.
+		reply = gjson.Get(response, "choices.0.delta")
+	}
+	if reply.IsObject() {
+		replyMessage := reply.Raw
+		if !reply.Get("role").Exists() {
+			replyMessage, _ = sjson.Set(replyMessage, "role", "assistant")
+		}
+		converted, err := langchain.FromChat([]byte(replyMessage))
+		if err != nil {
+			return nil, fmt.Errorf("reply: %w", err)
+		}
+		metadata := converted["data"].(map[string]any)["response_metadata"].(map[string]any)
+		metadata["model_name"] = gjson.Get(response, "model").String()
+		metadata["finish_reason"] = gjson.Get(response, "choices.0.finish_reason").String()
+		if usage := gjson.Get(response, "usage"); usage.IsObject() {
+			metadata["token_usage"] = json.RawMessage(usage.Raw)
+		}
+		converted["data"].(map[string]any)["id"] = gjson.Get(response, "id").String()
+		messages = append(messages, converted)
+	}
+	return map[string]any{"messages": messages}, nil
+}
+
+// FinishReason returns choices[0].finish_reason of the response, for streaming
+// responses it is taken from the last chunk that has one.
+func (r *Request) FinishReason() (string, error) {
+	if !r.ResponseBody.Valid {
+		return "", errors.New("response body is empty")
+	}
+	if gjson.Valid(r.ResponseBody.String) {
+		if finishReason := gjson.Get(r.ResponseBody.String, "choices.0.finish_reason"); finishReason.Type == gjson.String {
+			return finishReason.String(), nil
+		}
+		return "", errors.New("no finish_reason found in response body")
+	}
+	var finishReason string
+	scanner := bufio.NewScanner(strings.NewReader(r.ResponseBody.String))
+	scanner.Split(splitFunc)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(bytes.TrimPrefix(bytes.TrimSpace(scanner.Bytes()), []byte("data:")))
+		if result := gjson.GetBytes(line, "choices.0.finish_reason"); result.Type == gjson.String {
+			finishReason = result.String()
+		}
+	}
+	if finishReason == "" {
+		return "", errors.New("no finish_reason found in response body")
+	}
+	return finishReason, nil
+}
+
+// Inspection returns the columns printed by inspect, bodies are truncated to
+// maxBodyBytes unless it is 0.
+func (r *Request) Inspection(maxBodyBytes int) (inspection map[string]string) {
 	inspection = make(map[string]string, 8)
 	metadataJSON, _ := json.MarshalIndent(r.Metadata(), "", "    ")
 	inspection["metadata"] = string(metadataJSON)
 	inspection["request_header"] = r.RequestHeader.String
-	inspection["request_body"] = formatJSON(r.RequestBody.String)
+	inspection["request_body"] = truncateDisplay(formatJSON(r.RequestBody.String), maxBodyBytes)
 	inspection["response_header"] = r.ResponseHeader.String
-	responseBodyJSON := formatJSON(r.ResponseBody.String)
+	responseBodyJSON := truncateDisplay(formatJSON(r.ResponseBody.String), maxBodyBytes)
 	inspection["response_body"] = responseBodyJSON
 	if r.Error.Valid {
 		inspection["error"] = r.Error.String
@@ -571,34 +2047,47 @@ func (r *Request) Inspection() (inspection map[string]string) {
 	return inspection
 }
 
-func (r *Request) PrintRequest(w io.Writer) {
+func (r *Request) PrintRequest(w io.Writer, maxBodyBytes int) {
 	fmt.Fprintf(w, "%s %s HTTP/1.1\n", r.RequestMethod, r.Url())
 	if r.RequestHeader.Valid {
 		fmt.Fprintf(w, "%s\n", strings.TrimSpace(r.RequestHeader.String))
 		if r.RequestBody.Valid {
 			w.Write([]byte("\n"))
-			w.Write([]byte(formatJSON(r.RequestBody.String)))
+			w.Write([]byte(truncateDisplay(formatJSON(r.RequestBody.String), maxBodyBytes)))
 			w.Write([]byte("\n"))
 		}
 	}
 }
 
-func (r *Request) PrintResponse(w io.Writer, merge bool) {
+func (r *Request) PrintResponse(w io.Writer, merge bool, maxBodyBytes int) {
 	fmt.Fprintf(w, "HTTP/1.1 %s\n", r.Status())
 	if r.ResponseHeader.Valid {
 		fmt.Fprintf(w, "%s\n", strings.TrimSpace(r.ResponseHeader.String))
 		if r.ResponseBody.Valid {
 			w.Write([]byte("\n"))
+			body := r.ResponseBody.String
 			if merge && r.ResponseContentType.String == "text/event-stream" {
-				w.Write([]byte(formatJSON(mergeCompletion(r.ResponseBody.String))))
-			} else {
-				w.Write([]byte(formatJSON(r.ResponseBody.String)))
+				body = mergeCompletion(body)
 			}
+			w.Write([]byte(truncateDisplay(formatJSON(body), maxBodyBytes)))
 			w.Write([]byte("\n"))
 		}
 	}
 }
 
+// truncateDisplay keeps the first maxBytes bytes of a body to be displayed,
+// without splitting a UTF-8 character, 0 means no limit.
+func truncateDisplay(body string, maxBytes int) string {
+	if maxBytes <= 0 || len(body) <= maxBytes {
+		return body
+	}
+	n := maxBytes
+	for n > 0 && !utf8.RuneStart(body[n]) {
+		n--
+	}
+	return body[:n] + fmt.Sprintf("... (truncated, full size %d bytes)", len(body))
+}
+
 func marshalBody(body string) any {
 	if raw := json.RawMessage(body); json.Valid(raw) {
 		return raw
@@ -606,6 +2095,31 @@ func marshalBody(body string) any {
 	return body
 }
 
+const (
+	binaryBase64 = "base64"
+	binaryHex    = "hex"
+	binarySkip   = "skip"
+)
+
+var binaryModes = []string{binaryBase64, binaryHex, binarySkip}
+
+// marshalBinaryBody marshals bodies that are not valid UTF-8 according to mode
+// instead of letting encoding/json replace the invalid bytes, encoding tells how
+// the body is encoded, and is empty for valid UTF-8 bodies.
+func marshalBinaryBody(body string, mode string) (value any, encoding string) {
+	if utf8.ValidString(body) {
+		return marshalBody(body), ""
+	}
+	switch mode {
+	case binaryHex:
+		return hex.EncodeToString([]byte(body)), binaryHex
+	case binarySkip:
+		return nil, "skipped"
+	default:
+		return base64.StdEncoding.EncodeToString([]byte(body)), binaryBase64
+	}
+}
+
 func formatJSON(s string) string {
 	jsonBytes, err := json.MarshalIndent(json.RawMessage(s), "", "    ")
 	if err != nil {
@@ -642,6 +2156,46 @@ func (t *SqliteTime) Scan(src any) (err error) {
 	return nil
 }
 
+// Tags are stored as a JSON array so that requests can be filtered by tags with
+// the JSON functions of SQLite.
+type Tags []string
+
+func (t *Tags) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*t = nil
+		return nil
+	case string:
+		return json.Unmarshal([]byte(v), t)
+	case []byte:
+		return json.Unmarshal(v, t)
+	default:
+		return fmt.Errorf("cannot convert type %T to tags", src)
+	}
+}
+
+func (t Tags) Value() (driver.Value, error) {
+	if len(t) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal([]string(t))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Add appends the tags not seen before and reports whether any tag is added.
+func (t *Tags) Add(tags ...string) (added bool) {
+	for _, tag := range tags {
+		if tag != "" && !slices.Contains(*t, tag) {
+			*t = append(*t, tag)
+			added = true
+		}
+	}
+	return added
+}
+
 func mergeCompletion(data string) string {
 	completion := completionPool.Get().(map[string]any)
 	defer putCompletion(completion)
@@ -687,13 +2241,36 @@ func splitFunc(data []byte, atEOF bool) (advance int, token []byte, err error) {
 
 type Predicates []string
 
+// predicateFields are the field names usable in predicates besides the
+// columns, latency_ms is in milliseconds. JSON fields are only extracted from
+// valid JSON bodies, as json_extract fails on malformed JSON.
+var predicateFields = map[string]string{
+	"method":            "request_method",
+	"path":              "request_path",
+	"status":            "response_status_code",
+	"stream":            "iif(json_valid(request_body), json_extract(request_body, '$.stream'), null)",
+	"uid":               "moonshot_uid",
+	"gid":               "moonshot_gid",
+	"chatcmpl":          "moonshot_id",
+	"requestid":         "moonshot_request_id",
+	"request_id":        "moonshot_request_id",
+	"server_timing":     "moonshot_server_timing",
+	"requested_at":      "created_at",
+	"latency_ms":        "latency / 1000000",
+	"prompt_tokens":     "iif(json_valid(response_body), json_extract(response_body, '$.usage.prompt_tokens'), null)",
+	"completion_tokens": "iif(json_valid(response_body), json_extract(response_body, '$.usage.completion_tokens'), null)",
+	"tokens":            "iif(json_valid(response_body), json_extract(response_body, '$.usage.total_tokens'), null)",
+	"finish_reason":     "iif(json_valid(response_body), json_extract(response_body, '$.choices[0].finish_reason'), null)",
+	"tools":             "(" + toolCallCondition + ")",
+}
+
 func (p Predicates) Parse() (string, error) {
 	var sqlBuilder strings.Builder
 	for i, predicate := range p {
 		if i > 0 {
 			sqlBuilder.WriteString(" and ")
 		}
-		parsed, err := parser.Parse(predicate)
+		parsed, err := parser.ParseFields(predicate, predicateFields)
 		if err != nil {
 			return "", err
 		}
@@ -702,6 +2279,36 @@ func (p Predicates) Parse() (string, error) {
 	return sqlBuilder.String(), nil
 }
 
+// escapeLike escapes the wildcards of LIKE patterns with a backslash, which is
+// expected to be declared with "escape '\'".
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// likePatterns returns the texts escaped for like as a JSON array, which is
+// expanded with json_each to match any of them, or an empty string if none.
+func likePatterns(texts []string) string {
+	if len(texts) == 0 {
+		return ""
+	}
+	escaped := make([]string, len(texts))
+	for i, text := range texts {
+		escaped[i] = escapeLike(text)
+	}
+	patterns, _ := json.Marshal(escaped)
+	return string(patterns)
+}
+
+// compactTags trims the tags and drops empty and duplicate ones, as tags filters
+// compare the number of matched tags with the number of tags given.
+func compactTags(tags []string) []string {
+	var compacted Tags
+	for _, tag := range tags {
+		compacted.Add(strings.TrimSpace(tag))
+	}
+	return compacted
+}
+
 func sqliteRegexp(pat string, val string) bool {
 	match, _ := regexp.MatchString(pat, val)
 	return match
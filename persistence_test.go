@@ -0,0 +1,100 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// openTestDatabase opens an empty database in a temporary directory as
+// persistence, as openPersistence does for the commands.
+func openTestDatabase(tb testing.TB) {
+	tb.Helper()
+	if err := openDatabase("file:" + filepath.Join(tb.TempDir(), "moonpalace.sqlite")); err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() {
+		persistence.db.Close()
+	})
+}
+
+func testRequests(n int) []*Request {
+	requests := make([]*Request, n)
+	for i := range requests {
+		requests[i] = &Request{
+			RequestMethod:       "POST",
+			RequestPath:         "/v1/chat/completions",
+			RequestContentType:  sql.NullString{String: "application/json", Valid: true},
+			MoonshotID:          sql.NullString{String: fmt.Sprintf("chatcmpl-%d", i), Valid: true},
+			ResponseStatusCode:  sql.NullInt64{Int64: 200, Valid: true},
+			ResponseContentType: sql.NullString{String: "application/json", Valid: true},
+			RequestBody:         sql.NullString{String: fmt.Sprintf(`{"model":"moonshot-v1-8k","messages":[{"role":"user","content":"%d"}]}`, i), Valid: true},
+			ResponseBody:        sql.NullString{String: `{"choices":[{"index":0,"message":{"role":"assistant","content":"ok"}}]}`, Valid: true},
+			Model:               sql.NullString{String: "moonshot-v1-8k", Valid: true},
+			CreatedAt:           SqliteTime{time.Date(2024, 8, 1, 0, 0, i%60, 0, time.UTC)},
+		}
+	}
+	return requests
+}
+
+func TestInsertRequestBatch(t *testing.T) {
+	openTestDatabase(t)
+	// More rows than fit in the bind variables of a single statement, and not a
+	// multiple of the rows of a statement.
+	requests := testRequests(2*sqliteMaxVariables + 1)
+	if err := persistence.InsertRequestBatch(requests); err != nil {
+		t.Fatal(err)
+	}
+	var count int64
+	if err := persistence.db.Get(&count, "select count(*) from moonshot_requests"); err != nil {
+		t.Fatal(err)
+	}
+	if count != int64(len(requests)) {
+		t.Fatalf("expected %d requests, got %d", len(requests), count)
+	}
+	last, err := persistence.GetRequest(count, "", "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := requests[len(requests)-1]
+	if last.RequestBody.String != want.RequestBody.String ||
+		last.MoonshotID.String != want.MoonshotID.String ||
+		!last.CreatedAt.Equal(want.CreatedAt.Time) {
+		t.Errorf("last request was not stored as inserted: %+v", last)
+	}
+}
+
+func TestInsertRequestBatch_Empty(t *testing.T) {
+	openTestDatabase(t)
+	if err := persistence.InsertRequestBatch(nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func BenchmarkInsertRequests(b *testing.B) {
+	const rows = 1000
+	b.Run("individual", func(b *testing.B) {
+		openTestDatabase(b)
+		requests := testRequests(rows)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			for _, request := range requests {
+				if err := persistence.InsertRequestBatch([]*Request{request}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+	b.Run("batch", func(b *testing.B) {
+		openTestDatabase(b)
+		requests := testRequests(rows)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if err := persistence.InsertRequestBatch(requests); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
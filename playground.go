@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tidwall/sjson"
+)
+
+// defaultPlaygroundURL is the playground opened by the links of export --format
+// playground-link.
+const defaultPlaygroundURL = "https://platform.moonshot.cn/playground"
+
+// maxPlaygroundURLLength is the longest link written, longer URLs are truncated
+// or rejected by some browsers and chat apps.
+const maxPlaygroundURLLength = 8000
+
+// playgroundPayload returns the request body without the parameters of the
+// transport, which the playground decides on its own.
+func playgroundPayload(request *Request) (string, error) {
+	if !request.IsChat() {
+		return "", errors.New("not a chat request: " + request.Ident())
+	}
+	if request.IsRequestBodyTruncated() {
+		return "", errors.New("request body is truncated, unable to export a playground link of " + request.Ident())
+	}
+	payload := request.RequestBody.String
+	for _, key := range []string{"stream", "stream_options"} {
+		if deleted, err := sjson.Delete(payload, key); err == nil {
+			payload = deleted
+		}
+	}
+	return payload, nil
+}
+
+// writePlaygroundLink writes a link to baseURL that opens the conversation and
+// parameters of the request, encoded in base64url in the fragment, so that they
+// are not sent to the server. If the link is too long, the conversation is
+// written to a file in fallbackDir instead, to be loaded in the playground, and
+// a note saying so is written in place of the link.
+func writePlaygroundLink(w io.Writer, request *Request, baseURL string, fallbackDir string) error {
+	payload, err := playgroundPayload(request)
+	if err != nil {
+		return err
+	}
+	link := baseURL + "#conversation=" + base64.RawURLEncoding.EncodeToString([]byte(payload))
+	if len(link) <= maxPlaygroundURLLength {
+		_, err = io.WriteString(w, link+"\n")
+		return err
+	}
+	path := filepath.Join(fallbackDir, strings.TrimSuffix(genFilename(request), ".json")+".playground.json")
+	if err = os.WriteFile(path, []byte(formatJSON(payload)+"\n"), 0644); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "# the playground link of %s would be %d characters, longer than %d, "+
+		"the conversation is written to %s to be loaded in the playground instead\n",
+		request.Ident(), len(link), maxPlaygroundURLLength, path)
+	return err
+}
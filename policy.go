@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/tidwall/gjson"
+)
+
+func defaultContentPolicyPath() string {
+	return filepath.Join(getPalaceDir(), "content_policy.toml")
+}
+
+// contentPolicy lists the phrases and patterns which should not appear in the
+// messages sent to Moonshot AI, it is loaded from a TOML file such as:
+//
+//	phrases  = ["internal use only", "confidential"]
+//	patterns = ['\b\d{4}[- ]?\d{4}[- ]?\d{4}[- ]?\d{4}\b']
+//
+// Phrases are matched case-insensitively, patterns are regular expressions.
+type contentPolicy struct {
+	Phrases  []string `toml:"phrases"`
+	Patterns []string `toml:"patterns"`
+
+	patterns []*regexp.Regexp
+}
+
+func loadContentPolicy(path string) (*contentPolicy, error) {
+	policy := new(contentPolicy)
+	if _, err := toml.DecodeFile(path, policy); err != nil {
+		return nil, fmt.Errorf("content policy: %w", err)
+	}
+	for _, pattern := range policy.Patterns {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("content policy: pattern %q: %w", pattern, err)
+		}
+		policy.patterns = append(policy.patterns, compiled)
+	}
+	if len(policy.Phrases) == 0 && len(policy.patterns) == 0 {
+		return nil, fmt.Errorf("content policy: no phrases or patterns found in %s", path)
+	}
+	return policy, nil
+}
+
+// match returns the first phrase or the text matched by the first pattern found
+// in the text, or an empty string if none is found.
+func (p *contentPolicy) match(text string) string {
+	lower := strings.ToLower(text)
+	for _, phrase := range p.Phrases {
+		if phrase != "" && strings.Contains(lower, strings.ToLower(phrase)) {
+			return phrase
+		}
+	}
+	for _, pattern := range p.patterns {
+		if matched := pattern.FindString(text); matched != "" {
+			return matched
+		}
+	}
+	return ""
+}
+
+// Check reports each message of the request body in which the policy matches,
+// such as `messages[2]: "confidential"`.
+func (p *contentPolicy) Check(request *Request) string {
+	var violations []string
+	gjson.Get(request.RequestBody.String, "messages").ForEach(func(index, message gjson.Result) bool {
+		if matched := p.match(transcriptContent(message.Get("content"))); matched != "" {
+			violations = append(violations, "messages["+index.String()+"]: "+strconv.Quote(matched))
+		}
+		return true
+	})
+	return strings.Join(violations, ", ")
+}
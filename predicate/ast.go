@@ -61,6 +61,11 @@ func (t *Tree) String() string {
 					predicate.WriteString(" ")
 					predicate.WriteString(toOperator(itemExpr.Op, isNull && lit == Null))
 					pushExpr(itemExpr.Right)
+				case *NotExpr:
+					tree := Tree{Expr: &ComboExpr{Items: []ComboItem{itemExpr.Expr}}}
+					predicate.WriteString(" not (")
+					predicate.WriteString(tree.String())
+					predicate.WriteString(")")
 				case *ParenExpr:
 					// This is a very speechless hack, converting *ComboExpr to a string.
 					// itemExpr.Expr here must be *ComboExpr
@@ -136,6 +141,7 @@ func (*LiteralExpr) expr()     {}
 func (*BinaryExpr) expr()      {}
 func (*LiteralListExpr) expr() {}
 func (*ParenExpr) expr()       {}
+func (*NotExpr) expr()         {}
 func (*ComboExpr) expr()       {}
 
 type Ident struct {
@@ -267,6 +273,12 @@ type ParenExpr struct {
 	Expr Expr
 }
 
+// NotExpr negates an expression, either a binary expression or a parenthesized
+// predicate.
+type NotExpr struct {
+	Expr Expr
+}
+
 // ComboItem will only contain two types, Expr and []*OperatorType,
 // currently, the value of Expr should only be *BinaryExpr
 type ComboItem any
@@ -39,11 +39,13 @@ const IN = 57359
 const MINUS = 57360
 const AND = 57361
 const OR = 57362
-const IDENT = 57363
-const STRING = 57364
-const BOOLEAN = 57365
-const INTEGER = 57366
-const NULL = 57367
+const AND_KEYWORD = 57363
+const OR_KEYWORD = 57364
+const IDENT = 57365
+const STRING = 57366
+const BOOLEAN = 57367
+const INTEGER = 57368
+const NULL = 57369
 
 var predicateToknames = [...]string{
 	"$end",
@@ -66,6 +68,8 @@ var predicateToknames = [...]string{
 	"MINUS",
 	"AND",
 	"OR",
+	"AND_KEYWORD",
+	"OR_KEYWORD",
 	"IDENT",
 	"STRING",
 	"BOOLEAN",
@@ -79,7 +83,7 @@ const predicateEofCode = 1
 const predicateErrCode = 2
 const predicateInitialStackSize = 16
 
-//line predicate.y:217
+//line predicate.y:232
 
 //line yacctab:1
 var predicateExca = [...]int8{
@@ -90,65 +94,66 @@ var predicateExca = [...]int8{
 
 const predicatePrivate = 57344
 
-const predicateLast = 66
+const predicateLast = 72
 
 var predicateAct = [...]int8{
-	54, 53, 30, 49, 48, 61, 26, 27, 31, 43,
-	57, 55, 40, 25, 46, 42, 18, 51, 50, 38,
-	37, 24, 19, 20, 13, 14, 15, 16, 17, 4,
-	7, 29, 23, 9, 10, 22, 56, 45, 44, 9,
-	10, 32, 52, 33, 6, 34, 35, 36, 60, 3,
-	41, 60, 1, 62, 58, 47, 59, 39, 21, 2,
-	8, 63, 12, 28, 11, 5,
+	58, 57, 34, 53, 52, 65, 47, 61, 30, 31,
+	35, 44, 59, 50, 46, 28, 55, 29, 54, 42,
+	41, 27, 8, 26, 49, 56, 48, 10, 11, 12,
+	13, 10, 11, 12, 13, 4, 33, 37, 22, 38,
+	39, 40, 43, 5, 23, 24, 17, 18, 19, 20,
+	21, 36, 7, 60, 3, 51, 64, 64, 62, 45,
+	15, 66, 63, 2, 25, 67, 1, 9, 14, 16,
+	32, 6,
 }
 
 var predicatePact = [...]int16{
-	23, -1000, 20, -1000, 23, 11, -1000, -1000, 23, 16,
-	12, 14, -16, 28, 30, -2, -3, 49, -9, 25,
-	24, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
-	-10, 50, -21, -22, -4, -5, 34, -1000, -1000, -16,
-	-1000, -1000, -1000, -13, -1000, -1000, 31, -14, -1000, -1000,
-	-1000, -1000, -16, 47, -1000, -1000, -19, -1000, 44, -1000,
-	-16, -1000, -1000, -1000,
+	29, -1000, 12, -1000, 29, 29, 33, -1000, -1000, 29,
+	4, 1, -1000, -1000, 8, -1000, -16, 38, 24, -4,
+	-5, 34, -12, 13, 11, -1000, -1000, -1000, -1000, -1000,
+	-1000, -1000, -1000, -1000, -13, 50, -23, -24, -6, -8,
+	17, -1000, -1000, -16, -1000, -1000, -1000, -14, -1000, -1000,
+	48, -19, -1000, -1000, -1000, -1000, -16, 53, -1000, -1000,
+	-21, -1000, 52, -1000, -16, -1000, -1000, -1000,
 }
 
 var predicatePgo = [...]int8{
-	0, 65, 0, 31, 63, 1, 62, 60, 49, 59,
-	52,
+	0, 71, 0, 36, 70, 1, 69, 67, 54, 63,
+	66,
 }
 
 var predicateR1 = [...]int8{
 	0, 10, 9, 9, 8, 8, 8, 8, 8, 8,
-	8, 8, 8, 8, 6, 6, 6, 6, 6, 6,
-	7, 7, 5, 5, 2, 2, 2, 3, 3, 4,
-	4, 4, 1, 1, 1,
+	8, 8, 8, 8, 8, 6, 6, 6, 6, 6,
+	6, 7, 7, 7, 7, 5, 5, 2, 2, 2,
+	3, 3, 4, 4, 4, 1, 1, 1,
 }
 
 var predicateR2 = [...]int8{
-	0, 2, 1, 3, 3, 3, 4, 4, 3, 4,
-	3, 4, 5, 6, 1, 1, 2, 2, 2, 2,
-	2, 2, 3, 1, 1, 1, 1, 1, 2, 1,
-	4, 3, 3, 3, 1,
+	0, 2, 1, 3, 3, 2, 3, 4, 4, 3,
+	4, 3, 4, 5, 6, 1, 1, 2, 2, 2,
+	2, 2, 2, 1, 1, 3, 1, 1, 1, 1,
+	1, 2, 1, 4, 3, 3, 3, 1,
 }
 
 var predicateChk = [...]int16{
-	-1000, -10, -9, -8, 6, -1, 21, 10, -7, 19,
-	20, -9, -6, 13, 14, 15, 16, 17, 5, 11,
-	12, -8, 19, 20, 7, -2, 22, 23, -4, -3,
-	18, 24, 13, 13, 15, 16, 17, 22, 22, 8,
-	21, -3, 24, 18, 13, 13, 24, 5, 25, 25,
-	22, 22, 8, -5, -2, 24, 5, 24, -5, 9,
-	4, 24, 9, -2,
+	-1000, -10, -9, -8, 6, 14, -1, 23, 10, -7,
+	19, 20, 21, 22, -9, -8, -6, 13, 14, 15,
+	16, 17, 5, 11, 12, -8, 19, 20, 7, -2,
+	24, 25, -4, -3, 18, 26, 13, 13, 15, 16,
+	17, 24, 24, 8, 23, -3, 26, 18, 13, 13,
+	26, 5, 27, 27, 24, 24, 8, -5, -2, 26,
+	5, 26, -5, 9, 4, 26, 9, -2,
 }
 
 var predicateDef = [...]int8{
-	0, -2, 0, 2, 0, 0, 34, 1, 0, 0,
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 14,
-	15, 3, 20, 21, 4, 5, 24, 25, 26, 29,
-	0, 27, 18, 19, 0, 0, 0, 8, 10, 0,
-	32, 33, 27, 0, 16, 17, 28, 0, 6, 7,
-	9, 11, 0, 0, 23, 28, 0, 31, 0, 12,
-	0, 30, 13, 22,
+	0, -2, 0, 2, 0, 0, 0, 37, 1, 0,
+	0, 0, 23, 24, 0, 5, 0, 0, 0, 0,
+	0, 0, 0, 15, 16, 3, 21, 22, 4, 6,
+	27, 28, 29, 32, 0, 30, 19, 20, 0, 0,
+	0, 9, 11, 0, 35, 36, 30, 0, 17, 18,
+	31, 0, 7, 8, 10, 12, 0, 0, 26, 31,
+	0, 34, 0, 13, 0, 33, 14, 25,
 }
 
 var predicateTok1 = [...]int8{
@@ -158,7 +163,7 @@ var predicateTok1 = [...]int8{
 var predicateTok2 = [...]int8{
 	2, 3, 4, 5, 6, 7, 8, 9, 10, 11,
 	12, 13, 14, 15, 16, 17, 18, 19, 20, 21,
-	22, 23, 24, 25,
+	22, 23, 24, 25, 26, 27,
 }
 
 var predicateTok3 = [...]int8{
@@ -504,34 +509,42 @@ predicatedefault:
 
 	case 1:
 		predicateDollar = predicateS[predicatept-2 : predicatept+1]
-//line predicate.y:36
+//line predicate.y:37
 		{
 			predicateVAL.tree.Expr = predicateDollar[1].predicate
 		}
 	case 2:
 		predicateDollar = predicateS[predicatept-1 : predicatept+1]
-//line predicate.y:42
+//line predicate.y:43
 		{
 			predicateVAL.predicate.Items = []ComboItem{predicateDollar[1].expr}
 		}
 	case 3:
 		predicateDollar = predicateS[predicatept-3 : predicatept+1]
-//line predicate.y:46
+//line predicate.y:47
 		{
 			predicateVAL.predicate.Items = append(predicateDollar[1].predicate.Items, predicateDollar[2].operators)
 			predicateVAL.predicate.Items = append(predicateDollar[1].predicate.Items, predicateDollar[3].expr)
 		}
 	case 4:
 		predicateDollar = predicateS[predicatept-3 : predicatept+1]
-//line predicate.y:53
+//line predicate.y:54
 		{
 			predicateVAL.expr = &ParenExpr{
 				Expr: predicateDollar[2].predicate,
 			}
 		}
 	case 5:
+		predicateDollar = predicateS[predicatept-2 : predicatept+1]
+//line predicate.y:60
+		{
+			predicateVAL.expr = &NotExpr{
+				Expr: predicateDollar[2].expr,
+			}
+		}
+	case 6:
 		predicateDollar = predicateS[predicatept-3 : predicatept+1]
-//line predicate.y:59
+//line predicate.y:66
 		{
 			predicateVAL.expr = &BinaryExpr{
 				Op:    predicateDollar[2].operators,
@@ -539,9 +552,9 @@ predicatedefault:
 				Right: predicateDollar[3].lit,
 			}
 		}
-	case 6:
+	case 7:
 		predicateDollar = predicateS[predicatept-4 : predicatept+1]
-//line predicate.y:67
+//line predicate.y:74
 		{
 			predicateVAL.expr = &BinaryExpr{
 				Op:    []*OperatorType{predicateDollar[2].operator, predicateDollar[3].operator},
@@ -549,9 +562,9 @@ predicatedefault:
 				Right: predicateDollar[4].lit,
 			}
 		}
-	case 7:
+	case 8:
 		predicateDollar = predicateS[predicatept-4 : predicatept+1]
-//line predicate.y:75
+//line predicate.y:82
 		{
 			predicateVAL.expr = &BinaryExpr{
 				Op:    []*OperatorType{predicateDollar[2].operator, predicateDollar[3].operator},
@@ -559,9 +572,9 @@ predicatedefault:
 				Right: predicateDollar[4].lit,
 			}
 		}
-	case 8:
+	case 9:
 		predicateDollar = predicateS[predicatept-3 : predicatept+1]
-//line predicate.y:83
+//line predicate.y:90
 		{
 			predicateVAL.expr = &BinaryExpr{
 				Op:    []*OperatorType{predicateDollar[2].operator},
@@ -569,9 +582,9 @@ predicatedefault:
 				Right: predicateDollar[3].lit,
 			}
 		}
-	case 9:
+	case 10:
 		predicateDollar = predicateS[predicatept-4 : predicatept+1]
-//line predicate.y:91
+//line predicate.y:98
 		{
 			predicateVAL.expr = &BinaryExpr{
 				Op:    []*OperatorType{predicateDollar[2].operator, predicateDollar[3].operator},
@@ -579,9 +592,9 @@ predicatedefault:
 				Right: predicateDollar[4].lit,
 			}
 		}
-	case 10:
+	case 11:
 		predicateDollar = predicateS[predicatept-3 : predicatept+1]
-//line predicate.y:99
+//line predicate.y:106
 		{
 			predicateVAL.expr = &BinaryExpr{
 				Op:    []*OperatorType{predicateDollar[2].operator},
@@ -589,9 +602,9 @@ predicatedefault:
 				Right: predicateDollar[3].lit,
 			}
 		}
-	case 11:
+	case 12:
 		predicateDollar = predicateS[predicatept-4 : predicatept+1]
-//line predicate.y:107
+//line predicate.y:114
 		{
 			predicateVAL.expr = &BinaryExpr{
 				Op:    []*OperatorType{predicateDollar[2].operator, predicateDollar[3].operator},
@@ -599,9 +612,9 @@ predicatedefault:
 				Right: predicateDollar[4].lit,
 			}
 		}
-	case 12:
+	case 13:
 		predicateDollar = predicateS[predicatept-5 : predicatept+1]
-//line predicate.y:115
+//line predicate.y:122
 		{
 			predicateVAL.expr = &BinaryExpr{
 				Op:    []*OperatorType{predicateDollar[2].operator},
@@ -609,9 +622,9 @@ predicatedefault:
 				Right: predicateDollar[4].lits,
 			}
 		}
-	case 13:
+	case 14:
 		predicateDollar = predicateS[predicatept-6 : predicatept+1]
-//line predicate.y:123
+//line predicate.y:130
 		{
 			predicateVAL.expr = &BinaryExpr{
 				Op:    []*OperatorType{predicateDollar[2].operator, predicateDollar[3].operator},
@@ -619,102 +632,114 @@ predicatedefault:
 				Right: predicateDollar[5].lits,
 			}
 		}
-	case 14:
-		predicateDollar = predicateS[predicatept-1 : predicatept+1]
-//line predicate.y:133
-		{
-			predicateVAL.operators = []*OperatorType{predicateDollar[1].operator}
-		}
 	case 15:
 		predicateDollar = predicateS[predicatept-1 : predicatept+1]
-//line predicate.y:137
+//line predicate.y:140
 		{
 			predicateVAL.operators = []*OperatorType{predicateDollar[1].operator}
 		}
 	case 16:
-		predicateDollar = predicateS[predicatept-2 : predicatept+1]
-//line predicate.y:141
+		predicateDollar = predicateS[predicatept-1 : predicatept+1]
+//line predicate.y:144
 		{
-			predicateVAL.operators = []*OperatorType{predicateDollar[1].operator, predicateDollar[2].operator}
+			predicateVAL.operators = []*OperatorType{predicateDollar[1].operator}
 		}
 	case 17:
 		predicateDollar = predicateS[predicatept-2 : predicatept+1]
-//line predicate.y:145
+//line predicate.y:148
 		{
 			predicateVAL.operators = []*OperatorType{predicateDollar[1].operator, predicateDollar[2].operator}
 		}
 	case 18:
 		predicateDollar = predicateS[predicatept-2 : predicatept+1]
-//line predicate.y:149
+//line predicate.y:152
 		{
 			predicateVAL.operators = []*OperatorType{predicateDollar[1].operator, predicateDollar[2].operator}
 		}
 	case 19:
 		predicateDollar = predicateS[predicatept-2 : predicatept+1]
-//line predicate.y:153
+//line predicate.y:156
 		{
 			predicateVAL.operators = []*OperatorType{predicateDollar[1].operator, predicateDollar[2].operator}
 		}
 	case 20:
 		predicateDollar = predicateS[predicatept-2 : predicatept+1]
-//line predicate.y:159
+//line predicate.y:160
 		{
 			predicateVAL.operators = []*OperatorType{predicateDollar[1].operator, predicateDollar[2].operator}
 		}
 	case 21:
 		predicateDollar = predicateS[predicatept-2 : predicatept+1]
-//line predicate.y:163
+//line predicate.y:166
 		{
 			predicateVAL.operators = []*OperatorType{predicateDollar[1].operator, predicateDollar[2].operator}
 		}
 	case 22:
+		predicateDollar = predicateS[predicatept-2 : predicatept+1]
+//line predicate.y:170
+		{
+			predicateVAL.operators = []*OperatorType{predicateDollar[1].operator, predicateDollar[2].operator}
+		}
+	case 23:
+		predicateDollar = predicateS[predicatept-1 : predicatept+1]
+//line predicate.y:174
+		{
+			predicateVAL.operators = []*OperatorType{And, And}
+		}
+	case 24:
+		predicateDollar = predicateS[predicatept-1 : predicatept+1]
+//line predicate.y:178
+		{
+			predicateVAL.operators = []*OperatorType{Or, Or}
+		}
+	case 25:
 		predicateDollar = predicateS[predicatept-3 : predicatept+1]
-//line predicate.y:169
+//line predicate.y:184
 		{
 			predicateVAL.lits.List = append(predicateDollar[1].lits.List, predicateDollar[3].lit)
 		}
-	case 23:
+	case 26:
 		predicateDollar = predicateS[predicatept-1 : predicatept+1]
-//line predicate.y:173
+//line predicate.y:188
 		{
 			predicateVAL.lits.List = []*LiteralExpr{predicateDollar[1].lit}
 		}
-	case 28:
+	case 31:
 		predicateDollar = predicateS[predicatept-2 : predicatept+1]
-//line predicate.y:185
+//line predicate.y:200
 		{
 			predicateDollar[2].lit.Value = "-" + predicateDollar[2].lit.Value
 			predicateVAL.lit = predicateDollar[2].lit
 		}
-	case 30:
+	case 33:
 		predicateDollar = predicateS[predicatept-4 : predicatept+1]
-//line predicate.y:193
+//line predicate.y:208
 		{
 			predicateDollar[2].lit.Value = "-" + predicateDollar[2].lit.Value + "." + predicateDollar[4].lit.Value
 			predicateVAL.lit = predicateDollar[2].lit
 		}
-	case 31:
+	case 34:
 		predicateDollar = predicateS[predicatept-3 : predicatept+1]
-//line predicate.y:198
+//line predicate.y:213
 		{
 			predicateDollar[1].lit.Value = predicateDollar[1].lit.Value + "." + predicateDollar[3].lit.Value
 			predicateVAL.lit = predicateDollar[1].lit
 		}
-	case 32:
+	case 35:
 		predicateDollar = predicateS[predicatept-3 : predicatept+1]
-//line predicate.y:205
+//line predicate.y:220
 		{
 			predicateVAL.fields.Fields = append(predicateDollar[1].fields.Fields, predicateDollar[3].ident)
 		}
-	case 33:
+	case 36:
 		predicateDollar = predicateS[predicatept-3 : predicatept+1]
-//line predicate.y:209
+//line predicate.y:224
 		{
 			predicateVAL.fields.Fields = append(predicateDollar[1].fields.Fields, predicateDollar[3].lit)
 		}
-	case 34:
+	case 37:
 		predicateDollar = predicateS[predicatept-1 : predicatept+1]
-//line predicate.y:213
+//line predicate.y:228
 		{
 			predicateVAL.fields.Fields = append(predicateVAL.fields.Fields, predicateDollar[1].ident)
 		}
@@ -10,6 +10,12 @@ import (
 //go:generate goyacc -o "predicate.gen.go" -p "predicate" predicate.y
 
 func Parse(predicate string) (string, error) {
+	return ParseFields(predicate, nil)
+}
+
+// ParseFields is Parse with names that stand for SQL expressions, such as status
+// for response_status_code, which are used in place of the names.
+func ParseFields(predicate string, fields map[string]string) (string, error) {
 	l := &lexer{raw: predicate}
 	if status := predicateParse(l); status != 0 {
 		return "", l.err
@@ -17,9 +23,35 @@ func Parse(predicate string) (string, error) {
 	if l.err != nil {
 		return "", l.err
 	}
+	resolveFields(l.T.Expr, fields)
 	return l.T.String(), nil
 }
 
+func resolveFields(expr Expr, fields map[string]string) {
+	switch expr := expr.(type) {
+	case *ComboExpr:
+		for _, item := range expr.Items {
+			if itemExpr, ok := item.(Expr); ok {
+				resolveFields(itemExpr, fields)
+			}
+		}
+	case *ParenExpr:
+		resolveFields(expr.Expr, fields)
+	case *NotExpr:
+		resolveFields(expr.Expr, fields)
+	case *BinaryExpr:
+		resolveFields(expr.Left, fields)
+	case *FieldsExpr:
+		// Names only stand for whole columns, JSON paths start with a column.
+		if len(expr.Fields) == 1 {
+			ident := expr.Fields[0].(*Ident)
+			if field, ok := fields[ident.Name]; ok {
+				ident.Name = field
+			}
+		}
+	}
+}
+
 func ParseAST(predicate string) (*Tree, error) {
 	l := &lexer{raw: predicate}
 	if status := predicateParse(l); status != 0 {
@@ -32,6 +64,18 @@ func ParseAST(predicate string) (*Tree, error) {
 	return &l.T, nil
 }
 
+// keywords are case-insensitive aliases of the operators, and and or are the
+// same as && and ||.
+var keywords = map[string]struct {
+	token    int
+	operator *OperatorType
+}{
+	"and":  {AND_KEYWORD, And},
+	"or":   {OR_KEYWORD, Or},
+	"not":  {NOT, Not},
+	"like": {LIKE, Like},
+}
+
 var operators = map[string]int{
 	">": GREATER,
 	"<": LESS,
@@ -127,6 +171,10 @@ func (l *lexer) Lex(lval *predicateSymType) int {
 		}
 		return INTEGER
 	}
+	if keyword, ok := keywords[strings.ToLower(token)]; ok {
+		lval.operator = keyword.operator
+		return keyword.token
+	}
 	switch strings.ToLower(token) {
 	case "true", "false":
 		lval.lit = &LiteralExpr{
@@ -18,6 +18,7 @@ package predicate
 
 %token             COMMA DOT LPAREN RPAREN LBRACK RBRACK END
 %token <operator>  GREATER LESS EQUAL NOT LIKE MATCH IN MINUS AND OR
+%token <operator>  AND_KEYWORD OR_KEYWORD
 %token <ident>     IDENT
 %token <lit>       STRING BOOLEAN INTEGER NULL
 
@@ -55,6 +56,12 @@ expr:
             Expr: $2,
         }
     }
+|   NOT expr
+    {
+        $$ = &NotExpr{
+            Expr: $2,
+        }
+    }
 |   fields symbol lit
     {
         $$ = &BinaryExpr{
@@ -163,6 +170,14 @@ logic:
     {
         $$ = []*OperatorType{$1, $2}
     }
+|   AND_KEYWORD
+    {
+        $$ = []*OperatorType{And, And}
+    }
+|   OR_KEYWORD
+    {
+        $$ = []*OperatorType{Or, Or}
+    }
 
 lits:
     lits COMMA lit
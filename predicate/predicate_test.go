@@ -103,6 +103,26 @@ func TestParse(t *testing.T) {
 				predicate: "response_header % \"Msh-Context-Cache-Token-Saved: \\d+\"",
 				want:      "response_header is not null and response_header regexp cast('Msh-Context-Cache-Token-Saved: \\d+' as text)",
 			},
+			{
+				predicate: "response_status_code >= 400 AND model == 'kimi' and response_status_code != 404",
+				want:      "response_status_code >= 400 and model = 'kimi' and response_status_code != 404",
+			},
+			{
+				predicate: "NOT (response_status_code == 200 Or response_status_code == 204)",
+				want:      "not ((response_status_code = 200 or response_status_code = 204))",
+			},
+			{
+				predicate: "request_path LIKE '*/files' && request_path not like 'v2'",
+				want:      "request_path like '%/files' and request_path not like '%v2%'",
+			},
+			{
+				predicate: "! response_body ~ 'data:' || not error == null",
+				want:      "not (response_body like '%data:%') or not (error is null)",
+			},
+			{
+				predicate: "request_body.`not` == 1",
+				want:      "json_valid(request_body) and json_extract(request_body, '$.`not`') = 1",
+			},
 		}
 		for i, tc := range testcases {
 			t.Run(strconv.Itoa(i+1), func(t *testing.T) {
@@ -129,6 +149,10 @@ func TestParse(t *testing.T) {
 			"response_status_code @ (400, 401)",
 			"response_status_code @ [401, '403', null, false]",
 			"response_header ~ 'pytest''",
+			"response_status_code == 200 AND",
+			"response_status_code == 200 AND AND error == null",
+			"NOT",
+			"response_status_code NOT 200",
 		}
 		for i, predicate := range predicates {
 			t.Run(strconv.Itoa(i+1), func(t *testing.T) {
@@ -141,6 +165,21 @@ func TestParse(t *testing.T) {
 	})
 }
 
+func TestParseFields(t *testing.T) {
+	fields := map[string]string{
+		"status":  "response_status_code",
+		"latency": "latency / 1000000",
+	}
+	got, err := ParseFields("status >= 400 and latency > 1000 and request_body.status == 'ok'", fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "response_status_code >= 400 and latency / 1000000 > 1000 and json_valid(request_body) and json_extract(request_body, '$.status') = 'ok'"
+	if got != want {
+		t.Errorf("\nwant: %s\ngot:  %s", want, got)
+	}
+}
+
 func TestParseAST(t *testing.T) {
 	tree, err := ParseAST(`
 		request_body.messages.0.role == "system" && 
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/BurntSushi/toml"
+)
+
+func defaultPriceTablePath() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".config", "moonpalace", "prices.toml")
+}
+
+// priceTable maps models to their prices per 1000 tokens, it is loaded from a
+// TOML file such as:
+//
+//	[moonshot-v1-8k]
+//	prompt_price     = 0.012
+//	completion_price = 0.012
+//
+// A model without prices of its own is priced as its family, such as
+// moonshot-v1 for moonshot-v1-8k.
+type priceTable map[string]tokenPrices
+
+func loadPriceTable(path string) (priceTable, error) {
+	if path == "" {
+		return nil, fmt.Errorf("price table: no path is given")
+	}
+	prices := make(priceTable)
+	if _, err := toml.DecodeFile(path, &prices); err != nil {
+		return nil, fmt.Errorf("price table: %w", err)
+	}
+	if len(prices) == 0 {
+		return nil, fmt.Errorf("price table: no models found in %s", path)
+	}
+	return prices, nil
+}
+
+// Lookup returns the prices of the model, ok is false if neither the model nor
+// its family is in the table.
+func (p priceTable) Lookup(model string) (prices tokenPrices, ok bool) {
+	if prices, ok = p[model]; !ok {
+		prices, ok = p[ModelFamily(model)]
+	}
+	return prices, ok
+}
+
+func formatCost(cost float64) string {
+	return "$" + strconv.FormatFloat(cost, 'f', 4, 64)
+}
@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"net"
 	"net/http"
 	"os/signal"
 	"slices"
@@ -19,21 +20,38 @@ import (
 	"sync"
 	"syscall"
 	"time"
+	"unicode/utf8"
 
 	"github.com/spf13/cobra"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 
 	"github.com/MoonshotAI/moonpalace/detector/repeat"
+	"github.com/MoonshotAI/moonpalace/gzipbody"
 	"github.com/MoonshotAI/moonpalace/merge"
 )
 
 type StartConfig struct {
-	Port         int16               `yaml:"port"`
-	Key          string              `yaml:"key"`
-	DetectRepeat *DetectRepeatConfig `yaml:"detect-repeat"`
-	ForceStream  bool                `yaml:"force-stream"`
-	AutoCache    *AutoCacheConfig    `yaml:"auto-cache"`
+	Port                 int16               `yaml:"port"`
+	Key                  string              `yaml:"key"`
+	DetectRepeat         *DetectRepeatConfig `yaml:"detect-repeat"`
+	ForceStream          bool                `yaml:"force-stream"`
+	AutoCache            *AutoCacheConfig    `yaml:"auto-cache"`
+	MaxBodyStore         int                 `yaml:"max-body-store"`
+	RecordOnly           bool                `yaml:"record-only"`
+	InjectHeaders        []string            `yaml:"inject-header"`
+	ErrorWebhook         string              `yaml:"error-webhook"`
+	ErrorStatusThreshold int                 `yaml:"error-status-threshold"`
+	RewriteModel         map[string]string   `yaml:"rewrite-model"`
+	OtelEndpoint         string              `yaml:"otel-endpoint"`
+	UpstreamTimeout      time.Duration       `yaml:"upstream-timeout"`
+	UpstreamReadTimeout  time.Duration       `yaml:"upstream-read-timeout"`
+	ResponseCache        bool                `yaml:"response-cache"`
+	ResponseCacheTTL     time.Duration       `yaml:"response-cache-ttl"`
+	AdminAddr            string              `yaml:"admin-addr"`
+	AdminToken           string              `yaml:"admin-token"`
 }
 
 type DetectRepeatConfig struct {
@@ -78,6 +96,9 @@ func startCommand() *cobra.Command {
 	if cfg.Port == 0 {
 		cfg.Port = defaultPort
 	}
+	if cfg.ErrorStatusThreshold == 0 {
+		cfg.ErrorStatusThreshold = defaultErrorStatusThreshold
+	}
 	if cfg.DetectRepeat == nil {
 		cfg.DetectRepeat = &DetectRepeatConfig{
 			Threshold: defaultRepeatThreshold,
@@ -115,16 +136,45 @@ func startCommand() *cobra.Command {
 		cacheMinBytes   = cfg.AutoCache.MinBytes
 		cacheTTL        = cfg.AutoCache.TTL
 		cacheCleanup    = cfg.AutoCache.Cleanup
+		maxBodyStore    = cfg.MaxBodyStore
+		recordOnly      = cfg.RecordOnly
+		injectHeaders   = cfg.InjectHeaders
+		errorWebhook    = cfg.ErrorWebhook
+		errorThreshold  = cfg.ErrorStatusThreshold
+		rewriteModel    = cfg.RewriteModel
+		otelEndpoint    = cfg.OtelEndpoint
+		upstreamTimeout = cfg.UpstreamTimeout
+		readTimeout     = cfg.UpstreamReadTimeout
+		responseCache   = cfg.ResponseCache
+		responseTTL     = cfg.ResponseCacheTTL
+		adminAddr       = cfg.AdminAddr
+		adminToken      = cfg.AdminToken
 	)
 	cmd := &cobra.Command{
-		Use:   "start",
-		Short: "Start the MoonPalace proxy server",
+		Use:         "start",
+		Short:       "Start the MoonPalace proxy server",
+		Annotations: map[string]string{databaseAnnotation: databaseCreate},
 		Run: func(cmd *cobra.Command, args []string) {
 			ctx, stop := signal.NotifyContext(context.Background(),
 				syscall.SIGINT,
 				syscall.SIGTERM)
 			defer stop()
-			httpServer.Handler = http.HandlerFunc(buildProxy(
+			if recordOnly {
+				// In record-only mode, traffic is forwarded as is, so all features
+				// that modify requests or responses are turned off.
+				key = ""
+				detectRepeat = false
+				forceStream = false
+				autoCache = false
+				rewriteModel = nil
+				injectHeaders = nil
+				responseCache = false
+			}
+			injected, err := parseInjectHeaders(injectHeaders)
+			if err != nil {
+				logFatal(err)
+			}
+			httpServer.Handler = proxyHandler(buildProxy(
 				key,
 				detectRepeat,
 				repeatThreshold,
@@ -134,21 +184,71 @@ func startCommand() *cobra.Command {
 				cacheMinBytes,
 				cacheTTL,
 				cacheCleanup,
+				maxBodyStore,
+				injected,
+				recordOnly,
+				errorWebhook,
+				errorThreshold,
+				rewriteModel,
+				otelEndpoint,
+				upstreamTimeout,
+				readTimeout,
+				responseCache,
+				responseTTL,
 			))
 			httpServer.Addr = "127.0.0.1:" + strconv.Itoa(int(port))
+			listener, err := net.Listen("tcp", httpServer.Addr)
+			if err != nil {
+				logFatal(err)
+			}
+			// Streams left in flight by a previous run are never finished.
+			if err = persistence.ClearLiveStreams(); err != nil {
+				logFatal(err)
+			}
 			go func() {
-				if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				if err := httpServer.Serve(rawHeaderListener{listener}); err != nil && !errors.Is(err, http.ErrServerClosed) {
 					logFatal(err)
 				}
 			}()
 			logServerStarts("http://" + httpServer.Addr + "/v1")
+			var adminServer *http.Server
+			if adminAddr != "" {
+				if adminToken == "" {
+					logFatal(errors.New("--admin-addr requires admin-token in the start section of config.yaml"))
+				}
+				addr, err := adminListenAddr(adminAddr)
+				if err != nil {
+					logFatal(err)
+				}
+				adminListener, err := net.Listen("tcp", addr)
+				if err != nil {
+					logFatal(err)
+				}
+				adminServer = &http.Server{
+					Handler:           newAdminHandler(adminToken, time.Now(), responseCache),
+					ReadHeaderTimeout: 10 * time.Second,
+					ErrorLog:          serverErrorLogger,
+				}
+				go func() {
+					if err := adminServer.Serve(adminListener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+						logFatal(err)
+					}
+				}()
+				logAdminStarts(addr)
+			}
 			<-ctx.Done()
 			stop()
 			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 			defer cancel()
+			if adminServer != nil {
+				adminServer.Shutdown(shutdownCtx)
+			}
 			if err := httpServer.Shutdown(shutdownCtx); err != nil {
 				logFatal(err)
 			}
+			if responseCache {
+				logResponseCacheStats(responseCacheHits.Load(), responseCacheMisses.Load())
+			}
 		},
 	}
 	flags := cmd.PersistentFlags()
@@ -162,6 +262,25 @@ func startCommand() *cobra.Command {
 	flags.IntVar(&cacheMinBytes, "cache-min-bytes", cacheMinBytes, "minimum size of bytes to cache")
 	flags.IntVar(&cacheTTL, "cache-ttl", cacheTTL, "time to live in seconds for cached requests")
 	flags.IntVar(&cacheCleanup, "cache-cleanup", cacheCleanup, "time in seconds to cleanup expired caches")
+	flags.IntVar(&maxBodyStore, "max-body-store", maxBodyStore, "maximum size of bytes of the request body to store, larger bodies are truncated, 0 means no limit")
+	flags.BoolVar(&recordOnly, "record-only", recordOnly, "forward and store traffic without any modification to headers or bodies")
+	flags.StringArrayVar(&injectHeaders, "inject-header", injectHeaders, "add a header such as 'X-Trace-Id: $UUID' to forwarded requests, $UUID and $TIMESTAMP are substituted for each request, can be repeated")
+	flags.StringVar(&errorWebhook, "error-webhook", errorWebhook, "URL to POST a JSON notification to when a response is an error")
+	flags.IntVar(&errorThreshold, "error-status-threshold", errorThreshold, "minimum response status code to notify the error webhook of")
+	flags.StringToStringVar(&rewriteModel, "rewrite-model", rewriteModel, "rewrite the model of requests before forwarding, in the form of from=to, \"*\" as from matches any model")
+	flags.StringVar(&otelEndpoint, "otel-endpoint", otelEndpoint, "OTLP/HTTP collector to export a span per proxied request to, such as http://localhost:4318")
+	flags.DurationVar(&upstreamTimeout, "upstream-timeout", upstreamTimeout, "maximum time to wait for the response headers of the endpoint, 0 means no limit")
+	flags.DurationVar(&readTimeout, "upstream-read-timeout", readTimeout, "maximum time to wait for the next chunk of the response body of the endpoint, which lifts the limit on the total duration of streaming responses, 0 means no limit")
+	flags.BoolVar(&responseCache, "response-cache", responseCache, "serve requests identical to a stored successful one with the stored response instead of forwarding them")
+	flags.StringVar(&adminAddr, "admin-addr", adminAddr, "serve the admin API, to get stats, pause capture or clean up at runtime, on the address, such as :9989 which listens on localhost, requests must carry admin-token of config.yaml as a bearer token")
+	flags.DurationVar(&responseTTL, "response-cache-ttl", responseTTL, "maximum age of the stored responses served by --response-cache, 0 means they never expire")
+	cmd.MarkFlagsMutuallyExclusive("record-only", "key")
+	cmd.MarkFlagsMutuallyExclusive("record-only", "inject-header")
+	cmd.MarkFlagsMutuallyExclusive("record-only", "detect-repeat")
+	cmd.MarkFlagsMutuallyExclusive("record-only", "force-stream")
+	cmd.MarkFlagsMutuallyExclusive("record-only", "auto-cache")
+	cmd.MarkFlagsMutuallyExclusive("record-only", "rewrite-model")
+	cmd.MarkFlagsMutuallyExclusive("record-only", "response-cache")
 	return cmd
 }
 
@@ -170,6 +289,7 @@ var (
 		ReadHeaderTimeout: 1 * time.Minute,
 		WriteTimeout:      5 * time.Minute,
 		ErrorLog:          serverErrorLogger,
+		ConnContext:       rawHeaderConnContext,
 	}
 	httpClient = &http.Client{
 		Timeout: time.Minute * 5,
@@ -198,7 +318,7 @@ var (
 		},
 	}
 	merger = &merge.Merger{
-		StreamFields: []string{"content", "arguments"},
+		StreamFields: []string{"content", "reasoning_content", "reasoning", "arguments"},
 		IndexFields:  []string{"index"},
 	}
 )
@@ -261,7 +381,22 @@ func buildProxy(
 	cacheMinBytes int,
 	cacheTTL int,
 	cacheCleanup int,
+	maxBodyStore int,
+	injectHeaders []injectedHeader,
+	recordOnly bool,
+	errorWebhook string,
+	errorThreshold int,
+	rewriteModel map[string]string,
+	otelEndpoint string,
+	upstreamTimeout time.Duration,
+	readTimeout time.Duration,
+	responseCache bool,
+	responseCacheTTL time.Duration,
 ) func(w http.ResponseWriter, r *http.Request) {
+	client := httpClient
+	if upstreamTimeout > 0 || readTimeout > 0 {
+		client = newUpstreamClient(upstreamTimeout, readTimeout)
+	}
 	return func(w http.ResponseWriter, r *http.Request) {
 		var (
 			err                       error
@@ -272,7 +407,10 @@ func buildProxy(
 			requestAcceptEncodingGzip bool
 			requestUseStream          bool
 			requestBody               []byte
+			compressedRequestBody     []byte
+			decompressedRequestBody   []byte
 			responseBody              []byte
+			rawHeader                 = rawRequestHeader(r)
 			requestID                 = r.Header.Get("X-Request-Id")
 			requestContentType        = filterHeaderFlags(r.Header.Get("Content-Type"))
 			requestMethod             = r.Method
@@ -285,6 +423,9 @@ func buildProxy(
 			moonshotRequestID         string
 			moonshotServerTiming      int
 			moonshotContextCacheID    string
+			moonshotModel             string
+			moonshotSystemFingerprint string
+			originalModel             string
 			responseStatus            string
 			responseStatusCode        int
 			responseContentType       string
@@ -292,11 +433,33 @@ func buildProxy(
 			createdAt                 = time.Now()
 			latency                   time.Duration
 			tokenFinishLatency        time.Duration
+			trace                     *traceContext
+			live                      liveStream
+			cached                    *Request
 		)
+		// Requests are only traced when a collector is set, which continue the
+		// trace of the client if there is one.
+		if otelEndpoint != "" {
+			trace = newTraceContext(r.Header.Get("Traceparent"))
+		}
+		// The API key the request is forwarded with, which responses are
+		// cached for.
+		credentialHash := hashCredential(r.Header.Get("Authorization"))
+		if key != "" {
+			credentialHash = hashCredential("Bearer " + key)
+		}
+		proxiedRequests.Add(1)
+		inFlightRequests.Add(1)
+		defer inFlightRequests.Add(-1)
 		defer func() {
 			go func() {
 				loggingMutex.Lock()
 				defer loggingMutex.Unlock()
+				// Responses served from the cache are not stored again.
+				if cached != nil {
+					logResponseCacheHit(requestMethod, requestPath, cached.ID)
+					return
+				}
 				if latency == 0 {
 					latency = time.Since(createdAt)
 				}
@@ -351,36 +514,125 @@ func buildProxy(
 					requestHeader,
 					responseHeader,
 				)
-				var lastInsertID int64
-				lastInsertID, err = persistence.Persistence(
-					requestID,
-					requestContentType,
-					requestMethod,
-					requestPath,
-					requestQuery,
-					moonshotID,
-					moonshotGID,
-					moonshotUID,
-					moonshotRequestID,
-					moonshotServerTiming,
-					responseStatusCode,
-					responseContentType,
-					formatHeader(newRequest),
-					string(requestBody),
-					formatHeader(newResponse),
-					string(responseBody),
-					toErrMsg(err),
-					responseTTFT,
-					responseTPOT,
-					responseOTPS,
-					createdAt.Format(time.DateTime),
-					latency,
-					endpoint,
+				var (
+					lastInsertID    int64
+					errMsg          = toErrMsg(err)
+					storedBody      = string(requestBody)
+					requestBodySize int
+					requestBodyHash string
+					traceID         string
 				)
-				if err != nil {
-					logFatal(err)
+				if trace != nil {
+					traceID = trace.TraceID
+				}
+				// The hash is of the whole body, even if the stored one is truncated.
+				if len(requestBody) > 0 {
+					requestBodyHash = hashRequestBody(string(requestBody))
+				}
+				if maxBodyStore > 0 && len(requestBody) > maxBodyStore {
+					storedBody = truncateBody(requestBody, maxBodyStore)
+					requestBodySize = len(requestBody)
+				}
+				if capturePaused.Load() {
+					// Requests are still traced and notified while capture is
+					// paused with the admin API, they are only not stored.
+					live.finish()
+				} else {
+					lastInsertID, err = persistence.Persistence(
+						requestID,
+						requestContentType,
+						requestMethod,
+						requestPath,
+						requestQuery,
+						moonshotID,
+						moonshotGID,
+						moonshotUID,
+						moonshotRequestID,
+						moonshotServerTiming,
+						responseStatusCode,
+						responseContentType,
+						formatHeader(newRequest),
+						storedBody,
+						formatHeader(newResponse),
+						string(responseBody),
+						errMsg,
+						responseTTFT,
+						responseTPOT,
+						responseOTPS,
+						createdAt.Format(time.DateTime),
+						latency,
+						endpoint,
+						moonshotModel,
+						moonshotSystemFingerprint,
+						requestBodySize,
+						parseRateLimit(newResponse),
+						originalModel,
+						traceID,
+						rawHeader,
+						requestBodyHash,
+						credentialHash,
+					)
+					live.finish()
+					if err != nil {
+						logFatal(err)
+					}
+					logNewRow(lastInsertID)
+				}
+				if trace != nil {
+					span := &requestSpan{
+						traceContext: trace,
+						Name:         requestMethod + " " + requestPath,
+						Start:        createdAt,
+						End:          createdAt.Add(latency),
+						Attributes: map[string]any{
+							"moonpalace.id":             lastInsertID,
+							"http.request.method":       requestMethod,
+							"url.path":                  requestPath,
+							"http.response.status_code": responseStatusCode,
+							"gen_ai.system":             "moonshot",
+							"gen_ai.request.model":      gjson.GetBytes(requestBody, "model").String(),
+							"gen_ai.response.model":     moonshotModel,
+							"gen_ai.response.id":        moonshotID,
+							"moonpalace.latency_ms":     latency.Milliseconds(),
+						},
+					}
+					if moonshot != nil && moonshot.Usage != nil {
+						span.Attributes["gen_ai.usage.input_tokens"] = moonshot.Usage.PromptTokens
+						span.Attributes["gen_ai.usage.output_tokens"] = moonshot.Usage.CompletionTokens
+					}
+					// As for HTTP server spans, only 5xx responses are errors.
+					if errMsg != "" {
+						span.Error = errMsg
+					} else if responseStatusCode >= http.StatusInternalServerError {
+						span.Error = responseStatus
+					}
+					go func() {
+						if err := exportSpan(otelEndpoint, span); err != nil {
+							logOTLPFailure(err)
+						}
+					}()
+				}
+				// Proxy errors, such as failing to connect to the endpoint, are
+				// notified regardless of the threshold.
+				if errorWebhook != "" && (errMsg != "" || responseStatusCode >= errorThreshold) {
+					if errMsg == "" {
+						errMsg = string(responseBody)
+					}
+					model := moonshotModel
+					if model == "" {
+						model = gjson.GetBytes(requestBody, "model").String()
+					}
+					go func(notification *ErrorNotification) {
+						if err := notifyError(errorWebhook, notification); err != nil {
+							logWebhookFailure(err)
+						}
+					}(&ErrorNotification{
+						ID:     lastInsertID,
+						Status: responseStatusCode,
+						Model:  model,
+						Error:  errorSnippet(errMsg),
+					})
 				}
-				logNewRow(lastInsertID)
 			}()
 		}()
 		requestBody, err = io.ReadAll(r.Body)
@@ -394,6 +646,26 @@ func buildProxy(
 			)
 			return
 		}
+		// Bodies compressed by the client are decompressed to be inspected and
+		// stored, bodies failing to decompress are kept as they are.
+		if isGzip(r.Header) {
+			if decompressed, errDecompress := gzipbody.Decompress(requestBody); errDecompress == nil {
+				compressedRequestBody, decompressedRequestBody = requestBody, decompressed
+				requestBody = decompressed
+			}
+		}
+		if model := gjson.GetBytes(requestBody, "model"); len(rewriteModel) > 0 && model.Type == gjson.String {
+			target, ok := rewriteModel[model.String()]
+			if !ok {
+				target, ok = rewriteModel["*"]
+			}
+			if ok && target != model.String() {
+				if rewritten, errRewrite := sjson.SetBytes(requestBody, "model", target); errRewrite == nil {
+					requestBody = rewritten
+					originalModel = model.String()
+				}
+			}
+		}
 		if strings.HasSuffix(requestPath, "/chat/completions") && forceStream {
 			var streamRequest MoonshotStreamRequest
 			json.Unmarshal(requestBody, &streamRequest)
@@ -404,11 +676,42 @@ func buildProxy(
 				requestBody = forceUseStream(requestBody, streamRequest.Stream != nil)
 			}
 		}
+		if responseCache && len(requestBody) > 0 && credentialHash != "" {
+			cached, err = lookupCachedResponse(requestMethod, requestPath, requestQuery, hashRequestBody(string(requestBody)), credentialHash, responseCacheTTL)
+			if err != nil {
+				warnings = append(warnings, fmt.Errorf("response cache: %w", err))
+				err = nil
+			}
+			if cached != nil {
+				serveCachedResponse(w, cached)
+				return
+			}
+		}
+		forwardBody := requestBody
+		if compressedRequestBody != nil {
+			forwardBody, err = gzipbody.Forward(compressedRequestBody, decompressedRequestBody, requestBody)
+			if err != nil {
+				writeProxyError(
+					encoder,
+					w.Header(),
+					w.WriteHeader,
+					stepMakeNewRequest,
+					err,
+				)
+				return
+			}
+		}
+		ctx := r.Context()
+		var cancelRead context.CancelCauseFunc
+		if readTimeout > 0 {
+			ctx, cancelRead = context.WithCancelCause(ctx)
+			defer cancelRead(nil)
+		}
 		newRequest, err = http.NewRequestWithContext(
-			r.Context(),
+			ctx,
 			r.Method,
 			endpoint+requestPath,
-			bytes.NewReader(requestBody),
+			bytes.NewReader(forwardBody),
 		)
 		if err != nil {
 			writeProxyError(
@@ -437,13 +740,22 @@ func buildProxy(
 				newRequest.Header.Add(header, value)
 			}
 		}
+		for _, header := range injectHeaders {
+			newRequest.Header.Set(header.Name, header.expand(time.Now()))
+		}
 		if key != "" {
 			newRequest.Header.Set("Authorization", "Bearer "+key)
 		}
-		if requestAcceptEncodingGzip {
-			newRequest.Header.Set("Accept-Encoding", "gzip")
-		} else {
-			newRequest.Header.Del("Accept-Encoding")
+		if trace != nil && !recordOnly {
+			newRequest.Header.Set("Traceparent", trace.Traceparent())
+		}
+		// In record-only mode, the Accept-Encoding header sent by the client is kept.
+		if !recordOnly {
+			if requestAcceptEncodingGzip {
+				newRequest.Header.Set("Accept-Encoding", "gzip")
+			} else {
+				newRequest.Header.Del("Accept-Encoding")
+			}
 		}
 		if strings.HasSuffix(requestPath, "/chat/completions") && autoCache {
 			cKey := key
@@ -512,7 +824,7 @@ func buildProxy(
 			}
 		}
 		createdAt = time.Now()
-		newResponse, err = httpClient.Do(newRequest)
+		newResponse, err = client.Do(newRequest)
 		if err != nil {
 			writeProxyError(
 				encoder,
@@ -523,6 +835,12 @@ func buildProxy(
 			)
 			return
 		}
+		if readTimeout > 0 {
+			newResponse.Body = newIdleTimeoutBody(ctx, cancelRead, newResponse.Body, readTimeout)
+			// The body is guarded by readTimeout, the write deadline of the
+			// server would otherwise cut long streaming responses off.
+			http.NewResponseController(w).SetWriteDeadline(time.Time{})
+		}
 		defer newResponse.Body.Close()
 		for header, values := range newResponse.Header {
 			for _, value := range values {
@@ -598,6 +916,12 @@ func buildProxy(
 							}
 							moonshot.ID = chunk.ID
 							moonshotID = moonshot.ID
+							if chunk.Model != "" {
+								moonshotModel = chunk.Model
+							}
+							if chunk.SystemFingerprint != "" {
+								moonshotSystemFingerprint = chunk.SystemFingerprint
+							}
 							if chunk.Choices != nil && len(chunk.Choices) > 0 {
 								for _, choice := range chunk.Choices {
 									if responseTTFT == 0 && hasStreamToken(choice.Delta) {
@@ -668,6 +992,12 @@ func buildProxy(
 						}
 					}
 				}
+				live.update(moonshotID, responseBody)
+			}
+			// Streams cut off by the endpoint or by --upstream-read-timeout are
+			// recorded with the error.
+			if scanErr := scanner.Err(); scanErr != nil {
+				err = scanErr
 			}
 			tokenFinishLatency = time.Since(createdAt)
 			if forceStream && !requestUseStream {
@@ -721,6 +1051,8 @@ func buildProxy(
 					}
 					moonshot.ID = completion.ID
 					moonshotID = moonshot.ID
+					moonshotModel = completion.Model
+					moonshotSystemFingerprint = completion.SystemFingerprint
 					if completion.Usage != nil {
 						moonshot.Usage = &MoonshotUsage{
 							PromptTokens:     completion.Usage.PromptTokens,
@@ -749,16 +1081,7 @@ func buildProxy(
 		moonshotGID = newResponse.Header.Get("Msh-Gid")
 		moonshotUID = newResponse.Header.Get("Msh-Uid")
 		moonshotRequestID = newResponse.Header.Get("Msh-Request-Id")
-		if serverTiming := newResponse.Header.Get("Server-Timing"); serverTiming != "" {
-			parts := strings.Split(serverTiming, ";")
-			for _, part := range parts {
-				if part = strings.TrimSpace(part); strings.HasPrefix(part, "dur=") {
-					timing := strings.TrimPrefix(part, "dur=")
-					moonshotServerTiming, _ = strconv.Atoi(timing)
-					break
-				}
-			}
-		}
+		moonshotServerTiming = parseServerTiming(newResponse.Header.Get("Server-Timing"))
 		moonshotContextCacheID = newResponse.Header.Get("Msh-Context-Cache-Id")
 		responseStatus = newResponse.Status
 		responseStatusCode = newResponse.StatusCode
@@ -780,12 +1103,13 @@ type MoonshotStreamRequest struct {
 type MoonshotChunk = MoonshotCompletion
 
 type MoonshotCompletion struct {
-	ID      string            `json:"id"`
-	Created int64             `json:"created"`
-	Model   string            `json:"model"`
-	Object  string            `json:"object"`
-	Choices []*MoonshotChoice `json:"choices"`
-	Usage   *MoonshotUsage    `json:"usage"`
+	ID                string            `json:"id"`
+	Created           int64             `json:"created"`
+	Model             string            `json:"model"`
+	SystemFingerprint string            `json:"system_fingerprint"`
+	Object            string            `json:"object"`
+	Choices           []*MoonshotChoice `json:"choices"`
+	Usage             *MoonshotUsage    `json:"usage"`
 }
 
 type MoonshotChoice struct {
@@ -826,6 +1150,53 @@ type MoonshotUsage struct {
 	CachedTokens     int `json:"cached_tokens"`
 }
 
+// parseServerTiming returns the duration in milliseconds of the first metric of
+// the Server-Timing header, such as 123 of "inner; dur=123", which is the time
+// Moonshot AI spent processing the request. Fractional durations are rounded.
+func parseServerTiming(serverTiming string) int {
+	for _, part := range strings.FieldsFunc(serverTiming, func(r rune) bool { return r == ';' || r == ',' }) {
+		if part = strings.TrimSpace(part); strings.HasPrefix(part, "dur=") {
+			timing, err := strconv.ParseFloat(strings.TrimPrefix(part, "dur="), 64)
+			if err != nil || timing < 0 {
+				return 0
+			}
+			return int(math.Round(timing))
+		}
+	}
+	return 0
+}
+
+// parseRateLimit collects the X-Ratelimit-* headers of the response as a JSON
+// object, for example X-Ratelimit-Remaining-Tokens is keyed by remaining_tokens.
+func parseRateLimit(response *http.Response) string {
+	if response == nil {
+		return ""
+	}
+	rateLimits := make(map[string]string)
+	for name, values := range response.Header {
+		name = strings.ToLower(name)
+		if strings.HasPrefix(name, "x-ratelimit-") && len(values) > 0 {
+			rateLimits[strings.ReplaceAll(strings.TrimPrefix(name, "x-ratelimit-"), "-", "_")] = values[0]
+		}
+	}
+	if len(rateLimits) == 0 {
+		return ""
+	}
+	rateLimitJSON, _ := json.Marshal(rateLimits)
+	return string(rateLimitJSON)
+}
+
+// truncateBody keeps the first maxBytes bytes of body, without splitting a UTF-8
+// character, and appends a marker so that the stored body is never mistaken for
+// a complete one.
+func truncateBody(body []byte, maxBytes int) string {
+	n := maxBytes
+	for n > 0 && !utf8.RuneStart(body[n]) {
+		n--
+	}
+	return string(body[:n]) + fmt.Sprintf("...[truncated by MoonPalace, %d bytes in total]", len(body))
+}
+
 func isGzip(header http.Header) bool {
 	if encodings := header.Values("Content-Encoding"); encodings != nil {
 		for _, encoding := range encodings {
@@ -866,6 +1237,78 @@ func formatHeader[R *http.Request | *http.Response](r R) string {
 	return headerBuilder.String()
 }
 
+// proxyHandler serves HTTP/1.1 and, as the proxy listens without TLS, HTTP/2
+// as h2c to clients connecting with prior knowledge or upgrading from HTTP/1.1.
+// Streaming responses are flushed frame by frame the same as chunks in HTTP/1.1.
+func proxyHandler(proxy http.HandlerFunc) http.Handler {
+	return h2c.NewHandler(proxy, &http2.Server{})
+}
+
+// newUpstreamClient returns the client that forwards requests with timeouts,
+// the total timeout of httpClient is lifted when the response body is guarded
+// by readTimeout, so that long streaming responses are not cut off.
+func newUpstreamClient(upstreamTimeout, readTimeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = upstreamTimeout
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   httpClient.Timeout,
+	}
+	if readTimeout > 0 {
+		client.Timeout = 0
+	}
+	return client
+}
+
+type upstreamReadTimeoutError struct {
+	timeout time.Duration
+}
+
+func (e *upstreamReadTimeoutError) Error() string {
+	return "upstream read timeout: no data received from the endpoint for " + e.timeout.String()
+}
+
+// idleTimeoutBody cancels the request if a read of the response body waits for
+// timeout, which applies to the time between chunks of streaming responses. The
+// time between reads, such as while writing to a slow client, is not counted.
+type idleTimeoutBody struct {
+	io.ReadCloser
+	ctx     context.Context
+	timeout time.Duration
+	timer   *time.Timer
+}
+
+func newIdleTimeoutBody(ctx context.Context, cancel context.CancelCauseFunc, body io.ReadCloser, timeout time.Duration) *idleTimeoutBody {
+	timeoutErr := &upstreamReadTimeoutError{timeout: timeout}
+	timer := time.AfterFunc(timeout, func() { cancel(timeoutErr) })
+	timer.Stop()
+	return &idleTimeoutBody{
+		ReadCloser: body,
+		ctx:        ctx,
+		timeout:    timeout,
+		timer:      timer,
+	}
+}
+
+func (b *idleTimeoutBody) Read(p []byte) (int, error) {
+	b.timer.Reset(b.timeout)
+	n, err := b.ReadCloser.Read(p)
+	stopped := b.timer.Stop()
+	if err != nil && !stopped {
+		// Reads fail with context.Canceled, or even end with io.EOF, once the
+		// request is canceled, the cause tells it is the timeout.
+		if cause := context.Cause(b.ctx); errors.As(cause, new(*upstreamReadTimeoutError)) {
+			err = cause
+		}
+	}
+	return n, err
+}
+
+func (b *idleTimeoutBody) Close() error {
+	b.timer.Stop()
+	return b.ReadCloser.Close()
+}
+
 type object map[string]any
 
 const (
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
+
+	"github.com/MoonshotAI/moonpalace/gzipbody"
+)
+
+// startTestUpstream starts an upstream serving handler, which the requests of
+// the proxy to the endpoint are sent to, as the endpoint is a constant without
+// the endpoint_custom tag.
+func startTestUpstream(t *testing.T, handler http.HandlerFunc) {
+	t.Helper()
+	upstream := httptest.NewTLSServer(handler)
+	t.Cleanup(upstream.Close)
+	target, err := url.Parse(endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	endpointAddr := target.Host
+	if target.Port() == "" {
+		endpointAddr = net.JoinHostPort(target.Host, "443")
+	}
+	transport := http.DefaultTransport
+	redirected := transport.(*http.Transport).Clone()
+	redirected.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if addr == endpointAddr {
+			addr = upstream.Listener.Addr().String()
+		}
+		return new(net.Dialer).DialContext(ctx, network, addr)
+	}
+	redirected.TLSClientConfig = upstream.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+	redirected.TLSClientConfig.ServerName = "example.com"
+	// Clients created by the proxy, such as for timeouts, clone the default
+	// transport.
+	http.DefaultTransport = redirected
+	t.Cleanup(func() { http.DefaultTransport = transport })
+}
+
+// startTestProxy serves proxy as the start command does, and returns the base
+// URL of the server.
+func startTestProxy(t *testing.T, proxy http.HandlerFunc) string {
+	t.Helper()
+	server := httptest.NewUnstartedServer(proxyHandler(proxy))
+	server.Config.ConnContext = rawHeaderConnContext
+	server.Listener = rawHeaderListener{server.Listener}
+	server.Start()
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+// testProxy returns the proxy with the default flags of the start command.
+func testProxy() http.HandlerFunc {
+	return buildProxy("", false, 0, 0, false, false, 0, 0, 0, 0, nil, false, "", 0, nil, "", 0, 0, false, 0)
+}
+
+// waitForRequest waits for the request with id to be stored, which happens
+// after the response is written.
+func waitForRequest(t *testing.T, id int64) *Request {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		request, err := persistence.GetRequest(id, "", "", "", "")
+		if err == nil {
+			return request
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("request %d is not stored: %v", id, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+const testCompletion = `{"id":"chatcmpl-test","object":"chat.completion","model":"moonshot-v1-8k","choices":[{"index":0,"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}],"usage":{"prompt_tokens":1,"completion_tokens":1,"total_tokens":2}}`
+
+func writeTestCompletion(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(testCompletion))
+}
+
+// h2cClient connects with HTTP/2 with prior knowledge, as the proxy listens
+// without TLS.
+func h2cClient() *http.Client {
+	return &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, network, addr)
+		},
+	}}
+}
+
+func TestProxy_H2C(t *testing.T) {
+	openTestDatabase(t)
+	startTestUpstream(t, writeTestCompletion)
+	base := startTestProxy(t, testProxy())
+	client := h2cClient()
+	const body = `{"model":"moonshot-v1-8k","messages":[{"role":"user","content":"hi"}]}`
+	response, err := client.Post(base+"/v1/chat/completions", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.ProtoMajor != 2 {
+		t.Fatalf("expected an HTTP/2 response, got %s", response.Proto)
+	}
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status %s", response.Status)
+	}
+	request := waitForRequest(t, 1)
+	if request.RequestBody.String != body || request.ResponseBody.String != testCompletion {
+		t.Errorf("request was not captured as proxied: %+v", request)
+	}
+	if request.MoonshotID.String != "chatcmpl-test" {
+		t.Errorf("unexpected chatcmpl %q", request.MoonshotID.String)
+	}
+}
+
+func TestProxy_Gzip(t *testing.T) {
+	const body = `{"model":"moonshot-v1-8k","messages":[{"role":"user","content":"你好"}]}`
+	compressed, err := gzipbody.Compress([]byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name         string
+		rewriteModel map[string]string
+		// stored is the request body that is stored, which is also the body
+		// received by the upstream once decompressed.
+		stored string
+	}{
+		{
+			name:   "unmodified",
+			stored: body,
+		},
+		{
+			name:         "rewritten",
+			rewriteModel: map[string]string{"moonshot-v1-8k": "kimi-latest"},
+			stored:       strings.Replace(body, "moonshot-v1-8k", "kimi-latest", 1),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			openTestDatabase(t)
+			var received []byte
+			startTestUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+				received, _ = io.ReadAll(r.Body)
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("Content-Encoding", "gzip")
+				response, _ := gzipbody.Compress([]byte(testCompletion))
+				w.Write(response)
+			})
+			proxy := buildProxy("", false, 0, 0, false, false, 0, 0, 0, 0, nil, false, "", 0, tt.rewriteModel, "", 0, 0, false, 0)
+			base := startTestProxy(t, proxy)
+			request, err := http.NewRequest(http.MethodPost, base+"/v1/chat/completions", bytes.NewReader(compressed))
+			if err != nil {
+				t.Fatal(err)
+			}
+			request.Header.Set("Content-Type", "application/json")
+			request.Header.Set("Content-Encoding", "gzip")
+			response, err := http.DefaultClient.Do(request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			responseBody, _ := io.ReadAll(response.Body)
+			response.Body.Close()
+			if string(responseBody) != testCompletion {
+				t.Errorf("unexpected response %q", responseBody)
+			}
+			if tt.rewriteModel == nil && !bytes.Equal(received, compressed) {
+				t.Error("unmodified body is not forwarded as the bytes sent by the client")
+			}
+			if forwarded, err := gzipbody.Decompress(received); err != nil || string(forwarded) != tt.stored {
+				t.Errorf("upstream received %q (%v), want %q", forwarded, err, tt.stored)
+			}
+			stored := waitForRequest(t, 1)
+			if stored.RequestBody.String != tt.stored {
+				t.Errorf("stored request body %q, want %q", stored.RequestBody.String, tt.stored)
+			}
+			if stored.ResponseBody.String != testCompletion {
+				t.Errorf("stored response body %q, want %q", stored.ResponseBody.String, testCompletion)
+			}
+		})
+	}
+}
+
+// slowResponseWriter takes delay to write, as a client reading slowly does once
+// the buffers of the connection are full.
+type slowResponseWriter struct {
+	*httptest.ResponseRecorder
+	delay time.Duration
+}
+
+func (w *slowResponseWriter) Write(p []byte) (int, error) {
+	time.Sleep(w.delay)
+	return w.ResponseRecorder.Write(p)
+}
+
+func TestProxy_UpstreamReadTimeout(t *testing.T) {
+	const readTimeout = 200 * time.Millisecond
+	chunks := []string{
+		`data: {"id":"chatcmpl-test","object":"chat.completion.chunk","model":"moonshot-v1-8k","choices":[{"index":0,"delta":{"role":"assistant","content":"o"}}]}`,
+		`data: {"id":"chatcmpl-test","object":"chat.completion.chunk","model":"moonshot-v1-8k","choices":[{"index":0,"delta":{"content":"k"},"finish_reason":"stop"}]}`,
+		`data: [DONE]`,
+	}
+	tests := []struct {
+		name string
+		// stall is whether the upstream stops after the first chunk.
+		stall bool
+		// delay is the time the client takes to read each write.
+		delay time.Duration
+		err   string
+	}{
+		{name: "slow client", delay: readTimeout},
+		{name: "stalled upstream", stall: true, err: "upstream read timeout"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			openTestDatabase(t)
+			startTestUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/event-stream")
+				for i, chunk := range chunks {
+					if tt.stall && i == 1 {
+						<-r.Context().Done()
+						return
+					}
+					if i == len(chunks)-1 {
+						// Within the timeout, so that the stream is still open
+						// while the client is slow.
+						time.Sleep(readTimeout / 4)
+					}
+					io.WriteString(w, chunk+"\n\n")
+					w.(http.Flusher).Flush()
+				}
+			})
+			proxy := buildProxy("", false, 0, 0, false, false, 0, 0, 0, 0, nil, false, "", 0, nil, "", 0, readTimeout, false, 0)
+			request := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"moonshot-v1-8k","stream":true}`))
+			w := &slowResponseWriter{ResponseRecorder: httptest.NewRecorder(), delay: tt.delay}
+			proxy(w, request)
+			stored := waitForRequest(t, 1)
+			if !strings.Contains(stored.Error.String, tt.err) || (tt.err == "") != (stored.Error.String == "") {
+				t.Errorf("stored error %q, want %q", stored.Error.String, tt.err)
+			}
+			want := chunks[0] + "\n\n"
+			if !tt.stall {
+				want = strings.Join(chunks, "\n\n") + "\n\n"
+			}
+			if stored.ResponseBody.String != want {
+				t.Errorf("stored response body %q, want %q", stored.ResponseBody.String, want)
+			}
+		})
+	}
+}
+
+func TestProxy_UpstreamReadTimeout_WriteTimeout(t *testing.T) {
+	const (
+		readTimeout  = time.Second
+		writeTimeout = 200 * time.Millisecond
+	)
+	openTestDatabase(t)
+	var chunks []string
+	for i := range 5 {
+		chunks = append(chunks, `data: {"id":"chatcmpl-test","object":"chat.completion.chunk","model":"moonshot-v1-8k","choices":[{"index":0,"delta":{"content":"`+strconv.Itoa(i)+`"}}]}`)
+	}
+	chunks = append(chunks, `data: [DONE]`)
+	startTestUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range chunks {
+			// The stream lasts longer than the write timeout of the server,
+			// while each chunk comes within the read timeout.
+			time.Sleep(writeTimeout / 2)
+			io.WriteString(w, chunk+"\n\n")
+			w.(http.Flusher).Flush()
+		}
+	})
+	proxy := buildProxy("", false, 0, 0, false, false, 0, 0, 0, 0, nil, false, "", 0, nil, "", 0, readTimeout, false, 0)
+	server := httptest.NewUnstartedServer(proxyHandler(proxy))
+	server.Config.WriteTimeout = writeTimeout
+	server.Start()
+	t.Cleanup(server.Close)
+	response, err := http.Post(server.URL+"/v1/chat/completions", "application/json", strings.NewReader(`{"model":"moonshot-v1-8k","stream":true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("the stream is cut off after %q: %v", body, err)
+	}
+	if want := strings.Join(chunks, "\n\n") + "\n\n"; string(body) != want {
+		t.Errorf("response body %q, want %q", body, want)
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// rawHeaderLimit is the number of bytes most recently read from a connection
+// that are kept, request header blocks larger than it, or following a chunked
+// body larger than it, are not recorded. Bodies with a Content-Length are not
+// kept, so they are not limited.
+const rawHeaderLimit = 64 << 10
+
+// rawHeaderListener wraps the accepted connections with rawHeaderConn.
+type rawHeaderListener struct {
+	net.Listener
+}
+
+func (l rawHeaderListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &rawHeaderConn{Conn: conn}, nil
+}
+
+// rawHeaderConn keeps the bytes recently read from the connection, from which
+// the header block of the request being served is taken verbatim, as net/http
+// canonicalizes the names of headers and loses their order. Requests on an
+// HTTP/1.x connection follow each other, the offset of each one is found from
+// the framing of the body of the previous one. Once the header block is found,
+// a body with a Content-Length is skipped rather than kept, and bytes are kept
+// again from the next request on.
+type rawHeaderConn struct {
+	net.Conn
+	mu  sync.Mutex
+	buf []byte
+	// base is the offset on the connection of the first byte of buf.
+	base int64
+	// next is the offset of the next request, or of the chunked body of the
+	// previous request if chunked is set, -1 once it is not known.
+	next    int64
+	chunked bool
+	// skip is the number of bytes not read yet that precede the next request,
+	// which are dropped as they are read, buf is empty as long as it is set.
+	skip int64
+}
+
+func (c *rawHeaderConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if n > 0 {
+		c.mu.Lock()
+		data := p[:n]
+		if c.skip > 0 {
+			skipped := min(c.skip, int64(len(data)))
+			c.skip -= skipped
+			c.base += skipped
+			data = data[skipped:]
+		}
+		c.buf = append(c.buf, data...)
+		if len(c.buf) > 2*rawHeaderLimit {
+			dropped := len(c.buf) - rawHeaderLimit
+			c.buf = append(c.buf[:0], c.buf[dropped:]...)
+			c.base += int64(dropped)
+		}
+		c.mu.Unlock()
+	}
+	return n, err
+}
+
+// discard drops the bytes before offset, those not read yet are dropped as they
+// are read.
+func (c *rawHeaderConn) discard(offset int64) {
+	end := c.base + int64(len(c.buf))
+	if offset >= end {
+		c.buf = c.buf[:0]
+		c.base, c.skip = end, offset-end
+		return
+	}
+	c.buf = append(c.buf[:0], c.buf[offset-c.base:]...)
+	c.base = offset
+}
+
+// skipChunked returns the offset following the chunked body at offset, or -1
+// if it is no longer buffered.
+func (c *rawHeaderConn) skipChunked(offset int64) int64 {
+	if offset < c.base {
+		return -1
+	}
+	data := c.buf[offset-c.base:]
+	for {
+		line, rest, ok := bytes.Cut(data, []byte("\r\n"))
+		if !ok {
+			return -1
+		}
+		sizeField, _, _ := bytes.Cut(line, []byte(";"))
+		size, err := strconv.ParseInt(string(bytes.TrimSpace(sizeField)), 16, 64)
+		if err != nil || size < 0 {
+			return -1
+		}
+		if size == 0 {
+			// The trailer section ends with an empty line.
+			for {
+				line, rest, ok = bytes.Cut(rest, []byte("\r\n"))
+				if !ok {
+					return -1
+				}
+				if len(line) == 0 {
+					return c.base + int64(len(c.buf)-len(rest))
+				}
+			}
+		}
+		if int64(len(rest)) < size+2 {
+			return -1
+		}
+		data = rest[size+2:]
+	}
+}
+
+type rawHeaderConnKey struct{}
+
+// rawHeaderConnContext is used as http.Server.ConnContext, so that handlers can
+// find the connection a request is read from.
+func rawHeaderConnContext(ctx context.Context, conn net.Conn) context.Context {
+	if c, ok := conn.(*rawHeaderConn); ok {
+		return context.WithValue(ctx, rawHeaderConnKey{}, c)
+	}
+	return ctx
+}
+
+// rawRequestHeader returns the header block of the request as it was received,
+// without the request line and the Authorization header, or an empty string if
+// it is not available. It must be called for every request on the connection,
+// as the offset of the next request is found from the headers of this one.
+// HTTP/2 requests, whose headers are compressed in frames, are not covered.
+func rawRequestHeader(r *http.Request) string {
+	c, ok := r.Context().Value(rawHeaderConnKey{}).(*rawHeaderConn)
+	if !ok || r.ProtoMajor != 1 {
+		return ""
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	start := c.next
+	if start >= 0 && c.chunked {
+		start = c.skipChunked(start)
+	}
+	// Until the header block is found, the offset of the following request is
+	// not known either.
+	c.next, c.chunked = -1, false
+	if start < c.base {
+		return ""
+	}
+	block := c.buf[start-c.base:]
+	if !bytes.HasPrefix(block, []byte(r.Method+" "+r.RequestURI+" HTTP/")) {
+		return ""
+	}
+	end := bytes.Index(block, []byte("\r\n\r\n"))
+	if end < 0 {
+		return ""
+	}
+	c.next = start + int64(end) + 4
+	if len(r.TransferEncoding) > 0 && r.TransferEncoding[0] == "chunked" {
+		c.chunked = true
+	} else if r.ContentLength > 0 {
+		c.next += r.ContentLength
+	}
+	lines := bytes.SplitAfter(block[:end+2], []byte("\r\n"))
+	var header bytes.Buffer
+	for _, line := range lines[1:] {
+		name, _, _ := bytes.Cut(line, []byte(":"))
+		if !bytes.EqualFold(bytes.TrimSpace(name), []byte("Authorization")) {
+			header.Write(line)
+		}
+	}
+	// Chunked bodies are kept to find their end, other bodies are skipped.
+	if !c.chunked {
+		c.discard(c.next)
+	}
+	return header.String()
+}
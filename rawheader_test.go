@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// startRawHeaderServer serves the raw header of each request, as the proxy
+// records it.
+func startRawHeaderServer(t *testing.T) string {
+	t.Helper()
+	var mu sync.Mutex
+	return startTestProxy(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		header := rawRequestHeader(r)
+		io.Copy(io.Discard, r.Body)
+		io.WriteString(w, header)
+	})
+}
+
+func TestRawRequestHeader_KeepAlive(t *testing.T) {
+	base := startRawHeaderServer(t)
+	target, _ := url.Parse(base)
+	conn, err := net.Dial("tcp", target.Host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	// The body of the first request contains a request line of its own, and the
+	// requests are pipelined, so that the headers can only be found by offset.
+	requests := []struct {
+		raw    string
+		header string
+	}{
+		{
+			raw: "POST /v1/chat/completions HTTP/1.1\r\nHost: proxy\r\nx-first: 1\r\nContent-Length: 42\r\n\r\n" +
+				"GET /v1/models HTTP/1.1\r\nx-injected: 1\r\n\r\n",
+			header: "Host: proxy\r\nx-first: 1\r\nContent-Length: 42\r\n",
+		},
+		{
+			raw: "POST /v1/chat/completions HTTP/1.1\r\nHost: proxy\r\nTransfer-Encoding: chunked\r\nx-second: 2\r\n\r\n" +
+				"1b\r\nGET /v1/models HTTP/1.1\r\n\r\n\r\n0\r\nx-trailer: 1\r\n\r\n",
+			header: "Host: proxy\r\nTransfer-Encoding: chunked\r\nx-second: 2\r\n",
+		},
+		{
+			raw:    "GET /v1/models HTTP/1.1\r\nHost: proxy\r\nauthorization: Bearer sk-test\r\nX-THIRD: 3\r\n\r\n",
+			header: "Host: proxy\r\nX-THIRD: 3\r\n",
+		},
+	}
+	var pipelined strings.Builder
+	for _, request := range requests {
+		pipelined.WriteString(request.raw)
+	}
+	if _, err = io.WriteString(conn, pipelined.String()); err != nil {
+		t.Fatal(err)
+	}
+	reader := bufio.NewReader(conn)
+	for i, request := range requests {
+		response, err := http.ReadResponse(reader, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		header, _ := io.ReadAll(response.Body)
+		response.Body.Close()
+		if string(header) != request.header {
+			t.Errorf("request %d: raw header %q, want %q", i+1, header, request.header)
+		}
+	}
+}
+
+func TestRawRequestHeader_LargeBody(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		kept int
+	)
+	base := startTestProxy(t, func(w http.ResponseWriter, r *http.Request) {
+		header := rawRequestHeader(r)
+		io.Copy(io.Discard, r.Body)
+		c := r.Context().Value(rawHeaderConnKey{}).(*rawHeaderConn)
+		c.mu.Lock()
+		mu.Lock()
+		kept = max(kept, len(c.buf))
+		mu.Unlock()
+		c.mu.Unlock()
+		io.WriteString(w, header)
+	})
+	target, _ := url.Parse(base)
+	conn, err := net.Dial("tcp", target.Host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	// A body larger than rawHeaderLimit, such as one with a base64 image, is
+	// skipped rather than kept, and the header of the next request is found.
+	body := strings.Repeat("a", 3*rawHeaderLimit)
+	go io.WriteString(conn, "POST /v1/chat/completions HTTP/1.1\r\nHost: proxy\r\nContent-Length: "+strconv.Itoa(len(body))+"\r\n\r\n"+body+
+		"GET /v1/models HTTP/1.1\r\nHost: proxy\r\nx-next: 1\r\n\r\n")
+	reader := bufio.NewReader(conn)
+	for i, want := range []string{
+		"Host: proxy\r\nContent-Length: " + strconv.Itoa(len(body)) + "\r\n",
+		"Host: proxy\r\nx-next: 1\r\n",
+	} {
+		response, err := http.ReadResponse(reader, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		header, _ := io.ReadAll(response.Body)
+		response.Body.Close()
+		if string(header) != want {
+			t.Errorf("request %d: raw header %q, want %q", i+1, header, want)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if kept >= len(body)/3 {
+		t.Errorf("%d bytes of the body are kept", kept)
+	}
+}
+
+func TestRawRequestHeader_H2C(t *testing.T) {
+	base := startRawHeaderServer(t)
+	client := h2cClient()
+	for range 2 {
+		response, err := client.Get(base + "/v1/models")
+		if err != nil {
+			t.Fatal(err)
+		}
+		header, _ := io.ReadAll(response.Body)
+		response.Body.Close()
+		if response.ProtoMajor != 2 {
+			t.Fatalf("expected an HTTP/2 response, got %s", response.Proto)
+		}
+		if len(header) != 0 {
+			t.Errorf("HTTP/2 requests have no raw header, got %q", header)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+	"github.com/tidwall/gjson"
+)
+
+// reindexBatchSize is the number of requests loaded at a time by reindex.
+const reindexBatchSize = 500
+
+// derivedColumns are the columns filled by reindex, in the order they are
+// reported.
+var derivedColumns = []string{"moonshot_id", "model", "system_fingerprint", "rate_limit", "request_body_hash"}
+
+func reindexCommand() *cobra.Command {
+	var dryRun bool
+	cmd := &cobra.Command{
+		Use:         "reindex",
+		Short:       "Fill the columns derived from the stored bodies and headers of Moonshot AI requests captured before the columns were added",
+		Annotations: map[string]string{databaseAnnotation: databaseRead},
+		Run: func(cmd *cobra.Command, args []string) {
+			if !dryRun {
+				if err := checkWritable("fill the derived columns"); err != nil {
+					logFatal(err)
+				}
+			}
+			total, err := persistence.CountRequests()
+			if err != nil {
+				logFatal(err)
+			}
+			var (
+				scanned, updated int64
+				filled           = make(map[string]int64, len(derivedColumns))
+				lastID           int64
+			)
+			for {
+				requests, err := persistence.GetRequestsByRange(lastID+1, 0, "", nil, "", "", "", nil, nil, false, false, false, "", "", reindexBatchSize)
+				if err != nil {
+					logFatal(err)
+				}
+				if len(requests) == 0 {
+					break
+				}
+				for _, request := range requests {
+					columns := request.reindex()
+					if len(columns) == 0 {
+						continue
+					}
+					for _, column := range columns {
+						filled[column]++
+					}
+					updated++
+					if dryRun {
+						continue
+					}
+					if err = persistence.SetDerivedFields(
+						request.ID,
+						request.MoonshotID,
+						request.Model,
+						request.SystemFingerprint,
+						request.RateLimit,
+						request.RequestBodyHash,
+					); err != nil {
+						logFatal(err)
+					}
+				}
+				scanned += int64(len(requests))
+				lastID = requests[len(requests)-1].ID
+				logger.Printf("reindexed %d/%d requests\n", scanned, total)
+			}
+			t.AppendHeader(table.Row{"column", "filled"})
+			for _, column := range derivedColumns {
+				t.AppendRow(table.Row{column, filled[column]})
+			}
+			summary := strconv.FormatInt(updated, 10) + " of " + strconv.FormatInt(scanned, 10) + " requests updated"
+			if dryRun {
+				summary = strconv.FormatInt(updated, 10) + " of " + strconv.FormatInt(scanned, 10) + " requests would be updated"
+			}
+			t.AppendFooter(table.Row{"total", summary})
+			t.Render()
+		},
+	}
+	flags := cmd.PersistentFlags()
+	flags.BoolVar(&dryRun, "dry-run", false, "report the columns that would be filled without updating the requests")
+	return cmd
+}
+
+// reindex fills the missing columns that are derived from the stored request
+// and response, the same way they are at capture time, and returns the names of
+// the columns filled. Columns already set are left as they are.
+func (r *Request) reindex() (filled []string) {
+	fill := func(column string, field *sql.NullString, value string) {
+		if value != "" && field.String == "" {
+			*field = sql.NullString{String: value, Valid: true}
+			filled = append(filled, column)
+		}
+	}
+	if r.ResponseBody.String != "" {
+		response := r.ResponseBody.String
+		if r.ResponseContentType.String == "text/event-stream" && !gjson.Valid(response) {
+			response = mergeCompletion(response)
+		}
+		if gjson.Valid(response) {
+			fill("moonshot_id", &r.MoonshotID, gjson.Get(response, "id").String())
+			fill("model", &r.Model, gjson.Get(response, "model").String())
+			fill("system_fingerprint", &r.SystemFingerprint, gjson.Get(response, "system_fingerprint").String())
+		}
+	}
+	if r.ResponseHeader.Valid {
+		fill("rate_limit", &r.RateLimit, parseRateLimit(&http.Response{Header: r.ResponseHeaders()}))
+	}
+	// The hash of a truncated body would not match the one of the whole body.
+	if r.RequestBody.String != "" && !r.IsRequestBodyTruncated() {
+		fill("request_body_hash", &r.RequestBodyHash, hashRequestBody(r.RequestBody.String))
+	}
+	return filled
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tidwall/gjson"
+)
+
+func replayCommand() *cobra.Command {
+	var (
+		id          int64
+		chatcmpl    string
+		requestID   string
+		uid         string
+		atTime      string
+		key         = defaultAPIKey()
+		contentType string
+		modifyBody  []string
+		modifyJSON  string
+		storeResult bool
+		staleAfter  time.Duration
+		force       bool
+		semantic    bool
+		model       string
+		threshold   float64
+	)
+	cmd := &cobra.Command{
+		Use:         "replay",
+		Short:       "Replay a Moonshot AI request and print the response",
+		Annotations: map[string]string{databaseAnnotation: databaseRead},
+		Run: func(cmd *cobra.Command, args []string) {
+			request, err := selectRequest(id, chatcmpl, requestID, uid, atTime)
+			if err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					logFatal(sql.ErrNoRows)
+				}
+				logFatal(err)
+			}
+			if request.IsRequestBodyTruncated() {
+				logFatal(errors.New("request body is truncated, unable to replay " + request.Ident()))
+			}
+			if age := time.Since(request.CreatedAt.Time); age > staleAfter && !force {
+				logWarning(fmt.Sprintf(
+					"%s was captured %s ago, the API may behave differently since then, use --force to replay silently",
+					request.Ident(), formatAge(age),
+				))
+			}
+			if storeResult || semantic {
+				if err = checkWritable("store the replayed request or its score"); err != nil {
+					logFatal(err)
+				}
+			}
+			if semantic && (threshold < 0 || threshold > 1) {
+				logFatal(errors.New("--compare-threshold expects a score between 0 and 1, such as 0.8"))
+			}
+			body := json.RawMessage(request.RequestBody.String)
+			if len(modifyBody) > 0 || modifyJSON != "" {
+				patches := make([]json.RawMessage, 0, len(modifyBody)+1)
+				for _, modify := range modifyBody {
+					patch, err := parseBodyModification(modify)
+					if err != nil {
+						logFatal(err)
+					}
+					patches = append(patches, patch)
+				}
+				if modifyJSON != "" {
+					if !json.Valid([]byte(modifyJSON)) {
+						logFatal(errors.New("--modify-body-json is not a valid JSON"))
+					}
+					patches = append(patches, json.RawMessage(modifyJSON))
+				}
+				for _, patch := range patches {
+					if body, err = mergeJSON(body, patch); err != nil {
+						logFatal(err)
+					}
+				}
+			}
+			createdAt := time.Now()
+			newRequest, response, err := sendReplay(request, body, key, contentType)
+			if err != nil {
+				logFatal(err)
+			}
+			defer response.Body.Close()
+			logger.Printf("%s %s %s\n",
+				boldYellowf("%-6s", request.RequestMethod),
+				boldWhite(request.Url()),
+				response.Status,
+			)
+			var responseBody bytes.Buffer
+			if _, err = io.Copy(os.Stdout, io.TeeReader(response.Body, &responseBody)); err != nil {
+				logFatal(err)
+			}
+			result := newReplayResult(request, newRequest, body, response, responseBody.String())
+			result.CreatedAt = SqliteTime{createdAt}
+			result.Latency = sql.NullInt64{Int64: int64(time.Since(createdAt)), Valid: true}
+			var replayID sql.NullInt64
+			if storeResult {
+				lastInsertID, err := persistence.InsertRequest(result)
+				if err != nil {
+					logFatal(err)
+				}
+				logNewRow(lastInsertID)
+				replayID = sql.NullInt64{Int64: lastInsertID, Valid: true}
+			}
+			if semantic {
+				score, err := compareSemantic(request, result, model, key)
+				if err != nil {
+					logFatal(err)
+				}
+				if err = persistence.InsertBenchmarkRun(request.ID, replayID, model, score, threshold); err != nil {
+					logFatal(err)
+				}
+				logger.Printf("semantic similarity to %s: %.2f (threshold %.2f)\n", request.Ident(), score, threshold)
+				if score < threshold {
+					logFatal(fmt.Errorf("the replayed response differs from %s in meaning, similarity %.2f is below %.2f", request.Ident(), score, threshold))
+				}
+			}
+		},
+	}
+	flags := cmd.PersistentFlags()
+	flags.Int64Var(&id, "id", 0, "row id")
+	flags.StringVar(&chatcmpl, "chatcmpl", "", "chatcmpl")
+	flags.StringVar(&requestID, "requestid", "", "request id returned from Moonshot AI")
+	flags.StringVar(&uid, "uid", "", "user id, used together with --at-time")
+	flags.StringVar(&atTime, "at-time", "", "select the request of the user closest to the time in RFC3339 format")
+	flags.StringVarP(&key, "key", "k", key, "API key, defaults to $MOONSHOT_API_KEY")
+	flags.StringVar(&contentType, "content-type", "", "override Content-Type of the replayed request")
+	flags.StringArrayVar(&modifyBody, "modify-body", nil, "modify a field of the request body before replaying, such as temperature=0.0 or response_format.type=text")
+	flags.StringVar(&modifyJSON, "modify-body-json", "", "JSON object deep-merged into the request body before replaying")
+	flags.BoolVar(&storeResult, "store-result", false, "store the replayed request as a new row, whose parent_id is the original request")
+	flags.DurationVar(&staleAfter, "stale-after", 30*24*time.Hour, "warn when replaying a request captured longer ago than this")
+	flags.BoolVar(&force, "force", false, "replay stale requests without warning")
+	flags.BoolVar(&semantic, "compare-semantic", false, "ask --compare-model how similar the replayed reply is in meaning to the original one, store the score in benchmark_runs and fail if it is below --compare-threshold")
+	flags.StringVar(&model, "compare-model", defaultCompareModel, "model comparing the replies with --compare-semantic")
+	flags.Float64Var(&threshold, "compare-threshold", 0.8, "lowest similarity, between 0 and 1, for the replay to pass with --compare-semantic")
+	cmd.MarkFlagsOneRequired("id", "chatcmpl", "requestid", "at-time")
+	cmd.MarkFlagsRequiredTogether("uid", "at-time")
+	return cmd
+}
+
+// defaultAPIKey returns the key configured for the start command, or the key in
+// $MOONSHOT_API_KEY if none is configured.
+func defaultAPIKey() string {
+	if MoonConfig.Start != nil && MoonConfig.Start.Key != "" {
+		return MoonConfig.Start.Key
+	}
+	return os.Getenv("MOONSHOT_API_KEY")
+}
+
+// formatAge formats the age of a request in days, or as a duration rounded to
+// the minute if it is less than 2 days.
+func formatAge(age time.Duration) string {
+	if age < 48*time.Hour {
+		return age.Round(time.Minute).String()
+	}
+	return strconv.FormatInt(int64(age/(24*time.Hour)), 10) + " days"
+}
+
+// sendReplay sends the request again with the body, which may be modified, the
+// caller is responsible for closing the response body.
+func sendReplay(request *Request, body []byte, key string, contentType string) (*http.Request, *http.Response, error) {
+	newRequest, err := http.NewRequest(
+		request.RequestMethod,
+		request.Url(),
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	newRequest.Header = request.Header()
+	// Let the http.Client negotiate and decompress the response body.
+	newRequest.Header.Del("Accept-Encoding")
+	if contentType != "" {
+		newRequest.Header.Set("Content-Type", contentType)
+	}
+	if key != "" {
+		newRequest.Header.Set("Authorization", "Bearer "+key)
+	}
+	response, err := httpClient.Do(newRequest)
+	if err != nil {
+		return nil, nil, err
+	}
+	return newRequest, response, nil
+}
+
+// newReplayResult builds the row of a replayed request, fields read from the
+// response are extracted in the same way as the proxy does.
+func newReplayResult(
+	original *Request,
+	newRequest *http.Request,
+	requestBody []byte,
+	response *http.Response,
+	responseBody string,
+) *Request {
+	valid := func(s string) sql.NullString {
+		return sql.NullString{String: s, Valid: s != ""}
+	}
+	responseContentType := filterHeaderFlags(response.Header.Get("Content-Type"))
+	completion := responseBody
+	if responseContentType == "text/event-stream" {
+		completion = mergeCompletion(completion)
+	}
+	return &Request{
+		RequestMethod:       original.RequestMethod,
+		RequestPath:         original.RequestPath,
+		RequestQuery:        original.RequestQuery,
+		RequestContentType:  valid(filterHeaderFlags(newRequest.Header.Get("Content-Type"))),
+		RequestID:           valid(newRequest.Header.Get("X-Request-Id")),
+		MoonshotID:          valid(gjson.Get(completion, "id").String()),
+		MoonshotGID:         valid(response.Header.Get("Msh-Gid")),
+		MoonshotUID:         valid(response.Header.Get("Msh-Uid")),
+		MoonshotRequestID:   valid(response.Header.Get("Msh-Request-Id")),
+		ResponseStatusCode:  sql.NullInt64{Int64: int64(response.StatusCode), Valid: true},
+		ResponseContentType: valid(responseContentType),
+		RequestHeader:       valid(formatHeader(newRequest)),
+		RequestBody:         valid(string(requestBody)),
+		RequestBodyHash:     valid(hashRequestBody(string(requestBody))),
+		ResponseHeader:      valid(formatHeader(response)),
+		ResponseBody:        valid(responseBody),
+		Endpoint:            original.Endpoint,
+		Model:               valid(gjson.Get(completion, "model").String()),
+		SystemFingerprint:   valid(gjson.Get(completion, "system_fingerprint").String()),
+		RateLimit:           valid(parseRateLimit(response)),
+		ParentID:            sql.NullInt64{Int64: original.ID, Valid: true},
+	}
+}
+
+// parseBodyModification converts "a.b=value" to {"a":{"b":value}}, value is used
+// as is if it is a valid JSON, otherwise it is treated as a string.
+func parseBodyModification(modify string) (json.RawMessage, error) {
+	path, value, found := strings.Cut(modify, "=")
+	if !found || path == "" {
+		return nil, fmt.Errorf("invalid body modification %q, expects path=value", modify)
+	}
+	patch := json.RawMessage(value)
+	if !json.Valid(patch) {
+		patch, _ = json.Marshal(value)
+	}
+	keys := strings.Split(path, ".")
+	for i := len(keys) - 1; i >= 0; i-- {
+		object, err := json.Marshal(map[string]json.RawMessage{keys[i]: patch})
+		if err != nil {
+			return nil, err
+		}
+		patch = object
+	}
+	return patch, nil
+}
+
+// mergeJSON deep-merges patch into target, objects are merged key by key while
+// any other value in patch replaces the one in target.
+func mergeJSON(target, patch json.RawMessage) (json.RawMessage, error) {
+	var targetObject, patchObject map[string]json.RawMessage
+	if json.Unmarshal(patch, &patchObject) != nil || patchObject == nil {
+		return patch, nil
+	}
+	if json.Unmarshal(target, &targetObject) != nil || targetObject == nil {
+		return patch, nil
+	}
+	for key, value := range patchObject {
+		merged, err := mergeJSON(targetObject[key], value)
+		if err != nil {
+			return nil, err
+		}
+		targetObject[key] = merged
+	}
+	return json.Marshal(targetObject)
+}
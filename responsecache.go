@@ -0,0 +1,49 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// Hits and misses of --response-cache since the proxy started.
+var responseCacheHits, responseCacheMisses atomic.Int64
+
+// lookupCachedResponse returns the latest successful request with the same
+// method, path, query, request body hash and API key, stored within ttl if it
+// is set.
+func lookupCachedResponse(method, path, query, bodyHash, credentialHash string, ttl time.Duration) (*Request, error) {
+	var since string
+	if ttl > 0 {
+		since = time.Now().Add(-ttl).Format(time.DateTime)
+	}
+	request, err := persistence.GetCachedResponse(bodyHash, credentialHash, method, path, query, since)
+	if errors.Is(err, sql.ErrNoRows) {
+		responseCacheMisses.Add(1)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	responseCacheHits.Add(1)
+	return request, nil
+}
+
+// serveCachedResponse writes the stored response, the body is stored decoded so
+// headers about its encoding and length are not copied.
+func serveCachedResponse(w http.ResponseWriter, cached *Request) {
+	header := w.Header()
+	for name, values := range cached.ResponseHeaders() {
+		switch name {
+		case "Content-Length", "Content-Encoding", "Transfer-Encoding", "Connection":
+			continue
+		}
+		header[name] = values
+	}
+	header.Set("X-Moonpalace-Cache", "hit; id="+strconv.FormatInt(cached.ID, 10))
+	w.WriteHeader(int(cached.ResponseStatusCode.Int64))
+	w.Write([]byte(cached.ResponseBody.String))
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	_ "embed"
+	"errors"
+	"sync"
+
+	"github.com/MoonshotAI/moonpalace/jsonschema"
+	"github.com/tidwall/gjson"
+)
+
+// responseSchemaJSON describes the chat completions returned by Moonshot AI,
+// streamed responses are checked once their chunks are merged.
+//
+//go:embed responseschema.json
+var responseSchemaJSON []byte
+
+var responseSchema = sync.OnceValue(func() *jsonschema.Schema {
+	schema, err := jsonschema.Compile(responseSchemaJSON)
+	if err != nil {
+		panic(err)
+	}
+	return schema
+})
+
+// ResponseSchemaViolations validates the response body against the schema of
+// chat completions, requests other than successful chat completions are not
+// checked and have no violations.
+func (r *Request) ResponseSchemaViolations() ([]jsonschema.Violation, error) {
+	if !r.IsChat() || r.ResponseStatusCode.Int64 < 200 || r.ResponseStatusCode.Int64 > 299 || r.ResponseBody.String == "" {
+		return nil, nil
+	}
+	response := r.ResponseBody.String
+	if r.ResponseContentType.String == "text/event-stream" && !gjson.Valid(response) {
+		response = mergeCompletion(response)
+	}
+	if !gjson.Valid(response) {
+		return nil, errors.New("response body is not valid JSON")
+	}
+	return responseSchema().Validate([]byte(response))
+}
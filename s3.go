@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+const s3Scheme = "s3://"
+
+func isS3URL(path string) bool {
+	return strings.HasPrefix(path, s3Scheme)
+}
+
+// checkObjectURL rejects URLs of object stores other than S3, which would
+// otherwise be taken as local paths.
+func checkObjectURL(path string) error {
+	scheme, _, found := strings.Cut(path, "://")
+	switch {
+	case !found || scheme == "s3":
+		return nil
+	case scheme == "gs":
+		return fmt.Errorf("unsupported URL %q, Google Cloud Storage is supported through its S3 compatible API only, "+
+			"use an s3:// URL with AWS_ENDPOINT_URL_S3=https://storage.googleapis.com and an HMAC key", path)
+	default:
+		return fmt.Errorf("unsupported URL %q, only s3:// URLs are supported", path)
+	}
+}
+
+// parseS3URL splits s3://bucket/key into the bucket and the object key.
+func parseS3URL(url string) (bucket, key string, err error) {
+	bucket, key, _ = strings.Cut(strings.TrimPrefix(url, s3Scheme), "/")
+	if bucket == "" || key == "" {
+		return "", "", fmt.Errorf("invalid S3 URL %q, expects s3://bucket/key", url)
+	}
+	return bucket, key, nil
+}
+
+// newS3Client loads the configuration as the AWS CLI does, so credentials are
+// taken from the environment, the shared config and credentials files, which
+// include SSO, credential_process and assume-role profiles, or the ECS task
+// role and the EC2 instance profile. Path-style URLs are used for S3
+// compatible object stores set with AWS_ENDPOINT_URL_S3 or AWS_ENDPOINT_URL.
+func newS3Client(ctx context.Context) (*s3.Client, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	return s3.NewFromConfig(cfg, func(options *s3.Options) {
+		options.UsePathStyle = os.Getenv("AWS_ENDPOINT_URL_S3") != "" || os.Getenv("AWS_ENDPOINT_URL") != ""
+	}), nil
+}
+
+// putS3Object uploads the data to the object located by an s3://bucket/key URL,
+// large objects are uploaded in parts.
+func putS3Object(ctx context.Context, url string, data []byte, contentType string) error {
+	bucket, key, err := parseS3URL(url)
+	if err != nil {
+		return err
+	}
+	client, err := newS3Client(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = manager.NewUploader(client).Upload(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String(contentType),
+	})
+	if err != nil {
+		return fmt.Errorf("upload to %s: %w", url, err)
+	}
+	return nil
+}
+
+// s3Object buffers the exported content and uploads it when closed.
+type s3Object struct {
+	bytes.Buffer
+	url string
+}
+
+func (o *s3Object) Name() string {
+	return o.url
+}
+
+func (o *s3Object) Close() error {
+	contentType := "application/json"
+	switch filepath.Ext(o.url) {
+	case ".jsonl", ".ndjson":
+		contentType = "application/x-ndjson"
+	case ".txt":
+		contentType = "text/plain; charset=utf-8"
+	case ".ts":
+		contentType = "application/typescript"
+	}
+	return putS3Object(context.Background(), o.url, o.Bytes(), contentType)
+}
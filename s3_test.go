@@ -0,0 +1,70 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestS3Object(t *testing.T) {
+	var (
+		path, contentType, authorization string
+		body                             []byte
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			t.Errorf("unexpected %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		path, contentType, authorization = r.URL.Path, r.Header.Get("Content-Type"), r.Header.Get("Authorization")
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+	// Static credentials of an S3 compatible object store, no config file and
+	// no instance metadata.
+	dir := t.TempDir()
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY")
+	t.Setenv("AWS_ENDPOINT_URL", "")
+	t.Setenv("AWS_ENDPOINT_URL_S3", server.URL)
+	object := &s3Object{url: "s3://moonpalace/exports/2024-08-01/requests.jsonl"}
+	object.WriteString("{\"id\":1}\n")
+	if err := object.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if path != "/moonpalace/exports/2024-08-01/requests.jsonl" {
+		t.Errorf("uploaded to %s, expects a path-style URL", path)
+	}
+	if contentType != "application/x-ndjson" {
+		t.Errorf("Content-Type: %s", contentType)
+	}
+	if authorization == "" {
+		t.Error("the upload is not signed")
+	}
+	if string(body) != "{\"id\":1}\n" {
+		t.Errorf("uploaded %q", body)
+	}
+}
+
+func TestCheckObjectURL(t *testing.T) {
+	for path, supported := range map[string]bool{
+		"requests.json":             true,
+		"/tmp/requests.json":        true,
+		"s3://bucket/requests.json": true,
+		"gs://bucket/requests.json": false,
+		"az://bucket/requests.json": false,
+	} {
+		if err := checkObjectURL(path); (err == nil) != supported {
+			t.Errorf("checkObjectURL(%q): %v", path, err)
+		}
+	}
+}
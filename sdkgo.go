@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"go/format"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+)
+
+// sdkGoTemplate is a Go program sending the request with net/http, as there is
+// no Go SDK of Moonshot AI, whose response is written to stdout.
+var sdkGoTemplate = template.Must(template.New("sdk-go").Parse(`// Generated by MoonPalace from {{ .Ident }}.
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+)
+
+func main() {
+	body := strings.NewReader({{ .Body }})
+	req, err := http.NewRequestWithContext(context.Background(), {{ .Method }}, {{ .URL }}, body)
+	if err != nil {
+		log.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+os.Getenv({{ .APIKeyEnv }}))
+{{- range .Header }}
+	req.Header.Add({{ .Name }}, {{ .Value }})
+{{- end }}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		data, _ := io.ReadAll(resp.Body)
+		log.Fatal(fmt.Errorf("unexpected status %s: %s", resp.Status, data))
+	}
+	if _, err = io.Copy(os.Stdout, resp.Body); err != nil {
+		log.Fatal(err)
+	}
+}
+`))
+
+// sdkGoSkippedHeaders are the recorded headers the program does not set, as
+// net/http sets them itself: the body is only decompressed transparently if
+// Accept-Encoding is not set, the length is that of the body, and the host is
+// that of the URL, which may be another one with the baseURLEnv variable.
+var sdkGoSkippedHeaders = []string{"Accept-Encoding", "Content-Length", "Host"}
+
+// writeSDKGo writes the request as a Go program, the API key is read from the
+// apiKeyEnv variable and the recorded endpoint is replaced with the baseURLEnv
+// variable if it is not empty, as in writeCurlCommand.
+func writeSDKGo(w io.Writer, request *Request, apiKeyEnv, baseURLEnv string, canonicalHeaders bool) error {
+	if request.IsRequestBodyTruncated() {
+		return errors.New("request body is truncated, unable to export Go code of " + request.Ident())
+	}
+	url := strconv.Quote(request.Url())
+	if baseURLEnv != "" {
+		path := request.RequestPath
+		if request.RequestQuery != "" {
+			path += "?" + request.RequestQuery
+		}
+		url = "os.Getenv(" + strconv.Quote(baseURLEnv) + ")+" + strconv.Quote(path)
+	}
+	type field struct{ Name, Value string }
+	var header []field
+	for _, f := range exportedHeaderFields(request, "", canonicalHeaders) {
+		if slices.ContainsFunc(sdkGoSkippedHeaders, func(name string) bool {
+			return strings.EqualFold(name, f.Name)
+		}) {
+			continue
+		}
+		header = append(header, field{strconv.Quote(f.Name), strconv.Quote(f.Value)})
+	}
+	var source bytes.Buffer
+	if err := sdkGoTemplate.Execute(&source, map[string]any{
+		"Ident":     request.Ident(),
+		"Body":      goStringLiteral(request.RequestBody.String),
+		"Method":    strconv.Quote(request.RequestMethod),
+		"URL":       url,
+		"APIKeyEnv": strconv.Quote(apiKeyEnv),
+		"Header":    header,
+	}); err != nil {
+		return err
+	}
+	formatted, err := format.Source(source.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(formatted)
+	return err
+}
+
+// goStringLiteral returns s as a raw string literal, which keeps JSON bodies
+// readable, or as an interpreted one if s cannot be written in backquotes.
+func goStringLiteral(s string) string {
+	if utf8.ValidString(s) && !strings.ContainsAny(s, "`\r\x00") {
+		return "`" + s + "`"
+	}
+	return strconv.Quote(s)
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"strings"
+	"testing"
+)
+
+func TestWriteSDKGo_SkippedHeaders(t *testing.T) {
+	request := &Request{
+		RequestMethod:    "POST",
+		RequestPath:      "/v1/chat/completions",
+		RequestBody:      sql.NullString{String: `{"model":"moonshot-v1-8k"}`, Valid: true},
+		RawRequestHeader: sql.NullString{String: "Host: 127.0.0.1:9988\r\nContent-Length: 26\r\naccept-encoding: gzip\r\nX-Custom: 1\r\n", Valid: true},
+	}
+	var source bytes.Buffer
+	if err := writeSDKGo(&source, request, "MOONSHOT_API_KEY", "", false); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(source.String(), `req.Header.Add("X-Custom", "1")`) {
+		t.Errorf("recorded header is not set:\n%s", source.String())
+	}
+	for _, name := range sdkGoSkippedHeaders {
+		if strings.Contains(strings.ToLower(source.String()), strings.ToLower(`"`+name+`"`)) {
+			t.Errorf("%s is set:\n%s", name, source.String())
+		}
+	}
+}
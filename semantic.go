@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/tidwall/gjson"
+)
+
+// defaultCompareModel is the model asked to compare replies with replay
+// --compare-semantic, a small one is enough to tell whether two replies mean
+// the same.
+const defaultCompareModel = "moonshot-v1-8k"
+
+const semanticComparePrompt = `You compare two replies of an assistant to the same conversation, and judge how similar they are in meaning, regardless of wording, formatting and length. Reply with a JSON object such as {"score": 0.85}, where score is a number from 0, for replies that disagree or are about different things, to 1, for replies that say the same thing.`
+
+// compareSemantic asks the model of the endpoint of the original request how
+// similar the replies of both requests are in meaning, and returns the score
+// between 0 and 1.
+func compareSemantic(original, replayed *Request, model, key string) (float64, error) {
+	replies := make([]string, 0, 2)
+	for _, request := range []*Request{original, replayed} {
+		merged := *request
+		if merged.ResponseContentType.String == "text/event-stream" && !gjson.Valid(merged.ResponseBody.String) {
+			merged.ResponseBody.String = mergeCompletion(merged.ResponseBody.String)
+		}
+		reply, err := merged.AssistantReply()
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", request.Ident(), err)
+		}
+		replies = append(replies, reply)
+	}
+	body, err := json.Marshal(map[string]any{
+		"model":           model,
+		"temperature":     0,
+		"response_format": map[string]string{"type": "json_object"},
+		"messages": []map[string]string{
+			{"role": "system", "content": semanticComparePrompt},
+			{"role": "user", "content": "Reply A:\n" + replies[0] + "\n\nReply B:\n" + replies[1]},
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+	baseURL := endpoint
+	if original.Endpoint.Valid {
+		baseURL = original.Endpoint.String
+	}
+	compareRequest, err := http.NewRequest(http.MethodPost, baseURL+"/v1/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	compareRequest.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		compareRequest.Header.Set("Authorization", "Bearer "+key)
+	}
+	response, err := httpClient.Do(compareRequest)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return 0, err
+	}
+	if response.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("comparing with %s: %s: %s", model, response.Status, data)
+	}
+	content := gjson.GetBytes(data, "choices.0.message.content").String()
+	score := gjson.Get(content, "score")
+	if !gjson.Valid(content) || score.Type != gjson.Number {
+		return 0, errors.New("comparing with " + model + ": no score in the reply " + content)
+	}
+	if value := score.Float(); value >= 0 && value <= 1 {
+		return value, nil
+	}
+	return 0, fmt.Errorf("comparing with %s: score %s is not between 0 and 1", model, score.Raw)
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/mattn/go-sqlite3"
+	"github.com/spf13/cobra"
+)
+
+func statsCommand() *cobra.Command {
+	var (
+		predicates []string
+		drift      bool
+		prices     tokenPrices
+		percentile bool
+		byModel    bool
+		since      string
+		until      string
+	)
+	cmd := &cobra.Command{
+		Use:         "stats",
+		Short:       "Show statistics of Moonshot AI requests",
+		Annotations: map[string]string{databaseAnnotation: databaseRead},
+		Run: func(cmd *cobra.Command, args []string) {
+			for _, value := range []string{since, until} {
+				if value == "" {
+					continue
+				}
+				if err := checkDateTime(value); err != nil {
+					logFatal(err)
+				}
+			}
+			// created_at is compared as text, in the same way as the queries
+			// of export --since/--until.
+			if since != "" {
+				predicates = append(predicates, "created_at >= '"+since+"'")
+			}
+			if until != "" {
+				predicates = append(predicates, "created_at < '"+until+"'")
+			}
+			var predicate string
+			if parsed, err := Predicates(predicates).Parse(); err != nil {
+				logFatal(fmt.Errorf("predicate: %w", err))
+			} else {
+				predicate = parsed
+			}
+			requests, err := persistence.ListRequests(0, false, predicate)
+			if err != nil {
+				if sqliteErr := new(sqlite3.Error); errors.As(err, sqliteErr) {
+					logFatal(sqliteErr)
+				}
+				logFatal(err)
+			}
+			// ListRequests returns the latest request first, while statistics
+			// are computed in the order in which requests are made.
+			slices.Reverse(requests)
+			if percentile {
+				renderLatencyPercentiles(requests, byModel)
+				return
+			}
+			if drift {
+				renderDrift(requests)
+				return
+			}
+			renderSummary(requests, prices)
+		},
+	}
+	flags := cmd.PersistentFlags()
+	flags.StringArrayVarP(&predicates, "predicate", "p", nil, "predicate is used to set the conditions for query requests")
+	flags.BoolVar(&drift, "drift", false, "report when the system_fingerprint of a model changed over time")
+	flags.Float64Var(&prices.Prompt, "price-prompt", 0, "price per 1000 prompt tokens, shows the cost when set")
+	flags.Float64Var(&prices.Completion, "price-completion", 0, "price per 1000 completion tokens, shows the cost when set")
+	flags.BoolVar(&percentile, "latency-percentiles", false, "report p50/p90/p99 of the latency of requests")
+	flags.BoolVar(&byModel, "by-model", false, "with --latency-percentiles, report percentiles of each model")
+	flags.StringVar(&since, "since", "", "only count requests made at or after this time, YYYY-mm-dd or YYYY-mm-dd HH:MM:SS")
+	flags.StringVar(&until, "until", "", "only count requests made before this time, YYYY-mm-dd or YYYY-mm-dd HH:MM:SS")
+	cmd.MarkFlagsMutuallyExclusive("drift", "latency-percentiles")
+	return cmd
+}
+
+// tokenPrices are prices per 1000 tokens.
+type tokenPrices struct {
+	Prompt     float64 `toml:"prompt_price"`
+	Completion float64 `toml:"completion_price"`
+}
+
+func (p tokenPrices) isSet() bool {
+	return p.Prompt > 0 || p.Completion > 0
+}
+
+type modelSummary struct {
+	Model    string
+	Requests int64
+	Errors   int64
+	Latency  time.Duration
+	Turns    int64
+	Cost     float64
+}
+
+func renderSummary(requests []*Request, prices tokenPrices) {
+	var (
+		models    []string
+		summaries = make(map[string]*modelSummary)
+	)
+	for _, request := range requests {
+		model := request.ModelName()
+		summary, ok := summaries[model]
+		if !ok {
+			summary = &modelSummary{Model: model}
+			summaries[model] = summary
+			models = append(models, model)
+		}
+		summary.Requests++
+		if request.HasError() {
+			summary.Errors++
+		}
+		summary.Latency += time.Duration(request.Latency.Int64)
+		summary.Turns += int64(request.ConversationLength())
+		// Requests without usage, such as failed requests, cost nothing.
+		if cost, err := request.TotalCost(prices.Prompt, prices.Completion); err == nil {
+			summary.Cost += cost
+		}
+	}
+	slices.Sort(models)
+	header := table.Row{
+		"model",
+		"requests",
+		"errors",
+		"error_rate",
+		"avg_latency",
+		"avg_turns",
+	}
+	if prices.isSet() {
+		header = append(header, "cost")
+	}
+	t.AppendHeader(header)
+	var totalCost float64
+	for _, model := range models {
+		summary := summaries[model]
+		row := table.Row{
+			summary.Model,
+			strconv.FormatInt(summary.Requests, 10),
+			strconv.FormatInt(summary.Errors, 10),
+			strconv.FormatFloat(float64(summary.Errors)/float64(summary.Requests)*100, 'f', 2, 64) + "%",
+			strconv.FormatFloat((summary.Latency/time.Duration(summary.Requests)).Seconds(), 'f', 2, 64) + "s",
+			strconv.FormatFloat(float64(summary.Turns)/float64(summary.Requests), 'f', 2, 64),
+		}
+		if prices.isSet() {
+			row = append(row, strconv.FormatFloat(summary.Cost, 'f', 4, 64))
+			totalCost += summary.Cost
+		}
+		t.AppendRow(row)
+	}
+	if prices.isSet() {
+		t.AppendFooter(table.Row{"total", "", "", "", "", "", strconv.FormatFloat(totalCost, 'f', 4, 64)})
+	}
+	t.Render()
+}
+
+// latencyPercentiles are the percentiles reported by stats --latency-percentiles.
+var latencyPercentiles = []int{50, 90, 99}
+
+// percentileOf returns the p-th percentile of the sorted latencies with the
+// nearest-rank method.
+func percentileOf(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	return sorted[max(rank, 1)-1]
+}
+
+// renderLatencyPercentiles prints the latency percentiles of all requests, or of
+// each model if byModel is set, requests without latency are excluded.
+func renderLatencyPercentiles(requests []*Request, byModel bool) {
+	var (
+		groups    []string
+		latencies = make(map[string][]time.Duration)
+	)
+	for _, request := range requests {
+		if !request.Latency.Valid {
+			continue
+		}
+		var group string
+		if byModel {
+			group = request.ModelName()
+		}
+		if _, ok := latencies[group]; !ok {
+			groups = append(groups, group)
+		}
+		latencies[group] = append(latencies[group], time.Duration(request.Latency.Int64))
+	}
+	slices.Sort(groups)
+	header := table.Row{"requests"}
+	for _, p := range latencyPercentiles {
+		header = append(header, "p"+strconv.Itoa(p))
+	}
+	if byModel {
+		header = append(table.Row{"model"}, header...)
+	}
+	t.AppendHeader(header)
+	for _, group := range groups {
+		sorted := latencies[group]
+		slices.Sort(sorted)
+		row := table.Row{strconv.Itoa(len(sorted))}
+		for _, p := range latencyPercentiles {
+			row = append(row, strconv.FormatFloat(percentileOf(sorted, p).Seconds(), 'f', 3, 64)+"s")
+		}
+		if byModel {
+			row = append(table.Row{group}, row...)
+		}
+		t.AppendRow(row)
+	}
+	t.Render()
+}
+
+// renderDrift prints a row each time the system_fingerprint returned for a model
+// differs from the previous one, requests without a fingerprint are excluded.
+func renderDrift(requests []*Request) {
+	fingerprints := make(map[string]string)
+	t.AppendHeader(table.Row{
+		"id",
+		"model",
+		"from",
+		"to",
+		"changed_at",
+	})
+	for _, request := range requests {
+		if !request.SystemFingerprint.Valid || request.SystemFingerprint.String == "" {
+			continue
+		}
+		model := request.ModelName()
+		previous, seen := fingerprints[model]
+		if seen && previous != request.SystemFingerprint.String {
+			t.AppendRow(table.Row{
+				strconv.FormatInt(request.ID, 10),
+				model,
+				previous,
+				request.SystemFingerprint.String,
+				request.CreatedAt.Format(time.DateTime),
+			})
+		}
+		fingerprints[model] = request.SystemFingerprint.String
+	}
+	t.Render()
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func tagCommand() *cobra.Command {
+	var (
+		id        int64
+		chatcmpl  string
+		requestID string
+		goodCase  bool
+		badCase   bool
+		unset     bool
+	)
+	cmd := &cobra.Command{
+		Use:         "tag [tags...]",
+		Short:       "Tag or categorize a Moonshot AI request, or print its tags and category",
+		Annotations: map[string]string{databaseAnnotation: databaseRead},
+		Run: func(cmd *cobra.Command, args []string) {
+			request, err := selectRequest(id, chatcmpl, requestID, "", "")
+			if err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					logFatal(sql.ErrNoRows)
+				}
+				logFatal(err)
+			}
+			categorize := goodCase || badCase || unset
+			if !categorize && len(args) == 0 {
+				if request.Category.Valid {
+					fmt.Println("category: " + request.Category.String)
+				}
+				if len(request.Tags) > 0 {
+					fmt.Println("tags: " + strings.Join(request.Tags, ", "))
+				}
+				return
+			}
+			if err = checkWritable("tag requests"); err != nil {
+				logFatal(err)
+			}
+			var category sql.NullString
+			switch {
+			case goodCase:
+				category = sql.NullString{String: "goodcase", Valid: true}
+			case badCase:
+				category = sql.NullString{String: "badcase", Valid: true}
+			}
+			if categorize && category != request.Category {
+				if err = persistence.SetCategory(request.ID, category); err != nil {
+					logFatal(err)
+				}
+			}
+			if request.Tags.Add(args...) {
+				if err = persistence.SetTags(request.ID, request.Tags); err != nil {
+					logFatal(err)
+				}
+			}
+		},
+	}
+	flags := cmd.PersistentFlags()
+	flags.Int64Var(&id, "id", 0, "row id")
+	flags.StringVar(&chatcmpl, "chatcmpl", "", "chatcmpl")
+	flags.StringVar(&requestID, "requestid", "", "request id returned from Moonshot AI")
+	flags.BoolVar(&goodCase, "good", false, "categorize the request as a good case")
+	flags.BoolVar(&badCase, "bad", false, "categorize the request as a bad case")
+	flags.BoolVar(&unset, "unset-category", false, "unset the category of the request")
+	cmd.MarkFlagsOneRequired("id", "chatcmpl", "requestid")
+	cmd.MarkFlagsMutuallyExclusive("good", "bad", "unset-category")
+	return cmd
+}
@@ -0,0 +1,16 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd
+
+package main
+
+import "errors"
+
+var errTerminalUnsupported = errors.New("interactive terminal is not supported on this platform")
+
+func makeRaw(fd int) (restore func(), err error) {
+	return nil, errTerminalUnsupported
+}
+
+func terminalSize(fd int) (width, height int, err error) {
+	return 0, 0, errTerminalUnsupported
+}
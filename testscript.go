@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxAssertedContent is the maximum number of characters of the original reply
+// that the response of a test script is expected to contain.
+const maxAssertedContent = 80
+
+// writeTestScript writes the request as a shell script, which sends it with
+// curl and fails unless the response has the original status, and contains the
+// beginning of the original reply if assertContent is set.
+func writeTestScript(w io.Writer, request *Request, assertContent bool, apiKeyEnv, baseURLEnv string, canonicalHeaders bool) error {
+	var curl bytes.Buffer
+	if err := writeCurlCommand(&curl, request, "", apiKeyEnv, baseURLEnv, canonicalHeaders); err != nil {
+		return err
+	}
+	status := strconv.FormatInt(request.ResponseStatusCode.Int64, 10)
+	var script strings.Builder
+	script.WriteString("#!/bin/sh\n")
+	script.WriteString("# Exported by moonpalace from the request " + request.Ident() + ".\n")
+	script.WriteString("set -eu\n")
+	script.WriteString("response=$(mktemp)\n")
+	script.WriteString("trap 'rm -f \"$response\"' EXIT\n")
+	script.WriteString("status=$(curl -sS -o \"$response\" -w '%{http_code}' ")
+	script.WriteString(strings.TrimSuffix(strings.TrimPrefix(curl.String(), "curl "), "\n"))
+	script.WriteString(")\n")
+	script.WriteString("if [ \"$status\" != " + status + " ]; then\n")
+	script.WriteString("\techo \"expected status " + status + ", got $status\" >&2\n")
+	script.WriteString("\tcat \"$response\" >&2\n")
+	script.WriteString("\texit 1\n")
+	script.WriteString("fi\n")
+	if assertContent {
+		if content := assertedContent(request); content != "" {
+			quoted := "'" + strings.ReplaceAll(content, "'", `'"'"'`) + "'"
+			script.WriteString("if ! grep -qF -- " + quoted + " \"$response\"; then\n")
+			script.WriteString("\techo 'expected the response to contain:' " + quoted + " >&2\n")
+			script.WriteString("\tcat \"$response\" >&2\n")
+			script.WriteString("\texit 1\n")
+			script.WriteString("fi\n")
+		} else {
+			logWarning("no content is asserted for " + request.Ident() +
+				", whose response is streamed or has no reply to match")
+		}
+	}
+	script.WriteString("echo ok\n")
+	_, err := io.WriteString(w, script.String())
+	return err
+}
+
+// assertedContent returns the beginning of the original reply that is matched
+// against the raw response body, up to the first character escaped in JSON
+// strings. Streamed replies are split across events, and so are never matched.
+func assertedContent(request *Request) string {
+	if request.ResponseContentType.String == "text/event-stream" {
+		return ""
+	}
+	reply, err := request.AssistantReply()
+	if err != nil {
+		return ""
+	}
+	if i := strings.IndexFunc(reply, func(r rune) bool {
+		return r < ' ' || r == '"' || r == '\\' || r == utf8.RuneError
+	}); i >= 0 {
+		reply = reply[:i]
+	}
+	if utf8.RuneCountInString(reply) > maxAssertedContent {
+		reply = string([]rune(reply)[:maxAssertedContent])
+	}
+	return strings.TrimSpace(reply)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"io"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+var transcriptRoles = map[string]string{
+	"system":    "System",
+	"user":      "User",
+	"assistant": "Assistant",
+	"tool":      "Tool",
+}
+
+// reasoningFields are the fields of assistant messages which carry the thinking
+// of reasoning models, apart from the content of the final answer.
+var reasoningFields = []string{"reasoning_content", "reasoning"}
+
+// writeTranscript writes the messages of a chat request and the reply in the
+// response as plain text, such as "User: ..." and "Assistant: ...", the
+// reasoning of assistant messages is written before their content, such as
+// "Assistant (reasoning): ...", unless hideReasoning is set.
+func writeTranscript(w io.Writer, request *Request, hideReasoning bool) error {
+	var transcript strings.Builder
+	writeTurn := func(turn transcriptTurn) {
+		if turn.Reasoning != "" && !hideReasoning {
+			transcript.WriteString(turn.Role)
+			transcript.WriteString(" (reasoning): ")
+			transcript.WriteString(strings.TrimSpace(turn.Reasoning))
+			transcript.WriteString("\n")
+		}
+		transcript.WriteString(turn.Role)
+		transcript.WriteString(": ")
+		transcript.WriteString(strings.TrimSpace(unfence(turn.Content)))
+		transcript.WriteString("\n")
+	}
+	for _, turn := range transcriptTurns(request) {
+		writeTurn(turn)
+	}
+	if reply, ok := transcriptReply(request); ok {
+		writeTurn(reply)
+	}
+	transcript.WriteString("\n")
+	_, err := io.WriteString(w, transcript.String())
+	return err
+}
+
+// transcriptTurn is a message of the conversation, or a tool call in one.
+type transcriptTurn struct {
+	Role      string
+	Content   string
+	Reasoning string
+}
+
+// transcriptTurns returns the messages of a chat request, each tool call of a
+// message is a turn of its own.
+func transcriptTurns(request *Request) []transcriptTurn {
+	var turns []transcriptTurn
+	gjson.Get(request.RequestBody.String, "messages").ForEach(func(_, message gjson.Result) bool {
+		role, ok := transcriptRoles[message.Get("role").String()]
+		if !ok {
+			role = message.Get("role").String()
+		}
+		if content := transcriptContent(message.Get("content")); content != "" {
+			turns = append(turns, transcriptTurn{Role: role, Content: content, Reasoning: reasoningContent(message)})
+		}
+		message.Get("tool_calls").ForEach(func(_, toolCall gjson.Result) bool {
+			turns = append(turns, transcriptTurn{Role: role, Content: "(calls " + toolCall.Get("function.name").String() + ") " + toolCall.Get("function.arguments").String()})
+			return true
+		})
+		return true
+	})
+	return turns
+}
+
+// transcriptReply returns the reply in the response, or the status if the
+// request failed, streaming responses are merged into a completion first.
+func transcriptReply(request *Request) (turn transcriptTurn, ok bool) {
+	if request.ResponseContentType.String == "text/event-stream" && !gjson.Valid(request.ResponseBody.String) {
+		request.ResponseBody.String = mergeCompletion(request.ResponseBody.String)
+	}
+	if reply, err := request.AssistantReply(); err == nil {
+		message := gjson.Get(request.ResponseBody.String, "choices.0.message")
+		if !message.Exists() {
+			// Merged streaming responses keep the message in delta.
+			message = gjson.Get(request.ResponseBody.String, "choices.0.delta")
+		}
+		return transcriptTurn{Role: "Assistant", Content: reply, Reasoning: reasoningContent(message)}, true
+	} else if request.HasError() {
+		return transcriptTurn{Role: "Error", Content: request.Status()}, true
+	}
+	return transcriptTurn{}, false
+}
+
+// reasoningContent returns the reasoning of an assistant message, which is
+// empty for other messages and for models that do not reason.
+func reasoningContent(message gjson.Result) string {
+	for _, field := range reasoningFields {
+		if reasoning := message.Get(field).String(); reasoning != "" {
+			return reasoning
+		}
+	}
+	return ""
+}
+
+// transcriptContent returns the text of a message, images and other non-text
+// content parts are replaced with their type in brackets.
+func transcriptContent(content gjson.Result) string {
+	if !content.IsArray() {
+		return content.String()
+	}
+	var parts []string
+	content.ForEach(func(_, part gjson.Result) bool {
+		if typ := part.Get("type").String(); typ == "text" {
+			parts = append(parts, part.Get("text").String())
+		} else {
+			parts = append(parts, "["+typ+"]")
+		}
+		return true
+	})
+	return strings.Join(parts, "\n")
+}
+
+// unfence removes the ``` lines around code blocks, keeping the code itself.
+func unfence(text string) string {
+	if !strings.Contains(text, "```") {
+		return text
+	}
+	lines := strings.Split(text, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if !strings.HasPrefix(strings.TrimSpace(line), "```") {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
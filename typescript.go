@@ -0,0 +1,190 @@
+package main
+
+import (
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// tsShape is the structure inferred from one or more JSON values, a value seen
+// with different types becomes a union type.
+type tsShape struct {
+	primitives []string
+	object     *tsObject
+	element    *tsShape
+}
+
+// tsObject keeps the keys of objects in the order they are first seen, keys
+// missing in some of the objects become optional fields.
+type tsObject struct {
+	keys   []string
+	fields map[string]*tsShape
+	seen   map[string]int
+	count  int
+}
+
+func (s *tsShape) addPrimitive(primitive string) {
+	for _, seen := range s.primitives {
+		if seen == primitive {
+			return
+		}
+	}
+	s.primitives = append(s.primitives, primitive)
+}
+
+func (s *tsShape) add(value gjson.Result) {
+	switch value.Type {
+	case gjson.String:
+		s.addPrimitive("string")
+	case gjson.Number:
+		s.addPrimitive("number")
+	case gjson.True, gjson.False:
+		s.addPrimitive("boolean")
+	case gjson.Null:
+		s.addPrimitive("null")
+	case gjson.JSON:
+		if value.IsArray() {
+			if s.element == nil {
+				s.element = new(tsShape)
+			}
+			value.ForEach(func(_, element gjson.Result) bool {
+				s.element.add(element)
+				return true
+			})
+			return
+		}
+		if s.object == nil {
+			s.object = &tsObject{
+				fields: make(map[string]*tsShape),
+				seen:   make(map[string]int),
+			}
+		}
+		s.object.count++
+		value.ForEach(func(key, field gjson.Result) bool {
+			name := key.String()
+			shape, ok := s.object.fields[name]
+			if !ok {
+				shape = new(tsShape)
+				s.object.keys = append(s.object.keys, name)
+				s.object.fields[name] = shape
+			}
+			shape.add(field)
+			s.object.seen[name]++
+			return true
+		})
+	}
+}
+
+// tsGenerator renders shapes as TypeScript types, objects are declared as
+// interfaces named after the path leading to them, such as RequestBodyMessage.
+type tsGenerator struct {
+	interfaces []string
+	names      map[string]struct{}
+}
+
+var tsIdentifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+func (g *tsGenerator) typeOf(name string, s *tsShape) string {
+	var union []string
+	if s.object != nil {
+		union = append(union, g.declare(name, s.object))
+	}
+	if s.element != nil {
+		element := g.typeOf(singular(name), s.element)
+		if strings.Contains(element, " | ") {
+			element = "(" + element + ")"
+		}
+		union = append(union, element+"[]")
+	}
+	union = append(union, s.primitives...)
+	if len(union) == 0 {
+		return "unknown"
+	}
+	return strings.Join(union, " | ")
+}
+
+func (g *tsGenerator) declare(name string, object *tsObject) string {
+	if _, taken := g.names[name]; taken {
+		for i := 2; ; i++ {
+			if _, taken = g.names[name+strconv.Itoa(i)]; !taken {
+				name += strconv.Itoa(i)
+				break
+			}
+		}
+	}
+	g.names[name] = struct{}{}
+	// Reserve the position so that an interface is declared before the
+	// interfaces of its fields.
+	index := len(g.interfaces)
+	g.interfaces = append(g.interfaces, "")
+	var declaration strings.Builder
+	declaration.WriteString("export interface " + name + " {\n")
+	for _, key := range object.keys {
+		field := key
+		if !tsIdentifier.MatchString(field) {
+			field = strconv.Quote(field)
+		}
+		if object.seen[key] < object.count {
+			field += "?"
+		}
+		declaration.WriteString("    " + field + ": " + g.typeOf(name+pascalCase(key), object.fields[key]) + ";\n")
+	}
+	declaration.WriteString("}\n")
+	g.interfaces[index] = declaration.String()
+	return name
+}
+
+func pascalCase(key string) string {
+	var builder strings.Builder
+	for _, word := range strings.FieldsFunc(key, func(r rune) bool {
+		return !('0' <= r && r <= '9' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z')
+	}) {
+		builder.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	return builder.String()
+}
+
+// singular names the elements of an array, Messages becomes Message and
+// names not ending with "s" get the Item suffix.
+func singular(name string) string {
+	if strings.HasSuffix(name, "s") && !strings.HasSuffix(name, "ss") {
+		return strings.TrimSuffix(name, "s")
+	}
+	return name + "Item"
+}
+
+// writeTypeScript infers TypeScript interfaces named RequestBody and ResponseBody
+// from the bodies of a request, streaming responses are merged first.
+func writeTypeScript(w io.Writer, request *Request) error {
+	generator := &tsGenerator{names: make(map[string]struct{})}
+	responseBody := request.ResponseBody.String
+	if request.ResponseContentType.String == "text/event-stream" && !gjson.Valid(responseBody) {
+		responseBody = mergeCompletion(responseBody)
+	}
+	var builder strings.Builder
+	builder.WriteString("// Generated by MoonPalace from " + request.Ident() + "\n\n")
+	for _, body := range []struct {
+		name string
+		data string
+	}{
+		{"RequestBody", request.RequestBody.String},
+		{"ResponseBody", responseBody},
+	} {
+		shape := new(tsShape)
+		if gjson.Valid(body.data) {
+			shape.add(gjson.Parse(body.data))
+		}
+		if expr := generator.typeOf(body.name, shape); expr != body.name {
+			builder.WriteString("export type " + body.name + " = " + expr + ";\n\n")
+		}
+		for _, declaration := range generator.interfaces {
+			builder.WriteString(declaration + "\n")
+		}
+		generator.interfaces = generator.interfaces[:0]
+	}
+	_, err := io.WriteString(w, builder.String())
+	return err
+}
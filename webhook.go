@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	defaultErrorStatusThreshold = 500
+
+	errorSnippetLength = 512
+)
+
+// ErrorNotification is the JSON payload posted to the error webhook.
+type ErrorNotification struct {
+	ID     int64  `json:"id"`
+	Status int    `json:"status"`
+	Model  string `json:"model"`
+	Error  string `json:"error"`
+}
+
+var webhookClient = &http.Client{
+	Timeout: 10 * time.Second,
+}
+
+// errorSnippet shortens the error message or response body to errorSnippetLength
+// bytes without splitting a UTF-8 character.
+func errorSnippet(message string) string {
+	if len(message) <= errorSnippetLength {
+		return message
+	}
+	cut := errorSnippetLength
+	for cut > 0 && !utf8.RuneStart(message[cut]) {
+		cut--
+	}
+	return message[:cut] + "..."
+}
+
+// notifyError posts the notification to the webhook, a response status other
+// than 2xx is considered a failure.
+func notifyError(webhook string, notification *ErrorNotification) error {
+	payload, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+	response, err := webhookClient.Post(webhook, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("error webhook responded with %s", response.Status)
+	}
+	return nil
+}